@@ -11,6 +11,8 @@ require (
 	github.com/goccy/go-yaml v1.18.0
 	github.com/joho/godotenv v1.5.1
 	github.com/prometheus/client_golang v1.15.0
+	github.com/shirou/gopsutil/v4 v4.25.10
+	github.com/stretchr/testify v1.11.1
 	github.com/valyala/fasthttp v1.40.0
 	golang.org/x/time v0.3.0
 )
@@ -34,8 +36,6 @@ require (
 	github.com/prometheus/common v0.42.0 // indirect
 	github.com/prometheus/procfs v0.9.0 // indirect
 	github.com/ryanuber/go-glob v1.0.0 // indirect
-	github.com/shirou/gopsutil/v4 v4.25.10 // indirect
-	github.com/stretchr/testify v1.11.1 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	github.com/yusufpapurcu/wmi v1.2.4 // indirect
 	golang.org/x/crypto v0.42.0 // indirect
@@ -7,17 +7,21 @@ import (
 	"github.com/valyala/fasthttp"
 
 	"progressdb/pkg/ingest"
+	"progressdb/pkg/ingest/hooks"
 	"progressdb/pkg/ingest/queue"
 	"progressdb/pkg/ingest/tracking"
 	"progressdb/pkg/ingest/wally"
 	"progressdb/pkg/state/logger"
 	"progressdb/pkg/state/sensor"
 	"progressdb/pkg/state/telemetry"
+	"progressdb/pkg/store/db/auditdb"
 	indexdb "progressdb/pkg/store/db/indexdb"
 	storedb "progressdb/pkg/store/db/storedb"
+	"progressdb/pkg/store/features/audit"
 	"progressdb/pkg/store/migrations"
 
 	"progressdb/internal/retention"
+	"progressdb/internal/scheduler"
 	"progressdb/pkg/config"
 	"progressdb/pkg/state"
 	"progressdb/pkg/store/encryption"
@@ -25,6 +29,7 @@ import (
 
 type App struct {
 	retentionCancel context.CancelFunc
+	schedulerCancel context.CancelFunc
 	version         string
 	commit          string
 	buildDate       string
@@ -64,6 +69,9 @@ func New(version, commit, buildDate string) (*App, error) {
 		runtimeCfg.BackendKeys[k] = struct{}{}
 	}
 	for _, k := range cfg.Server.APIKeys.Signing {
+		if _, dup := runtimeCfg.SigningKeys[k]; !dup {
+			runtimeCfg.SigningKeyList = append(runtimeCfg.SigningKeyList, k)
+		}
 		runtimeCfg.SigningKeys[k] = struct{}{}
 	}
 	config.SetRuntime(runtimeCfg)
@@ -73,6 +81,11 @@ func New(version, commit, buildDate string) (*App, error) {
 		return nil, fmt.Errorf("invalid encryption fields: %w", err)
 	}
 
+	// register content policy hooks
+	if err := hooks.Configure(cfg.ContentPolicy); err != nil {
+		return nil, fmt.Errorf("invalid content policy: %w", err)
+	}
+
 	a := &App{version: version, commit: commit, buildDate: buildDate}
 	return a, nil
 }
@@ -122,11 +135,22 @@ func (a *App) Run(ctx context.Context) error {
 	}
 	logger.Info("database_opened", "path", state.PathsVar.Index)
 
+	// open auditdb
+	if err := auditdb.Open(state.PathsVar.Audit); err != nil {
+		return fmt.Errorf("failed to open pebble at %s: %w", state.PathsVar.Audit, err)
+	}
+	logger.Info("database_opened", "path", state.PathsVar.Audit)
+
 	// run version checks and migrations after databases are opened
 	if _, err := migrations.Run(ctx, a.version); err != nil {
 		return fmt.Errorf("migrations run failed: %w", err)
 	}
 
+	// audit records staged by batches applied before the last shutdown
+	if err := audit.Sync(); err != nil {
+		logger.Error("audit_sync_failed", "error", err)
+	}
+
 	// start retention scheduler if enabled
 	if cancel, err := retention.Start(ctx); err != nil {
 		return err
@@ -156,6 +180,13 @@ func (a *App) Run(ctx context.Context) error {
 	ingestor.Start()
 	a.ingestIngestor = ingestor
 
+	// start scheduled message delivery
+	if cancel, err := scheduler.Start(ctx); err != nil {
+		return err
+	} else {
+		a.schedulerCancel = cancel
+	}
+
 	// start hardware sensor
 	sensor := sensor.NewSensorFromConfig()
 	sensor.Start()
@@ -173,14 +204,11 @@ func (a *App) Run(ctx context.Context) error {
 
 // sets into state
 func initFieldPolicy() error {
-	cfg := config.GetConfig()
-	fieldPaths := cfg.Encryption.Fields
-	if len(fieldPaths) == 0 {
-		return nil
-	}
-	fields := make([]string, 0, len(fieldPaths))
-	for _, path := range fieldPaths {
-		fields = append(fields, path)
+	for _, namespace := range config.Namespaces() {
+		_, fields := config.EncryptionPolicy(namespace)
+		if err := encryption.SetEncryptionFieldPolicy(namespace, fields); err != nil {
+			return err
+		}
 	}
-	return encryption.SetEncryptionFieldPolicy(fields)
+	return nil
 }
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 	"strings"
 	"time"
@@ -69,9 +70,18 @@ func (a *App) startHTTP(_ context.Context) <-chan error {
 		RPS:            cfg.Server.RateLimit.RPS,
 		Burst:          cfg.Server.RateLimit.Burst,
 		IPWhitelist:    append([]string{}, cfg.Server.IPWhitelist...),
+		AdminIPs:       append([]string{}, cfg.Server.IPAllowlists.Admin...),
+		BackendIPs:     append([]string{}, cfg.Server.IPAllowlists.Backend...),
+		FrontendIPs:    append([]string{}, cfg.Server.IPAllowlists.Frontend...),
+		TrustedProxies: append([]string{}, cfg.Server.TrustedProxies...),
 		BackendKeys:    map[string]struct{}{},
 		FrontendKeys:   map[string]struct{}{},
 		AdminKeys:      map[string]struct{}{},
+		KeyNamespaces:  map[string]string{},
+		Limits:         cfg.Limits,
+		AuthFailures:   config.AuditSettings().AuthFailures,
+		CertAdmins:     map[string]struct{}{},
+		CertBackends:   map[string]struct{}{},
 	}
 	// fill backend keys
 	for _, k := range cfg.Server.APIKeys.Backend {
@@ -85,6 +95,28 @@ func (a *App) startHTTP(_ context.Context) <-chan error {
 	for _, k := range cfg.Server.APIKeys.Admin {
 		secCfg.AdminKeys[k] = struct{}{}
 	}
+	// fill the keys of each named namespace
+	for _, ns := range cfg.Namespaces {
+		for _, k := range ns.APIKeys.Backend {
+			secCfg.BackendKeys[k] = struct{}{}
+			secCfg.KeyNamespaces[k] = ns.ID
+		}
+		for _, k := range ns.APIKeys.Frontend {
+			secCfg.FrontendKeys[k] = struct{}{}
+			secCfg.KeyNamespaces[k] = ns.ID
+		}
+		for _, k := range ns.APIKeys.Admin {
+			secCfg.AdminKeys[k] = struct{}{}
+			secCfg.KeyNamespaces[k] = ns.ID
+		}
+	}
+	// fill client certificate names
+	for _, n := range cfg.Server.TLS.ClientRoles.Admin {
+		secCfg.CertAdmins[n] = struct{}{}
+	}
+	for _, n := range cfg.Server.TLS.ClientRoles.Backend {
+		secCfg.CertBackends[n] = struct{}{}
+	}
 
 	// http router registration
 	r := router.New()
@@ -126,12 +158,30 @@ func (a *App) startHTTP(_ context.Context) <-chan error {
 
 	// start server in goroutine and return error channel
 	errCh := make(chan error, 1)
+	var tlsCfg *tls.Config
+	if cfg.Server.TLS.Enabled() {
+		c, err := buildTLSConfig(cfg.Server.TLS)
+		if err != nil {
+			errCh <- err
+			return errCh
+		}
+		tlsCfg = c
+	}
 	go func() {
-		// fasthttp has no direct TLS helper like ListenAndServeTLS here; for
-		// simplicity run plain TCP. TLS can be handled by a proxy in production.
 		cfg := config.GetConfig()
 		addr := cfg.Addr()
 
+		// tls terminates here unless the server sits behind a proxy that does it
+		if tlsCfg != nil {
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			errCh <- a.srvFast.Serve(tls.NewListener(listener, tlsCfg))
+			return
+		}
+
 		// Check if user explicitly configured IPv6 address
 		// Default to IPv4 for maximum performance, opt-in to IPv6 with explicit config
 		if cfg.Server.Address == "::" || (strings.Contains(cfg.Server.Address, ":") && cfg.Server.Address != "") {
@@ -8,7 +8,7 @@ import (
 
 func (a *App) Shutdown(ctx context.Context) error {
 	a.state = "shutting_down"
-	err := shutdown.ShutdownApp(ctx, a.srvFast, a.retentionCancel, a.ingestIngestor, a.hwSensor)
+	err := shutdown.ShutdownApp(ctx, a.srvFast, a.retentionCancel, a.schedulerCancel, a.ingestIngestor, a.hwSensor)
 	if err == nil {
 		a.state = "stopped"
 	}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"progressdb/pkg/config"
+	"progressdb/pkg/state/logger"
+)
+
+const defaultCertReloadInterval = 10 * time.Second
+
+// buildTLSConfig loads the server certificate and, when a client ca is set, the pool
+// client certificates are verified against.
+func buildTLSConfig(tc config.TLSConfig) (*tls.Config, error) {
+	interval := time.Duration(tc.ReloadInterval)
+	if interval <= 0 {
+		interval = defaultCertReloadInterval
+	}
+	reloader := &certReloader{certFile: tc.CertFile, keyFile: tc.KeyFile, interval: interval}
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+
+	out := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if tc.MinVersion == "1.3" {
+		out.MinVersion = tls.VersionTLS13
+	}
+	if tc.ClientCAFile != "" {
+		pem, err := os.ReadFile(tc.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client ca %s: no certificates found", tc.ClientCAFile)
+		}
+		out.ClientCAs = pool
+		out.ClientAuth = tls.VerifyClientCertIfGiven
+		if tc.RequireClientCert {
+			out.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return out, nil
+}
+
+// certReloader serves the certificate in certFile and keyFile, picking up replaced
+// files at most once per interval so renewals need no restart.
+type certReloader struct {
+	certFile, keyFile string
+	interval          time.Duration
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time // newest modification time of the two files when loaded
+	checked time.Time
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	cert, due := r.cert, time.Since(r.checked) >= r.interval
+	r.mu.RUnlock()
+	if due {
+		r.mu.Lock()
+		if time.Since(r.checked) >= r.interval {
+			if err := r.reloadIfChanged(); err != nil {
+				// keep serving the certificate we have
+				logger.Warn("tls_cert_reload_failed", "error", err)
+			}
+			cert = r.cert
+		}
+		r.mu.Unlock()
+	}
+	return cert, nil
+}
+
+func (r *certReloader) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.modTime = time.Time{}
+	return r.reloadIfChanged()
+}
+
+// reloadIfChanged reads the files when either was modified since the last load.
+// Callers hold the lock.
+func (r *certReloader) reloadIfChanged() error {
+	r.checked = time.Now()
+	modTime, err := newestModTime(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	if r.cert != nil && !modTime.After(r.modTime) {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load tls certificate: %w", err)
+	}
+	if r.cert != nil {
+		logger.Info("tls_cert_reloaded", "cert_file", r.certFile)
+	}
+	r.cert, r.modTime = &cert, modTime
+	return nil
+}
+
+func newestModTime(paths ...string) (time.Time, error) {
+	var newest time.Time
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("stat tls file: %w", err)
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+	return newest, nil
+}
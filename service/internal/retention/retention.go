@@ -4,16 +4,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/adhocore/gronx"
+	"github.com/cockroachdb/pebble"
 
 	"progressdb/pkg/config"
 	"progressdb/pkg/models"
 	"progressdb/pkg/state/logger"
 	"progressdb/pkg/store/db/indexdb"
 	storedb "progressdb/pkg/store/db/storedb"
+	"progressdb/pkg/store/features/audit"
+	"progressdb/pkg/store/features/messages"
+	"progressdb/pkg/store/features/policies"
+	"progressdb/pkg/store/features/scheduled"
 	"progressdb/pkg/store/iterator/admin/ki"
 	"progressdb/pkg/store/keys"
 	"progressdb/pkg/store/pagination"
@@ -57,16 +65,18 @@ func Start(ctx context.Context) (context.CancelFunc, error) {
 	return cancel, nil
 }
 
-func RunImmediate() error {
+// RunImmediate runs a retention pass now. A dry run reports what would be purged
+// without deleting anything.
+func RunImmediate(dryRun bool) (*Run, error) {
 	managerMutex.Lock()
 	rm := globalManager
 	managerMutex.Unlock()
 
 	if rm == nil {
-		return fmt.Errorf("retention manager not initialized - call Start() first")
+		return nil, fmt.Errorf("retention manager not initialized - call Start() first")
 	}
 
-	return rm.runPurge()
+	return rm.runPurge(TriggerManual, dryRun)
 }
 
 func (rm *RetentionManager) scheduleLoop() {
@@ -118,22 +128,49 @@ func (rm *RetentionManager) runJob() {
 		rm.mutex.Unlock()
 	}()
 
-	if err := rm.runPurge(); err != nil {
+	if _, err := rm.runPurge(TriggerCron, false); err != nil {
 		logger.Error("[RETENTION] retention_run_error", "error", err)
 	}
 }
 
-func (rm *RetentionManager) runPurge() error {
-	runID := fmt.Sprintf("run-%d", timeutil.Now().UnixNano())
-	logger.Info("[RETENTION] retention_run_start", "run_id", runID)
+func (rm *RetentionManager) runPurge(trigger string, dryRun bool) (*Run, error) {
+	run := newRun(trigger, dryRun)
+	logger.Info("[RETENTION] retention_run_start", "run_id", run.ID, "dry_run", dryRun)
+	defer func() {
+		run.FinishedAt = time.Now().UTC()
+		if err := saveRun(run); err != nil {
+			logger.Error("[RETENTION] retention_run_save_failed", "run_id", run.ID, "error", err)
+		}
+		logger.Info("[RETENTION] retention_run_done", "run_id", run.ID, "dry_run", dryRun, "scanned", run.Scanned,
+			"threads", run.Threads, "messages", run.Messages, "bytes", run.Bytes, "errors", len(run.Errors))
+	}()
+
+	threads := newThreadRetention()
+	for _, namespace := range config.Namespaces() {
+		if err := rm.purgeNamespace(run, namespace, threads); err != nil {
+			return run, err
+		}
+	}
 
+	if !dryRun {
+		if err := indexdb.DeleteMessageMentions(run.purged); err != nil {
+			run.fail("delete mentions: %v", err)
+			logger.Error("[RETENTION] failed_to_delete_purged_mentions", "error", err)
+		}
+	}
+	return run, nil
+}
+
+// purgeNamespace runs the retention passes over one namespace's keys.
+func (rm *RetentionManager) purgeNamespace(run *Run, namespace string, threads *threadRetention) error {
 	keyIter := ki.NewKeyIterator(indexdb.Client)
-	deleteMarkers, _, err := keyIter.ExecuteKeyQuery(keys.GenSoftDeletePrefix(), pagination.PaginationRequest{Limit: 10000})
+	deleteMarkers, _, err := keyIter.ExecuteKeyQuery(keys.GenSoftDeletePrefix(namespace), pagination.PaginationRequest{Limit: 10000})
 	if err != nil {
+		run.fail("scan soft delete markers: %v", err)
 		return fmt.Errorf("scan soft delete markers: %w", err)
 	}
+	run.Scanned += len(deleteMarkers)
 
-	var purged int
 	for _, deleteMarkerKey := range deleteMarkers {
 		deleteMarker, err := keys.ParseSoftDeleteMarker(deleteMarkerKey)
 		if err != nil {
@@ -147,41 +184,325 @@ func (rm *RetentionManager) runPurge() error {
 		}
 
 		parsedOriginalKey, err := keys.ParseKey(originalKey)
-		if err != nil || parsedOriginalKey.Type != keys.KeyTypeThread {
+		if err != nil {
+			continue
+		}
+		if parsedOriginalKey.Type == keys.KeyTypeMessage {
+			rm.purgeDeletedMessage(run, originalKey, threads)
+			continue
+		}
+		if parsedOriginalKey.Type != keys.KeyTypeThread {
 			continue
 		}
 
 		// parsed keys = thread keys
-		data, err := storedb.GetKey(originalKey)
-		if err != nil {
+		resolved, err := threads.get(originalKey)
+		if err != nil || resolved == nil {
 			continue
 		}
-
-		var thread models.Thread
-		if err := json.Unmarshal([]byte(data), &thread); err != nil {
-			logger.Error("[RETENTION] retention_invalid_thread_json", "key", originalKey, "error", err)
+		if !resolved.eff.CanPurge() {
+			logger.Debug("[RETENTION] purge_skipped", "key", originalKey, "held", resolved.eff.Held)
 			continue
 		}
 
 		// second check
-		if thread.Deleted {
-			deletedTime := time.Unix(0, thread.UpdatedTS)
+		if resolved.thread.Deleted {
+			deletedTime := time.Unix(0, resolved.thread.UpdatedTS)
 			age := time.Since(deletedTime)
-			if age > rm.cfg.TTTL {
+			if age > resolved.eff.DeletedThreadTTL {
 				// purge thread & its associated resources
-				if err := rm.purgeThreadCompletely(originalKey); err != nil {
-					logger.Error("[RETENTION] purge_failed", "key", originalKey, "error", err)
-				} else {
-					purged++
-				}
+				rm.purgeThread(run, originalKey, "deleted_thread")
 			}
 		}
 	}
 
-	logger.Info("[RETENTION] retention_run_done", "run_id", runID, "scanned", len(deleteMarkers), "purged", purged)
+	if err := rm.purgeAgedContent(run, namespace, threads); err != nil {
+		run.fail("aged purge: %v", err)
+		logger.Error("[RETENTION] aged_purge_failed", "run_id", run.ID, "namespace", namespace, "error", err)
+	}
+
+	if err := rm.purgeExpiredMessages(run, namespace, threads); err != nil {
+		run.fail("expired purge: %v", err)
+		logger.Error("[RETENTION] expired_purge_failed", "run_id", run.ID, "namespace", namespace, "error", err)
+	}
+	return nil
+}
+
+// purgeThread removes a thread with all its content, or only records it on a dry run.
+func (rm *RetentionManager) purgeThread(run *Run, threadKey, reason string) bool {
+	messagePrefix, err := keys.GenAllThreadMessagesPrefix(threadKey)
+	if err != nil {
+		return false
+	}
+	messageKeys, err := rm.listMessageKeysBefore(messagePrefix, math.MaxInt64)
+	if err != nil {
+		run.fail("scan messages of %s: %v", threadKey, err)
+		return false
+	}
+	size := threadSize(threadKey, messagePrefix)
+
+	if !run.DryRun {
+		if err := rm.purgeThreadCompletely(threadKey); err != nil {
+			run.fail("purge thread %s: %v", threadKey, err)
+			logger.Error("[RETENTION] purge_failed", "key", threadKey, "error", err)
+			return false
+		}
+		recordPurge(run, models.AuditActionPurgeThread, threadKey, "", reason)
+	}
+	for _, messageKey := range messageKeys {
+		run.purged[messageKey] = true
+	}
+	run.recordThread(threadKey, reason, size)
+	return true
+}
+
+// purgeMessage hard deletes one message, or only records it on a dry run.
+func (rm *RetentionManager) purgeMessage(run *Run, messageKey, reason string) bool {
+	if run.purged[messageKey] {
+		return false
+	}
+	size := messageSize(messageKey)
+
+	if !run.DryRun {
+		if err := messages.PurgeMessagePermanently(messageKey); err != nil {
+			run.fail("purge message %s: %v", messageKey, err)
+			logger.Error("[RETENTION] purge_message_failed", "key", messageKey, "reason", reason, "error", err)
+			return false
+		}
+		threadTS, _ := keys.ExtractThreadKeyFromMessage(messageKey)
+		recordPurge(run, models.AuditActionPurgeMessage, keys.GenThreadKey(threadTS), messageKey, reason)
+	}
+	run.recordMessage(messageKey, reason, size)
+	return true
+}
+
+func recordPurge(run *Run, action, threadKey, target, reason string) {
+	rec := models.AuditRecord{
+		Action:    action,
+		Role:      "system",
+		Namespace: keys.NamespaceOf(threadKey),
+		Thread:    threadKey,
+		Target:    target,
+		Detail:    map[string]string{"reason": reason, "run_id": run.ID},
+	}
+	if err := audit.Record(rec); err != nil {
+		logger.Error("[RETENTION] audit_record_failed", "key", threadKey, "error", err)
+	}
+}
+
+// threadRetention resolves each thread's effective policy once per run, starting from
+// the retention settings of the thread's namespace.
+type threadRetention struct {
+	resolved map[string]*resolvedThread
+}
+
+type resolvedThread struct {
+	thread models.Thread
+	eff    policies.Effective
+}
+
+func newThreadRetention() *threadRetention {
+	return &threadRetention{resolved: make(map[string]*resolvedThread)}
+}
+
+// get returns nil when the thread no longer exists.
+func (tr *threadRetention) get(threadKey string) (*resolvedThread, error) {
+	if r, ok := tr.resolved[threadKey]; ok {
+		return r, nil
+	}
+	data, err := storedb.GetKey(threadKey)
+	if err != nil {
+		if storedb.IsNotFound(err) {
+			tr.resolved[threadKey] = nil
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	r := &resolvedThread{}
+	if err := json.Unmarshal([]byte(data), &r.thread); err != nil {
+		logger.Error("[RETENTION] retention_invalid_thread_json", "key", threadKey, "error", err)
+		return nil, err
+	}
+	r.thread.Key = threadKey
+	if r.eff, err = policies.Resolve(&r.thread, config.RetentionFor(keys.NamespaceOf(threadKey))); err != nil {
+		return nil, err
+	}
+	tr.resolved[threadKey] = r
+	return r, nil
+}
+
+// purgeDeletedMessage hard deletes a soft-deleted message once its thread's ttl passes.
+func (rm *RetentionManager) purgeDeletedMessage(run *Run, messageKey string, threads *threadRetention) {
+	threadTS, err := keys.ExtractThreadKeyFromMessage(messageKey)
+	if err != nil {
+		return
+	}
+	resolved, err := threads.get(keys.GenThreadKey(threadTS))
+	if err != nil || resolved == nil || !resolved.eff.CanPurge() || resolved.thread.Deleted {
+		// deleted threads are purged as a whole
+		return
+	}
+
+	data, err := storedb.GetKey(messageKey)
+	if err != nil {
+		return
+	}
+	var msg models.Message
+	if err := json.Unmarshal([]byte(data), &msg); err != nil || !msg.Deleted {
+		return
+	}
+	if time.Since(time.Unix(0, msg.UpdatedTS)) <= resolved.eff.DeletedMessageTTL {
+		return
+	}
+	rm.purgeMessage(run, messageKey, "deleted_message")
+}
+
+// purgeAgedContent hard deletes messages older than their thread's max age and whole
+// threads idle for longer than their max idle time.
+func (rm *RetentionManager) purgeAgedContent(run *Run, namespace string, threads *threadRetention) error {
+	if defaults := config.RetentionFor(namespace); defaults.MaxAge <= 0 && defaults.MaxIdle <= 0 {
+		hasAge, err := policies.HasAgePolicies(namespace)
+		if err != nil || !hasAge {
+			return err
+		}
+	}
+
+	threadKeys, err := rm.listThreadKeys(namespace)
+	if err != nil {
+		return err
+	}
+
+	now := timeutil.Now().UnixNano()
+	for _, threadKey := range threadKeys {
+		resolved, err := threads.get(threadKey)
+		if err != nil || resolved == nil || resolved.thread.Deleted || !resolved.eff.CanPurge() {
+			continue
+		}
+
+		if idle := resolved.eff.ThreadIdleTTL; idle > 0 && rm.lastActivity(resolved.thread) < now-idle.Nanoseconds() {
+			rm.purgeThread(run, threadKey, "idle_thread")
+			continue
+		}
+
+		if resolved.eff.MessageTTL <= 0 {
+			continue
+		}
+		messagePrefix, err := keys.GenAllThreadMessagesPrefix(threadKey)
+		if err != nil {
+			continue
+		}
+		cutoff := now - resolved.eff.MessageTTL.Nanoseconds()
+		messageKeys, err := rm.listMessageKeysBefore(messagePrefix, cutoff)
+		if err != nil {
+			run.fail("scan messages of %s: %v", threadKey, err)
+			logger.Error("[RETENTION] scan_thread_messages_failed", "thread", threadKey, "error", err)
+			continue
+		}
+		for _, messageKey := range messageKeys {
+			rm.purgeMessage(run, messageKey, "aged_message")
+		}
+	}
+	return nil
+}
+
+// lastActivity is the newest of the thread's own timestamps and its last message write.
+func (rm *RetentionManager) lastActivity(thread models.Thread) int64 {
+	last := thread.CreatedTS
+	if thread.UpdatedTS > last {
+		last = thread.UpdatedTS
+	}
+	if lu, err := indexdb.GetThreadIndexData(thread.Key, "last_updated_at"); err == nil {
+		if ts, err := strconv.ParseInt(lu, 10, 64); err == nil && ts > last {
+			last = ts
+		}
+	}
+	return last
+}
+
+// purgeExpiredMessages hard deletes messages past their expires_at, deleted or not.
+func (rm *RetentionManager) purgeExpiredMessages(run *Run, namespace string, threads *threadRetention) error {
+	keyIter := ki.NewKeyIterator(indexdb.Client)
+	expiryMarkers, _, err := keyIter.ExecuteKeyQuery(keys.GenExpiryPrefix(namespace), pagination.PaginationRequest{Limit: 10000})
+	if err != nil {
+		return fmt.Errorf("scan expiry markers: %w", err)
+	}
+
+	now := timeutil.Now().UnixNano()
+	for _, expiryMarkerKey := range expiryMarkers {
+		parsed, err := keys.ParseKey(expiryMarkerKey)
+		if err != nil || parsed.Type != keys.KeyTypeExpiryMarker {
+			logger.Error("[RETENTION] failed_to_parse_expiry_marker", "marker", expiryMarkerKey, "error", err)
+			continue
+		}
+
+		messageKey := parsed.OriginalKey
+		expired, err := indexdb.IsExpired(messageKey, now)
+		if err != nil || !expired {
+			continue
+		}
+		if threadTS, err := keys.ExtractThreadKeyFromMessage(messageKey); err == nil {
+			// expired messages stay hidden while their thread must be kept
+			if resolved, err := threads.get(keys.GenThreadKey(threadTS)); err == nil && resolved != nil && !resolved.eff.CanPurge() {
+				continue
+			}
+		}
+		rm.purgeMessage(run, messageKey, "expired_message")
+	}
 	return nil
 }
 
+// listMessageKeysBefore returns the keys under messagePrefix created before cutoff.
+func (rm *RetentionManager) listMessageKeysBefore(messagePrefix string, cutoff int64) ([]string, error) {
+	iter, err := storedb.Iter()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	defer iter.Close()
+
+	// message keys sort by creation time, so stop at the first one inside the window
+	var messageKeys []string
+	for ok := iter.SeekGE([]byte(messagePrefix)); ok && iter.Valid(); ok = iter.Next() {
+		key := string(iter.Key())
+		if !strings.HasPrefix(key, messagePrefix) {
+			break
+		}
+		parsed, err := keys.ParseKey(key)
+		if err != nil || parsed.Type != keys.KeyTypeMessage {
+			continue
+		}
+		createdTS, err := keys.KeyTimestampNumbered(parsed.MessageTS)
+		if err != nil || createdTS >= cutoff {
+			break
+		}
+		messageKeys = append(messageKeys, key)
+	}
+	return messageKeys, nil
+}
+
+// listThreadKeys returns the key of every thread stored in a namespace, skipping over
+// message keys.
+func (rm *RetentionManager) listThreadKeys(namespace string) ([]string, error) {
+	iter, err := storedb.Iter()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	defer iter.Close()
+
+	prefix := keys.GenThreadMetadataPrefix(namespace)
+	var threadKeys []string
+	for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid(); ok = iter.Next() {
+		key := string(iter.Key())
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+		if keys.IsThreadKey(key) {
+			threadKeys = append(threadKeys, key)
+		}
+	}
+	return threadKeys, nil
+}
+
 func (rm *RetentionManager) purgeThreadCompletely(threadKey string) error {
 	parsed, err := keys.ParseKey(threadKey)
 	if err != nil {
@@ -190,6 +511,11 @@ func (rm *RetentionManager) purgeThreadCompletely(threadKey string) error {
 	if parsed.Type != keys.KeyTypeThread {
 		return fmt.Errorf("expected thread key, got %s", parsed.Type)
 	}
+	if held, err := indexdb.IsOnLegalHold(threadKey); err != nil {
+		return fmt.Errorf("check legal hold: %w", err)
+	} else if held {
+		return fmt.Errorf("%w: %s", models.ErrLegalHold, threadKey)
+	}
 
 	threadUserPrefix, err := keys.GenThreadUserRelPrefix(threadKey)
 	if err != nil {
@@ -202,12 +528,7 @@ func (rm *RetentionManager) purgeThreadCompletely(threadKey string) error {
 	}
 
 	for _, userID := range userIDs {
-		fullUserThreadKey, err := keys.GenUserThreadRelPrefix(userID)
-		if err != nil {
-			logger.Error("[RETENTION] failed_to_generate_user_thread_prefix", "user_id", userID, "error", err)
-			continue
-		}
-		fullUserThreadKey += parsed.ThreadTS
+		fullUserThreadKey := keys.GenUserOwnsThreadKey(userID, threadKey)
 		if err := indexdb.DeleteKey(fullUserThreadKey); err != nil {
 			logger.Error("[RETENTION] failed_to_delete_user_thread_rel", "key", fullUserThreadKey, "error", err)
 		}
@@ -217,6 +538,23 @@ func (rm *RetentionManager) purgeThreadCompletely(threadKey string) error {
 		logger.Error("[RETENTION] failed_to_delete_thread_user_rels", "prefix", threadUserPrefix, "error", err)
 	}
 
+	if data, err := storedb.GetKey(threadKey); err == nil {
+		var thread models.Thread
+		if json.Unmarshal([]byte(data), &thread) == nil {
+			if thread.IsDirect() {
+				if err := indexdb.DeleteDirectThread(thread.DM, threadKey); err != nil {
+					logger.Error("[RETENTION] failed_to_delete_direct_thread_lookup", "thread_key", threadKey, "error", err)
+				}
+			}
+			if thread.ExternalID != "" {
+				lookupKey := keys.GenThreadExternalIDKey(parsed.Namespace, thread.Author, thread.ExternalID)
+				if err := indexdb.DeleteExternalID(lookupKey, threadKey); err != nil {
+					logger.Error("[RETENTION] failed_to_delete_thread_external_id", "thread_key", threadKey, "error", err)
+				}
+			}
+		}
+	}
+
 	if err := storedb.DeleteKey(threadKey); err != nil {
 		logger.Error("[RETENTION] failed_to_delete_thread_metadata", "key", threadKey, "error", err)
 	}
@@ -238,6 +576,29 @@ func (rm *RetentionManager) purgeThreadCompletely(threadKey string) error {
 	if err := rm.deleteByPrefixFromStoreDB(messagePrefix); err != nil {
 		logger.Error("[RETENTION] failed_to_delete_thread_messages", "prefix", messagePrefix, "error", err)
 	}
+	if err := rm.deleteByPrefixFromIndexDB(keys.GenExpiryMarkerKey(messagePrefix)); err != nil {
+		logger.Error("[RETENTION] failed_to_delete_thread_expiry_markers", "prefix", messagePrefix, "error", err)
+	}
+	if err := rm.deleteByPrefixFromIndexDB(keys.GenSoftDeleteMarkerKey(messagePrefix)); err != nil {
+		logger.Error("[RETENTION] failed_to_delete_message_delete_markers", "prefix", messagePrefix, "error", err)
+	}
+	if err := rm.deleteByPrefixFromIndexDB(keys.GenMovedMessageKey(messagePrefix)); err != nil {
+		logger.Error("[RETENTION] failed_to_delete_message_redirects", "prefix", messagePrefix, "error", err)
+	}
+	if externalIDsPrefix, err := keys.GenThreadExternalIDsPrefix(threadKey); err == nil {
+		if err := rm.deleteByPrefixFromIndexDB(externalIDsPrefix); err != nil {
+			logger.Error("[RETENTION] failed_to_delete_message_external_ids", "prefix", externalIDsPrefix, "error", err)
+		}
+	}
+	if versionsPrefix, err := keys.GenAllThreadVersionsPrefix(threadKey); err == nil {
+		if err := rm.deleteByPrefixFromIndexDB(versionsPrefix); err != nil {
+			logger.Error("[RETENTION] failed_to_delete_message_versions", "prefix", versionsPrefix, "error", err)
+		}
+	}
+
+	if err := scheduled.DeleteThread(threadKey); err != nil {
+		logger.Error("[RETENTION] failed_to_delete_scheduled_messages", "thread_key", threadKey, "error", err)
+	}
 
 	deleteMarker := keys.GenSoftDeleteMarkerKey(threadKey)
 	if err := indexdb.DeleteKey(deleteMarker); err != nil {
@@ -301,3 +662,45 @@ func (rm *RetentionManager) deleteByPrefixFromStoreDB(prefix string) error {
 
 	return nil
 }
+
+// messageSize is the stored size of a message and its versions.
+func messageSize(messageKey string) int64 {
+	var size int64
+	if data, err := storedb.GetKey(messageKey); err == nil {
+		size += int64(len(data))
+	}
+	if versionsPrefix, err := keys.GenAllMessageVersionsPrefix(messageKey); err == nil {
+		size += prefixSize(indexdb.DBIter, versionsPrefix)
+	}
+	return size
+}
+
+// threadSize is the stored size of a thread, its messages and their versions.
+func threadSize(threadKey, messagePrefix string) int64 {
+	var size int64
+	if data, err := storedb.GetKey(threadKey); err == nil {
+		size += int64(len(data))
+	}
+	size += prefixSize(storedb.Iter, messagePrefix)
+	if versionsPrefix, err := keys.GenAllThreadVersionsPrefix(threadKey); err == nil {
+		size += prefixSize(indexdb.DBIter, versionsPrefix)
+	}
+	return size
+}
+
+func prefixSize(newIter func() (*pebble.Iterator, error), prefix string) int64 {
+	iter, err := newIter()
+	if err != nil {
+		return 0
+	}
+	defer iter.Close()
+
+	var size int64
+	for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid(); ok = iter.Next() {
+		if !strings.HasPrefix(string(iter.Key()), prefix) {
+			break
+		}
+		size += int64(len(iter.Value()))
+	}
+	return size
+}
@@ -0,0 +1,154 @@
+package retention
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"progressdb/pkg/state"
+	"progressdb/pkg/state/logger"
+)
+
+const (
+	runsFile      = "runs.jsonl"
+	maxRunErrors  = 50
+	TriggerCron   = "cron"
+	TriggerManual = "manual"
+)
+
+// Run summarises one retention pass. Dry runs purge nothing and additionally list
+// the keys that would have been purged; those lists are not persisted.
+type Run struct {
+	ID         string         `json:"id"`
+	Trigger    string         `json:"trigger"`
+	DryRun     bool           `json:"dry_run"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Scanned    int            `json:"scanned"`
+	Threads    int            `json:"threads"`
+	Messages   int            `json:"messages"`
+	Bytes      int64          `json:"bytes"`
+	Reasons    map[string]int `json:"reasons"`
+	Errors     []string       `json:"errors,omitempty"`
+
+	ThreadKeys  []string `json:"thread_keys,omitempty"`
+	MessageKeys []string `json:"message_keys,omitempty"`
+
+	purged map[string]bool // message keys already purged this run
+}
+
+func newRun(trigger string, dryRun bool) *Run {
+	started := time.Now().UTC()
+	return &Run{
+		ID:        fmt.Sprintf("run-%d", started.UnixNano()),
+		Trigger:   trigger,
+		DryRun:    dryRun,
+		StartedAt: started,
+		Reasons:   make(map[string]int),
+		purged:    make(map[string]bool),
+	}
+}
+
+func (r *Run) fail(format string, args ...interface{}) {
+	if len(r.Errors) < maxRunErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+	}
+}
+
+func (r *Run) recordThread(threadKey, reason string, size int64) {
+	r.Threads++
+	r.Bytes += size
+	r.Reasons[reason]++
+	if r.DryRun {
+		r.ThreadKeys = append(r.ThreadKeys, threadKey)
+	}
+}
+
+func (r *Run) recordMessage(messageKey, reason string, size int64) {
+	r.purged[messageKey] = true
+	r.Messages++
+	r.Bytes += size
+	r.Reasons[reason]++
+	if r.DryRun {
+		r.MessageKeys = append(r.MessageKeys, messageKey)
+	}
+}
+
+var runsMu sync.Mutex
+
+// saveRun appends the run summary to the history under the retention state path.
+func saveRun(run *Run) error {
+	dir := state.PathsVar.Retention
+	if dir == "" {
+		return fmt.Errorf("retention path not initialized")
+	}
+
+	summary := *run
+	summary.ThreadKeys = nil
+	summary.MessageKeys = nil
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("marshal retention run: %w", err)
+	}
+
+	runsMu.Lock()
+	defer runsMu.Unlock()
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create retention dir: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, runsFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open retention runs: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write retention run: %w", err)
+	}
+	return nil
+}
+
+// ListRuns returns up to limit persisted runs, newest first.
+func ListRuns(limit int) ([]Run, error) {
+	dir := state.PathsVar.Retention
+	if dir == "" {
+		return nil, fmt.Errorf("retention path not initialized")
+	}
+
+	runsMu.Lock()
+	defer runsMu.Unlock()
+
+	f, err := os.Open(filepath.Join(dir, runsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Run{}, nil
+		}
+		return nil, fmt.Errorf("open retention runs: %w", err)
+	}
+	defer f.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			logger.Warn("[RETENTION] retention_run_corrupt", "error", err)
+			continue
+		}
+		runs = append(runs, run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read retention runs: %w", err)
+	}
+
+	out := make([]Run, 0, limit)
+	for i := len(runs) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, runs[i])
+	}
+	return out, nil
+}
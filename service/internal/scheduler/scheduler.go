@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"progressdb/pkg/config"
+	"progressdb/pkg/ingest/queue"
+	"progressdb/pkg/ingest/tracking"
+	"progressdb/pkg/ingest/types"
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/db/indexdb"
+	"progressdb/pkg/store/features/scheduled"
+	"progressdb/pkg/store/features/schemas"
+	"progressdb/pkg/store/features/threads"
+	"progressdb/pkg/store/keys"
+	"progressdb/pkg/timeutil"
+)
+
+const (
+	defaultPollInterval = time.Second
+	releaseBatchLimit   = 1000
+)
+
+// errDropped marks a scheduled message that can never be delivered and is discarded.
+var errDropped = errors.New("scheduled message dropped")
+
+type Scheduler struct {
+	interval time.Duration
+	ctx      context.Context
+}
+
+// Start runs the loop that releases scheduled messages into their threads once due.
+func Start(ctx context.Context) (context.CancelFunc, error) {
+	interval := defaultPollInterval
+	if cfg := config.GetConfig(); cfg != nil && cfg.Scheduler.PollInterval.Duration() > 0 {
+		interval = cfg.Scheduler.PollInterval.Duration()
+	}
+
+	ctx2, cancel := context.WithCancel(ctx)
+	s := &Scheduler{interval: interval, ctx: ctx2}
+
+	logger.Info("[SCHEDULER] scheduler_started", "poll_interval", interval)
+	go s.loop()
+	return cancel, nil
+}
+
+func (s *Scheduler) loop() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.releaseDue()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Scheduler) releaseDue() {
+	for _, namespace := range config.Namespaces() {
+		if !s.releaseDueIn(namespace) {
+			return
+		}
+	}
+}
+
+// releaseDueIn releases a namespace's due messages. It returns false when the rest of
+// this tick should be skipped.
+func (s *Scheduler) releaseDueIn(namespace string) bool {
+	due, err := scheduled.Due(namespace, timeutil.Now().UnixNano(), releaseBatchLimit)
+	if err != nil {
+		logger.Error("[SCHEDULER] scan_due_failed", "namespace", namespace, "error", err)
+		return true
+	}
+
+	for _, dueKey := range due {
+		if s.ctx.Err() != nil {
+			return false
+		}
+		err := scheduled.Release(dueKey, deliver)
+		if errors.Is(err, errDropped) {
+			// undeliverable - discard so it is not retried every tick
+			if parsed, perr := keys.ParseKey(dueKey); perr == nil {
+				if cerr := scheduled.Cancel(parsed.MessageProvKey); cerr != nil && !errors.Is(cerr, scheduled.ErrNotFound) {
+					logger.Error("[SCHEDULER] drop_failed", "key", dueKey, "error", cerr)
+				}
+			}
+			logger.Warn("[SCHEDULER] scheduled_message_dropped", "key", dueKey, "reason", err)
+			continue
+		}
+		if err != nil {
+			logger.Error("[SCHEDULER] release_failed", "key", dueKey, "error", err)
+			if errors.Is(err, queue.ErrQueueFull) || errors.Is(err, queue.ErrQueueClosed) {
+				return false // retry the rest next tick
+			}
+		}
+	}
+	return true
+}
+
+// deliver enqueues msg as a new message under the key fixed for its release, carrying the
+// metadata of the request that scheduled it. Content policies ran when it was scheduled.
+func deliver(msg *models.Message, req types.RequestMetadata, releasedTS int64) error {
+	if exists, err := threads.CheckThreadExists(msg.Thread); err != nil || !exists {
+		return fmt.Errorf("%w: thread %s not found", errDropped, msg.Thread)
+	}
+	if deleted, err := indexdb.IsSoftDeleted(msg.Thread); err != nil {
+		return err
+	} else if deleted {
+		return fmt.Errorf("%w: thread %s deleted", errDropped, msg.Thread)
+	}
+
+	released := *msg
+	released.Key = keys.GenMessagePrvKey(msg.Thread, fmt.Sprintf("%d", releasedTS))
+	released.CreatedTS = releasedTS
+	released.UpdatedTS = releasedTS
+	released.DeliverAt = 0
+
+	// an earlier attempt already got it in; only the cleanup failed
+	if _, found, err := tracking.GlobalKeyMapper.ResolveKey(released.Key); err != nil {
+		return err
+	} else if found {
+		logger.Info("[SCHEDULER] scheduled_message_already_released", "scheduled_key", msg.Key, "key", released.Key)
+		return nil
+	}
+
+	tracking.GlobalInflightTracker.Add(released.Key)
+	err := queue.GlobalIngestQueue.EnqueueWithoutHooks(&types.QueueOp{
+		Handler: types.HandlerMessageCreate,
+		Payload: &released,
+		TS:      releasedTS,
+		Extras:  req,
+	})
+	if err != nil {
+		tracking.GlobalInflightTracker.Remove(released.Key)
+	}
+	var invalid *schemas.ValidationError
+	if errors.As(err, &invalid) {
+		return fmt.Errorf("%w: %v", errDropped, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Info("[SCHEDULER] scheduled_message_released", "scheduled_key", msg.Key, "key", released.Key, "thread", msg.Thread)
+	return nil
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/router"
+	"progressdb/pkg/api/utils"
+	"progressdb/pkg/config"
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/features/apikeys"
+	"progressdb/pkg/store/features/audit"
+)
+
+const handledUserValue = "handled"
+
+// auditActor names the caller without revealing its credential: runtime keys by id,
+// config keys by a short fingerprint and certificates by name.
+func auditActor(key string) string {
+	if strings.HasPrefix(key, "cert:") {
+		return key
+	}
+	if id, ok := apikeys.KeyID(key); ok {
+		return "key:" + id
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "key:" + hex.EncodeToString(sum[:8])
+}
+
+// markHandled flags that the request got past the gateway to a handler.
+func markHandled(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.SetUserValue(handledUserValue, true)
+		next(ctx)
+	}
+}
+
+// maxSuppressedIPs bounds how many client ips hold an unreported failure count.
+const maxSuppressedIPs = 10000
+
+// authFailureSampler keeps a flood of failures from one client ip out of the trail. Past
+// the ip's bucket failures are only counted; the next one recorded carries the count.
+type authFailureSampler struct {
+	pool       *limiterPool
+	mu         sync.Mutex
+	suppressed map[string]int
+}
+
+func newAuthFailureSampler(cfg config.RateLimitConfig) *authFailureSampler {
+	return &authFailureSampler{pool: newLimiterPool(cfg.RPS, cfg.Burst), suppressed: make(map[string]int)}
+}
+
+// sample reports whether a failure from ip is recorded, and how many were dropped
+// since the last one that was.
+func (s *authFailureSampler) sample(ip string) (bool, int) {
+	allowed := s.pool.Allow(ip)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !allowed {
+		if _, ok := s.suppressed[ip]; ok || len(s.suppressed) < maxSuppressedIPs {
+			s.suppressed[ip]++
+		}
+		return false, 0
+	}
+	dropped := s.suppressed[ip]
+	delete(s.suppressed, ip)
+	return true, dropped
+}
+
+// auditAuthFailures records requests the gateway turned away with 401 or 403, sampled
+// per client ip.
+func auditAuthFailures(sampler *authFailureSampler, gate fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		gate(ctx)
+		status := ctx.Response.StatusCode()
+		if status != fasthttp.StatusUnauthorized && status != fasthttp.StatusForbidden {
+			return
+		}
+		if handled, _ := ctx.UserValue(handledUserValue).(bool); handled {
+			return
+		}
+		ip := utils.GetClientIP(ctx)
+		record, dropped := sampler.sample(ip)
+		if !record {
+			return
+		}
+		var body struct {
+			Error string `json:"error"`
+		}
+		_ = json.Unmarshal(ctx.Response.Body(), &body)
+		actor, _ := ctx.UserValue(router.AuditActorUserValue).(string)
+		rec := models.AuditRecord{
+			Action:    models.AuditActionAuthFailure,
+			Outcome:   audit.OutcomeFailed,
+			Role:      utils.GetApiRole(ctx),
+			Actor:     actor,
+			Namespace: router.Namespace(ctx),
+			UserID:    utils.GetUserID(ctx), // as claimed; it was not verified
+			Target:    utils.GetPath(ctx),
+			ReqID:     utils.GetHeader(ctx, "X-Request-Id"),
+			IP:        ip,
+			Detail: map[string]string{
+				"method": string(ctx.Method()),
+				"status": strconv.Itoa(status),
+				"reason": body.Error,
+			},
+		}
+		if dropped > 0 {
+			rec.Detail["suppressed"] = strconv.Itoa(dropped)
+		}
+		if err := audit.Record(rec); err != nil {
+			logger.Error("audit_record_failed", "action", rec.Action, "error", err)
+		}
+	}
+}
+
+// auditAdminRequests records admin requests that change state, unless the handler
+// recorded a more specific entry itself.
+func auditAdminRequests(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		next(ctx)
+		method := string(ctx.Method())
+		if method == fasthttp.MethodGet || method == fasthttp.MethodHead || router.Audited(ctx) {
+			return
+		}
+		status := ctx.Response.StatusCode()
+		rec := models.AuditRecord{
+			Action: models.AuditActionAdminRequest,
+			Target: utils.GetPath(ctx),
+			Detail: map[string]string{"method": method, "status": strconv.Itoa(status)},
+		}
+		if status >= fasthttp.StatusBadRequest {
+			rec.Outcome = audit.OutcomeFailed
+		}
+		router.RecordAudit(ctx, rec)
+	}
+}
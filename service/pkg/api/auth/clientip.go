@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/utils"
+	"progressdb/pkg/state/logger"
+)
+
+// ipList matches addresses against ips and cidr ranges.
+type ipList []netip.Prefix
+
+// parseIPList reads ips and cidr ranges, skipping invalid entries; config validation
+// rejects them before the server starts.
+func parseIPList(entries []string) ipList {
+	out := make(ipList, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		var p netip.Prefix
+		var err error
+		if strings.Contains(e, "/") {
+			p, err = netip.ParsePrefix(e)
+		} else {
+			var addr netip.Addr
+			if addr, err = netip.ParseAddr(e); err == nil {
+				p = netip.PrefixFrom(addr, addr.BitLen())
+			}
+		}
+		if err != nil {
+			logger.Warn("ip_list_entry_invalid", "entry", e, "error", err)
+			continue
+		}
+		out = append(out, p.Masked())
+	}
+	return out
+}
+
+func (l ipList) contains(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range l {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveClientIP returns the address of the client behind any trusted proxies. When
+// the peer is a trusted proxy, X-Forwarded-For is read from the right and the first
+// address not itself a trusted proxy is the client; X-Real-IP is the fallback.
+func resolveClientIP(ctx *fasthttp.RequestCtx, trusted ipList) string {
+	remote := ctx.RemoteIP().String()
+	if len(trusted) == 0 || !trusted.contains(remote) {
+		return remote
+	}
+	if xff := utils.GetHeader(ctx, "X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		client := ""
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				// a malformed hop ends the chain we can vouch for
+				break
+			}
+			client = addr.Unmap().String()
+			if !trusted.contains(client) {
+				return client
+			}
+		}
+		if client != "" {
+			return client
+		}
+	}
+	if real := strings.TrimSpace(utils.GetHeader(ctx, "X-Real-IP")); real != "" {
+		if addr, err := netip.ParseAddr(real); err == nil {
+			return addr.Unmap().String()
+		}
+	}
+	return remote
+}
@@ -1,20 +1,32 @@
 package auth
 
 import (
-	"net"
 	"strings"
 
 	"github.com/valyala/fasthttp"
+	"golang.org/x/time/rate"
 
 	"progressdb/pkg/api/router"
 	"progressdb/pkg/api/utils"
+	"progressdb/pkg/models"
 	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/features/apikeys"
 )
 
 func AuthenticateRequestMiddleware(cfg SecConfig) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
-	limiters := &limiterPool{cfg: cfg}
+	limiters := &limiterPool{limit: rate.Limit(cfg.RPS), burst: cfg.Burst}
+	limits := newLayeredLimits(cfg.Limits)
+	failures := newAuthFailureSampler(cfg.AuthFailures)
+	whitelist := parseIPList(cfg.IPWhitelist)
+	trusted := parseIPList(cfg.TrustedProxies)
+	roleAllowlists := map[Role]ipList{
+		RoleAdmin:    parseIPList(cfg.AdminIPs),
+		RoleBackend:  parseIPList(cfg.BackendIPs),
+		RoleFrontend: parseIPList(cfg.FrontendIPs),
+	}
 	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
-		return func(ctx *fasthttp.RequestCtx) {
+		next = markHandled(next)
+		return auditAuthFailures(failures, func(ctx *fasthttp.RequestCtx) {
 			logger.LogRequestFast(ctx)
 
 			// cors headers and handle options shortcut
@@ -25,24 +37,35 @@ func AuthenticateRequestMiddleware(cfg SecConfig) func(fasthttp.RequestHandler)
 				ctx.Response.Header.Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,PATCH,OPTIONS")
 				ctx.Response.Header.Set("Access-Control-Max-Age", "600")
 				ctx.Response.Header.Set("Access-Control-Allow-Headers", "Authorization,Content-Type,X-API-Key,X-User-ID,X-User-Signature")
-				ctx.Response.Header.Set("Access-Control-Expose-Headers", "X-Role-Name")
+				ctx.Response.Header.Set("Access-Control-Expose-Headers", "X-Role-Name,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After")
 			}
 			if string(ctx.Method()) == fasthttp.MethodOptions {
 				ctx.SetStatusCode(fasthttp.StatusNoContent)
 				return
 			}
 
+			// the role header is ours to set; drop any the client sent
+			ctx.Request.Header.Del("X-Role-Name")
+
+			// client ip, looking through trusted proxies
+			ip := resolveClientIP(ctx, trusted)
+			ctx.SetUserValue(utils.ClientIPUserValue, ip)
+
 			// ip whitelist check (always before all other checks except cors/options)
-			if len(cfg.IPWhitelist) > 0 {
-				ip := clientIPFast(ctx)
+			if len(whitelist) > 0 {
 				logger.Debug("ip_check", "ip", ip)
-				if !ipWhitelisted(ip, cfg.IPWhitelist) {
+				if !whitelist.contains(ip) {
 					router.WriteJSONError(ctx, fasthttp.StatusForbidden, "forbidden")
 					logger.Warn("request_blocked", "reason", "ip_not_whitelisted", "ip", ip, "path", utils.GetPath(ctx))
 					return
 				}
 			}
 
+			// per-ip limit, before any key is looked at
+			if !takeLimit(ctx, limits.ip, ip, "ip") {
+				return
+			}
+
 			// public endpoint check (before auth for health)
 			if publicAllowedPath(ctx) {
 				ctx.Request.Header.Set("X-Role-Name", "unauth")
@@ -51,7 +74,10 @@ func AuthenticateRequestMiddleware(cfg SecConfig) func(fasthttp.RequestHandler)
 			}
 
 			// api key validation and role extraction
-			role, key, hasAPIKey := validateAPIKey(ctx, cfg)
+			role, key, hasAPIKey := validateAPIKey(ctx, cfg, ip)
+			if hasAPIKey {
+				ctx.SetUserValue(router.AuditActorUserValue, auditActor(key))
+			}
 
 			var roleName string
 			switch role {
@@ -72,20 +98,32 @@ func AuthenticateRequestMiddleware(cfg SecConfig) func(fasthttp.RequestHandler)
 				return
 			}
 
+			// roles may be confined to some networks
+			if list := roleAllowlists[role]; len(list) > 0 && !list.contains(ip) {
+				router.WriteJSONError(ctx, fasthttp.StatusForbidden, "forbidden")
+				logger.Warn("request_blocked", "reason", "ip_not_allowed_for_role", "role", roleName, "ip", ip, "path", utils.GetPath(ctx))
+				return
+			}
+
 			// set for downstream usage
 			ctx.Request.Header.Set("X-Role-Name", roleName)
 
 			// rate limit bfore key handling
-			if !limiters.Allow(key) {
-				router.WriteJSONError(ctx, fasthttp.StatusTooManyRequests, "rate limit exceeded")
-				logger.Warn("rate_limited", "has_api_key", hasAPIKey, "path", utils.GetPath(ctx))
+			if !takeLimit(ctx, limiters, key, "key") {
 				return
 			}
 
 			// request info for logging
 			path := utils.GetPath(ctx)
 			remote := ctx.RemoteAddr().String()
-			reqInfo := []interface{}{"path", path, "remote", remote}
+			reqInfo := []interface{}{"path", path, "remote", remote, "ip", ip}
+
+			// runtime keys may be narrowed to some permissions and paths
+			if status, msg := authorizeKeyGrant(ctx); status != 0 {
+				router.WriteJSONError(ctx, status, msg)
+				logger.Warn("api_key_grant_denied", append(reqInfo, "reason", msg)...)
+				return
+			}
 
 			// explicit role-path handling with specific logic per combination
 			switch role {
@@ -111,38 +149,40 @@ func AuthenticateRequestMiddleware(cfg SecConfig) func(fasthttp.RequestHandler)
 				}
 				logger.Debug("frontend_path_allowed", reqInfo...)
 
-				// frontend requires signature verification
-				if !utils.HasUserSignature(ctx) {
+				// frontend requires signature (or jwt) verification
+				if !utils.HasUserSignature(ctx) && utils.GetBearerJWT(ctx) == "" {
 					router.WriteJSONError(ctx, fasthttp.StatusUnauthorized, "signature required")
 					logger.Warn("frontend_missing_signature", reqInfo...)
 					return
 				}
-				RequireSignedAuthorMiddleware(next)(ctx)
+				RequireSignedAuthorMiddleware(limits.wrap(next, key))(ctx)
 				return
 			}
 
 			// authorized: continue to handler for admin/backend
-			next(ctx)
-		}
-	}
-}
-
-func clientIPFast(ctx *fasthttp.RequestCtx) string {
-	host := ctx.RemoteAddr().String()
-	h, _, err := net.SplitHostPort(host)
-	if err != nil {
-		return host
+			if role == RoleAdmin {
+				limits.wrap(auditAdminRequests(next), key)(ctx)
+				return
+			}
+			limits.wrap(next, key)(ctx)
+		})
 	}
-	return h
 }
 
-func validateAPIKey(ctx *fasthttp.RequestCtx, cfg SecConfig) (Role, string, bool) {
+func validateAPIKey(ctx *fasthttp.RequestCtx, cfg SecConfig, ip string) (Role, string, bool) {
 	key := utils.ExtractAPIKey(ctx)
 
 	if key == "" {
-		return RoleUnauth, clientIPFast(ctx), false
+		// a verified client certificate stands in for a key
+		if role, name, ok := clientCertRole(ctx, cfg); ok {
+			return role, "cert:" + name, true
+		}
+		return RoleUnauth, ip, false
 	}
 
+	if namespace := cfg.KeyNamespaces[key]; namespace != "" {
+		ctx.SetUserValue(router.NamespaceUserValue, namespace)
+	}
 	if cfg.AdminKeys != nil {
 		if _, ok := cfg.AdminKeys[key]; ok {
 			return RoleAdmin, key, true
@@ -158,9 +198,75 @@ func validateAPIKey(ctx *fasthttp.RequestCtx, cfg SecConfig) (Role, string, bool
 			return RoleFrontend, key, true
 		}
 	}
+
+	// runtime keys created through the admin api
+	if apiKey, ok := apikeys.Authenticate(key); ok {
+		if grant := apiKey.APIKeyGrant; grant.Restricted() {
+			ctx.SetUserValue(router.APIKeyGrantUserValue, &grant)
+		}
+		if apiKey.Namespace != "" {
+			ctx.SetUserValue(router.NamespaceUserValue, apiKey.Namespace)
+		}
+		switch apiKey.Role {
+		case models.APIKeyRoleAdmin:
+			return RoleAdmin, key, true
+		case models.APIKeyRoleBackend:
+			return RoleBackend, key, true
+		case models.APIKeyRoleFrontend:
+			return RoleFrontend, key, true
+		}
+	}
 	return RoleUnauth, key, true
 }
 
+// clientCertRole maps the verified client certificate of a tls connection to a role by
+// its common name or one of its DNS names. Admin names are checked first.
+func clientCertRole(ctx *fasthttp.RequestCtx, cfg SecConfig) (Role, string, bool) {
+	state := ctx.TLSConnectionState()
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return RoleUnauth, "", false
+	}
+	cert := state.VerifiedChains[0][0]
+	names := append([]string{cert.Subject.CommonName}, cert.DNSNames...)
+	for _, set := range []struct {
+		role  Role
+		names map[string]struct{}
+	}{{RoleAdmin, cfg.CertAdmins}, {RoleBackend, cfg.CertBackends}} {
+		for _, n := range names {
+			if _, ok := set.names[n]; ok && n != "" {
+				return set.role, n, true
+			}
+		}
+	}
+	return RoleUnauth, "", false
+}
+
+// authorizeKeyGrant checks the request against the permissions and paths of the key's
+// grant, returning a status and message when it falls outside them.
+func authorizeKeyGrant(ctx *fasthttp.RequestCtx) (int, string) {
+	grant, ok := ctx.UserValue(router.APIKeyGrantUserValue).(*models.APIKeyGrant)
+	if !ok {
+		return 0, ""
+	}
+	path := utils.GetPath(ctx)
+	if !grant.AllowsPath(path) {
+		return fasthttp.StatusForbidden, "api key not permitted for this path"
+	}
+
+	method := string(ctx.Method())
+	permission := models.APIKeyPermWrite
+	switch {
+	case method == fasthttp.MethodPost && path == "/backend/v1/sign":
+		permission = models.APIKeyPermSign
+	case method == fasthttp.MethodGet || method == fasthttp.MethodHead:
+		permission = models.APIKeyPermRead
+	}
+	if !grant.Allows(permission) {
+		return fasthttp.StatusForbidden, "api key lacks the " + permission + " permission"
+	}
+	return 0, ""
+}
+
 func originAllowed(origin string, allowed []string) bool {
 	if len(allowed) == 0 {
 		return false
@@ -173,15 +279,6 @@ func originAllowed(origin string, allowed []string) bool {
 	return false
 }
 
-func ipWhitelisted(ip string, list []string) bool {
-	for _, w := range list {
-		if ip == w {
-			return true
-		}
-	}
-	return false
-}
-
 func publicAllowedPath(ctx *fasthttp.RequestCtx) bool {
 	path := utils.GetPath(ctx)
 	method := string(ctx.Method())
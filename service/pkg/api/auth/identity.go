@@ -4,6 +4,8 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"strings"
 
 	"github.com/valyala/fasthttp"
 
@@ -11,6 +13,8 @@ import (
 	"progressdb/pkg/api/utils"
 	"progressdb/pkg/config"
 	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/features/signingkeys"
+	"progressdb/pkg/timeutil"
 )
 
 type Role int
@@ -35,14 +39,35 @@ func CreateHMACSignature(userID, key string) (string, error) {
 	return hex.EncodeToString(mac.Sum(nil)), nil
 }
 
-func VerifyHMACSignature(userID, signature string) bool {
-	keys := config.GetSigningKeys()
+// CreateKeyedSignature signs userID and prefixes the signing key's ID, so verification
+// knows which key to use: <key_id>:<hex hmac>.
+func CreateKeyedSignature(userID, keyID, key string) (string, error) {
+	sig, err := CreateHMACSignature(userID, key)
+	if err != nil {
+		return "", err
+	}
+	return keyID + ":" + sig, nil
+}
 
+// VerifyHMACSignature checks a signature against the namespace's key it names, or against
+// every key of the namespace that is not retired for bare signatures issued before key IDs.
+func VerifyHMACSignature(namespace, userID, signature string) bool {
 	if userID == "" || signature == "" {
 		return false
 	}
 
-	for k := range keys {
+	candidates := []string{}
+	if i := strings.IndexByte(signature, ':'); i >= 0 {
+		key, ok := signingkeys.Lookup(namespace, signature[:i])
+		if !ok {
+			return false
+		}
+		candidates, signature = append(candidates, key), signature[i+1:]
+	} else {
+		candidates = signingkeys.Verifiable(namespace)
+	}
+
+	for _, k := range candidates {
 		expected, err := CreateHMACSignature(userID, k)
 		if err != nil {
 			continue
@@ -59,9 +84,18 @@ type SecConfig struct {
 	RPS            float64
 	Burst          int
 	IPWhitelist    []string
+	AdminIPs       []string // per-role allowlists; empty allows any ip
+	BackendIPs     []string
+	FrontendIPs    []string
+	TrustedProxies []string
 	BackendKeys    map[string]struct{}
 	FrontendKeys   map[string]struct{}
 	AdminKeys      map[string]struct{}
+	KeyNamespaces  map[string]string // config keys of a named namespace -> its id
+	Limits         config.LimitsConfig
+	AuthFailures   config.RateLimitConfig // per-ip sampling of auth failures in the audit trail
+	CertAdmins     map[string]struct{}    // verified client certificate names granted admin
+	CertBackends   map[string]struct{}    // and backend
 }
 
 func RequireSignedAuthorMiddleware(next fasthttp.RequestHandler) fasthttp.RequestHandler {
@@ -74,23 +108,83 @@ func RequireSignedAuthorMiddleware(next fasthttp.RequestHandler) fasthttp.Reques
 		userID := utils.GetUserID(ctx)
 		sig := utils.GetUserSignature(ctx)
 
+		namespace := router.Namespace(ctx)
+
+		// identity-provider jwt in place of a signature
+		if jwt := utils.GetBearerJWT(ctx); jwt != "" && sig == "" {
+			// the identity provider is shared, so its users cannot be told apart by namespace
+			if namespace != "" {
+				logger.Warn("jwt_rejected_in_namespace", append(logMeta, "namespace", namespace)...)
+				router.WriteJSONError(ctx, fasthttp.StatusUnauthorized, "jwt not accepted in this namespace")
+				return
+			}
+			jwtUser, err := VerifyJWT(jwt, timeutil.Now())
+			if err != nil {
+				logger.Warn("invalid_jwt", append(logMeta, "error", err)...)
+				router.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err.Error())
+				return
+			}
+			if err := router.ValidateUserID(jwtUser); err != nil {
+				logger.Warn("invalid_jwt_user", append(logMeta, "error", err)...)
+				router.WriteJSONError(ctx, fasthttp.StatusUnauthorized, ErrJWTUser.Error())
+				return
+			}
+			if userID != "" && userID != jwtUser {
+				logger.Warn("jwt_user_mismatch", logMeta...)
+				router.WriteJSONError(ctx, fasthttp.StatusUnauthorized, "user id does not match jwt")
+				return
+			}
+			logger.Info("jwt_verified", logMeta...)
+			ctx.SetUserValue("author", jwtUser)
+			ctx.Request.Header.Set("X-User-ID", jwtUser)
+			next(ctx)
+			return
+		}
+
 		// frontend signature verification only
 		if sig == "" {
 			logger.Warn("missing_signature", logMeta...)
 			router.WriteJSONError(ctx, fasthttp.StatusUnauthorized, "missing signature")
 			return
 		}
-		if userID == "" {
-			logger.Warn("missing_user_id", logMeta...)
-			router.WriteJSONError(ctx, fasthttp.StatusUnauthorized, "missing user id")
-			return
-		}
 
-		// crypto verify the request: user_id <> hmac is not tampered
-		if VerifyHMACSignature(userID, sig) == false {
-			logger.Warn("invalid_signature", logMeta...)
-			router.WriteJSONError(ctx, fasthttp.StatusUnauthorized, "invalid signature")
-			return
+		if IsFrontendToken(sig) {
+			// tokens carry the user id, expiry and scopes
+			claims, err := VerifyFrontendToken(namespace, sig, timeutil.Now())
+			if err != nil {
+				logger.Warn("invalid_token", append(logMeta, "error", err)...)
+				router.WriteJSONError(ctx, fasthttp.StatusUnauthorized, err.Error())
+				return
+			}
+			if userID != "" && userID != claims.UserID {
+				logger.Warn("token_user_mismatch", logMeta...)
+				router.WriteJSONError(ctx, fasthttp.StatusUnauthorized, "user id does not match token")
+				return
+			}
+			if status, msg := authorizeTokenScope(ctx, claims); status != 0 {
+				logger.Warn("token_scope_denied", append(logMeta, "reason", msg)...)
+				router.WriteJSONError(ctx, status, msg)
+				return
+			}
+			userID = claims.UserID
+		} else {
+			if config.RequireFrontendTokens() {
+				logger.Warn("legacy_signature_rejected", logMeta...)
+				router.WriteJSONError(ctx, fasthttp.StatusUnauthorized, "token required")
+				return
+			}
+			if userID == "" {
+				logger.Warn("missing_user_id", logMeta...)
+				router.WriteJSONError(ctx, fasthttp.StatusUnauthorized, "missing user id")
+				return
+			}
+
+			// crypto verify the request: user_id <> hmac is not tampered
+			if VerifyHMACSignature(namespace, userID, sig) == false {
+				logger.Warn("invalid_signature", logMeta...)
+				router.WriteJSONError(ctx, fasthttp.StatusUnauthorized, "invalid signature")
+				return
+			}
 		}
 
 		// signature verified - continue
@@ -102,3 +196,44 @@ func RequireSignedAuthorMiddleware(next fasthttp.RequestHandler) fasthttp.Reques
 		next(ctx)
 	}
 }
+
+// authorizeTokenScope checks the request against the token's scopes, returning a status
+// and message when it falls outside them.
+func authorizeTokenScope(ctx *fasthttp.RequestCtx, claims *TokenClaims) (int, string) {
+	method := string(ctx.Method())
+	if !claims.AllowsWrite() && method != fasthttp.MethodGet && method != fasthttp.MethodHead {
+		return fasthttp.StatusForbidden, "token is read-only"
+	}
+	if len(claims.Threads) == 0 {
+		return 0, ""
+	}
+	// requests naming no thread in the path, multi-operation batches included, are refused
+	path := utils.GetPath(ctx)
+	if !claims.AllowsThread(threadFromPath(path)) {
+		return fasthttp.StatusForbidden, "token not scoped for this thread"
+	}
+	// moves and copies also write to the target thread named in the body
+	if strings.HasSuffix(path, ":move") || strings.HasSuffix(path, ":copy") {
+		var body struct {
+			Target string `json:"target"`
+		}
+		_ = json.Unmarshal(ctx.PostBody(), &body)
+		if !claims.AllowsThread(body.Target) {
+			return fasthttp.StatusForbidden, "token not scoped for the target thread"
+		}
+	}
+	return 0, ""
+}
+
+// threadFromPath returns the thread addressed by a /frontend/v1/threads/{threadKey} path, if any.
+func threadFromPath(path string) string {
+	const prefix = "/frontend/v1/threads/"
+	if !strings.HasPrefix(path, prefix) {
+		return ""
+	}
+	rest := path[len(prefix):]
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		rest = rest[:i]
+	}
+	return rest
+}
@@ -0,0 +1,325 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"progressdb/pkg/config"
+	"progressdb/pkg/state/logger"
+)
+
+var (
+	ErrJWTMalformed = errors.New("malformed jwt")
+	ErrJWTAlgorithm = errors.New("unsupported jwt algorithm")
+	ErrJWTKey       = errors.New("unknown jwt signing key")
+	ErrJWTSignature = errors.New("invalid jwt signature")
+	ErrJWTExpired   = errors.New("jwt expired")
+	ErrJWTNotYet    = errors.New("jwt not yet valid")
+	ErrJWTIssuer    = errors.New("jwt issuer not accepted")
+	ErrJWTAudience  = errors.New("jwt audience not accepted")
+	ErrJWTUser      = errors.New("jwt user claim missing or invalid")
+)
+
+const (
+	// jwksFileCheckInterval bounds how often a JWKS file is stat'ed for changes.
+	jwksFileCheckInterval = time.Second
+	// jwksMinRefetch bounds refetches of a JWKS URL triggered by unknown key ids.
+	jwksMinRefetch   = 30 * time.Second
+	jwksFetchTimeout = 5 * time.Second
+)
+
+// VerifyJWT checks signature, expiry, issuer and audience, returning the author from the
+// configured user claim.
+func VerifyJWT(token string, now time.Time) (string, error) {
+	settings := config.JWTSettings()
+	if settings == nil {
+		return "", errors.New("jwt auth not enabled")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrJWTMalformed
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return "", ErrJWTMalformed
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrJWTMalformed
+	}
+
+	// verify
+	key, err := jwksKeys.lookup(settings, header.Kid, now)
+	if err != nil {
+		return "", err
+	}
+	if err := verifyJWS(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return "", err
+	}
+
+	// claims
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return "", ErrJWTMalformed
+	}
+	leeway := settings.Leeway.Duration()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return "", ErrJWTMalformed
+	}
+	if now.Add(-leeway).Unix() >= int64(exp) {
+		return "", ErrJWTExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(leeway).Unix() < int64(nbf) {
+		return "", ErrJWTNotYet
+	}
+	if settings.Issuer != "" && claims["iss"] != settings.Issuer {
+		return "", ErrJWTIssuer
+	}
+	if settings.Audience != "" && !audienceContains(claims["aud"], settings.Audience) {
+		return "", ErrJWTAudience
+	}
+	userID, _ := claims[settings.UserClaim].(string)
+	if userID == "" {
+		return "", ErrJWTUser
+	}
+	return userID, nil
+}
+
+func decodeJWTPart(part string, out interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func audienceContains(aud interface{}, want string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == want
+	case []interface{}:
+		for _, a := range v {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func verifyJWS(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrJWTKey
+		}
+		sum := sha256.Sum256([]byte(signed))
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig) != nil {
+			return ErrJWTSignature
+		}
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() {
+			return ErrJWTKey
+		}
+		if len(sig) != 64 {
+			return ErrJWTSignature
+		}
+		sum := sha256.Sum256([]byte(signed))
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, sum[:], r, s) {
+			return ErrJWTSignature
+		}
+	case "EdDSA":
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return ErrJWTKey
+		}
+		if !ed25519.Verify(pub, []byte(signed), sig) {
+			return ErrJWTSignature
+		}
+	default:
+		return ErrJWTAlgorithm
+	}
+	return nil
+}
+
+// jwksCache holds the parsed key set, reloaded when the file changes, the refresh interval
+// passes, or a token names a key id the set lacks.
+type jwksCache struct {
+	mu        sync.Mutex
+	source    string
+	keys      map[string]crypto.PublicKey
+	loadedAt  time.Time
+	checkedAt time.Time
+	modTime   time.Time
+}
+
+var jwksKeys = &jwksCache{}
+
+func (c *jwksCache) lookup(settings *config.JWTConfig, kid string, now time.Time) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	source := settings.JWKSFile
+	if source == "" {
+		source = settings.JWKSURL
+	}
+	if source != c.source {
+		c.source, c.keys = source, nil
+	}
+
+	if c.stale(settings, now) {
+		c.reload(settings, now)
+	}
+	key, ok := c.find(kid)
+	if !ok && settings.JWKSURL != "" && now.Sub(c.loadedAt) >= jwksMinRefetch {
+		c.reload(settings, now)
+		key, ok = c.find(kid)
+	}
+	if !ok {
+		return nil, ErrJWTKey
+	}
+	return key, nil
+}
+
+func (c *jwksCache) find(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, k := range c.keys {
+			return k, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+func (c *jwksCache) stale(settings *config.JWTConfig, now time.Time) bool {
+	if c.keys == nil {
+		return true
+	}
+	if settings.JWKSURL != "" {
+		return now.Sub(c.loadedAt) >= settings.RefreshInterval.Duration()
+	}
+	if now.Sub(c.checkedAt) < jwksFileCheckInterval {
+		return false
+	}
+	c.checkedAt = now
+	info, err := os.Stat(settings.JWKSFile)
+	return err == nil && !info.ModTime().Equal(c.modTime)
+}
+
+// reload keeps the previous keys when the new set cannot be read.
+func (c *jwksCache) reload(settings *config.JWTConfig, now time.Time) {
+	var raw []byte
+	var err error
+	if settings.JWKSFile != "" {
+		var info os.FileInfo
+		if info, err = os.Stat(settings.JWKSFile); err == nil {
+			c.modTime = info.ModTime()
+			raw, err = os.ReadFile(settings.JWKSFile)
+		}
+	} else {
+		raw, err = fetchJWKS(settings.JWKSURL)
+	}
+	c.loadedAt, c.checkedAt = now, now
+	if err == nil {
+		var keys map[string]crypto.PublicKey
+		if keys, err = ParseJWKS(raw); err == nil {
+			c.keys = keys
+			logger.Info("jwks_loaded", "source", c.source, "keys", len(keys))
+			return
+		}
+	}
+	logger.Error("jwks_load_failed", "source", c.source, "error", err)
+	if c.keys == nil {
+		c.keys = map[string]crypto.PublicKey{}
+	}
+}
+
+func fetchJWKS(url string) ([]byte, error) {
+	client := &http.Client{Timeout: jwksFetchTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks fetch returned status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// ParseJWKS reads the RSA, P-256 and Ed25519 signing keys of a JWK set, keyed by kid.
+// Keys of other types or uses are skipped.
+func ParseJWKS(raw []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("invalid jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch {
+		case k.Kty == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+				return nil, fmt.Errorf("invalid RSA key %q", k.Kid)
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				return nil, fmt.Errorf("invalid EC key %q", k.Kid)
+			}
+			pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+			if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+				return nil, fmt.Errorf("invalid EC key %q", k.Kid)
+			}
+			keys[k.Kid] = pub
+		case k.Kty == "OKP" && k.Crv == "Ed25519":
+			x, err := base64.RawURLEncoding.DecodeString(k.X)
+			if err != nil || len(x) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("invalid Ed25519 key %q", k.Kid)
+			}
+			keys[k.Kid] = ed25519.PublicKey(x)
+		}
+	}
+	return keys, nil
+}
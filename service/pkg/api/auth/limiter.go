@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"math"
 	"sync"
 	"time"
 
@@ -17,13 +18,26 @@ type limiterEntry struct {
 type limiterPool struct {
 	mu            sync.Mutex
 	m             map[string]*limiterEntry
-	cfg           SecConfig
+	limit         rate.Limit
+	burst         int
 	startCleanup  sync.Once
 	ttl           time.Duration
 	cleanupPeriod time.Duration
 	stopCh        chan struct{} // Channel to signal cleanup goroutine to stop
 }
 
+// newLimiterPool returns a pool of token buckets, or nil when rps is zero (no limit).
+// A zero burst defaults to rps, at least 1.
+func newLimiterPool(rps float64, burst int) *limiterPool {
+	if rps <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = int(math.Ceil(rps))
+	}
+	return &limiterPool{limit: rate.Limit(rps), burst: burst}
+}
+
 // get limiter for key, create if missing; start cleanup once
 func (p *limiterPool) get(key string) *rate.Limiter {
 	p.startCleanup.Do(func() {
@@ -47,7 +61,7 @@ func (p *limiterPool) get(key string) *rate.Limiter {
 		return e.l
 	}
 
-	l := rate.NewLimiter(rate.Limit(p.cfg.RPS), p.cfg.Burst)
+	l := rate.NewLimiter(p.limit, p.burst)
 	p.m[key] = &limiterEntry{l: l, lastSeen: timeutil.Now()}
 	return l
 }
@@ -57,6 +71,50 @@ func (p *limiterPool) Allow(key string) bool {
 	return p.get(key).Allow()
 }
 
+// limitResult is the state of one bucket after a request, for the RateLimit-* headers.
+type limitResult struct {
+	allowed    bool
+	limit      int
+	remaining  int
+	reset      time.Duration // until the bucket is full again
+	retryAfter time.Duration // until the next request fits, when denied
+}
+
+// Take spends one token for key, leaving the bucket untouched when it is empty.
+func (p *limiterPool) Take(key string) limitResult {
+	l := p.get(key)
+	now := timeutil.Now()
+	res := limitResult{limit: p.burst}
+
+	r := l.ReserveN(now, 1)
+	if !r.OK() {
+		// a zero burst never admits anything
+		res.retryAfter = time.Second
+		return res
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		res.retryAfter = delay
+		res.reset = p.untilFull(l, now)
+		return res
+	}
+	res.allowed = true
+	res.remaining = int(math.Max(0, math.Floor(l.TokensAt(now))))
+	res.reset = p.untilFull(l, now)
+	return res
+}
+
+func (p *limiterPool) untilFull(l *rate.Limiter, now time.Time) time.Duration {
+	if p.limit <= 0 || p.limit == rate.Inf {
+		return 0
+	}
+	missing := float64(p.burst) - l.TokensAt(now)
+	if missing <= 0 {
+		return 0
+	}
+	return time.Duration(missing / float64(p.limit) * float64(time.Second))
+}
+
 // Shutdown gracefully stops the cleanup goroutine.
 func (p *limiterPool) Shutdown() {
 	if p.stopCh != nil {
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/router"
+	"progressdb/pkg/api/utils"
+	"progressdb/pkg/config"
+	"progressdb/pkg/state/logger"
+)
+
+// Route classes for limits.routes.
+const (
+	routeRead          = "read"
+	routeMessageCreate = "message_create"
+	routeWrite         = "write"
+)
+
+// layeredLimits holds the optional limits applied on top of the per-key limit. A nil
+// pool is disabled.
+type layeredLimits struct {
+	ip     *limiterPool
+	user   *limiterPool
+	routes map[string]*limiterPool
+}
+
+func newLayeredLimits(cfg config.LimitsConfig) *layeredLimits {
+	return &layeredLimits{
+		ip:   newLimiterPool(cfg.IP.RPS, cfg.IP.Burst),
+		user: newLimiterPool(cfg.User.RPS, cfg.User.Burst),
+		routes: map[string]*limiterPool{
+			routeRead:          newLimiterPool(cfg.Routes.Read.RPS, cfg.Routes.Read.Burst),
+			routeMessageCreate: newLimiterPool(cfg.Routes.MessageCreate.RPS, cfg.Routes.MessageCreate.Burst),
+			routeWrite:         newLimiterPool(cfg.Routes.Write.RPS, cfg.Routes.Write.Burst),
+		},
+	}
+}
+
+// wrap applies the user and route limits once the caller's user, if any, is known.
+// key identifies callers that act without a user.
+func (l *layeredLimits) wrap(next fasthttp.RequestHandler, key string) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		user := requestUser(ctx)
+		if user != "" && !takeLimit(ctx, l.user, "user:"+user, "user") {
+			return
+		}
+		class := routeClass(ctx)
+		subject := "key:" + key
+		if user != "" {
+			subject = "user:" + user
+		}
+		if !takeLimit(ctx, l.routes[class], class+":"+subject, "route_"+class) {
+			return
+		}
+		next(ctx)
+	}
+}
+
+// requestUser returns the verified frontend author or the user a backend acts for.
+func requestUser(ctx *fasthttp.RequestCtx) string {
+	if author, ok := ctx.UserValue("author").(string); ok && author != "" {
+		return author
+	}
+	return utils.GetUserID(ctx)
+}
+
+// routeClass sorts a request into reads, message creates and other writes. Anything that
+// can put messages into a thread counts as a message create: batches, moves and copies too.
+func routeClass(ctx *fasthttp.RequestCtx) string {
+	method := string(ctx.Method())
+	if method == fasthttp.MethodGet || method == fasthttp.MethodHead {
+		return routeRead
+	}
+	if method != fasthttp.MethodPost {
+		return routeWrite
+	}
+	path := utils.GetPath(ctx)
+	if path == "/frontend/v1/batch" {
+		return routeMessageCreate
+	}
+	if strings.HasPrefix(path, "/frontend/v1/threads/") {
+		for _, suffix := range []string{"/messages", "/messages:batch", "/messages:move", "/messages:copy"} {
+			if strings.HasSuffix(path, suffix) {
+				return routeMessageCreate
+			}
+		}
+	}
+	return routeWrite
+}
+
+// takeLimit spends a token from pool and reports the tightest bucket seen so far in
+// the RateLimit-* headers. Denied requests get a 429 with Retry-After.
+func takeLimit(ctx *fasthttp.RequestCtx, pool *limiterPool, key, layer string) bool {
+	if pool == nil {
+		return true
+	}
+	res := pool.Take(key)
+	setRateLimitHeaders(ctx, res)
+	if !res.allowed {
+		router.WriteTooManyRequests(ctx, res.retryAfter, "rate limit exceeded")
+		logger.Warn("rate_limited", "layer", layer, "path", utils.GetPath(ctx))
+		return false
+	}
+	return true
+}
+
+func setRateLimitHeaders(ctx *fasthttp.RequestCtx, res limitResult) {
+	if res.allowed {
+		if cur := ctx.Response.Header.Peek("RateLimit-Remaining"); len(cur) > 0 {
+			if n, err := strconv.Atoi(string(cur)); err == nil && n <= res.remaining {
+				return
+			}
+		}
+	}
+	ctx.Response.Header.Set("RateLimit-Limit", strconv.Itoa(res.limit))
+	ctx.Response.Header.Set("RateLimit-Remaining", strconv.Itoa(res.remaining))
+	ctx.Response.Header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.reset)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -90,23 +90,51 @@ func wrapHTTPHandler(h http.Handler) func(ctx *fasthttp.RequestCtx) {
 
 // RegisterRoutes wires all API routes onto the provided router.
 func RegisterRoutes(r *router.Router) {
+	// requests stay in the namespace of their api key; admins may pick one with ?namespace=
+	r.ResolveParams(router.ScopeNamespaceParams)
+	// ext:-prefixed threadKey and message id parameters resolve to keys before handlers run
+	r.ResolveParams(router.ResolveExternalIDParams)
+	// restricted api keys are checked against the resolved thread and user
+	r.ResolveParams(router.AuthorizeKeyGrantParams)
+
 	// client auth endpoints
 	r.POST("/backend/v1/sign", backendRoutes.Sign)
 
 	// thread metadata operations
 	r.POST("/frontend/v1/threads", frontendRoutes.EnqueueCreateThread)
 	r.GET("/frontend/v1/threads", frontendRoutes.ReadThreadsList)
+	r.GET("/frontend/v1/threads/by-external-id/{externalId}", frontendRoutes.ReadThreadByExternalID)
 	r.PUT("/frontend/v1/threads/{threadKey}", frontendRoutes.EnqueueUpdateThread)
 	r.GET("/frontend/v1/threads/{threadKey}", frontendRoutes.ReadThreadItem)
 	r.DELETE("/frontend/v1/threads/{threadKey}", frontendRoutes.EnqueueDeleteThread)
+	r.POST("/frontend/v1/threads/dm", frontendRoutes.EnqueueGetOrCreateDirectThread)
+
+	// thread participant operations
+	r.GET("/frontend/v1/threads/{threadKey}/participants", frontendRoutes.ReadThreadParticipants)
+	r.PUT("/frontend/v1/threads/{threadKey}/participants/{userId}", frontendRoutes.EnqueueSetThreadParticipant)
+	r.DELETE("/frontend/v1/threads/{threadKey}/participants/{userId}", frontendRoutes.EnqueueRemoveThreadParticipant)
 
 	// thread message operations
 	r.POST("/frontend/v1/threads/{threadKey}/messages", frontendRoutes.EnqueueCreateMessage)
+	r.POST("/frontend/v1/threads/{threadKey}/messages:batch", frontendRoutes.EnqueueCreateMessageBatch)
+	r.POST("/frontend/v1/threads/{threadKey}/messages:move", frontendRoutes.EnqueueMoveMessages)
+	r.POST("/frontend/v1/threads/{threadKey}/messages:copy", frontendRoutes.EnqueueCopyMessages)
 	r.GET("/frontend/v1/threads/{threadKey}/messages", frontendRoutes.ReadThreadMessages)
 	r.GET("/frontend/v1/threads/{threadKey}/messages/{id}", frontendRoutes.ReadThreadMessage)
 	r.PUT("/frontend/v1/threads/{threadKey}/messages/{id}", frontendRoutes.EnqueueUpdateMessage)
 	r.DELETE("/frontend/v1/threads/{threadKey}/messages/{id}", frontendRoutes.EnqueueDeleteMessage)
 
+	// multi-operation batches
+	r.POST("/frontend/v1/batch", frontendRoutes.EnqueueBatch)
+
+	// scheduled message operations
+	r.GET("/frontend/v1/threads/{threadKey}/scheduled", frontendRoutes.ReadScheduledMessages)
+	r.DELETE("/frontend/v1/threads/{threadKey}/scheduled/{id}", frontendRoutes.CancelScheduledMessage)
+
+	// mention feed operations
+	r.GET("/frontend/v1/mentions", frontendRoutes.ReadMentions)
+	r.POST("/frontend/v1/mentions/read", frontendRoutes.MarkMentionsRead)
+
 	// admin data routes
 	r.GET("/admin/health", adminRoutes.Health)
 	r.GET("/admin/stats", adminRoutes.Stats)
@@ -118,6 +146,38 @@ func RegisterRoutes(r *router.Router) {
 	r.GET("/admin/users/{userId}/threads", adminRoutes.ListUserThreads)
 	r.GET("/admin/users/{userId}/threads/{threadKey}/messages", adminRoutes.ListThreadMessages)
 	r.GET("/admin/users/{userId}/threads/{threadKey}/messages/{messageKey}", adminRoutes.GetThreadMessage)
+	r.POST("/admin/users/{userId}/threads/transfer", adminRoutes.TransferUserThreads)
+	r.POST("/admin/threads/{threadKey}/transfer", adminRoutes.TransferThread)
+
+	// admin schema routes
+	r.GET("/admin/schemas", adminRoutes.ListSchemas)
+	r.PUT("/admin/schemas/global", adminRoutes.PutGlobalSchema)
+	r.DELETE("/admin/schemas/global", adminRoutes.DeleteGlobalSchema)
+	r.PUT("/admin/schemas/tags/{tag}", adminRoutes.PutTagSchema)
+	r.DELETE("/admin/schemas/tags/{tag}", adminRoutes.DeleteTagSchema)
+
+	// admin api key routes
+	r.GET("/admin/api-keys", adminRoutes.ListAPIKeys)
+	r.POST("/admin/api-keys", adminRoutes.CreateAPIKey)
+	r.PUT("/admin/api-keys/{keyId}", adminRoutes.UpdateAPIKey)
+	r.DELETE("/admin/api-keys/{keyId}", adminRoutes.RevokeAPIKey)
+
+	// admin signing key routes
+	r.GET("/admin/signing-keys", adminRoutes.ListSigningKeys)
+	r.POST("/admin/signing-keys/rotate", adminRoutes.RotateSigningKey)
+	r.PUT("/admin/signing-keys/{keyId}", adminRoutes.PutSigningKeyState)
+
+	// admin audit routes
+	r.GET("/admin/audit", adminRoutes.ListAudit)
+	r.GET("/admin/audit/verify", adminRoutes.VerifyAudit)
+
+	// admin retention routes
+	r.GET("/admin/retention/policies", adminRoutes.ListRetentionPolicies)
+	r.PUT("/admin/retention/policies/{scope}/{target}", adminRoutes.PutRetentionPolicy)
+	r.DELETE("/admin/retention/policies/{scope}/{target}", adminRoutes.DeleteRetentionPolicy)
+	r.GET("/admin/retention/holds", adminRoutes.ListLegalHolds)
+	r.PUT("/admin/retention/holds/{threadKey}", adminRoutes.PutLegalHold)
+	r.DELETE("/admin/retention/holds/{threadKey}", adminRoutes.DeleteLegalHold)
 
 	// admin enc routes
 	r.POST("/admin/encryption/encrypt-threads", adminRoutes.EncryptThreads)
@@ -132,6 +192,7 @@ func RegisterRoutes(r *router.Router) {
 
 	// admin job routes
 	r.POST("/admin/jobs/purge", adminRoutes.RunRetentionCleanup)
+	r.GET("/admin/jobs/retention/runs", adminRoutes.ListRetentionRuns)
 }
 
 // Handler returns the fasthttp handler for the ProgressDB API.
@@ -0,0 +1,36 @@
+package router
+
+import (
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/utils"
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/features/audit"
+)
+
+// AuditActorUserValue holds who made the request for the audit trail: an api key id,
+// a fingerprint of a config key, or a client certificate name. Never the key itself.
+const AuditActorUserValue = "audit_actor"
+
+const auditedUserValue = "audited"
+
+// RecordAudit appends rec to the audit trail with the caller's role, actor, namespace,
+// request id and ip filled in, and marks the request as audited so no generic record is added.
+func RecordAudit(ctx *fasthttp.RequestCtx, rec models.AuditRecord) {
+	rec.Role = utils.GetApiRole(ctx)
+	rec.Actor, _ = ctx.UserValue(AuditActorUserValue).(string)
+	rec.Namespace = Namespace(ctx)
+	rec.ReqID = utils.GetHeader(ctx, "X-Request-Id")
+	rec.IP = utils.GetClientIP(ctx)
+	ctx.SetUserValue(auditedUserValue, true)
+	if err := audit.Record(rec); err != nil {
+		logger.Error("audit_record_failed", "action", rec.Action, "error", err)
+	}
+}
+
+// Audited reports whether a handler already recorded the request.
+func Audited(ctx *fasthttp.RequestCtx) bool {
+	v, _ := ctx.UserValue(auditedUserValue).(bool)
+	return v
+}
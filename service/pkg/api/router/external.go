@@ -0,0 +1,79 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/ingest/tracking"
+)
+
+// ExternalIDPrefix marks a threadKey or message id path parameter given as an external id.
+const ExternalIDPrefix = "ext:"
+
+// ValidateExternalID checks a client-supplied external id. Colons and slashes are
+// rejected so the id stays unambiguous in lookup keys and path parameters.
+func ValidateExternalID(externalID string) error {
+	const maxLen = 128
+
+	if len(externalID) == 0 {
+		return fmt.Errorf("external_id: cannot be empty")
+	}
+	if len(externalID) > maxLen {
+		return fmt.Errorf("external_id: too long (maximum %d characters)", maxLen)
+	}
+	for _, r := range externalID {
+		if r < 32 || r == 127 || r == ':' || r == '/' {
+			return fmt.Errorf("external_id: contains invalid characters")
+		}
+	}
+	return nil
+}
+
+// ResolveExternalIDParams rewrites ext:-prefixed threadKey and id path parameters to the
+// keys they name. Thread external ids resolve against the calling author; message external
+// ids resolve within the (resolved) thread. Returns false once an error has been written.
+func ResolveExternalIDParams(ctx *fasthttp.RequestCtx) bool {
+	threadKey := PathParam(ctx, "threadKey")
+	if externalID, ok := strings.CutPrefix(threadKey, ExternalIDPrefix); ok {
+		author, authErr := ValidateAuthor(ctx, "")
+		if authErr != nil {
+			WriteValidationError(ctx, authErr)
+			return false
+		}
+		resolved, err := tracking.GlobalKeyMapper.ResolveThreadExternalID(Namespace(ctx), author, externalID)
+		if err != nil {
+			WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
+			return false
+		}
+		if resolved == "" {
+			WriteJSONError(ctx, fasthttp.StatusNotFound, "thread not found")
+			return false
+		}
+		// creates still in flight are visible to the next read
+		tracking.GlobalInflightTracker.WaitForInflight(resolved)
+		threadKey = resolved
+		ctx.SetUserValue("threadKey", threadKey)
+	}
+
+	messageKey := PathParam(ctx, "id")
+	if externalID, ok := strings.CutPrefix(messageKey, ExternalIDPrefix); ok {
+		if threadKey == "" {
+			WriteJSONError(ctx, fasthttp.StatusBadRequest, "message external id requires a thread")
+			return false
+		}
+		resolved, err := tracking.GlobalKeyMapper.ResolveMessageExternalID(threadKey, externalID)
+		if err != nil {
+			WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
+			return false
+		}
+		if resolved == "" {
+			WriteJSONError(ctx, fasthttp.StatusNotFound, "message not found")
+			return false
+		}
+		tracking.GlobalInflightTracker.WaitForInflight(resolved)
+		ctx.SetUserValue("id", resolved)
+	}
+	return true
+}
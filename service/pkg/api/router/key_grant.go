@@ -0,0 +1,115 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/ingest/tracking"
+	"progressdb/pkg/models"
+	storedb "progressdb/pkg/store/db/storedb"
+	thread_store "progressdb/pkg/store/features/threads"
+)
+
+// APIKeyGrantUserValue holds the *models.APIKeyGrant of a runtime key that narrows its role.
+const APIKeyGrantUserValue = "api_key_grant"
+
+func keyGrant(ctx *fasthttp.RequestCtx) *models.APIKeyGrant {
+	grant, _ := ctx.UserValue(APIKeyGrantUserValue).(*models.APIKeyGrant)
+	return grant
+}
+
+// AuthorizeKeyUser refuses users outside the key's user prefix.
+func AuthorizeKeyUser(ctx *fasthttp.RequestCtx, userID string) *AuthorResolutionError {
+	if grant := keyGrant(ctx); grant != nil && !grant.AllowsUser(userID) {
+		return &AuthorResolutionError{Type: "forbidden", Message: "api key not permitted for this user", Code: fasthttp.StatusForbidden}
+	}
+	return nil
+}
+
+// AuthorizeKeyGrantParams applies a key's user and thread-tag restrictions to the resolved
+// path parameters. A tag-restricted key may only address single threads carrying one of
+// its tags, create threads with one of them, or sign. Returns false once a response is written.
+func AuthorizeKeyGrantParams(ctx *fasthttp.RequestCtx) bool {
+	grant := keyGrant(ctx)
+	if grant == nil {
+		return true
+	}
+	if userID := PathParam(ctx, "userId"); userID != "" {
+		if err := AuthorizeKeyUser(ctx, userID); err != nil {
+			WriteValidationError(ctx, err)
+			return false
+		}
+	}
+	if len(grant.ThreadTags) == 0 {
+		return true
+	}
+
+	path := string(ctx.Path())
+	method := string(ctx.Method())
+	threadKey := PathParam(ctx, "threadKey")
+	switch {
+	case threadKey != "":
+		if !authorizeKeyThread(ctx, grant, threadKey) {
+			return false
+		}
+		// moves and copies also write to the target thread
+		if strings.HasSuffix(path, ":move") || strings.HasSuffix(path, ":copy") {
+			var body struct {
+				Target string `json:"target"`
+			}
+			_ = json.Unmarshal(ctx.PostBody(), &body)
+			if body.Target != "" && !authorizeKeyThread(ctx, grant, body.Target) {
+				return false
+			}
+		}
+		return true
+	case method == fasthttp.MethodPost && path == "/backend/v1/sign":
+		return true
+	case method == fasthttp.MethodPost && path == "/frontend/v1/threads":
+		var body struct {
+			Tags []string `json:"tags"`
+		}
+		_ = json.Unmarshal(ctx.PostBody(), &body)
+		tags, _ := NormalizeThreadTags(body.Tags)
+		if !grant.AllowsTags(tags) {
+			WriteJSONError(ctx, fasthttp.StatusForbidden, fmt.Sprintf("api key may only create threads tagged %s", strings.Join(grant.ThreadTags, ", ")))
+			return false
+		}
+		return true
+	}
+	WriteJSONError(ctx, fasthttp.StatusForbidden, "api key is restricted to single tagged threads")
+	return false
+}
+
+// authorizeKeyThread refuses threads that do not carry one of the key's tags. A thread
+// whose tags cannot be read is refused too, unknown threads alike so a restricted key
+// learns nothing about threads it may not address.
+func authorizeKeyThread(ctx *fasthttp.RequestCtx, grant *models.APIKeyGrant, threadKey string) bool {
+	resolvedKey, err := tracking.GlobalKeyMapper.ResolveKeyOrWait(threadKey)
+	if err != nil {
+		WriteJSONError(ctx, fasthttp.StatusForbidden, "api key not permitted for this thread")
+		return false
+	}
+	stored, err := thread_store.GetThreadData(resolvedKey)
+	if err != nil {
+		if storedb.IsNotFound(err) {
+			WriteJSONError(ctx, fasthttp.StatusForbidden, "api key not permitted for this thread")
+		} else {
+			WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to read thread: %v", err))
+		}
+		return false
+	}
+	var thread models.Thread
+	if err := json.Unmarshal([]byte(stored), &thread); err != nil {
+		WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to parse thread: %v", err))
+		return false
+	}
+	if !grant.AllowsTags(thread.Tags) {
+		WriteJSONError(ctx, fasthttp.StatusForbidden, "api key not permitted for this thread")
+		return false
+	}
+	return true
+}
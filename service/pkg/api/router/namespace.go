@@ -0,0 +1,83 @@
+package router
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/utils"
+	"progressdb/pkg/config"
+	"progressdb/pkg/store/keys"
+)
+
+// NamespaceUserValue holds the namespace a request works in. Unset means the default
+// namespace.
+const NamespaceUserValue = "namespace"
+
+// Namespace returns the namespace of the request, "" for the default one.
+func Namespace(ctx *fasthttp.RequestCtx) string {
+	namespace, _ := ctx.UserValue(NamespaceUserValue).(string)
+	return namespace
+}
+
+// serverWidePaths are admin routes over state all namespaces share; only admins of the
+// default namespace reach them.
+var serverWidePaths = []string{"/admin/debug/", "/admin/jobs/", "/admin/audit/verify"}
+
+// InNamespace reports whether key belongs to the namespace of the request.
+func InNamespace(ctx *fasthttp.RequestCtx, key string) bool {
+	return keys.NamespaceOf(key) == Namespace(ctx)
+}
+
+// ScopeNamespaceParams confines a request to its namespace. Admins of the default
+// namespace may pick another one with ?namespace=; keys of a named namespace are held to
+// it. Thread and message keys of another namespace are reported as not found, so a key
+// learns nothing about data outside its namespace. Returns false once a response is written.
+func ScopeNamespaceParams(ctx *fasthttp.RequestCtx) bool {
+	path := string(ctx.Path())
+	if Namespace(ctx) != "" {
+		for _, prefix := range serverWidePaths {
+			if strings.HasPrefix(path, prefix) {
+				WriteJSONError(ctx, fasthttp.StatusForbidden, "api key not permitted for this route")
+				return false
+			}
+		}
+	}
+	if requested := utils.GetQuery(ctx, "namespace"); requested != "" && strings.HasPrefix(path, "/admin/") {
+		current := Namespace(ctx)
+		switch {
+		case current != "" && requested != current:
+			WriteJSONError(ctx, fasthttp.StatusForbidden, "api key not permitted for this namespace")
+			return false
+		case !config.NamespaceExists(requested):
+			WriteJSONError(ctx, fasthttp.StatusNotFound, "namespace not found")
+			return false
+		}
+		ctx.SetUserValue(NamespaceUserValue, requested)
+	}
+
+	for _, param := range []string{"threadKey", "id", "messageKey"} {
+		value := PathParam(ctx, param)
+		if value == "" || strings.HasPrefix(value, ExternalIDPrefix) {
+			continue
+		}
+		if !InNamespace(ctx, value) {
+			WriteJSONError(ctx, fasthttp.StatusNotFound, "not found")
+			return false
+		}
+	}
+
+	// moves and copies also write to the target thread
+	if strings.HasSuffix(path, ":move") || strings.HasSuffix(path, ":copy") {
+		var body struct {
+			Target string `json:"target"`
+		}
+		_ = json.Unmarshal(ctx.PostBody(), &body)
+		if body.Target != "" && !strings.HasPrefix(body.Target, ExternalIDPrefix) && !InNamespace(ctx, body.Target) {
+			WriteJSONError(ctx, fasthttp.StatusNotFound, "target thread not found")
+			return false
+		}
+	}
+	return true
+}
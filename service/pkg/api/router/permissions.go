@@ -1,17 +1,23 @@
 package router
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 
 	"github.com/valyala/fasthttp"
 
+	"progressdb/pkg/models"
 	"progressdb/pkg/store/db/indexdb"
+	thread_store "progressdb/pkg/store/features/threads"
 	"progressdb/pkg/store/keys"
 )
 
 var (
 	ErrThreadDeleted  = errors.New("thread not found")
 	ErrMessageDeleted = errors.New("message not found")
+
+	ErrDirectThreadParticipants = errors.New("participants of a direct-message thread cannot be changed")
 )
 
 // ValidateThreadNotDeleted returns an error if thread is deleted
@@ -24,6 +30,23 @@ func ValidateThreadNotDeleted(threadKey string) error {
 	return nil
 }
 
+// ValidateParticipantsMutable returns an error if thread is a direct-message thread,
+// whose participant set is fixed by its lookup key.
+func ValidateParticipantsMutable(threadKey string) error {
+	stored, err := thread_store.GetThreadData(threadKey)
+	if err != nil {
+		return nil // missing threads are reported by the apply step
+	}
+	var thread models.Thread
+	if err := json.Unmarshal([]byte(stored), &thread); err != nil {
+		return fmt.Errorf("failed to parse thread: %w", err)
+	}
+	if thread.IsDirect() {
+		return ErrDirectThreadParticipants
+	}
+	return nil
+}
+
 // ValidateMessageNotDeleted returns an error if message is deleted
 func ValidateMessageNotDeleted(messageKey string) error {
 	deleteMarkerKey := keys.GenSoftDeleteMarkerKey(messageKey)
@@ -55,3 +78,18 @@ func HandleDeletedError(ctx *fasthttp.RequestCtx, err error) bool {
 	}
 	return false
 }
+
+// AuthorizeThreadAccess resolves the author's thread role and applies the thread access policy,
+// writing a 403 response when the action is not allowed
+func AuthorizeThreadAccess(ctx *fasthttp.RequestCtx, threadKey, author string, action models.ThreadAction) bool {
+	role, err := indexdb.GetThreadUserRole(threadKey, author)
+	if err != nil {
+		WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to resolve thread role: %v", err))
+		return false
+	}
+	if err := models.AuthorizeThreadAction(role, action); err != nil {
+		WriteJSONError(ctx, fasthttp.StatusForbidden, err.Error())
+		return false
+	}
+	return true
+}
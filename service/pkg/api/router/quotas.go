@@ -0,0 +1,37 @@
+package router
+
+import (
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/features/quotas"
+)
+
+// ConsumeQuotaOrFail counts n creations of kind by userID against limit, writing a
+// 429 with Retry-After when the user's daily quota is used up. Once it succeeds, defer
+// RefundQuotaOnError with the same arguments so failed requests are not counted.
+func ConsumeQuotaOrFail(ctx *fasthttp.RequestCtx, kind, userID string, n, limit int) bool {
+	ok, retryAfter, err := quotas.Consume(Namespace(ctx), kind, userID, n, limit)
+	if err != nil {
+		logger.Error("quota_check_failed", "kind", kind, "error", err)
+		WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to check quota")
+		return false
+	}
+	if !ok {
+		logger.Warn("quota_exceeded", "kind", kind, "user", userID)
+		WriteTooManyRequests(ctx, retryAfter, "daily "+kind+" quota exceeded")
+		return false
+	}
+	return true
+}
+
+// RefundQuotaOnError gives back what ConsumeQuotaOrFail counted when the request ended in
+// an error response: it was rejected by a hook, schema or the queue and created nothing.
+func RefundQuotaOnError(ctx *fasthttp.RequestCtx, kind, userID string, n, limit int) {
+	if ctx.Response.StatusCode() < fasthttp.StatusBadRequest {
+		return
+	}
+	if err := quotas.Refund(Namespace(ctx), kind, userID, n, limit); err != nil {
+		logger.Error("quota_refund_failed", "kind", kind, "error", err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 type Router struct {
 	routes   map[string][]route
 	notFound fasthttp.RequestHandler
+	params   []func(ctx *fasthttp.RequestCtx) bool
 }
 
 type route struct {
@@ -44,6 +45,11 @@ func (r *Router) Handler(ctx *fasthttp.RequestCtx) {
 				for k, v := range values {
 					ctx.SetUserValue(k, v)
 				}
+				for _, resolve := range r.params {
+					if !resolve(ctx) {
+						return
+					}
+				}
 				rt.handler(ctx)
 				return
 			}
@@ -76,6 +82,13 @@ func (r *Router) DELETE(path string, h fasthttp.RequestHandler) {
 	r.add("DELETE", path, h)
 }
 
+// ResolveParams registers a hook that may rewrite path parameters before the
+// handler runs. Hooks run in registration order. Returning false stops the
+// request; the hook writes the response.
+func (r *Router) ResolveParams(fn func(ctx *fasthttp.RequestCtx) bool) {
+	r.params = append(r.params, fn)
+}
+
 // NotFound registers a handler for unmatched routes.
 func (r *Router) NotFound(h fasthttp.RequestHandler) {
 	r.notFound = h
@@ -2,6 +2,9 @@ package router
 
 import (
 	"encoding/json"
+	"math"
+	"strconv"
+	"time"
 
 	"github.com/valyala/fasthttp"
 )
@@ -19,6 +22,16 @@ func WriteJSONError(ctx *fasthttp.RequestCtx, status int, message string) {
 	_ = json.NewEncoder(ctx).Encode(map[string]string{"error": message})
 }
 
+// WriteTooManyRequests writes a 429 error with a Retry-After header in whole seconds.
+func WriteTooManyRequests(ctx *fasthttp.RequestCtx, retryAfter time.Duration, message string) {
+	secs := int(math.Ceil(retryAfter.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	ctx.Response.Header.Set("Retry-After", strconv.Itoa(secs))
+	WriteJSONError(ctx, fasthttp.StatusTooManyRequests, message)
+}
+
 // WriteJSONOk writes a simple OK JSON response.
 func WriteJSONOk(ctx *fasthttp.RequestCtx, data map[string]interface{}) {
 	ctx.Response.Header.Set("Content-Type", "application/json")
@@ -0,0 +1,58 @@
+package router
+
+import (
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/ingest/tracking"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/features/schemas"
+	"progressdb/pkg/store/keys"
+)
+
+type SchemaValidationError struct {
+	Error  string               `json:"error"`
+	Fields []schemas.FieldError `json:"fields"`
+}
+
+// ValidateMessageBodySchema checks a message body against the registered schemas
+// for its thread, writing a 400 with field-level errors when it does not match.
+func ValidateMessageBodySchema(ctx *fasthttp.RequestCtx, threadKey string, body interface{}) bool {
+	tags, err := threadTagsForSchemas(threadKey)
+	if err != nil {
+		logger.Error("schema_thread_lookup_failed", "thread", threadKey, "error", err)
+		WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to resolve message schema")
+		return false
+	}
+	return ValidateMessageBodyForTags(ctx, threadKey, tags, body)
+}
+
+// ValidateMessageBodyForTags is ValidateMessageBodySchema for a thread whose tags are
+// already known, such as one created earlier in the same batch.
+func ValidateMessageBodyForTags(ctx *fasthttp.RequestCtx, threadKey string, tags []string, body interface{}) bool {
+	fieldErrs, err := schemas.ValidateBody(keys.NamespaceOf(threadKey), body, tags)
+	if err != nil {
+		logger.Error("schema_validate_failed", "thread", threadKey, "error", err)
+		WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to resolve message schema")
+		return false
+	}
+	if len(fieldErrs) == 0 {
+		return true
+	}
+
+	WriteSchemaValidationError(ctx, fieldErrs)
+	return false
+}
+
+// WriteSchemaValidationError writes a 400 listing the schema violations of a body.
+func WriteSchemaValidationError(ctx *fasthttp.RequestCtx, fields []schemas.FieldError) {
+	ctx.SetStatusCode(fasthttp.StatusBadRequest)
+	_ = WriteJSON(ctx, SchemaValidationError{Error: "body does not match schema", Fields: fields})
+}
+
+func threadTagsForSchemas(threadKey string) ([]string, error) {
+	hasTagSchemas, err := schemas.HasTagSchemas(keys.NamespaceOf(threadKey))
+	if err != nil || !hasTagSchemas {
+		return nil, err
+	}
+	return tracking.GlobalKeyMapper.ThreadTags(threadKey)
+}
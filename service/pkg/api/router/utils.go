@@ -71,7 +71,7 @@ func NewRequestMetadata(ctx *fasthttp.RequestCtx, author string) *RequestMetadat
 		ApiRole: utils.GetApiRole(ctx),
 		UserID:  author,
 		ReqID:   utils.GetHeader(ctx, "X-Request-Id"),
-		ReqIP:   ctx.RemoteAddr().String(),
+		ReqIP:   utils.GetClientIP(ctx),
 	}
 }
 
@@ -3,7 +3,9 @@ package router
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
+	"time"
 
 	"progressdb/pkg/api/utils"
 	"progressdb/pkg/ingest/tracking"
@@ -11,6 +13,7 @@ import (
 	"progressdb/pkg/store/db/indexdb"
 	message_store "progressdb/pkg/store/features/messages"
 	thread_store "progressdb/pkg/store/features/threads"
+	"progressdb/pkg/timeutil"
 
 	"github.com/valyala/fasthttp"
 )
@@ -47,7 +50,7 @@ func ValidateAllFieldsNonEmpty(p interface{}) error {
 			if v.UpdatedTS == 0 {
 				errors = append(errors, "updated_ts: cannot be zero")
 			}
-			if v.Title == "" {
+			if v.Title == "" && v.Tags == nil {
 				errors = append(errors, "title: cannot be empty")
 			}
 
@@ -80,6 +83,25 @@ func ValidateAllFieldsNonEmpty(p interface{}) error {
 				errors = append(errors, "updated_ts: cannot be zero")
 			}
 		}
+	case *models.ThreadParticipantPartial:
+		if v == nil {
+			errors = append(errors, "ThreadParticipantPartial cannot be nil")
+		} else {
+			if v.Thread == "" {
+				errors = append(errors, "thread: cannot be empty")
+			}
+			if v.UserID == "" {
+				errors = append(errors, "user_id: cannot be empty")
+			}
+			if !v.Removed {
+				if role, ok := models.ParseThreadRole(string(v.Role)); !ok || role == models.ThreadRoleOwner {
+					errors = append(errors, "role: must be one of admin, member, read_only")
+				}
+			}
+			if v.UpdatedTS == 0 {
+				errors = append(errors, "updated_ts: cannot be zero")
+			}
+		}
 	case *models.MessageDeletePartial:
 		if v == nil {
 			errors = append(errors, "MessageDeletePartial cannot be nil")
@@ -119,6 +141,12 @@ func ValidateAllFieldsNonEmpty(p interface{}) error {
 			if v.UpdatedTS == 0 {
 				errors = append(errors, "updated_ts: cannot be zero")
 			}
+			if v.DeliverAt != 0 && v.DeliverAt <= v.CreatedTS {
+				errors = append(errors, "deliver_at: must be in the future")
+			}
+			if v.ExpiresAt != 0 && (v.ExpiresAt <= v.CreatedTS || v.ExpiresAt <= v.DeliverAt) {
+				errors = append(errors, "expires_at: must be after the message is delivered")
+			}
 		}
 	case *models.Thread:
 		if v == nil {
@@ -265,7 +293,7 @@ func ValidateReadMessage(messageKey, author string, requireOwnership bool) (*mod
 		}
 	}
 
-	if message.Deleted {
+	if message.Deleted || (message.ExpiresAt != 0 && message.ExpiresAt <= timeutil.Now().UnixNano()) {
 		return nil, &AuthorResolutionError{
 			Type:    "message_not_found",
 			Message: "message not found",
@@ -294,8 +322,19 @@ func validateAuthor(a string) *AuthorResolutionError {
 	return nil
 }
 
-// extract author - depending on frontend or backend role
+// extract author - depending on frontend or backend role - within the api key's user prefix
 func ResolveAuthorFromRequestFast(ctx *fasthttp.RequestCtx, bodyAuthor string) (string, *AuthorResolutionError) {
+	author, err := resolveAuthor(ctx, bodyAuthor)
+	if err != nil {
+		return "", err
+	}
+	if err := AuthorizeKeyUser(ctx, author); err != nil {
+		return "", err
+	}
+	return author, nil
+}
+
+func resolveAuthor(ctx *fasthttp.RequestCtx, bodyAuthor string) (string, *AuthorResolutionError) {
 	// signature-verified author from user value if present
 	if v := ctx.UserValue("author"); v != nil {
 		if id, ok := v.(string); ok && id != "" {
@@ -383,3 +422,72 @@ func ValidateUserID(userID string) error {
 	}
 	return nil
 }
+
+var threadTagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]*$`)
+
+// NormalizeThreadTags lowercases, de-duplicates and validates thread tags.
+func NormalizeThreadTags(tags []string) ([]string, error) {
+	const (
+		maxTags   = 20
+		maxTagLen = 32
+	)
+
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("tags: at most %d tags allowed", maxTags)
+	}
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if err := ValidateThreadTag(tag); err != nil {
+			return nil, err
+		}
+		if len(tag) > maxTagLen {
+			return nil, fmt.Errorf("tags: %q too long (maximum %d characters)", tag, maxTagLen)
+		}
+		if _, dup := seen[tag]; dup {
+			continue
+		}
+		seen[tag] = struct{}{}
+		out = append(out, tag)
+	}
+	return out, nil
+}
+
+// ParseExpiresIn reads the optional expires_in field of a message payload, given
+// as a duration string ("90s", "24h") or a number of seconds.
+func ParseExpiresIn(payload []byte) (time.Duration, error) {
+	var req struct {
+		ExpiresIn json.RawMessage `json:"expires_in"`
+	}
+	if err := json.Unmarshal(payload, &req); err != nil || len(req.ExpiresIn) == 0 || string(req.ExpiresIn) == "null" {
+		return 0, nil
+	}
+
+	var ttl time.Duration
+	var raw string
+	var seconds float64
+	switch {
+	case json.Unmarshal(req.ExpiresIn, &raw) == nil:
+		d, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return 0, fmt.Errorf("expires_in: invalid duration %q", raw)
+		}
+		ttl = d
+	case json.Unmarshal(req.ExpiresIn, &seconds) == nil:
+		ttl = time.Duration(seconds * float64(time.Second))
+	default:
+		return 0, fmt.Errorf("expires_in: must be a duration string or a number of seconds")
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("expires_in: must be positive")
+	}
+	return ttl, nil
+}
+
+func ValidateThreadTag(tag string) error {
+	if !threadTagPattern.MatchString(tag) {
+		return fmt.Errorf("tags: %q must be lowercase letters, digits, '_', '.', ':' or '-'", tag)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/router"
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/features/apikeys"
+)
+
+func ListAPIKeys(ctx *fasthttp.RequestCtx) {
+	entries, err := apikeys.List(router.Namespace(ctx))
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to list api keys: "+err.Error())
+		return
+	}
+	_ = router.WriteJSON(ctx, map[string]interface{}{"keys": entries})
+}
+
+func CreateAPIKey(ctx *fasthttp.RequestCtx) {
+	payload, ok := router.ExtractPayloadOrFail(ctx)
+	if !ok {
+		return
+	}
+	var req struct {
+		Role      string `json:"role"`
+		Label     string `json:"label"`
+		ExpiresIn int64  `json:"expires_in"` // seconds; 0 never expires
+
+		models.APIKeyGrant
+	}
+	if err := json.Unmarshal(payload, &req); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid api key payload")
+		return
+	}
+	tags, err := router.NormalizeThreadTags(req.ThreadTags)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "thread_"+err.Error())
+		return
+	}
+	req.ThreadTags = tags
+
+	key, secret, err := apikeys.Create(router.Namespace(ctx), models.APIKeyRole(req.Role), req.Label, time.Duration(req.ExpiresIn)*time.Second, req.APIKeyGrant)
+	if err != nil {
+		writeAPIKeyError(ctx, err)
+		return
+	}
+	logger.Info("api_key_created", "id", key.ID, "role", key.Role)
+	router.RecordAudit(ctx, models.AuditRecord{
+		Action: models.AuditActionAPIKeyCreate,
+		Target: key.ID,
+		Detail: map[string]string{"role": string(key.Role)},
+	})
+	ctx.SetStatusCode(fasthttp.StatusCreated)
+	_ = router.WriteJSON(ctx, map[string]interface{}{"key": secret, "api_key": key})
+}
+
+func UpdateAPIKey(ctx *fasthttp.RequestCtx) {
+	id, ok := extractParamOrFail(ctx, "keyId", "key id missing")
+	if !ok {
+		return
+	}
+	payload, ok := router.ExtractPayloadOrFail(ctx)
+	if !ok {
+		return
+	}
+	var req struct {
+		Label       *string   `json:"label"`
+		ExpiresIn   *int64    `json:"expires_in"` // seconds from now; 0 removes the expiry
+		Permissions *[]string `json:"permissions"`
+		Paths       *[]string `json:"paths"`
+		ThreadTags  *[]string `json:"thread_tags"`
+		UserPrefix  *string   `json:"user_prefix"`
+	}
+	if err := json.Unmarshal(payload, &req); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid api key payload")
+		return
+	}
+	changes := apikeys.Changes{
+		Label:       req.Label,
+		Permissions: req.Permissions,
+		Paths:       req.Paths,
+		UserPrefix:  req.UserPrefix,
+	}
+	if req.ExpiresIn != nil {
+		d := time.Duration(*req.ExpiresIn) * time.Second
+		changes.ExpiresIn = &d
+	}
+	if req.ThreadTags != nil {
+		tags, err := router.NormalizeThreadTags(*req.ThreadTags)
+		if err != nil {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "thread_"+err.Error())
+			return
+		}
+		changes.ThreadTags = &tags
+	}
+
+	key, err := apikeys.Update(router.Namespace(ctx), id, changes)
+	if err != nil {
+		writeAPIKeyError(ctx, err)
+		return
+	}
+	logger.Info("api_key_updated", "id", key.ID)
+	router.RecordAudit(ctx, models.AuditRecord{Action: models.AuditActionAPIKeyUpdate, Target: key.ID})
+	_ = router.WriteJSON(ctx, key)
+}
+
+func RevokeAPIKey(ctx *fasthttp.RequestCtx) {
+	id, ok := extractParamOrFail(ctx, "keyId", "key id missing")
+	if !ok {
+		return
+	}
+	key, err := apikeys.Revoke(router.Namespace(ctx), id)
+	if err != nil {
+		writeAPIKeyError(ctx, err)
+		return
+	}
+	logger.Info("api_key_revoked", "id", key.ID, "role", key.Role)
+	router.RecordAudit(ctx, models.AuditRecord{
+		Action: models.AuditActionAPIKeyRevoke,
+		Target: key.ID,
+		Detail: map[string]string{"role": string(key.Role)},
+	})
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+func writeAPIKeyError(ctx *fasthttp.RequestCtx, err error) {
+	switch {
+	case errors.Is(err, apikeys.ErrNotFound):
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, err.Error())
+	case apikeys.IsInvalid(err):
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+	default:
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
+	}
+}
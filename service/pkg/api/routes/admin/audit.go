@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/router"
+	"progressdb/pkg/api/utils"
+	"progressdb/pkg/models"
+	"progressdb/pkg/store/features/audit"
+	"progressdb/pkg/store/pagination"
+)
+
+// ListAudit returns audit records newest first. Pass next_before back as before to
+// fetch the following page.
+func ListAudit(ctx *fasthttp.RequestCtx) {
+	limit := utils.GetQueryInt(ctx, "limit", pagination.AdminDefaultLimit)
+	if limit <= 0 || limit > pagination.AdminMaxLimit {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("invalid limit: must be between 1 and %d", pagination.AdminMaxLimit))
+		return
+	}
+	filter := models.AuditFilter{
+		UserID:    utils.GetQuery(ctx, "user"),
+		Thread:    utils.GetQuery(ctx, "thread"),
+		Action:    utils.GetQuery(ctx, "action"),
+		Namespace: router.Namespace(ctx),
+		Limit:     limit + 1, // one extra tells whether another page follows
+	}
+	var err error
+	if filter.From, err = parseAuditTime(utils.GetQuery(ctx, "from")); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "from: "+err.Error())
+		return
+	}
+	if filter.To, err = parseAuditTime(utils.GetQuery(ctx, "to")); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "to: "+err.Error())
+		return
+	}
+	if before := utils.GetQuery(ctx, "before"); before != "" {
+		if filter.Before, err = strconv.ParseUint(before, 10, 64); err != nil || filter.Before == 0 {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "before: must be a record sequence number")
+			return
+		}
+	}
+
+	records, err := audit.Query(filter)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to query audit trail: "+err.Error())
+		return
+	}
+	resp := map[string]interface{}{"records": records}
+	if len(records) > limit {
+		records = records[:limit]
+		resp["records"] = records
+		resp["next_before"] = records[limit-1].Seq
+	}
+	_ = router.WriteJSON(ctx, resp)
+}
+
+// VerifyAudit walks the whole trail and reports the first break in its hash chain.
+func VerifyAudit(ctx *fasthttp.RequestCtx) {
+	res, err := audit.Verify()
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to verify audit trail: "+err.Error())
+		return
+	}
+	_ = router.WriteJSON(ctx, res)
+}
+
+// parseAuditTime accepts RFC 3339 or unix nanoseconds; empty means unbounded.
+func parseAuditTime(v string) (int64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	if ns, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return ns, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return 0, fmt.Errorf("must be RFC 3339 or unix nanoseconds")
+	}
+	return t.UnixNano(), nil
+}
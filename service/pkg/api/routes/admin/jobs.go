@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 
 	"github.com/valyala/fasthttp"
 
 	"progressdb/internal/retention"
 	"progressdb/pkg/api/router"
+	"progressdb/pkg/api/utils"
 	"progressdb/pkg/models"
 	storedb "progressdb/pkg/store/db/storedb"
 	"progressdb/pkg/store/encryption"
@@ -30,6 +32,10 @@ type EncryptThreadsResult struct {
 }
 
 func encryptThread(threadKey string) EncryptThreadsResult {
+	if !encryption.NamespaceEncrypted(keys.NamespaceOf(threadKey)) {
+		return EncryptThreadsResult{ThreadKey: threadKey, Status: "skipped", Error: "encryption disabled for namespace"}
+	}
+
 	// Get thread data
 	stored, err := thread_store.GetThreadData(threadKey)
 	if err != nil {
@@ -117,9 +123,9 @@ func encryptThread(threadKey string) EncryptThreadsResult {
 	}
 }
 
-func getAllThreads() ([]string, error) {
-	// Use key iterator to get all thread metadata keys
-	threadPrefix := keys.GenThreadMetadataPrefix()
+func getAllThreads(namespace string) ([]string, error) {
+	// Use key iterator to get all thread metadata keys of the namespace
+	threadPrefix := keys.GenThreadMetadataPrefix(namespace)
 	keyIter := ki.NewKeyIterator(storedb.Client)
 
 	threadKeys, _, err := keyIter.ExecuteKeyQuery(threadPrefix, pagination.PaginationRequest{
@@ -148,7 +154,7 @@ func EncryptThreads(ctx *fasthttp.RequestCtx) {
 	}
 
 	// Get all threads
-	threadKeys, err := getAllThreads()
+	threadKeys, err := getAllThreads(router.Namespace(ctx))
 	if err != nil {
 		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to get threads: "+err.Error())
 		return
@@ -194,9 +200,34 @@ func EncryptThreads(ctx *fasthttp.RequestCtx) {
 }
 
 func RunRetentionCleanup(ctx *fasthttp.RequestCtx) {
-	if err := retention.RunImmediate(); err != nil {
+	dryRun := utils.GetQueryLower(ctx, "dry_run") == "true"
+	run, err := retention.RunImmediate(dryRun)
+	if err != nil {
 		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
 		return
 	}
-	_ = router.WriteJSON(ctx, map[string]string{"status": "ok", "message": "retention run triggered"})
+	message := "retention run triggered"
+	if dryRun {
+		message = "retention dry run completed, nothing was purged"
+	}
+	_ = router.WriteJSON(ctx, map[string]interface{}{"status": "ok", "message": message, "run": run})
+}
+
+const (
+	defaultRetentionRunsLimit = 20
+	maxRetentionRunsLimit     = 200
+)
+
+func ListRetentionRuns(ctx *fasthttp.RequestCtx) {
+	limit := utils.GetQueryInt(ctx, "limit", defaultRetentionRunsLimit)
+	if limit <= 0 || limit > maxRetentionRunsLimit {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("invalid limit: must be between 1 and %d", maxRetentionRunsLimit))
+		return
+	}
+	runs, err := retention.ListRuns(limit)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to list retention runs: "+err.Error())
+		return
+	}
+	_ = router.WriteJSON(ctx, map[string]interface{}{"runs": runs})
 }
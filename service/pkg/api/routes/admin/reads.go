@@ -31,11 +31,13 @@ func Health(ctx *fasthttp.RequestCtx) {
 func Stats(ctx *fasthttp.RequestCtx) {
 	ctx.Response.Header.Set("Content-Type", "application/json")
 
+	namespace := router.Namespace(ctx)
+
 	// Count total messages from indexdb by scanning idx:t:*:ms:end keys
 	var totalMessages int64
 	msgIter, err := indexdb.Client.NewIter(&pebble.IterOptions{
-		LowerBound: []byte("idx:t:"),
-		UpperBound: nextPrefix([]byte("idx:t:")),
+		LowerBound: []byte(keys.Namespaced(namespace, "idx:t:")),
+		UpperBound: nextPrefix([]byte(keys.Namespaced(namespace, "idx:t:"))),
 	})
 	if err != nil {
 		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
@@ -57,8 +59,8 @@ func Stats(ctx *fasthttp.RequestCtx) {
 	// Count deleted threads by scanning del:t:* keys
 	var deletedThreads int
 	delIter, err := indexdb.Client.NewIter(&pebble.IterOptions{
-		LowerBound: []byte("del:t:"),
-		UpperBound: nextPrefix([]byte("del:t:")),
+		LowerBound: []byte(keys.Namespaced(namespace, "del:t:")),
+		UpperBound: nextPrefix([]byte(keys.Namespaced(namespace, "del:t:"))),
 	})
 	if err != nil {
 		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
@@ -67,11 +69,11 @@ func Stats(ctx *fasthttp.RequestCtx) {
 	defer delIter.Close()
 
 	for valid := delIter.First(); valid; valid = delIter.Next() {
-		keyStr := string(delIter.Key())
+		_, keyStr := keys.SplitNamespace(string(delIter.Key()))
 		// Verify this is a thread delete marker (del:t:*)
 		if len(keyStr) > 5 && keyStr[:5] == "del:t:" {
 			// Extract the original thread key and validate it's a thread
-			originalKey := keyStr[4:] // Remove "del:" prefix
+			originalKey := keys.Namespaced(namespace, keyStr[4:]) // Remove "del:" prefix
 			if parsed, err := keys.ParseKey(originalKey); err == nil && parsed.Type == keys.KeyTypeThread {
 				deletedThreads++
 			}
@@ -81,8 +83,8 @@ func Stats(ctx *fasthttp.RequestCtx) {
 	// Count total threads by scanning t:* keys in storedb
 	var totalThreads int
 	threadIter, err := storedb.Client.NewIter(&pebble.IterOptions{
-		LowerBound: []byte("t:"),
-		UpperBound: nextPrefix([]byte("t:")),
+		LowerBound: []byte(keys.Namespaced(namespace, "t:")),
+		UpperBound: nextPrefix([]byte(keys.Namespaced(namespace, "t:"))),
 	})
 	if err != nil {
 		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
@@ -114,7 +116,7 @@ func ListThreads(ctx *fasthttp.RequestCtx) {
 
 	// Get all thread keys with large limit
 	req := pagination.PaginationRequest{Limit: 10000}
-	threadKeys, _, err := keyIter.ExecuteKeyQuery(keys.GenThreadMetadataPrefix(), req)
+	threadKeys, _, err := keyIter.ExecuteKeyQuery(keys.GenThreadMetadataPrefix(router.Namespace(ctx)), req)
 	if err != nil {
 		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
 		return
@@ -149,6 +151,10 @@ func ListKeys(ctx *fasthttp.RequestCtx) {
 		store = "main"
 	}
 	paginationReq := utils.ParsePaginationRequest(ctx)
+	// admins of a named namespace browse only its keys
+	if namespace := router.Namespace(ctx); namespace != "" {
+		prefix = keys.Namespaced(namespace, prefix)
+	}
 
 	// Use admin key iterator for proper pagination
 	var keys []string
@@ -185,6 +191,10 @@ func GetKey(ctx *fasthttp.RequestCtx) {
 		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid key encoding")
 		return
 	}
+	if router.Namespace(ctx) != "" && !router.InNamespace(ctx, key) {
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, "key not found")
+		return
+	}
 	storeParam, _ := extractQueryOrFail(ctx, "store", "")
 
 	logger.Debug("GetKey: storeParam", storeParam)
@@ -205,7 +215,7 @@ func GetKey(ctx *fasthttp.RequestCtx) {
 
 func ListUsers(ctx *fasthttp.RequestCtx) {
 	// Use direct database iteration with prefix bounds
-	lowerBound := []byte(keys.UserThreadsRelPrefix)
+	lowerBound := []byte(keys.Namespaced(router.Namespace(ctx), keys.UserThreadsRelPrefix))
 	upperBound := nextPrefix(lowerBound)
 
 	iter, err := indexdb.Client.NewIter(&pebble.IterOptions{
@@ -265,7 +275,7 @@ func ListUserThreads(ctx *fasthttp.RequestCtx) {
 	}
 
 	// Use direct database iteration with prefix bounds
-	prefix, err := keys.GenUserThreadRelPrefix(userID)
+	prefix, err := keys.GenUserThreadRelPrefix(router.Namespace(ctx), userID)
 	if err != nil {
 		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
 		return
@@ -0,0 +1,161 @@
+package admin
+
+import (
+	"encoding/json"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/router"
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/features/policies"
+)
+
+func ListRetentionPolicies(ctx *fasthttp.RequestCtx) {
+	entries, err := policies.ListPolicies(router.Namespace(ctx))
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to list retention policies: "+err.Error())
+		return
+	}
+	_ = router.WriteJSON(ctx, map[string]interface{}{"policies": entries})
+}
+
+func PutRetentionPolicy(ctx *fasthttp.RequestCtx) {
+	scope, target, ok := extractRetentionTarget(ctx)
+	if !ok {
+		return
+	}
+	payload, ok := router.ExtractPayloadOrFail(ctx)
+	if !ok {
+		return
+	}
+	var policy models.RetentionPolicy
+	if err := json.Unmarshal(payload, &policy); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid retention policy payload")
+		return
+	}
+	policy.Scope = scope
+	policy.Target = target
+
+	if err := policies.SetPolicy(router.Namespace(ctx), policy); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+	logger.Info("retention_policy_set", "scope", scope, "target", target)
+	_ = router.WriteJSON(ctx, map[string]string{"scope": string(scope), "target": target})
+}
+
+func DeleteRetentionPolicy(ctx *fasthttp.RequestCtx) {
+	scope, target, ok := extractRetentionTarget(ctx)
+	if !ok {
+		return
+	}
+	deleted, err := policies.DeletePolicy(router.Namespace(ctx), scope, target)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
+		return
+	}
+	if !deleted {
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, "retention policy not found")
+		return
+	}
+	logger.Info("retention_policy_removed", "scope", scope, "target", target)
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+func ListLegalHolds(ctx *fasthttp.RequestCtx) {
+	holds, err := policies.ListHolds(router.Namespace(ctx))
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to list legal holds: "+err.Error())
+		return
+	}
+	_ = router.WriteJSON(ctx, map[string]interface{}{"holds": holds})
+}
+
+func PutLegalHold(ctx *fasthttp.RequestCtx) {
+	threadKey, ok := extractHoldThread(ctx)
+	if !ok {
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if body := ctx.PostBody(); len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid legal hold payload")
+			return
+		}
+	}
+
+	hold, err := policies.PlaceHold(threadKey, req.Reason)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, err.Error())
+		return
+	}
+	logger.Info("legal_hold_placed", "thread", threadKey)
+	_ = router.WriteJSON(ctx, hold)
+}
+
+func DeleteLegalHold(ctx *fasthttp.RequestCtx) {
+	threadKey, ok := extractHoldThread(ctx)
+	if !ok {
+		return
+	}
+	released, err := policies.ReleaseHold(threadKey)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
+		return
+	}
+	if !released {
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, "legal hold not found")
+		return
+	}
+	logger.Info("legal_hold_released", "thread", threadKey)
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+func extractRetentionTarget(ctx *fasthttp.RequestCtx) (models.RetentionScope, string, bool) {
+	rawScope, ok := extractParamOrFail(ctx, "scope", "scope missing")
+	if !ok {
+		return "", "", false
+	}
+	scope, valid := models.ParseRetentionScope(rawScope)
+	if !valid {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "scope: must be one of thread, tag, owner")
+		return "", "", false
+	}
+	target, ok := extractParamOrFail(ctx, "target", "target missing")
+	if !ok {
+		return "", "", false
+	}
+
+	var err error
+	switch scope {
+	case models.RetentionScopeThread:
+		err = router.ValidateThreadKey(target)
+	case models.RetentionScopeTag:
+		var tags []string
+		if tags, err = router.NormalizeThreadTags([]string{target}); err == nil {
+			target = tags[0]
+		}
+	case models.RetentionScopeOwner:
+		err = router.ValidateUserID(target)
+	}
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return "", "", false
+	}
+	return scope, target, true
+}
+
+func extractHoldThread(ctx *fasthttp.RequestCtx) (string, bool) {
+	threadKey, ok := extractParamOrFail(ctx, "threadKey", "thread key missing")
+	if !ok {
+		return "", false
+	}
+	if err := router.ValidateThreadKey(threadKey); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return "", false
+	}
+	return threadKey, true
+}
@@ -0,0 +1,93 @@
+package admin
+
+import (
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/router"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/features/schemas"
+)
+
+func ListSchemas(ctx *fasthttp.RequestCtx) {
+	entries, err := schemas.List(router.Namespace(ctx))
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to list schemas: "+err.Error())
+		return
+	}
+	_ = router.WriteJSON(ctx, map[string]interface{}{"schemas": entries})
+}
+
+func PutGlobalSchema(ctx *fasthttp.RequestCtx) {
+	payload, ok := router.ExtractPayloadOrFail(ctx)
+	if !ok {
+		return
+	}
+	if err := schemas.SetGlobal(router.Namespace(ctx), payload); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+	logger.Info("schema_registered", "scope", schemas.ScopeGlobal)
+	_ = router.WriteJSON(ctx, map[string]string{"scope": schemas.ScopeGlobal})
+}
+
+func DeleteGlobalSchema(ctx *fasthttp.RequestCtx) {
+	deleted, err := schemas.DeleteGlobal(router.Namespace(ctx))
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
+		return
+	}
+	if !deleted {
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, "schema not found")
+		return
+	}
+	logger.Info("schema_removed", "scope", schemas.ScopeGlobal)
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+func PutTagSchema(ctx *fasthttp.RequestCtx) {
+	tag, ok := extractSchemaTag(ctx)
+	if !ok {
+		return
+	}
+	payload, ok := router.ExtractPayloadOrFail(ctx)
+	if !ok {
+		return
+	}
+	if err := schemas.SetTag(router.Namespace(ctx), tag, payload); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+	logger.Info("schema_registered", "scope", schemas.ScopeTag, "tag", tag)
+	_ = router.WriteJSON(ctx, map[string]string{"scope": schemas.ScopeTag, "tag": tag})
+}
+
+func DeleteTagSchema(ctx *fasthttp.RequestCtx) {
+	tag, ok := extractSchemaTag(ctx)
+	if !ok {
+		return
+	}
+	deleted, err := schemas.DeleteTag(router.Namespace(ctx), tag)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
+		return
+	}
+	if !deleted {
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, "schema not found")
+		return
+	}
+	logger.Info("schema_removed", "scope", schemas.ScopeTag, "tag", tag)
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+func extractSchemaTag(ctx *fasthttp.RequestCtx) (string, bool) {
+	tag, ok := extractParamOrFail(ctx, "tag", "tag missing")
+	if !ok {
+		return "", false
+	}
+	tags, err := router.NormalizeThreadTags([]string{tag})
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return "", false
+	}
+	return tags[0], true
+}
@@ -0,0 +1,96 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/router"
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/features/signingkeys"
+)
+
+func ListSigningKeys(ctx *fasthttp.RequestCtx) {
+	entries, err := signingkeys.List(router.Namespace(ctx))
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to list signing keys: "+err.Error())
+		return
+	}
+	_ = router.WriteJSON(ctx, map[string]interface{}{"keys": entries})
+}
+
+func RotateSigningKey(ctx *fasthttp.RequestCtx) {
+	payload, ok := router.ExtractPayloadOrFail(ctx)
+	if !ok {
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+	}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &req); err != nil {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid signing key payload")
+			return
+		}
+	}
+
+	key, err := signingkeys.Rotate(router.Namespace(ctx), req.ID)
+	if err != nil {
+		writeSigningKeyError(ctx, err)
+		return
+	}
+	logger.Info("signing_key_rotated", "key_id", key.ID)
+	router.RecordAudit(ctx, models.AuditRecord{Action: models.AuditActionSigningKeyRotate, Target: key.ID})
+	_ = router.WriteJSON(ctx, key)
+}
+
+func PutSigningKeyState(ctx *fasthttp.RequestCtx) {
+	id, ok := extractParamOrFail(ctx, "keyId", "key id missing")
+	if !ok {
+		return
+	}
+	payload, ok := router.ExtractPayloadOrFail(ctx)
+	if !ok {
+		return
+	}
+	var req struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal(payload, &req); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid signing key payload")
+		return
+	}
+	state, ok := models.ParseSigningKeyState(req.State)
+	if !ok {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "state: must be one of active, verify, retired")
+		return
+	}
+
+	key, err := signingkeys.SetState(router.Namespace(ctx), id, state)
+	if err != nil {
+		writeSigningKeyError(ctx, err)
+		return
+	}
+	logger.Info("signing_key_state_set", "key_id", key.ID, "state", key.State)
+	router.RecordAudit(ctx, models.AuditRecord{
+		Action: models.AuditActionSigningKeyState,
+		Target: key.ID,
+		Detail: map[string]string{"state": string(key.State)},
+	})
+	_ = router.WriteJSON(ctx, key)
+}
+
+func writeSigningKeyError(ctx *fasthttp.RequestCtx, err error) {
+	switch {
+	case errors.Is(err, signingkeys.ErrInvalidID):
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+	case errors.Is(err, signingkeys.ErrNotFound):
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, err.Error())
+	case errors.Is(err, signingkeys.ErrExists), errors.Is(err, signingkeys.ErrActiveKey):
+		router.WriteJSONError(ctx, fasthttp.StatusConflict, err.Error())
+	default:
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,193 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/router"
+	"progressdb/pkg/ingest/queue"
+	"progressdb/pkg/ingest/types"
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/db/indexdb"
+	thread_store "progressdb/pkg/store/features/threads"
+	"progressdb/pkg/store/keys"
+	"progressdb/pkg/timeutil"
+)
+
+type TransferRequest struct {
+	To                string `json:"to"`
+	KeepPreviousOwner bool   `json:"keep_previous_owner"`
+	PreviousOwnerRole string `json:"previous_owner_role"`
+}
+
+// TransferThread moves a single thread to a new owner.
+func TransferThread(ctx *fasthttp.RequestCtx) {
+	threadKey, ok := extractParamOrFail(ctx, "threadKey", "missing threadKey")
+	if !ok {
+		return
+	}
+	if err := router.ValidateThreadKey(threadKey); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+	req, ok := parseTransferRequest(ctx)
+	if !ok {
+		return
+	}
+
+	data, err := thread_store.GetThreadData(threadKey)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, "thread not found")
+		return
+	}
+	var thread models.Thread
+	if err := json.Unmarshal([]byte(data), &thread); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, "invalid thread data")
+		return
+	}
+	if thread.Author == req.To {
+		router.WriteJSONError(ctx, fasthttp.StatusConflict, "thread is already owned by "+req.To)
+		return
+	}
+	if thread.IsDirect() {
+		router.WriteJSONError(ctx, fasthttp.StatusConflict, "direct-message threads cannot be transferred")
+		return
+	}
+
+	if err := enqueueThreadTransfer(ctx, threadKey, thread.Author, req); err != nil {
+		writeQueueError(ctx, err)
+		return
+	}
+	ctx.SetStatusCode(fasthttp.StatusAccepted)
+	_ = router.WriteJSON(ctx, map[string]string{"key": threadKey, "from": thread.Author, "to": req.To})
+}
+
+// TransferUserThreads moves every thread owned by a user to a new owner. Direct-message
+// threads stay with their participants.
+func TransferUserThreads(ctx *fasthttp.RequestCtx) {
+	userID, ok := extractParamOrFail(ctx, "userId", "missing userId")
+	if !ok {
+		return
+	}
+	if err := router.ValidateUserID(userID); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid userID format")
+		return
+	}
+	req, ok := parseTransferRequest(ctx)
+	if !ok {
+		return
+	}
+	if userID == req.To {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "to: must differ from the current owner")
+		return
+	}
+
+	relKeys, err := indexdb.ListUserThreadKeys(router.Namespace(ctx), userID)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
+		return
+	}
+
+	threadKeys := make([]string, 0, len(relKeys))
+	for _, relKey := range relKeys {
+		parsed, err := keys.ParseKey(relKey)
+		if err != nil || parsed.Type != keys.KeyTypeUserOwnsThread || parsed.UserID != userID {
+			continue
+		}
+		if direct, err := isDirectThread(parsed.ThreadKey); err != nil || direct {
+			continue
+		}
+		if err := enqueueThreadTransfer(ctx, parsed.ThreadKey, userID, req); err != nil {
+			// threads already queued still move; report where the job stopped
+			logger.Error("thread_transfer_enqueue_failed", "thread", parsed.ThreadKey, "error", err)
+			writeQueueError(ctx, err)
+			return
+		}
+		threadKeys = append(threadKeys, parsed.ThreadKey)
+	}
+
+	logger.Info("user_threads_transfer_queued", "from", userID, "to", req.To, "threads", len(threadKeys))
+	ctx.SetStatusCode(fasthttp.StatusAccepted)
+	_ = router.WriteJSON(ctx, map[string]interface{}{"from": userID, "to": req.To, "threads": threadKeys})
+}
+
+func isDirectThread(threadKey string) (bool, error) {
+	data, err := thread_store.GetThreadData(threadKey)
+	if err != nil {
+		return false, err
+	}
+	var thread models.Thread
+	if err := json.Unmarshal([]byte(data), &thread); err != nil {
+		return false, err
+	}
+	return thread.IsDirect(), nil
+}
+
+func parseTransferRequest(ctx *fasthttp.RequestCtx) (TransferRequest, bool) {
+	var req TransferRequest
+	payload, ok := router.ExtractPayloadOrFail(ctx)
+	if !ok {
+		return req, false
+	}
+	if err := json.Unmarshal(payload, &req); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid transfer payload")
+		return req, false
+	}
+	if err := router.ValidateUserID(req.To); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "to: "+err.Error())
+		return req, false
+	}
+	if req.KeepPreviousOwner {
+		if req.PreviousOwnerRole == "" {
+			req.PreviousOwnerRole = string(models.ThreadRoleMember)
+		}
+		role, valid := models.ParseThreadRole(req.PreviousOwnerRole)
+		if !valid || role == models.ThreadRoleOwner {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "previous_owner_role: must be one of read_only, member, admin")
+			return req, false
+		}
+		req.PreviousOwnerRole = string(role)
+	} else if req.PreviousOwnerRole != "" {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "previous_owner_role: requires keep_previous_owner")
+		return req, false
+	}
+	return req, true
+}
+
+func enqueueThreadTransfer(ctx *fasthttp.RequestCtx, threadKey, from string, req TransferRequest) error {
+	reqtime := timeutil.Now().UnixNano()
+	metadata := router.NewRequestMetadata(ctx, "")
+	return queue.GlobalIngestQueue.Enqueue(&types.QueueOp{
+		Handler: types.HandlerThreadTransfer,
+		Payload: &models.ThreadTransferPartial{
+			Thread:            threadKey,
+			From:              from,
+			To:                req.To,
+			KeepPreviousOwner: req.KeepPreviousOwner,
+			PreviousOwnerRole: models.ThreadRole(req.PreviousOwnerRole),
+			UpdatedTS:         reqtime,
+		},
+		TS: reqtime,
+		Extras: types.RequestMetadata{
+			ApiRole: metadata.ApiRole,
+			UserID:  metadata.UserID,
+			ReqID:   metadata.ReqID,
+			ReqIP:   metadata.ReqIP,
+		},
+	})
+}
+
+func writeQueueError(ctx *fasthttp.RequestCtx, err error) {
+	switch {
+	case errors.Is(err, queue.ErrQueueFull):
+		router.WriteJSONError(ctx, fasthttp.StatusTooManyRequests, "server busy; try again")
+	case errors.Is(err, queue.ErrQueueClosed):
+		router.WriteJSONError(ctx, fasthttp.StatusServiceUnavailable, "server shutting down")
+	default:
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("enqueue failed: %v", err))
+	}
+}
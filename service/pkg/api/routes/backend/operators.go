@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/valyala/fasthttp"
 
@@ -12,6 +13,8 @@ import (
 	"progressdb/pkg/api/utils"
 	"progressdb/pkg/config"
 	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/features/signingkeys"
+	"progressdb/pkg/timeutil"
 )
 
 func Sign(ctx *fasthttp.RequestCtx) {
@@ -26,7 +29,10 @@ func Sign(ctx *fasthttp.RequestCtx) {
 	}
 
 	var payload struct {
-		UserID string `json:"userId"`
+		UserID    string   `json:"userId"`
+		ExpiresIn int64    `json:"expiresIn"` // seconds; the configured token ttl when zero
+		Scope     string   `json:"scope"`     // read or write (default)
+		Threads   []string `json:"threads"`   // limits the token to these threads
 	}
 	if err := json.NewDecoder(bytes.NewReader(ctx.PostBody())).Decode(&payload); err != nil {
 		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid JSON payload")
@@ -38,22 +44,83 @@ func Sign(ctx *fasthttp.RequestCtx) {
 		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("invalid user ID: %s", err.Error()))
 		return
 	}
+	if err := router.AuthorizeKeyUser(ctx, payload.UserID); err != nil {
+		router.WriteValidationError(ctx, err)
+		return
+	}
+
+	// Validate token options
+	ttl, maxTTL := config.TokenTTLs()
+	if payload.ExpiresIn < 0 {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "expiresIn: must not be negative")
+		return
+	}
+	if payload.ExpiresIn > 0 {
+		ttl = time.Duration(payload.ExpiresIn) * time.Second
+		if ttl > maxTTL {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("expiresIn: at most %d seconds allowed", int64(maxTTL/time.Second)))
+			return
+		}
+	}
+	switch payload.Scope {
+	case "":
+		payload.Scope = auth.TokenScopeWrite
+	case auth.TokenScopeRead, auth.TokenScopeWrite:
+	default:
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "scope: must be read or write")
+		return
+	}
+	for _, threadKey := range payload.Threads {
+		if err := router.ValidateThreadKey(threadKey); err != nil {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("threads: %s", err.Error()))
+			return
+		}
+		if !router.InNamespace(ctx, threadKey) {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("threads: %s is not in this namespace", threadKey))
+			return
+		}
+	}
 
-	signingKey, err := getSigningKey()
+	signingKey, err := signingkeys.Active(router.Namespace(ctx))
 	if err != nil {
 		logger.Error("failed to get signing key", "error", err, "remote", ctx.RemoteAddr().String())
 		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
 		return
 	}
 
-	sig, sigErr := auth.CreateHMACSignature(payload.UserID, signingKey)
-	if sigErr != nil {
-		logger.Error("failed to create HMAC signature", "error", sigErr, "remote", ctx.RemoteAddr().String())
-		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to create HMAC signature")
+	now := timeutil.Now()
+	claims := auth.TokenClaims{
+		UserID:    payload.UserID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+		Scope:     payload.Scope,
+		Threads:   payload.Threads,
+	}
+	token, err := auth.CreateFrontendToken(claims, signingKey.ID, signingKey.Secret)
+	if err != nil {
+		logger.Error("failed to create frontend token", "error", err, "remote", ctx.RemoteAddr().String())
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to create token")
 		return
 	}
+	resp := map[string]interface{}{
+		"userId":    payload.UserID,
+		"token":     token,
+		"keyId":     signingKey.ID,
+		"expiresAt": claims.ExpiresAt,
+	}
 
-	if err := router.WriteJSON(ctx, map[string]string{"userId": payload.UserID, "signature": sig}); err != nil {
+	// legacy signatures never expire - only issued while still accepted
+	if !config.RequireFrontendTokens() {
+		sig, sigErr := auth.CreateKeyedSignature(payload.UserID, signingKey.ID, signingKey.Secret)
+		if sigErr != nil {
+			logger.Error("failed to create HMAC signature", "error", sigErr, "remote", ctx.RemoteAddr().String())
+			router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to create HMAC signature")
+			return
+		}
+		resp["signature"] = sig
+	}
+
+	if err := router.WriteJSON(ctx, resp); err != nil {
 		logger.Error("failed to encode signHandler response", "error", err, "remote", ctx.RemoteAddr().String())
 	}
 }
@@ -61,17 +128,3 @@ func Sign(ctx *fasthttp.RequestCtx) {
 func isBackendRequest(ctx *fasthttp.RequestCtx) bool {
 	return utils.IsBackendRole(ctx)
 }
-
-func getSigningKey() (string, error) {
-	signingKeys := config.GetSigningKeys()
-	if len(signingKeys) == 0 {
-		return "", fmt.Errorf("signing keys not configured")
-	}
-
-	// Return the first available signing key
-	for k := range signingKeys {
-		return k, nil
-	}
-
-	return "", fmt.Errorf("no signing keys available")
-}
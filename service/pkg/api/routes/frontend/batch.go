@@ -0,0 +1,644 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/router"
+	"progressdb/pkg/config"
+	"progressdb/pkg/ingest/hooks"
+	"progressdb/pkg/ingest/queue"
+	"progressdb/pkg/ingest/tracking"
+	"progressdb/pkg/ingest/types"
+	"progressdb/pkg/models"
+	"progressdb/pkg/store/encryption"
+	"progressdb/pkg/store/features/quotas"
+	"progressdb/pkg/store/keys"
+	"progressdb/pkg/timeutil"
+)
+
+const maxBatchMessages = 100
+
+type MessageBatchRequest struct {
+	Messages []json.RawMessage `json:"messages"`
+}
+
+// EnqueueCreateMessageBatch creates several messages in one request. The batch is enqueued
+// as a single operation, so the messages are applied together with contiguous sequences
+// in the order given.
+func EnqueueCreateMessageBatch(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.Set("Content-Type", "application/json")
+
+	// sync
+	reqtime := timeutil.Now().UnixNano()
+
+	threadKey, ok := router.ExtractParamOrFail(ctx, "threadKey", "thread id missing")
+	if !ok {
+		return
+	}
+
+	// validate
+	if err := router.ValidateThreadKey(threadKey); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+
+	// validate - del status
+	if err := router.ValidateThreadNotDeleted(threadKey); err != nil {
+		router.HandleDeletedError(ctx, err)
+		return
+	}
+
+	// resolve
+	author, authErr := router.ValidateAuthor(ctx, "")
+	if authErr != nil {
+		router.WriteValidationError(ctx, authErr)
+		return
+	}
+
+	// parse
+	payload, ok := router.ExtractPayloadOrFail(ctx)
+	if !ok {
+		return
+	}
+	var req MessageBatchRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid message batch payload")
+		return
+	}
+	if len(req.Messages) == 0 {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "messages: at least one message is required")
+		return
+	}
+	if len(req.Messages) > maxBatchMessages {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("messages: at most %d messages allowed", maxBatchMessages))
+		return
+	}
+	metadata := router.NewRequestMetadata(ctx, author)
+	extras := types.RequestMetadata{
+		ApiRole: metadata.ApiRole,
+		UserID:  metadata.UserID,
+		ReqID:   metadata.ReqID,
+		ReqIP:   metadata.ReqIP,
+	}
+
+	// sync - each message gets its own timestamp so keys keep the given order
+	batch := models.MessageBatchPartial{
+		Thread:   threadKey,
+		Author:   author,
+		Messages: make([]models.Message, len(req.Messages)),
+	}
+	messageKeys := make([]string, len(req.Messages))
+	var externalIDs, externalKeys []string
+	seen := make(map[string]bool)
+	for i, raw := range req.Messages {
+		m := &batch.Messages[i]
+		if err := json.Unmarshal(raw, m); err != nil {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("messages[%d]: invalid message payload", i))
+			return
+		}
+		ts := reqtime + int64(i)
+		messageKeys[i] = keys.GenMessagePrvKey(threadKey, fmt.Sprintf("%d", ts))
+		if err := prepareMessage(m, raw, messageKeys[i], threadKey, author, ts); err != nil {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("messages[%d]: %v", i, err))
+			return
+		}
+		if m.DeliverAt != 0 {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("messages[%d]: deliver_at: not supported in a batch", i))
+			return
+		}
+		if m.ExternalID != "" {
+			if seen[m.ExternalID] {
+				router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("messages[%d]: external_id: duplicated in batch", i))
+				return
+			}
+			seen[m.ExternalID] = true
+			externalIDs = append(externalIDs, m.ExternalID)
+			externalKeys = append(externalKeys, messageKeys[i])
+		}
+
+		// content policies run per message, as they would for single creates
+		if err := hooks.Run(context.Background(), &types.QueueOp{
+			Handler: types.HandlerMessageCreate,
+			Payload: m,
+			TS:      ts,
+			Extras:  extras,
+		}); err != nil {
+			handleQueueError(ctx, err)
+			return
+		}
+
+		// validate - body schema, on the body as the hooks left it
+		if !router.ValidateMessageBodySchema(ctx, threadKey, m.Body) {
+			return
+		}
+	}
+
+	// quota
+	messageQuota := config.DailyQuotas().MessagesPerDay
+	if !router.ConsumeQuotaOrFail(ctx, quotas.KindMessages, author, len(batch.Messages), messageQuota) {
+		return
+	}
+	defer router.RefundQuotaOnError(ctx, quotas.KindMessages, author, len(batch.Messages), messageQuota)
+
+	enqueue := func() error {
+		// Track message creation in-flight
+		for _, messageKey := range messageKeys {
+			tracking.GlobalInflightTracker.Add(messageKey)
+		}
+		err := queue.GlobalIngestQueue.Enqueue(&types.QueueOp{
+			Handler: types.HandlerMessageBatch,
+			Payload: &batch,
+			TS:      reqtime,
+			Extras:  extras,
+		})
+		if err != nil {
+			for _, messageKey := range messageKeys {
+				tracking.GlobalInflightTracker.Remove(messageKey)
+			}
+		}
+		return err
+	}
+
+	// external ids are unique per thread - all are reserved or none
+	if len(externalIDs) > 0 {
+		taken, err := tracking.GlobalKeyMapper.ReserveMessageExternalIDs(threadKey, externalIDs, externalKeys, enqueue)
+		if err != nil {
+			handleQueueError(ctx, err)
+			return
+		}
+		if len(taken) > 0 {
+			ctx.SetStatusCode(fasthttp.StatusConflict)
+			_ = router.WriteJSON(ctx, map[string]interface{}{"error": "external_id already in use", "existing": taken})
+			return
+		}
+	} else if err := enqueue(); err != nil {
+		handleQueueError(ctx, err)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusAccepted)
+	_ = router.WriteJSON(ctx, map[string]interface{}{"thread": threadKey, "keys": messageKeys})
+}
+
+const maxBatchOps = 100
+
+type BatchRequest struct {
+	Ops []BatchOpRequest `json:"ops"`
+}
+
+// BatchOpRequest is one operation of a multi-operation batch. Op names the ingest handler;
+// Thread takes a thread key or "$<ref>" for a thread created earlier in the batch.
+type BatchOpRequest struct {
+	Op      string          `json:"op"`
+	Ref     string          `json:"ref,omitempty"`
+	Thread  string          `json:"thread,omitempty"`
+	UserID  string          `json:"user_id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// EnqueueBatch runs heterogeneous operations in order as a single ingest op. Creates
+// return provisional keys at once, so later ops may reference them by ref.
+func EnqueueBatch(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.Set("Content-Type", "application/json")
+
+	// sync
+	reqtime := timeutil.Now().UnixNano()
+
+	// resolve
+	author, authErr := router.ValidateAuthor(ctx, "")
+	if authErr != nil {
+		router.WriteValidationError(ctx, authErr)
+		return
+	}
+
+	// parse
+	payload, ok := router.ExtractPayloadOrFail(ctx)
+	if !ok {
+		return
+	}
+	var req BatchRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid batch payload")
+		return
+	}
+	if len(req.Ops) == 0 {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "ops: at least one operation is required")
+		return
+	}
+	if len(req.Ops) > maxBatchOps {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("ops: at most %d operations allowed", maxBatchOps))
+		return
+	}
+	metadata := router.NewRequestMetadata(ctx, author)
+
+	// sync - each op gets its own timestamps so created keys keep the given order; a move
+	// takes one per message
+	b := &batchBuilder{
+		author:    author,
+		namespace: router.Namespace(ctx),
+		extras:    types.RequestMetadata{ApiRole: metadata.ApiRole, UserID: metadata.UserID, ReqID: metadata.ReqID, ReqIP: metadata.ReqIP},
+		refs:      make(map[string]string),
+		tags:      make(map[string][]string),
+		created:   make(map[string]bool),
+		messages:  make(map[string]*models.Message),
+		batch:     models.BatchPartial{Author: author, Ops: make([]models.BatchOpPartial, 0, len(req.Ops))},
+		results:   make([]BatchOpResult, 0, len(req.Ops)),
+	}
+	for i, op := range req.Ops {
+		if !b.add(ctx, i, op, reqtime+int64(i)*maxBatchMessages) {
+			return
+		}
+	}
+
+	// quota
+	var threadCount, messageCount int
+	for _, op := range b.batch.Ops {
+		switch types.HandlerID(op.Handler) {
+		case types.HandlerThreadCreate:
+			threadCount++
+		case types.HandlerMessageCreate:
+			messageCount++
+		}
+	}
+	quota := config.DailyQuotas()
+	if !router.ConsumeQuotaOrFail(ctx, quotas.KindThreads, author, threadCount, quota.ThreadsPerDay) {
+		return
+	}
+	defer router.RefundQuotaOnError(ctx, quotas.KindThreads, author, threadCount, quota.ThreadsPerDay)
+	if !router.ConsumeQuotaOrFail(ctx, quotas.KindMessages, author, messageCount, quota.MessagesPerDay) {
+		return
+	}
+	defer router.RefundQuotaOnError(ctx, quotas.KindMessages, author, messageCount, quota.MessagesPerDay)
+
+	// Track creations in-flight
+	for _, key := range b.keys {
+		tracking.GlobalInflightTracker.Add(key)
+	}
+	if err := queue.GlobalIngestQueue.Enqueue(&types.QueueOp{
+		Handler: types.HandlerBatch,
+		Payload: &b.batch,
+		TS:      reqtime,
+		Extras:  b.extras,
+	}); err != nil {
+		for _, key := range b.keys {
+			tracking.GlobalInflightTracker.Remove(key)
+		}
+		handleQueueError(ctx, err)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusAccepted)
+	_ = router.WriteJSON(ctx, BatchResponse{Results: b.results})
+}
+
+// batchBuilder turns the ops of a batch request into ingest ops, remembering what earlier
+// ops created so later ones can reference it.
+type batchBuilder struct {
+	author    string
+	namespace string
+	extras    types.RequestMetadata
+	refs      map[string]string          // ref -> provisional key
+	tags      map[string][]string        // thread key -> tags as set earlier in the batch
+	created   map[string]bool            // threads created by the batch
+	messages  map[string]*models.Message // messages created by the batch, by provisional key
+	keys      []string                   // provisional keys created by the batch
+	batch     models.BatchPartial
+	results   []BatchOpResult
+}
+
+// add validates op and appends it to the batch, writing an error response when it fails.
+func (b *batchBuilder) add(ctx *fasthttp.RequestCtx, i int, op BatchOpRequest, ts int64) bool {
+	fail := func(status int, msg string) bool {
+		router.WriteJSONError(ctx, status, fmt.Sprintf("ops[%d]: %s", i, msg))
+		return false
+	}
+
+	// validate - refs name keys created by this op
+	if op.Ref != "" {
+		if op.Op != string(types.HandlerThreadCreate) && op.Op != string(types.HandlerMessageCreate) {
+			return fail(fasthttp.StatusBadRequest, "ref: only allowed on create operations")
+		}
+		if _, dup := b.refs[op.Ref]; dup {
+			return fail(fasthttp.StatusBadRequest, "ref: duplicated in batch")
+		}
+	}
+
+	var built models.BatchOpPartial
+	result := BatchOpResult{Op: op.Op, Ref: op.Ref}
+	switch types.HandlerID(op.Op) {
+	case types.HandlerThreadCreate:
+		var th models.Thread
+		if err := json.Unmarshal(op.Payload, &th); err != nil {
+			return fail(fasthttp.StatusBadRequest, "invalid thread payload")
+		}
+
+		// sync
+		threadKey := keys.Namespaced(b.namespace, keys.GenThreadPrvKey(fmt.Sprintf("%d", ts)))
+		th.Key = threadKey
+		th.Author = b.author
+		th.CreatedTS = ts
+		th.UpdatedTS = ts
+		th.DM = nil
+
+		// validate
+		if th.ExternalID != "" {
+			return fail(fasthttp.StatusBadRequest, "external_id: not supported in a batch")
+		}
+		if err := router.ValidateAllFieldsNonEmpty(&th); err != nil {
+			return fail(fasthttp.StatusBadRequest, err.Error())
+		}
+		tags, err := router.NormalizeThreadTags(th.Tags)
+		if err != nil {
+			return fail(fasthttp.StatusBadRequest, err.Error())
+		}
+		th.Tags = tags
+
+		b.created[threadKey] = true
+		b.tags[threadKey] = tags
+		b.keys = append(b.keys, threadKey)
+		built.Thread = &th
+		result.Key = threadKey
+
+	case types.HandlerThreadUpdate:
+		threadKey, ok := b.resolveThread(fail, op.Thread)
+		if !ok {
+			return false
+		}
+		var update models.ThreadUpdatePartial
+		if err := json.Unmarshal(op.Payload, &update); err != nil {
+			return fail(fasthttp.StatusBadRequest, "invalid thread update payload")
+		}
+
+		// sync
+		update.Key = threadKey
+		update.UpdatedTS = ts
+
+		// validate
+		if err := router.ValidateAllFieldsNonEmpty(&update); err != nil {
+			return fail(fasthttp.StatusBadRequest, err.Error())
+		}
+		if update.Tags != nil {
+			tags, err := router.NormalizeThreadTags(update.Tags)
+			if err != nil {
+				return fail(fasthttp.StatusBadRequest, err.Error())
+			}
+			update.Tags = tags
+			b.tags[threadKey] = tags
+		}
+
+		built.ThreadUpdate = &update
+		result.Key = threadKey
+
+	case types.HandlerThreadParticipant:
+		threadKey, ok := b.resolveThread(fail, op.Thread)
+		if !ok {
+			return false
+		}
+		if err := router.ValidateUserID(op.UserID); err != nil {
+			return fail(fasthttp.StatusBadRequest, err.Error())
+		}
+		if !b.created[threadKey] {
+			if err := router.ValidateParticipantsMutable(threadKey); err != nil {
+				if errors.Is(err, router.ErrDirectThreadParticipants) {
+					return fail(fasthttp.StatusConflict, err.Error())
+				}
+				return fail(fasthttp.StatusInternalServerError, err.Error())
+			}
+		}
+		var update models.ThreadParticipantPartial
+		if err := json.Unmarshal(op.Payload, &update); err != nil {
+			return fail(fasthttp.StatusBadRequest, "invalid participant payload")
+		}
+
+		// sync
+		update.Thread = threadKey
+		update.UserID = op.UserID
+		update.Removed = false
+		update.UpdatedTS = ts
+
+		// validate
+		if err := router.ValidateAllFieldsNonEmpty(&update); err != nil {
+			return fail(fasthttp.StatusBadRequest, err.Error())
+		}
+
+		built.Participant = &update
+		result.Key = threadKey
+		result.UserID = op.UserID
+
+	case types.HandlerMessageCreate:
+		threadKey, ok := b.resolveThread(fail, op.Thread)
+		if !ok {
+			return false
+		}
+		var m models.Message
+		if err := json.Unmarshal(op.Payload, &m); err != nil {
+			return fail(fasthttp.StatusBadRequest, "invalid message payload")
+		}
+
+		// sync
+		messageKey := keys.GenMessagePrvKey(threadKey, fmt.Sprintf("%d", ts))
+		if err := prepareMessage(&m, op.Payload, messageKey, threadKey, b.author, ts); err != nil {
+			return fail(fasthttp.StatusBadRequest, err.Error())
+		}
+
+		// validate
+		if m.DeliverAt != 0 {
+			return fail(fasthttp.StatusBadRequest, "deliver_at: not supported in a batch")
+		}
+		if m.ExternalID != "" {
+			return fail(fasthttp.StatusBadRequest, "external_id: not supported in a batch")
+		}
+
+		b.messages[messageKey] = &m
+		b.keys = append(b.keys, messageKey)
+		built.Message = &m
+		result.Key = messageKey
+
+	case types.HandlerMessageMove:
+		threadKey, ok := b.resolveThread(fail, op.Thread)
+		if !ok {
+			return false
+		}
+		var req MessageMoveRequest
+		if err := json.Unmarshal(op.Payload, &req); err != nil {
+			return fail(fasthttp.StatusBadRequest, "invalid message move payload")
+		}
+		if len(req.Messages) == 0 {
+			return fail(fasthttp.StatusBadRequest, "messages: at least one message is required")
+		}
+		if len(req.Messages) > maxBatchMessages {
+			return fail(fasthttp.StatusBadRequest, fmt.Sprintf("messages: at most %d messages allowed", maxBatchMessages))
+		}
+		targetKey, ok := b.resolveThread(fail, req.Target)
+		if !ok {
+			return false
+		}
+		if targetKey == threadKey {
+			return fail(fasthttp.StatusBadRequest, "target: must differ from the source thread")
+		}
+
+		// sync
+		move := models.MessageMovePartial{
+			Thread:     threadKey,
+			Target:     targetKey,
+			Keys:       make([]string, len(req.Messages)),
+			TargetKeys: make([]string, len(req.Messages)),
+			Bodies:     make([]interface{}, len(req.Messages)),
+			UpdatedTS:  ts,
+		}
+		seen := make(map[string]bool)
+		for j, ref := range req.Messages {
+			message, ok := b.resolveMessage(fail, j, ref, threadKey)
+			if !ok {
+				return false
+			}
+			if seen[message.Key] {
+				return fail(fasthttp.StatusBadRequest, fmt.Sprintf("messages[%d]: duplicated in request", j))
+			}
+			seen[message.Key] = true
+			move.Keys[j] = message.Key
+			move.TargetKeys[j] = keys.GenMessagePrvKey(targetKey, fmt.Sprintf("%d", ts+int64(j)))
+
+			// content policies of the target run as they would for a new message there
+			arriving, err := arrivingMessage(message, targetKey, move.TargetKeys[j], ts+int64(j), b.extras)
+			if err != nil {
+				handleQueueError(ctx, err)
+				return false
+			}
+			if !b.validateBody(ctx, targetKey, arriving.Body) {
+				return false
+			}
+			move.Bodies[j] = arriving.Body
+		}
+
+		b.keys = append(b.keys, move.TargetKeys...)
+		built.Move = &move
+		result.Key = targetKey
+		result.Keys = move.TargetKeys
+
+	default:
+		return fail(fasthttp.StatusBadRequest, fmt.Sprintf("op: unsupported operation %q", op.Op))
+	}
+	built.Handler = op.Op
+	built.TS = ts
+
+	// content policies run per op, as they would for single requests
+	if err := hooks.Run(context.Background(), &types.QueueOp{
+		Handler: types.HandlerID(built.Handler),
+		Payload: built.Payload(),
+		TS:      ts,
+		Extras:  b.extras,
+	}); err != nil {
+		handleQueueError(ctx, err)
+		return false
+	}
+
+	// validate - body schema on the body as the hooks left it
+	if m := built.Message; m != nil && !b.validateBody(ctx, m.Thread, m.Body) {
+		return false
+	}
+
+	if op.Ref != "" {
+		b.refs[op.Ref] = result.Key
+	}
+	b.batch.Ops = append(b.batch.Ops, built)
+	b.results = append(b.results, result)
+	return true
+}
+
+// validateBody checks a message body against the thread's schemas, using the tags set
+// earlier in the batch when there are any.
+func (b *batchBuilder) validateBody(ctx *fasthttp.RequestCtx, threadKey string, body interface{}) bool {
+	if tags, ok := b.tags[threadKey]; ok {
+		return router.ValidateMessageBodyForTags(ctx, threadKey, tags, body)
+	}
+	return router.ValidateMessageBodySchema(ctx, threadKey, body)
+}
+
+// resolveMessage resolves the i-th message of a move, given as a key or as "$<ref>" of a
+// message created earlier in the batch, and returns it with its body in the clear.
+func (b *batchBuilder) resolveMessage(fail func(int, string) bool, i int, message, threadKey string) (*models.Message, bool) {
+	if strings.HasPrefix(message, "$") {
+		key, ok := b.refs[message[1:]]
+		created, isMessage := b.messages[key]
+		if !ok || !isMessage {
+			return nil, fail(fasthttp.StatusBadRequest, fmt.Sprintf("messages[%d]: %s does not name a message created earlier in the batch", i, message))
+		}
+		if created.Thread != threadKey {
+			return nil, fail(fasthttp.StatusNotFound, fmt.Sprintf("messages[%d]: message not found in thread", i))
+		}
+		return created, true
+	}
+
+	// resolve provisional keys to final keys
+	messageKey, err := tracking.GlobalKeyMapper.ResolveKeyOrWait(message)
+	if err != nil {
+		return nil, fail(fasthttp.StatusNotFound, fmt.Sprintf("messages[%d]: message not found", i))
+	}
+	if err := router.ValidateMessageKey(messageKey); err != nil {
+		return nil, fail(fasthttp.StatusBadRequest, fmt.Sprintf("messages[%d]: %v", i, err))
+	}
+	stored, vErr := router.ValidateReadMessage(messageKey, b.author, false)
+	if vErr == nil {
+		vErr = router.ValidateMessageThreadRelationship(stored, threadKey)
+	}
+	if vErr != nil {
+		return nil, fail(vErr.Code, fmt.Sprintf("messages[%d]: %s", i, vErr.Message))
+	}
+
+	// validate - bodies are checked in the clear
+	if stored.Body != nil {
+		kmsMeta, err := encryption.GetThreadKMS(threadKey)
+		if err == nil && kmsMeta != nil {
+			stored.Body, err = encryption.DecryptMessageBody(stored, kmsMeta.KeyID)
+		}
+		if err != nil {
+			return nil, fail(fasthttp.StatusInternalServerError, fmt.Sprintf("messages[%d]: failed to decrypt message: %v", i, err))
+		}
+	}
+	return stored, true
+}
+
+// resolveThread resolves an op's thread, given as a key or as "$<ref>" of a thread created
+// earlier in the batch, to its final key.
+func (b *batchBuilder) resolveThread(fail func(int, string) bool, thread string) (string, bool) {
+	if thread == "" {
+		return "", fail(fasthttp.StatusBadRequest, "thread: cannot be empty")
+	}
+	if strings.HasPrefix(thread, "$") {
+		key, ok := b.refs[thread[1:]]
+		if !ok || !b.created[key] {
+			return "", fail(fasthttp.StatusBadRequest, fmt.Sprintf("thread: %s does not name a thread created earlier in the batch", thread))
+		}
+		thread = key
+	}
+	if keys.NamespaceOf(thread) != b.namespace {
+		return "", fail(fasthttp.StatusNotFound, "thread not found")
+	}
+
+	// resolve provisional keys to final keys
+	threadKey, err := tracking.GlobalKeyMapper.ResolveKeyOrWait(thread)
+	if err != nil {
+		return "", fail(fasthttp.StatusNotFound, "thread not found")
+	}
+	if b.created[threadKey] {
+		return threadKey, true
+	}
+
+	// validate
+	if err := router.ValidateThreadKey(threadKey); err != nil {
+		return "", fail(fasthttp.StatusBadRequest, err.Error())
+	}
+
+	// validate - del status
+	if err := router.ValidateThreadNotDeleted(threadKey); err != nil {
+		return "", fail(fasthttp.StatusNotFound, err.Error())
+	}
+	return threadKey, true
+}
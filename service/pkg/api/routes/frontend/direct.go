@@ -0,0 +1,126 @@
+package frontend
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/router"
+	"progressdb/pkg/ingest/queue"
+	"progressdb/pkg/ingest/tracking"
+	"progressdb/pkg/ingest/types"
+	"progressdb/pkg/models"
+	"progressdb/pkg/store/keys"
+	"progressdb/pkg/timeutil"
+)
+
+const (
+	maxDirectParticipants = 32
+	defaultDirectTitle    = "Direct message"
+)
+
+type DirectThreadRequest struct {
+	Participants []string `json:"participants"`
+	Title        string   `json:"title,omitempty"`
+}
+
+// EnqueueGetOrCreateDirectThread returns the direct-message thread for the caller and the
+// given participants, creating it on first use. The same participant set, in any order,
+// always resolves to the same thread.
+func EnqueueGetOrCreateDirectThread(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.Set("Content-Type", "application/json")
+
+	// sync
+	reqtime := timeutil.Now().UnixNano()
+
+	// resolve
+	author, authErr := router.ValidateAuthor(ctx, "")
+	if authErr != nil {
+		router.WriteValidationError(ctx, authErr)
+		return
+	}
+
+	// parse
+	payload, ok := router.ExtractPayloadOrFail(ctx)
+	if !ok {
+		return
+	}
+	var req DirectThreadRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid direct thread payload")
+		return
+	}
+
+	// validate
+	for _, userID := range req.Participants {
+		if err := router.ValidateUserID(userID); err != nil {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "participants: "+err.Error())
+			return
+		}
+	}
+	participants := models.CanonicalDMParticipants(append(req.Participants, author))
+	if len(participants) < 2 {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "participants: at least one other user is required")
+		return
+	}
+	if len(participants) > maxDirectParticipants {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("participants: at most %d users allowed", maxDirectParticipants))
+		return
+	}
+	metadata := router.NewRequestMetadata(ctx, author)
+
+	// sync
+	th := models.Thread{
+		Key:       keys.Namespaced(router.Namespace(ctx), keys.GenThreadPrvKey(fmt.Sprintf("%d", reqtime))),
+		Title:     req.Title,
+		Author:    author,
+		CreatedTS: reqtime,
+		UpdatedTS: reqtime,
+		DM:        participants,
+	}
+	if th.Title == "" {
+		th.Title = defaultDirectTitle
+	}
+	if err := router.ValidateAllFieldsNonEmpty(&th); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+
+	threadKey, created, err := tracking.GlobalKeyMapper.GetOrCreateDirectThread(router.Namespace(ctx), participants, th.Key, func() error {
+		// tracked before enqueueing so a fast apply cannot finish ahead of the reservation
+		tracking.GlobalInflightTracker.Add(th.Key)
+		err := queue.GlobalIngestQueue.Enqueue(&types.QueueOp{
+			Handler: types.HandlerThreadCreate,
+			Payload: &th,
+			TS:      reqtime,
+			Extras: types.RequestMetadata{
+				ApiRole: metadata.ApiRole,
+				UserID:  metadata.UserID,
+				ReqID:   metadata.ReqID,
+				ReqIP:   metadata.ReqIP,
+			},
+		})
+		if err != nil {
+			tracking.GlobalInflightTracker.Remove(th.Key)
+		}
+		return err
+	})
+	if err != nil {
+		handleQueueError(ctx, err)
+		return
+	}
+
+	// check access - a thread another request created is checked once its create applied
+	if !created {
+		tracking.GlobalInflightTracker.WaitForInflight(threadKey)
+		if !router.AuthorizeThreadAccess(ctx, threadKey, author, models.ThreadActionRead) {
+			return
+		}
+	}
+
+	if created {
+		ctx.SetStatusCode(fasthttp.StatusAccepted)
+	}
+	_ = router.WriteJSON(ctx, map[string]interface{}{"key": threadKey, "created": created, "participants": participants})
+}
@@ -0,0 +1,145 @@
+package frontend
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/router"
+	"progressdb/pkg/api/utils"
+	"progressdb/pkg/models"
+	"progressdb/pkg/store/db/indexdb"
+	"progressdb/pkg/timeutil"
+)
+
+const (
+	defaultMentionsLimit = 50
+	maxMentionsLimit     = 200
+)
+
+func ReadMentions(ctx *fasthttp.RequestCtx) {
+	author, _, ok := router.SetupReadHandler(ctx, "read_mentions")
+	if !ok {
+		return
+	}
+
+	limit := utils.GetQueryInt(ctx, "limit", defaultMentionsLimit)
+	if limit <= 0 || limit > maxMentionsLimit {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("invalid limit: must be between 1 and %d", maxMentionsLimit))
+		return
+	}
+	unreadOnly := utils.GetQueryLower(ctx, "unread") == "true"
+
+	all, err := indexdb.ListUserMentions(router.Namespace(ctx), author)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to read mentions: %v", err))
+		return
+	}
+
+	// only surface mentions in threads the user can still read
+	now := timeutil.Now().UnixNano()
+	roles := make(map[string]bool)
+	mentions := make([]models.Mention, 0, len(all))
+	unread := 0
+	for _, mention := range all {
+		canRead, seen := roles[mention.Thread]
+		if !seen {
+			role, err := indexdb.GetThreadUserRole(mention.Thread, author)
+			canRead = err == nil && models.AuthorizeThreadAction(role, models.ThreadActionRead) == nil
+			if canRead {
+				if deleted, err := indexdb.IsSoftDeleted(mention.Thread); err != nil || deleted {
+					canRead = false
+				}
+			}
+			roles[mention.Thread] = canRead
+		}
+		if !canRead {
+			continue
+		}
+		if deleted, err := indexdb.IsSoftDeleted(mention.MessageKey); err != nil || deleted {
+			continue
+		}
+		if expired, err := indexdb.IsExpired(mention.MessageKey, now); err != nil || expired {
+			continue
+		}
+		if !mention.Read {
+			unread++
+		}
+		if unreadOnly && mention.Read {
+			continue
+		}
+		mentions = append(mentions, mention)
+	}
+
+	// newest first
+	sort.Slice(mentions, func(i, j int) bool { return mentions[i].CreatedTS > mentions[j].CreatedTS })
+	if len(mentions) > limit {
+		mentions = mentions[:limit]
+	}
+
+	_ = router.WriteJSON(ctx, MentionsListResponse{Mentions: mentions, Unread: unread})
+}
+
+func MarkMentionsRead(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.Set("Content-Type", "application/json")
+
+	// resolve
+	author, authErr := router.ValidateAuthor(ctx, "")
+	if authErr != nil {
+		router.WriteValidationError(ctx, authErr)
+		return
+	}
+
+	// parse
+	payload, ok := router.ExtractPayloadOrFail(ctx)
+	if !ok {
+		return
+	}
+	var req struct {
+		Keys []string `json:"keys"`
+		All  bool     `json:"all"`
+	}
+	if err := json.Unmarshal(payload, &req); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid mentions payload")
+		return
+	}
+
+	// validate
+	if !req.All && len(req.Keys) == 0 {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "keys: cannot be empty unless all is set")
+		return
+	}
+
+	if req.All {
+		mentions, err := indexdb.ListUserMentions(router.Namespace(ctx), author)
+		if err != nil {
+			router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to read mentions: %v", err))
+			return
+		}
+		req.Keys = req.Keys[:0]
+		for _, mention := range mentions {
+			if !mention.Read {
+				req.Keys = append(req.Keys, mention.MessageKey)
+			}
+		}
+	}
+
+	marked := 0
+	for _, messageKey := range req.Keys {
+		if !router.InNamespace(ctx, messageKey) {
+			continue
+		}
+		if err := indexdb.MarkUserMentionRead(author, messageKey); err != nil {
+			if indexdb.IsNotFound(err) {
+				continue
+			}
+			router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to mark mention read: %v", err))
+			return
+		}
+		marked++
+	}
+
+	_ = router.WriteJSON(ctx, map[string]int{"marked": marked})
+}
@@ -0,0 +1,223 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/router"
+	"progressdb/pkg/ingest/hooks"
+	"progressdb/pkg/ingest/queue"
+	"progressdb/pkg/ingest/tracking"
+	"progressdb/pkg/ingest/types"
+	"progressdb/pkg/models"
+	"progressdb/pkg/store/encryption"
+	"progressdb/pkg/store/keys"
+	"progressdb/pkg/timeutil"
+)
+
+type MessageMoveRequest struct {
+	Target   string   `json:"target"`
+	Messages []string `json:"messages"`
+}
+
+func EnqueueMoveMessages(ctx *fasthttp.RequestCtx) {
+	enqueueMessageMove(ctx, false)
+}
+
+func EnqueueCopyMessages(ctx *fasthttp.RequestCtx) {
+	enqueueMessageMove(ctx, true)
+}
+
+// enqueueMessageMove moves (or copies) messages into another thread as one ingest op, so
+// they land with contiguous sequences in the order given.
+func enqueueMessageMove(ctx *fasthttp.RequestCtx, copy bool) {
+	ctx.Response.Header.Set("Content-Type", "application/json")
+
+	// sync
+	reqtime := timeutil.Now().UnixNano()
+
+	// extract
+	threadKey, ok := router.ExtractParamOrFail(ctx, "threadKey", "thread id missing")
+	if !ok {
+		return
+	}
+
+	// parse
+	payload, ok := router.ExtractPayloadOrFail(ctx)
+	if !ok {
+		return
+	}
+	var req MessageMoveRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid message move payload")
+		return
+	}
+	if len(req.Messages) == 0 {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "messages: at least one message is required")
+		return
+	}
+	if len(req.Messages) > maxBatchMessages {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("messages: at most %d messages allowed", maxBatchMessages))
+		return
+	}
+
+	// resolve provisional keys to final keys
+	resolvedThreadKey, err := tracking.GlobalKeyMapper.ResolveKeyOrWait(threadKey)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, "thread not found")
+		return
+	}
+	targetKey, err := tracking.GlobalKeyMapper.ResolveKeyOrWait(req.Target)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, "target thread not found")
+		return
+	}
+
+	// validate
+	if err := router.ValidateThreadKey(resolvedThreadKey); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+	if err := router.ValidateThreadKey(targetKey); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "target: "+err.Error())
+		return
+	}
+	if targetKey == resolvedThreadKey {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "target: must differ from the source thread")
+		return
+	}
+
+	// validate - del status
+	if err := router.ValidateThreadNotDeleted(resolvedThreadKey); err != nil {
+		router.HandleDeletedError(ctx, err)
+		return
+	}
+	if err := router.ValidateThreadNotDeleted(targetKey); err != nil {
+		router.HandleDeletedError(ctx, err)
+		return
+	}
+
+	// resolve
+	author, authErr := router.ValidateAuthor(ctx, "")
+	if authErr != nil {
+		router.WriteValidationError(ctx, authErr)
+		return
+	}
+	metadata := router.NewRequestMetadata(ctx, author)
+	extras := types.RequestMetadata{
+		ApiRole: metadata.ApiRole,
+		UserID:  metadata.UserID,
+		ReqID:   metadata.ReqID,
+		ReqIP:   metadata.ReqIP,
+	}
+
+	// bodies go through the target's hooks and schemas in the clear
+	kmsMeta, err := encryption.GetThreadKMS(resolvedThreadKey)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to get KMS metadata: %v", err))
+		return
+	}
+
+	// sync - each target key gets its own timestamp so the given order is kept
+	move := models.MessageMovePartial{
+		Thread:     resolvedThreadKey,
+		Target:     targetKey,
+		Keys:       make([]string, len(req.Messages)),
+		TargetKeys: make([]string, len(req.Messages)),
+		Bodies:     make([]interface{}, len(req.Messages)),
+		Copy:       copy,
+		UpdatedTS:  reqtime,
+	}
+	moved := make([]MovedMessage, len(req.Messages))
+	seen := make(map[string]bool)
+	for i, messageKey := range req.Messages {
+		resolvedMessageKey, err := tracking.GlobalKeyMapper.ResolveKeyOrWait(messageKey)
+		if err != nil {
+			router.WriteJSONError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("messages[%d]: message not found", i))
+			return
+		}
+		if err := router.ValidateMessageKey(resolvedMessageKey); err != nil {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("messages[%d]: %v", i, err))
+			return
+		}
+		if seen[resolvedMessageKey] {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("messages[%d]: duplicated in request", i))
+			return
+		}
+		seen[resolvedMessageKey] = true
+
+		message, vErr := router.ValidateReadMessage(resolvedMessageKey, author, false)
+		if vErr != nil {
+			router.WriteValidationError(ctx, vErr)
+			return
+		}
+		if vErr := router.ValidateMessageThreadRelationship(message, resolvedThreadKey); vErr != nil {
+			router.WriteValidationError(ctx, vErr)
+			return
+		}
+
+		ts := reqtime + int64(i)
+		move.Keys[i] = resolvedMessageKey
+		move.TargetKeys[i] = keys.GenMessagePrvKey(targetKey, fmt.Sprintf("%d", ts))
+
+		// content policies of the target run as they would for a new message there
+		if kmsMeta != nil && message.Body != nil {
+			if message.Body, err = encryption.DecryptMessageBody(message, kmsMeta.KeyID); err != nil {
+				router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("messages[%d]: failed to decrypt message: %v", i, err))
+				return
+			}
+		}
+		arriving, err := arrivingMessage(message, targetKey, move.TargetKeys[i], ts, extras)
+		if err != nil {
+			handleQueueError(ctx, err)
+			return
+		}
+
+		// validate - body schema of the target thread, on the body as the hooks left it
+		if !router.ValidateMessageBodySchema(ctx, targetKey, arriving.Body) {
+			return
+		}
+		move.Bodies[i] = arriving.Body
+		moved[i] = MovedMessage{From: resolvedMessageKey, Key: move.TargetKeys[i]}
+	}
+
+	// Track target messages in-flight
+	for _, key := range move.TargetKeys {
+		tracking.GlobalInflightTracker.Add(key)
+	}
+	if err := queue.GlobalIngestQueue.Enqueue(&types.QueueOp{
+		Handler: types.HandlerMessageMove,
+		Payload: &move,
+		TS:      reqtime,
+		Extras:  extras,
+	}); err != nil {
+		for _, key := range move.TargetKeys {
+			tracking.GlobalInflightTracker.Remove(key)
+		}
+		handleQueueError(ctx, err)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusAccepted)
+	_ = router.WriteJSON(ctx, MessageMoveResponse{Thread: resolvedThreadKey, Target: targetKey, Messages: moved})
+}
+
+// arrivingMessage returns a copy of message, its body in the clear, as it arrives in the
+// target under targetMessageKey once the content policies for a new message there ran.
+func arrivingMessage(message *models.Message, target, targetMessageKey string, ts int64, extras types.RequestMetadata) (*models.Message, error) {
+	arriving := *message
+	arriving.Key = targetMessageKey
+	arriving.Thread = target
+	if err := hooks.Run(context.Background(), &types.QueueOp{
+		Handler: types.HandlerMessageCreate,
+		Payload: &arriving,
+		TS:      ts,
+		Extras:  extras,
+	}); err != nil {
+		return nil, err
+	}
+	return &arriving, nil
+}
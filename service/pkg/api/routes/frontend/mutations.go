@@ -2,16 +2,22 @@ package frontend
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/valyala/fasthttp"
 
 	"progressdb/pkg/api/router"
+	"progressdb/pkg/config"
+	"progressdb/pkg/ingest/hooks"
 	"progressdb/pkg/ingest/queue"
 	"progressdb/pkg/ingest/tracking"
 	"progressdb/pkg/ingest/types"
 	"progressdb/pkg/models"
 	"progressdb/pkg/state/logger"
+	message_store "progressdb/pkg/store/features/messages"
+	"progressdb/pkg/store/features/quotas"
+	"progressdb/pkg/store/features/schemas"
 	"progressdb/pkg/store/keys"
 	"progressdb/pkg/timeutil"
 )
@@ -21,6 +27,20 @@ func handleQueueError(ctx *fasthttp.RequestCtx, err error) {
 	if err == nil {
 		return
 	}
+	var rejected *hooks.RejectError
+	if errors.As(err, &rejected) {
+		router.WriteJSONError(ctx, fasthttp.StatusUnprocessableEntity, rejected.Error())
+		return
+	}
+	var invalid *schemas.ValidationError
+	if errors.As(err, &invalid) {
+		router.WriteSchemaValidationError(ctx, invalid.Fields)
+		return
+	}
+	if errors.Is(err, hooks.ErrPolicyUnavailable) {
+		router.WriteJSONError(ctx, fasthttp.StatusServiceUnavailable, "content policy unavailable; try again")
+		return
+	}
 	switch err {
 	case queue.ErrQueueFull:
 		router.WriteJSONError(ctx, fasthttp.StatusTooManyRequests, "server busy; try again")
@@ -59,40 +79,82 @@ func EnqueueCreateThread(ctx *fasthttp.RequestCtx) {
 	metadata := router.NewRequestMetadata(ctx, author)
 
 	// sync
-	threadKey := keys.GenThreadPrvKey(fmt.Sprintf("%d", reqtime))
+	threadKey := keys.Namespaced(router.Namespace(ctx), keys.GenThreadPrvKey(fmt.Sprintf("%d", reqtime)))
 	th.Key = threadKey
 	th.Author = author
 	th.CreatedTS = reqtime
 	th.UpdatedTS = reqtime
+	th.DM = nil // direct-message threads are only created through the dm endpoint
 
 	// validate
 	if err := router.ValidateAllFieldsNonEmpty(&th); err != nil {
 		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
 		return
 	}
-
-	if err := queue.GlobalIngestQueue.Enqueue(&types.QueueOp{
-		Handler: types.HandlerThreadCreate,
-		Payload: &th,
-		TS:      reqtime,
-		Extras: types.RequestMetadata{
-			ApiRole: metadata.ApiRole,
-			UserID:  metadata.UserID,
-			ReqID:   metadata.ReqID,
-			ReqIP:   metadata.ReqIP,
-		},
-	}); err != nil {
+	tags, err := router.NormalizeThreadTags(th.Tags)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+	th.Tags = tags
+
+	// quota
+	threadQuota := config.DailyQuotas().ThreadsPerDay
+	if !router.ConsumeQuotaOrFail(ctx, quotas.KindThreads, author, 1, threadQuota) {
+		return
+	}
+	defer router.RefundQuotaOnError(ctx, quotas.KindThreads, author, 1, threadQuota)
+
+	enqueue := func() error {
+		// Track thread creation in-flight
+		tracking.GlobalInflightTracker.Add(threadKey)
+		err := queue.GlobalIngestQueue.Enqueue(&types.QueueOp{
+			Handler: types.HandlerThreadCreate,
+			Payload: &th,
+			TS:      reqtime,
+			Extras: types.RequestMetadata{
+				ApiRole: metadata.ApiRole,
+				UserID:  metadata.UserID,
+				ReqID:   metadata.ReqID,
+				ReqIP:   metadata.ReqIP,
+			},
+		})
+		if err != nil {
+			tracking.GlobalInflightTracker.Remove(threadKey)
+		}
+		return err
+	}
+
+	// external ids are unique per author
+	if th.ExternalID != "" {
+		if err := router.ValidateExternalID(th.ExternalID); err != nil {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+			return
+		}
+		existing, created, err := tracking.GlobalKeyMapper.ReserveThreadExternalID(router.Namespace(ctx), author, th.ExternalID, threadKey, enqueue)
+		if err != nil {
+			handleQueueError(ctx, err)
+			return
+		}
+		if !created {
+			writeExternalIDConflict(ctx, existing)
+			return
+		}
+	} else if err := enqueue(); err != nil {
 		handleQueueError(ctx, err)
 		return
 	}
 
-	// Track thread creation in-flight
-	tracking.GlobalInflightTracker.Add(threadKey)
-
 	ctx.SetStatusCode(fasthttp.StatusAccepted)
 	_ = router.WriteJSON(ctx, map[string]string{"key": threadKey})
 }
 
+// writeExternalIDConflict reports an external id already in use, with the key holding it.
+func writeExternalIDConflict(ctx *fasthttp.RequestCtx, existing string) {
+	ctx.SetStatusCode(fasthttp.StatusConflict)
+	_ = router.WriteJSON(ctx, map[string]string{"error": "external_id already in use", "key": existing})
+}
+
 func EnqueueUpdateThread(ctx *fasthttp.RequestCtx) {
 	ctx.Response.Header.Set("Content-Type", "application/json")
 
@@ -153,6 +215,14 @@ func EnqueueUpdateThread(ctx *fasthttp.RequestCtx) {
 		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
 		return
 	}
+	if update.Tags != nil {
+		tags, err := router.NormalizeThreadTags(update.Tags)
+		if err != nil {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+			return
+		}
+		update.Tags = tags
+	}
 
 	if err := queue.GlobalIngestQueue.Enqueue(&types.QueueOp{
 		Handler: types.HandlerThreadUpdate,
@@ -238,6 +308,114 @@ func EnqueueDeleteThread(ctx *fasthttp.RequestCtx) {
 	_ = router.WriteJSON(ctx, map[string]string{"key": resolvedThreadKey})
 }
 
+// participant management
+func EnqueueSetThreadParticipant(ctx *fasthttp.RequestCtx) {
+	enqueueThreadParticipant(ctx, false)
+}
+
+func EnqueueRemoveThreadParticipant(ctx *fasthttp.RequestCtx) {
+	enqueueThreadParticipant(ctx, true)
+}
+
+func enqueueThreadParticipant(ctx *fasthttp.RequestCtx, removed bool) {
+	ctx.Response.Header.Set("Content-Type", "application/json")
+
+	// sync
+	reqtime := timeutil.Now().UnixNano()
+
+	// extract
+	threadKey, ok := router.ExtractParamOrFail(ctx, "threadKey", "thread id missing")
+	if !ok {
+		return
+	}
+
+	userID, ok := router.ExtractParamOrFail(ctx, "userId", "user id missing")
+	if !ok {
+		return
+	}
+
+	// resolve provisional keys to final keys
+	resolvedThreadKey, err := tracking.GlobalKeyMapper.ResolveKeyOrWait(threadKey)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, "thread not found")
+		return
+	}
+
+	// validate
+	if err := router.ValidateThreadKey(resolvedThreadKey); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+	if err := router.ValidateUserID(userID); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+
+	// validate - del status
+	if err := router.ValidateThreadNotDeleted(resolvedThreadKey); err != nil {
+		router.HandleDeletedError(ctx, err)
+		return
+	}
+	if err := router.ValidateParticipantsMutable(resolvedThreadKey); err != nil {
+		if errors.Is(err, router.ErrDirectThreadParticipants) {
+			router.WriteJSONError(ctx, fasthttp.StatusConflict, err.Error())
+		} else {
+			router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	// resolve
+	author, authErr := router.ValidateAuthor(ctx, "")
+	if authErr != nil {
+		router.WriteValidationError(ctx, authErr)
+		return
+	}
+
+	// parse
+	var update models.ThreadParticipantPartial
+	if !removed {
+		payload, ok := router.ExtractPayloadOrFail(ctx)
+		if !ok {
+			return
+		}
+		if err := json.Unmarshal(payload, &update); err != nil {
+			router.WriteJSONError(ctx, fasthttp.StatusBadRequest, "invalid participant payload")
+			return
+		}
+	}
+	metadata := router.NewRequestMetadata(ctx, author)
+
+	// sync
+	update.Thread = resolvedThreadKey
+	update.UserID = userID
+	update.Removed = removed
+	update.UpdatedTS = reqtime
+
+	// validate
+	if err := router.ValidateAllFieldsNonEmpty(&update); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := queue.GlobalIngestQueue.Enqueue(&types.QueueOp{
+		Handler: types.HandlerThreadParticipant,
+		Payload: &update,
+		TS:      reqtime,
+		Extras: types.RequestMetadata{
+			ApiRole: metadata.ApiRole,
+			UserID:  metadata.UserID,
+			ReqID:   metadata.ReqID,
+			ReqIP:   metadata.ReqIP,
+		},
+	}); err != nil {
+		handleQueueError(ctx, err)
+		return
+	}
+	ctx.SetStatusCode(fasthttp.StatusAccepted)
+	_ = router.WriteJSON(ctx, map[string]string{"key": resolvedThreadKey, "user_id": userID})
+}
+
 // message operations
 func EnqueueCreateMessage(ctx *fasthttp.RequestCtx) {
 	ctx.Response.Header.Set("Content-Type", "application/json")
@@ -283,40 +461,105 @@ func EnqueueCreateMessage(ctx *fasthttp.RequestCtx) {
 
 	// sync
 	messageKey := keys.GenMessagePrvKey(threadKey, fmt.Sprintf("%d", reqtime))
-	m.Author = author
-	m.Key = messageKey
-	m.Thread = threadKey
-	m.CreatedTS = reqtime
-	m.UpdatedTS = reqtime
-
-	//validate
-	if err := router.ValidateAllFieldsNonEmpty(&m); err != nil {
+	if err := prepareMessage(&m, payload, messageKey, threadKey, author, reqtime); err != nil {
 		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
 		return
 	}
 
-	if err := queue.GlobalIngestQueue.Enqueue(&types.QueueOp{
-		Handler: types.HandlerMessageCreate,
-		Payload: &m,
-		TS:      reqtime,
-		Extras: types.RequestMetadata{
-			ApiRole: metadata.ApiRole,
-			UserID:  metadata.UserID,
-			ReqID:   metadata.ReqID,
-			ReqIP:   metadata.ReqIP,
-		},
-	}); err != nil {
+	// quota
+	messageQuota := config.DailyQuotas().MessagesPerDay
+	if !router.ConsumeQuotaOrFail(ctx, quotas.KindMessages, author, 1, messageQuota) {
+		return
+	}
+	defer router.RefundQuotaOnError(ctx, quotas.KindMessages, author, 1, messageQuota)
+
+	// scheduled - held back until deliver_at, then released by the scheduler
+	if m.DeliverAt != 0 {
+		scheduleMessage(ctx, &m, metadata)
+		return
+	}
+
+	enqueue := func() error {
+		// Track message creation in-flight
+		tracking.GlobalInflightTracker.Add(messageKey)
+		err := queue.GlobalIngestQueue.Enqueue(&types.QueueOp{
+			Handler: types.HandlerMessageCreate,
+			Payload: &m,
+			TS:      reqtime,
+			Extras: types.RequestMetadata{
+				ApiRole: metadata.ApiRole,
+				UserID:  metadata.UserID,
+				ReqID:   metadata.ReqID,
+				ReqIP:   metadata.ReqIP,
+			},
+		})
+		if err != nil {
+			tracking.GlobalInflightTracker.Remove(messageKey)
+		}
+		return err
+	}
+
+	// external ids are unique per thread
+	if m.ExternalID != "" {
+		existing, created, err := tracking.GlobalKeyMapper.ReserveMessageExternalID(threadKey, m.ExternalID, messageKey, enqueue)
+		if err != nil {
+			handleQueueError(ctx, err)
+			return
+		}
+		if !created {
+			writeExternalIDConflict(ctx, existing)
+			return
+		}
+	} else if err := enqueue(); err != nil {
 		handleQueueError(ctx, err)
 		return
 	}
 
-	// Track message creation in-flight
-	tracking.GlobalInflightTracker.Add(messageKey)
-
 	ctx.SetStatusCode(fasthttp.StatusAccepted)
 	_ = router.WriteJSON(ctx, map[string]string{"key": messageKey})
 }
 
+// prepareMessage fills the server-side fields of a new message parsed from payload and
+// validates it. Body schema and thread access are left to the caller.
+func prepareMessage(m *models.Message, payload []byte, messageKey, threadKey, author string, reqtime int64) error {
+	m.Author = author
+	m.Key = messageKey
+	m.Thread = threadKey
+	m.CreatedTS = reqtime
+	m.UpdatedTS = reqtime
+
+	// ephemeral - ttl runs from delivery for scheduled messages
+	ttl, err := router.ParseExpiresIn(payload)
+	if err != nil {
+		return err
+	}
+	if ttl > 0 {
+		if m.ExpiresAt != 0 {
+			return errors.New("expires_in: cannot be combined with expires_at")
+		}
+		m.ExpiresAt = reqtime + ttl.Nanoseconds()
+		if m.DeliverAt != 0 {
+			m.ExpiresAt = m.DeliverAt + ttl.Nanoseconds()
+		}
+	}
+
+	//validate
+	if err := router.ValidateAllFieldsNonEmpty(m); err != nil {
+		return err
+	}
+
+	// validate - external id
+	if m.ExternalID != "" {
+		if err := router.ValidateExternalID(m.ExternalID); err != nil {
+			return err
+		}
+		if m.DeliverAt != 0 {
+			return errors.New("external_id: not supported on scheduled messages")
+		}
+	}
+	return nil
+}
+
 func EnqueueUpdateMessage(ctx *fasthttp.RequestCtx) {
 	ctx.Response.Header.Set("Content-Type", "application/json")
 
@@ -364,6 +607,17 @@ func EnqueueUpdateMessage(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	// validate - the message belongs to the thread in the path
+	message, err := storedMessage(resolvedMessageKey)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, "message not found")
+		return
+	}
+	if vErr := router.ValidateMessageThreadRelationship(message, threadKey); vErr != nil {
+		router.WriteValidationError(ctx, vErr)
+		return
+	}
+
 	// parse
 	payload, ok := router.ExtractPayloadOrFail(ctx)
 	if !ok {
@@ -453,6 +707,17 @@ func EnqueueDeleteMessage(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	// validate - the message belongs to the thread in the path
+	message, err := storedMessage(resolvedMessageKey)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, "message not found")
+		return
+	}
+	if vErr := router.ValidateMessageThreadRelationship(message, threadKey); vErr != nil {
+		router.WriteValidationError(ctx, vErr)
+		return
+	}
+
 	metadata := router.NewRequestMetadata(ctx, author)
 
 	// sync
@@ -486,3 +751,16 @@ func EnqueueDeleteMessage(ctx *fasthttp.RequestCtx) {
 	ctx.SetStatusCode(fasthttp.StatusAccepted)
 	_ = router.WriteJSON(ctx, map[string]string{"key": resolvedMessageKey})
 }
+
+// storedMessage reads a message as stored, expiring or not.
+func storedMessage(messageKey string) (*models.Message, error) {
+	stored, err := message_store.GetMessageData(messageKey)
+	if err != nil {
+		return nil, err
+	}
+	var message models.Message
+	if err := json.Unmarshal([]byte(stored), &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
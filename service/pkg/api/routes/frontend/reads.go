@@ -8,11 +8,13 @@ import (
 	"progressdb/pkg/api/router"
 	"progressdb/pkg/api/utils"
 	"progressdb/pkg/ingest/tracking"
+	"progressdb/pkg/models"
 	"progressdb/pkg/store/db/indexdb"
 	"progressdb/pkg/store/db/storedb"
 	"progressdb/pkg/store/encryption"
 	"progressdb/pkg/store/iterator/frontend/mi"
 	"progressdb/pkg/store/iterator/frontend/ti"
+	"progressdb/pkg/store/keys"
 )
 
 func ReadThreadsList(ctx *fasthttp.RequestCtx) {
@@ -29,7 +31,7 @@ func ReadThreadsList(ctx *fasthttp.RequestCtx) {
 	}
 
 	threadIter := ti.NewThreadIterator(indexdb.Client)
-	threadKeys, paginationResp, err := threadIter.ExecuteThreadQuery(author, req)
+	threadKeys, paginationResp, err := threadIter.ExecuteThreadQuery(router.Namespace(ctx), author, req)
 	if err != nil {
 		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to read threads: %v", err))
 		return
@@ -64,31 +66,36 @@ func ReadThreadItem(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	// check access via ownership or participation
-	hasOwnership, err := indexdb.DoesUserOwnThread(author, resolvedThreadKey)
-	if err != nil {
-		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to check thread ownership: %v", err))
+	// check access via thread role
+	if !router.AuthorizeThreadAccess(ctx, resolvedThreadKey, author, models.ThreadActionRead) {
 		return
 	}
 
-	hasParticipation, err := indexdb.DoesThreadHaveUser(resolvedThreadKey, author)
-	if err != nil {
-		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to check thread participation: %v", err))
+	thread, validationErr := router.ValidateReadThread(resolvedThreadKey, author, false)
+	if validationErr != nil {
+		router.WriteValidationError(ctx, validationErr)
 		return
 	}
 
-	if !hasOwnership && !hasParticipation {
-		router.WriteJSONError(ctx, fasthttp.StatusForbidden, "access denied: not thread owner or participant")
+	_ = router.WriteJSON(ctx, ThreadResponse{Thread: *thread})
+}
+
+// ReadThreadByExternalID resolves one of the caller's external ids and reads that thread.
+func ReadThreadByExternalID(ctx *fasthttp.RequestCtx) {
+	externalID, valid := router.ValidatePathParam(ctx, "externalId")
+	if !valid {
 		return
 	}
-
-	thread, validationErr := router.ValidateReadThread(resolvedThreadKey, author, true)
-	if validationErr != nil {
-		router.WriteValidationError(ctx, validationErr)
+	if err := router.ValidateExternalID(externalID); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
 		return
 	}
 
-	_ = router.WriteJSON(ctx, ThreadResponse{Thread: *thread})
+	ctx.SetUserValue("threadKey", router.ExternalIDPrefix+externalID)
+	if !router.ResolveExternalIDParams(ctx) {
+		return
+	}
+	ReadThreadItem(ctx)
 }
 
 func ReadThreadMessages(ctx *fasthttp.RequestCtx) {
@@ -109,21 +116,8 @@ func ReadThreadMessages(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	// check access via ownership or participation
-	hasOwnership, err := indexdb.DoesUserOwnThread(author, resolvedThreadKey)
-	if err != nil {
-		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to check thread ownership: %v", err))
-		return
-	}
-
-	hasParticipation, err := indexdb.DoesThreadHaveUser(resolvedThreadKey, author)
-	if err != nil {
-		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to check thread participation: %v", err))
-		return
-	}
-
-	if !hasOwnership && !hasParticipation {
-		router.WriteJSONError(ctx, fasthttp.StatusForbidden, "access denied: not thread owner or participant")
+	// check access via thread role
+	if !router.AuthorizeThreadAccess(ctx, resolvedThreadKey, author, models.ThreadActionRead) {
 		return
 	}
 
@@ -179,21 +173,24 @@ func ReadThreadMessage(ctx *fasthttp.RequestCtx) {
 
 	threadKey := router.PathParam(ctx, "threadKey")
 
-	// check access via ownership or participation
-	hasOwnership, err := indexdb.DoesUserOwnThread(author, threadKey)
+	// follow moves - old keys resolve to the message in its new thread
+	movedKey, err := indexdb.ResolveMovedMessage(resolvedMessageKey)
 	if err != nil {
-		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to check thread ownership: %v", err))
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, "failed to resolve moved message")
 		return
 	}
-
-	hasParticipation, err := indexdb.DoesThreadHaveUser(threadKey, author)
-	if err != nil {
-		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to check thread participation: %v", err))
-		return
+	if movedKey != resolvedMessageKey {
+		threadTS, err := keys.ExtractThreadKeyFromMessage(movedKey)
+		if err != nil {
+			router.WriteJSONError(ctx, fasthttp.StatusNotFound, "message not found")
+			return
+		}
+		threadKey = keys.GenThreadKey(threadTS)
+		resolvedMessageKey = movedKey
 	}
 
-	if !hasOwnership && !hasParticipation {
-		router.WriteJSONError(ctx, fasthttp.StatusForbidden, "access denied: not thread owner or participant")
+	// check access via thread role
+	if !router.AuthorizeThreadAccess(ctx, threadKey, author, models.ThreadActionRead) {
 		return
 	}
 
@@ -235,3 +232,40 @@ func ReadThreadMessage(ctx *fasthttp.RequestCtx) {
 
 	_ = router.WriteJSON(ctx, MessageResponse{Message: *message})
 }
+
+func ReadThreadParticipants(ctx *fasthttp.RequestCtx) {
+	author, _, ok := router.SetupReadHandler(ctx, "read_thread_participants")
+	if !ok {
+		return
+	}
+
+	threadKey, valid := router.ValidatePathParam(ctx, "threadKey")
+	if !valid {
+		return
+	}
+
+	// resolve provisional keys to final keys
+	resolvedThreadKey, err := tracking.GlobalKeyMapper.ResolveKeyOrWait(threadKey)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, "thread not found")
+		return
+	}
+
+	// check access via thread role
+	if !router.AuthorizeThreadAccess(ctx, resolvedThreadKey, author, models.ThreadActionRead) {
+		return
+	}
+
+	if _, validationErr := router.ValidateReadThread(resolvedThreadKey, author, false); validationErr != nil {
+		router.WriteValidationError(ctx, validationErr)
+		return
+	}
+
+	participants, err := indexdb.ListThreadParticipants(resolvedThreadKey)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to read participants: %v", err))
+		return
+	}
+
+	_ = router.WriteJSON(ctx, ThreadParticipantsResponse{Thread: resolvedThreadKey, Participants: participants})
+}
@@ -0,0 +1,159 @@
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+
+	"progressdb/pkg/api/router"
+	"progressdb/pkg/ingest/hooks"
+	"progressdb/pkg/ingest/tracking"
+	"progressdb/pkg/ingest/types"
+	"progressdb/pkg/models"
+	"progressdb/pkg/store/db/indexdb"
+	"progressdb/pkg/store/features/scheduled"
+)
+
+// scheduleMessage stores a validated message for later delivery instead of enqueueing it.
+func scheduleMessage(ctx *fasthttp.RequestCtx, m *models.Message, metadata *router.RequestMetadata) {
+	// resolve - the thread may still be in flight
+	resolvedThreadKey, err := tracking.GlobalKeyMapper.ResolveKeyOrWait(m.Thread)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, "thread not found")
+		return
+	}
+	m.Thread = resolvedThreadKey
+
+	// check access - rechecked when the message is released
+	if !router.AuthorizeThreadAccess(ctx, resolvedThreadKey, m.Author, models.ThreadActionPostMessage) {
+		return
+	}
+
+	// content policies run now so rejections reach the author; release does not rerun them
+	extras := types.RequestMetadata{
+		ApiRole: metadata.ApiRole,
+		UserID:  metadata.UserID,
+		ReqID:   metadata.ReqID,
+		ReqIP:   metadata.ReqIP,
+	}
+	if err := hooks.Run(context.Background(), &types.QueueOp{
+		Handler: types.HandlerMessageCreate,
+		Payload: m,
+		TS:      m.CreatedTS,
+		Extras:  extras,
+	}); err != nil {
+		handleQueueError(ctx, err)
+		return
+	}
+
+	// validate - body schema, on the body as the hooks left it
+	if !router.ValidateMessageBodySchema(ctx, m.Thread, m.Body) {
+		return
+	}
+
+	if err := scheduled.Schedule(m, extras); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to schedule message: %v", err))
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusAccepted)
+	_ = router.WriteJSON(ctx, map[string]interface{}{"key": m.Key, "deliver_at": m.DeliverAt})
+}
+
+func ReadScheduledMessages(ctx *fasthttp.RequestCtx) {
+	author, _, ok := router.SetupReadHandler(ctx, "read_scheduled_messages")
+	if !ok {
+		return
+	}
+
+	threadKey, valid := router.ValidatePathParam(ctx, "threadKey")
+	if !valid {
+		return
+	}
+	if err := router.ValidateThreadKey(threadKey); err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+
+	// check access via thread role
+	if !router.AuthorizeThreadAccess(ctx, threadKey, author, models.ThreadActionRead) {
+		return
+	}
+
+	all, err := scheduled.List(threadKey)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to read scheduled messages: %v", err))
+		return
+	}
+
+	// moderators see every pending message, everyone else only their own
+	role, err := indexdb.GetThreadUserRole(threadKey, author)
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to resolve thread role: %v", err))
+		return
+	}
+	moderator := models.AuthorizeThreadAction(role, models.ThreadActionModerateMessages) == nil
+
+	messages := make([]models.Message, 0, len(all))
+	for _, msg := range all {
+		if moderator || msg.Author == author {
+			messages = append(messages, msg)
+		}
+	}
+
+	_ = router.WriteJSON(ctx, ScheduledMessagesListResponse{Thread: threadKey, Messages: messages})
+}
+
+func CancelScheduledMessage(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.Set("Content-Type", "application/json")
+
+	// extract
+	threadKey, ok := router.ExtractParamOrFail(ctx, "threadKey", "thread id missing")
+	if !ok {
+		return
+	}
+	messageKey, ok := router.ExtractParamOrFail(ctx, "id", "message id missing")
+	if !ok {
+		return
+	}
+
+	// resolve
+	author, authErr := router.ValidateAuthor(ctx, "")
+	if authErr != nil {
+		router.WriteValidationError(ctx, authErr)
+		return
+	}
+
+	// fetch existing
+	msg, err := scheduled.Get(messageKey)
+	if errors.Is(err, scheduled.ErrNotFound) || (err == nil && msg.Thread != threadKey) {
+		router.WriteJSONError(ctx, fasthttp.StatusNotFound, "scheduled message not found")
+		return
+	}
+	if err != nil {
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to read scheduled message: %v", err))
+		return
+	}
+
+	// check access - same rule as deleting a delivered message
+	action := models.ThreadActionModerateMessages
+	if msg.Author == author {
+		action = models.ThreadActionEditOwnMessage
+	}
+	if !router.AuthorizeThreadAccess(ctx, threadKey, author, action) {
+		return
+	}
+
+	if err := scheduled.Cancel(messageKey); err != nil {
+		if errors.Is(err, scheduled.ErrNotFound) {
+			router.WriteJSONError(ctx, fasthttp.StatusNotFound, "scheduled message not found")
+			return
+		}
+		router.WriteJSONError(ctx, fasthttp.StatusInternalServerError, fmt.Sprintf("failed to cancel scheduled message: %v", err))
+		return
+	}
+
+	_ = router.WriteJSON(ctx, map[string]string{"key": messageKey})
+}
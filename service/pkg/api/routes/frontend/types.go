@@ -2,6 +2,7 @@ package frontend
 
 import (
 	"progressdb/pkg/models"
+	"progressdb/pkg/store/db/indexdb"
 	"progressdb/pkg/store/pagination"
 )
 
@@ -35,6 +36,45 @@ type MessagesListResponse struct {
 	Pagination *pagination.PaginationResponse `json:"pagination"`
 }
 
+type ScheduledMessagesListResponse struct {
+	Thread   string           `json:"thread"`
+	Messages []models.Message `json:"messages"`
+}
+
 type MessageResponse struct {
 	Message models.Message `json:"message"`
 }
+
+type ThreadParticipantsResponse struct {
+	Thread       string                      `json:"thread"`
+	Participants []indexdb.ThreadParticipant `json:"participants"`
+}
+
+type BatchOpResult struct {
+	Op     string   `json:"op"`
+	Ref    string   `json:"ref,omitempty"`
+	Key    string   `json:"key"`
+	Keys   []string `json:"keys,omitempty"`
+	UserID string   `json:"user_id,omitempty"`
+}
+
+type BatchResponse struct {
+	Results []BatchOpResult `json:"results"`
+}
+
+// MovedMessage pairs a source message key with the provisional key it moved to.
+type MovedMessage struct {
+	From string `json:"from"`
+	Key  string `json:"key"`
+}
+
+type MessageMoveResponse struct {
+	Thread   string         `json:"thread"`
+	Target   string         `json:"target"`
+	Messages []MovedMessage `json:"messages"`
+}
+
+type MentionsListResponse struct {
+	Mentions []models.Mention `json:"mentions"`
+	Unread   int              `json:"unread"`
+}
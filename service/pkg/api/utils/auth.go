@@ -6,8 +6,17 @@ import (
 	"github.com/valyala/fasthttp"
 )
 
-// Extracts an API key from either the Authorization header or the X-API-Key header
+// Extracts an API key from either the Authorization header or the X-API-Key header.
+// A JWT bearer is an identity, not a key, so the key then comes from X-API-Key.
 func ExtractAPIKey(ctx *fasthttp.RequestCtx) string {
+	if bearer := GetBearer(ctx); bearer != "" && !isJWT(bearer) {
+		return bearer
+	}
+	return GetHeader(ctx, "X-API-Key")
+}
+
+// Returns the "Bearer <token>" credential of the Authorization header
+func GetBearer(ctx *fasthttp.RequestCtx) string {
 	auth := GetHeader(ctx, "Authorization")
 
 	// "Bearer <token>" with flexible whitespace
@@ -18,8 +27,20 @@ func ExtractAPIKey(ctx *fasthttp.RequestCtx) string {
 			return parts[1]
 		}
 	}
+	return ""
+}
 
-	return GetHeader(ctx, "X-API-Key")
+// Returns the JWT carried as the Authorization bearer, if any
+func GetBearerJWT(ctx *fasthttp.RequestCtx) string {
+	if bearer := GetBearer(ctx); isJWT(bearer) {
+		return bearer
+	}
+	return ""
+}
+
+// compact JWS: base64url JSON header ("eyJ") and three segments
+func isJWT(value string) bool {
+	return strings.HasPrefix(value, "eyJ") && strings.Count(value, ".") == 2
 }
 
 // Returns the value of the X-Role-Name header, lowercased
@@ -27,6 +48,17 @@ func GetApiRole(ctx *fasthttp.RequestCtx) string {
 	return GetHeaderLower(ctx, "X-Role-Name")
 }
 
+// ClientIPUserValue holds the client ip resolved by the auth gateway.
+const ClientIPUserValue = "client_ip"
+
+// Returns the client ip resolved by the auth gateway, or the connection's address
+func GetClientIP(ctx *fasthttp.RequestCtx) string {
+	if ip, ok := ctx.UserValue(ClientIPUserValue).(string); ok && ip != "" {
+		return ip
+	}
+	return ctx.RemoteIP().String()
+}
+
 // Returns the value of the X-User-ID header
 func GetUserID(ctx *fasthttp.RequestCtx) string {
 	return GetHeader(ctx, "X-User-ID")
@@ -74,10 +74,24 @@ func PrintWithEff(eff config.EffectiveConfigResult, version string) {
 		fmt.Println("- DB Path: not set (use --db or PROGRESSDB_SERVER_DB_PATH)")
 	}
 
+	// TLS
+	if eff.Config != nil && eff.Config.Server.TLS.Enabled() {
+		if eff.Config.Server.TLS.ClientCAFile != "" {
+			fmt.Println("- TLS: enabled (client certificates verified)")
+		} else {
+			fmt.Println("- TLS: enabled")
+		}
+	} else {
+		fmt.Println("- TLS: disabled (terminate at a proxy)")
+	}
+
 	// Encryption / KMS
 	enc := false
-	if eff.Config != nil && eff.Config.Encryption.Enabled {
-		enc = true
+	if eff.Config != nil {
+		enc = eff.Config.Encryption.Enabled
+		for _, ns := range eff.Config.Namespaces {
+			enc = enc || ns.Encryption.Enabled
+		}
 	}
 	if enc {
 		// check for master key or external endpoint
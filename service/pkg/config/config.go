@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/adhocore/gronx"
 	"github.com/goccy/go-yaml"
@@ -60,6 +61,31 @@ func GetSigningKeys() map[string]struct{} {
 	return out
 }
 
+// GetSigningKeyList returns the signing keys configured for a namespace in configuration order.
+func GetSigningKeyList(namespace string) []string {
+	if namespace != "" {
+		ns, ok := namespaceConfig(namespace)
+		if !ok {
+			return nil
+		}
+		var list []string
+		seen := make(map[string]struct{})
+		for _, k := range ns.APIKeys.Signing {
+			if _, dup := seen[k]; !dup {
+				seen[k] = struct{}{}
+				list = append(list, k)
+			}
+		}
+		return list
+	}
+	runtimeMu.RLock()
+	defer runtimeMu.RUnlock()
+	if runtimeCfg == nil {
+		return nil
+	}
+	return append([]string(nil), runtimeCfg.SigningKeyList...)
+}
+
 func GetMaxPayloadSize() int {
 	runtimeMu.RLock()
 	defer runtimeMu.RUnlock()
@@ -119,3 +145,162 @@ func ResolveConfigPath(flagPath string, flagSet bool) string {
 	}
 	return flagPath
 }
+
+// TokenTTLs returns the default and maximum lifetimes of frontend tokens.
+func TokenTTLs() (time.Duration, time.Duration) {
+	def, max := time.Hour, 24*time.Hour
+	if cfg := GetConfig(); cfg != nil {
+		if d := cfg.Auth.TokenTTL.Duration(); d > 0 {
+			def = d
+		}
+		if d := cfg.Auth.MaxTokenTTL.Duration(); d > 0 {
+			max = d
+		}
+	}
+	if def > max {
+		def = max
+	}
+	return def, max
+}
+
+// JWTSettings returns the JWT auth settings with fallbacks applied, or nil when disabled.
+func JWTSettings() *JWTConfig {
+	cfg := GetConfig()
+	if cfg == nil || !cfg.Auth.JWT.Enabled {
+		return nil
+	}
+	j := cfg.Auth.JWT
+	if j.RefreshInterval <= 0 {
+		j.RefreshInterval = Duration(5 * time.Minute)
+	}
+	if strings.TrimSpace(j.UserClaim) == "" {
+		j.UserClaim = "sub"
+	}
+	return &j
+}
+
+// RequireFrontendTokens reports whether plain user-id signatures are rejected.
+func RequireFrontendTokens() bool {
+	cfg := GetConfig()
+	return cfg != nil && cfg.Auth.RequireTokens
+}
+
+// MentionField returns the body path scanned for mentions, or "" when mentions are disabled.
+func MentionField() string {
+	cfg := GetConfig()
+	if cfg == nil || !cfg.Mentions.Enabled {
+		return ""
+	}
+	if f := strings.TrimSpace(cfg.Mentions.Field); f != "" {
+		return f
+	}
+	return "body.content"
+}
+
+// AuditSettings returns the audit trail bounds with fallbacks applied.
+func AuditSettings() AuditConfig {
+	var a AuditConfig
+	if cfg := GetConfig(); cfg != nil {
+		a = cfg.Audit
+	}
+	if a.Retention <= 0 {
+		a.Retention = Duration(90 * 24 * time.Hour)
+	}
+	if a.AuthFailures.RPS <= 0 {
+		a.AuthFailures.RPS = 1
+	}
+	if a.AuthFailures.Burst <= 0 {
+		a.AuthFailures.Burst = 10
+	}
+	return a
+}
+
+// DailyQuotas returns the per-user daily creation caps; 0 is unlimited.
+func DailyQuotas() DailyQuotaConfig {
+	if cfg := GetConfig(); cfg != nil {
+		return cfg.Limits.Quotas
+	}
+	return DailyQuotaConfig{}
+}
+
+// Namespaces returns the id of every namespace, the default one ("") first.
+func Namespaces() []string {
+	ids := []string{""}
+	if cfg := GetConfig(); cfg != nil {
+		for _, ns := range cfg.Namespaces {
+			ids = append(ids, ns.ID)
+		}
+	}
+	return ids
+}
+
+// NamespaceExists reports whether id is the default namespace or a configured one.
+func NamespaceExists(id string) bool {
+	if id == "" {
+		return true
+	}
+	_, ok := namespaceConfig(id)
+	return ok
+}
+
+func namespaceConfig(id string) (NamespaceConfig, bool) {
+	if cfg := GetConfig(); cfg != nil {
+		for _, ns := range cfg.Namespaces {
+			if ns.ID == id {
+				return ns, true
+			}
+		}
+	}
+	return NamespaceConfig{}, false
+}
+
+// EncryptionPolicy returns whether a namespace encrypts message bodies and which body
+// fields; no fields encrypts whole bodies.
+func EncryptionPolicy(namespace string) (bool, []string) {
+	if namespace == "" {
+		cfg := GetConfig()
+		if cfg == nil {
+			return false, nil
+		}
+		return cfg.Encryption.Enabled, cfg.Encryption.Fields
+	}
+	ns, _ := namespaceConfig(namespace)
+	return ns.Encryption.Enabled, ns.Encryption.Fields
+}
+
+// EncryptionInUse reports whether any namespace encrypts, so the KMS has to run.
+func EncryptionInUse() bool {
+	for _, ns := range Namespaces() {
+		if enabled, _ := EncryptionPolicy(ns); enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// RetentionFor returns the retention settings of a namespace: the top-level ones with
+// the namespace's overrides applied.
+func RetentionFor(namespace string) RetentionConfig {
+	var r RetentionConfig
+	if cfg := GetConfig(); cfg != nil {
+		r = cfg.Retention
+	}
+	ns, ok := namespaceConfig(namespace)
+	if !ok {
+		return r
+	}
+	o := ns.Retention
+	if o.MTTL > 0 {
+		r.MTTL = o.MTTL
+	}
+	if o.TTTL > 0 {
+		r.TTTL = o.TTTL
+	}
+	if o.MaxAge > 0 {
+		r.MaxAge = o.MaxAge
+	}
+	if o.MaxIdle > 0 {
+		r.MaxIdle = o.MaxIdle
+	}
+	return r
+}
@@ -94,10 +94,15 @@ func ParseConfigEnvs() (*Config, EnvResult) {
 		"ENCRYPTION_ENABLED":  os.Getenv("PROGRESSDB_ENCRYPTION_ENABLED"),
 
 		// data retention feature
-		"RETENTION_ENABLED": os.Getenv("PROGRESSDB_RETENTION_ENABLED"),
-		"RETENTION_CRON":    os.Getenv("PROGRESSDB_RETENTION_CRON"),
-		"RETENTION_MTTL":    os.Getenv("PROGRESSDB_RETENTION_MTTL"),
-		"RETENTION_TTTL":    os.Getenv("PROGRESSDB_RETENTION_TTTL"),
+		"RETENTION_ENABLED":  os.Getenv("PROGRESSDB_RETENTION_ENABLED"),
+		"RETENTION_CRON":     os.Getenv("PROGRESSDB_RETENTION_CRON"),
+		"RETENTION_MTTL":     os.Getenv("PROGRESSDB_RETENTION_MTTL"),
+		"RETENTION_TTTL":     os.Getenv("PROGRESSDB_RETENTION_TTTL"),
+		"RETENTION_MAX_AGE":  os.Getenv("PROGRESSDB_RETENTION_MAX_AGE"),
+		"RETENTION_MAX_IDLE": os.Getenv("PROGRESSDB_RETENTION_MAX_IDLE"),
+
+		// scheduler
+		"SCHEDULER_POLL_INTERVAL": os.Getenv("PROGRESSDB_SCHEDULER_POLL_INTERVAL"),
 
 		// telemetry
 		"TELEMETRY_BUFFER_SIZE":    os.Getenv("PROGRESSDB_TELEMETRY_BUFFER_SIZE"),
@@ -129,6 +134,53 @@ func ParseConfigEnvs() (*Config, EnvResult) {
 		// apply
 		"APPLY_BATCH_COUNT":   os.Getenv("PROGRESSDB_APPLY_BATCH_COUNT"),
 		"APPLY_BATCH_TIMEOUT": os.Getenv("PROGRESSDB_APPLY_BATCH_TIMEOUT"),
+
+		// mentions
+		"MENTIONS_ENABLED":          os.Getenv("PROGRESSDB_MENTIONS_ENABLED"),
+		"MENTIONS_FIELD":            os.Getenv("PROGRESSDB_MENTIONS_FIELD"),
+		"MENTIONS_AUTO_PARTICIPATE": os.Getenv("PROGRESSDB_MENTIONS_AUTO_PARTICIPATE"),
+
+		// auth
+		"AUTH_REQUIRE_TOKENS":       os.Getenv("PROGRESSDB_AUTH_REQUIRE_TOKENS"),
+		"AUTH_TOKEN_TTL":            os.Getenv("PROGRESSDB_AUTH_TOKEN_TTL"),
+		"AUTH_MAX_TOKEN_TTL":        os.Getenv("PROGRESSDB_AUTH_MAX_TOKEN_TTL"),
+		"AUTH_JWT_ENABLED":          os.Getenv("PROGRESSDB_AUTH_JWT_ENABLED"),
+		"AUTH_JWT_JWKS_FILE":        os.Getenv("PROGRESSDB_AUTH_JWT_JWKS_FILE"),
+		"AUTH_JWT_JWKS_URL":         os.Getenv("PROGRESSDB_AUTH_JWT_JWKS_URL"),
+		"AUTH_JWT_REFRESH_INTERVAL": os.Getenv("PROGRESSDB_AUTH_JWT_REFRESH_INTERVAL"),
+		"AUTH_JWT_ISSUER":           os.Getenv("PROGRESSDB_AUTH_JWT_ISSUER"),
+		"AUTH_JWT_AUDIENCE":         os.Getenv("PROGRESSDB_AUTH_JWT_AUDIENCE"),
+		"AUTH_JWT_USER_CLAIM":       os.Getenv("PROGRESSDB_AUTH_JWT_USER_CLAIM"),
+		"AUTH_JWT_LEEWAY":           os.Getenv("PROGRESSDB_AUTH_JWT_LEEWAY"),
+
+		// client ips
+		"TRUSTED_PROXIES":       os.Getenv("PROGRESSDB_TRUSTED_PROXIES"),
+		"IP_ALLOWLIST_ADMIN":    os.Getenv("PROGRESSDB_IP_ALLOWLIST_ADMIN"),
+		"IP_ALLOWLIST_BACKEND":  os.Getenv("PROGRESSDB_IP_ALLOWLIST_BACKEND"),
+		"IP_ALLOWLIST_FRONTEND": os.Getenv("PROGRESSDB_IP_ALLOWLIST_FRONTEND"),
+
+		// tls
+		"TLS_CERT_FILE":           os.Getenv("PROGRESSDB_SERVER_TLS_CERT_FILE"),
+		"TLS_KEY_FILE":            os.Getenv("PROGRESSDB_SERVER_TLS_KEY_FILE"),
+		"TLS_MIN_VERSION":         os.Getenv("PROGRESSDB_SERVER_TLS_MIN_VERSION"),
+		"TLS_RELOAD_INTERVAL":     os.Getenv("PROGRESSDB_SERVER_TLS_RELOAD_INTERVAL"),
+		"TLS_CLIENT_CA_FILE":      os.Getenv("PROGRESSDB_SERVER_TLS_CLIENT_CA_FILE"),
+		"TLS_REQUIRE_CLIENT_CERT": os.Getenv("PROGRESSDB_SERVER_TLS_REQUIRE_CLIENT_CERT"),
+		"TLS_CLIENT_ADMIN":        os.Getenv("PROGRESSDB_SERVER_TLS_CLIENT_ADMIN"),
+		"TLS_CLIENT_BACKEND":      os.Getenv("PROGRESSDB_SERVER_TLS_CLIENT_BACKEND"),
+
+		// limits
+		"LIMITS_IP_RPS":               os.Getenv("PROGRESSDB_LIMITS_IP_RPS"),
+		"LIMITS_IP_BURST":             os.Getenv("PROGRESSDB_LIMITS_IP_BURST"),
+		"LIMITS_USER_RPS":             os.Getenv("PROGRESSDB_LIMITS_USER_RPS"),
+		"LIMITS_USER_BURST":           os.Getenv("PROGRESSDB_LIMITS_USER_BURST"),
+		"LIMITS_QUOTA_MESSAGES_DAILY": os.Getenv("PROGRESSDB_LIMITS_QUOTA_MESSAGES_PER_DAY"),
+		"LIMITS_QUOTA_THREADS_DAILY":  os.Getenv("PROGRESSDB_LIMITS_QUOTA_THREADS_PER_DAY"),
+
+		// audit
+		"AUDIT_RETENTION":           os.Getenv("PROGRESSDB_AUDIT_RETENTION"),
+		"AUDIT_AUTH_FAILURES_RPS":   os.Getenv("PROGRESSDB_AUDIT_AUTH_FAILURES_RPS"),
+		"AUDIT_AUTH_FAILURES_BURST": os.Getenv("PROGRESSDB_AUDIT_AUTH_FAILURES_BURST"),
 	}
 
 	// check if any env was set
@@ -231,6 +283,46 @@ func ParseConfigEnvs() (*Config, EnvResult) {
 	if v := envs["IP_WHITELIST"]; v != "" {
 		envCfg.Server.IPWhitelist = parseList(v)
 	}
+
+	// client ip env overrides
+	if v := envs["TRUSTED_PROXIES"]; v != "" {
+		envCfg.Server.TrustedProxies = parseList(v)
+	}
+	if v := envs["IP_ALLOWLIST_ADMIN"]; v != "" {
+		envCfg.Server.IPAllowlists.Admin = parseList(v)
+	}
+	if v := envs["IP_ALLOWLIST_BACKEND"]; v != "" {
+		envCfg.Server.IPAllowlists.Backend = parseList(v)
+	}
+	if v := envs["IP_ALLOWLIST_FRONTEND"]; v != "" {
+		envCfg.Server.IPAllowlists.Frontend = parseList(v)
+	}
+
+	// tls env overrides
+	if v := envs["TLS_CERT_FILE"]; v != "" {
+		envCfg.Server.TLS.CertFile = strings.TrimSpace(v)
+	}
+	if v := envs["TLS_KEY_FILE"]; v != "" {
+		envCfg.Server.TLS.KeyFile = strings.TrimSpace(v)
+	}
+	if v := envs["TLS_MIN_VERSION"]; v != "" {
+		envCfg.Server.TLS.MinVersion = strings.TrimSpace(v)
+	}
+	if v := envs["TLS_RELOAD_INTERVAL"]; v != "" {
+		envCfg.Server.TLS.ReloadInterval = parseDuration(v)
+	}
+	if v := envs["TLS_CLIENT_CA_FILE"]; v != "" {
+		envCfg.Server.TLS.ClientCAFile = strings.TrimSpace(v)
+	}
+	if v := envs["TLS_REQUIRE_CLIENT_CERT"]; v != "" {
+		envCfg.Server.TLS.RequireClientCert = parseBool(v, false)
+	}
+	if v := envs["TLS_CLIENT_ADMIN"]; v != "" {
+		envCfg.Server.TLS.ClientRoles.Admin = parseList(v)
+	}
+	if v := envs["TLS_CLIENT_BACKEND"]; v != "" {
+		envCfg.Server.TLS.ClientRoles.Backend = parseList(v)
+	}
 	if v := envs["API_BACKEND_KEYS"]; v != "" {
 		envCfg.Server.APIKeys.Backend = parseList(v)
 	}
@@ -302,6 +394,16 @@ func ParseConfigEnvs() (*Config, EnvResult) {
 			envCfg.Retention.TTTL = d
 		}
 	}
+	if v := envs["RETENTION_MAX_AGE"]; v != "" {
+		if d, err := time.ParseDuration(strings.TrimSpace(v)); err == nil {
+			envCfg.Retention.MaxAge = d
+		}
+	}
+	if v := envs["RETENTION_MAX_IDLE"]; v != "" {
+		if d, err := time.ParseDuration(strings.TrimSpace(v)); err == nil {
+			envCfg.Retention.MaxIdle = d
+		}
+	}
 
 	// telemetry env overrides
 	if v := envs["TELEMETRY_BUFFER_SIZE"]; v != "" {
@@ -319,6 +421,11 @@ func ParseConfigEnvs() (*Config, EnvResult) {
 		}
 	}
 
+	// scheduler env overrides
+	if v := envs["SCHEDULER_POLL_INTERVAL"]; v != "" {
+		envCfg.Scheduler.PollInterval = parseDuration(v)
+	}
+
 	// sensor env overrides
 	if v := envs["SENSOR_POLL_INTERVAL"]; v != "" {
 		envCfg.Sensor.PollInterval = parseDuration(v)
@@ -394,6 +501,99 @@ func ParseConfigEnvs() (*Config, EnvResult) {
 			envCfg.Ingest.Apply.BatchTimeout = Duration(d)
 		}
 	}
+
+	// mentions env overrides
+	if v := envs["MENTIONS_ENABLED"]; v != "" {
+		envCfg.Mentions.Enabled = parseBool(v, false)
+	}
+	if v := envs["MENTIONS_FIELD"]; v != "" {
+		envCfg.Mentions.Field = strings.TrimSpace(v)
+	}
+	if v := envs["MENTIONS_AUTO_PARTICIPATE"]; v != "" {
+		envCfg.Mentions.AutoParticipate = parseBool(v, false)
+	}
+
+	// auth env overrides
+	if v := envs["AUTH_REQUIRE_TOKENS"]; v != "" {
+		envCfg.Auth.RequireTokens = parseBool(v, false)
+	}
+	if v := envs["AUTH_TOKEN_TTL"]; v != "" {
+		envCfg.Auth.TokenTTL = parseDuration(v)
+	}
+	if v := envs["AUTH_MAX_TOKEN_TTL"]; v != "" {
+		envCfg.Auth.MaxTokenTTL = parseDuration(v)
+	}
+	if v := envs["AUTH_JWT_ENABLED"]; v != "" {
+		envCfg.Auth.JWT.Enabled = parseBool(v, false)
+	}
+	if v := envs["AUTH_JWT_JWKS_FILE"]; v != "" {
+		envCfg.Auth.JWT.JWKSFile = strings.TrimSpace(v)
+	}
+	if v := envs["AUTH_JWT_JWKS_URL"]; v != "" {
+		envCfg.Auth.JWT.JWKSURL = strings.TrimSpace(v)
+	}
+	if v := envs["AUTH_JWT_REFRESH_INTERVAL"]; v != "" {
+		envCfg.Auth.JWT.RefreshInterval = parseDuration(v)
+	}
+	if v := envs["AUTH_JWT_ISSUER"]; v != "" {
+		envCfg.Auth.JWT.Issuer = strings.TrimSpace(v)
+	}
+	if v := envs["AUTH_JWT_AUDIENCE"]; v != "" {
+		envCfg.Auth.JWT.Audience = strings.TrimSpace(v)
+	}
+	if v := envs["AUTH_JWT_USER_CLAIM"]; v != "" {
+		envCfg.Auth.JWT.UserClaim = strings.TrimSpace(v)
+	}
+	if v := envs["AUTH_JWT_LEEWAY"]; v != "" {
+		envCfg.Auth.JWT.Leeway = parseDuration(v)
+	}
+
+	// limits env overrides
+	if v := envs["LIMITS_IP_RPS"]; v != "" {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			envCfg.Limits.IP.RPS = f
+		}
+	}
+	if v := envs["LIMITS_IP_BURST"]; v != "" {
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			envCfg.Limits.IP.Burst = n
+		}
+	}
+	if v := envs["LIMITS_USER_RPS"]; v != "" {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			envCfg.Limits.User.RPS = f
+		}
+	}
+	if v := envs["LIMITS_USER_BURST"]; v != "" {
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			envCfg.Limits.User.Burst = n
+		}
+	}
+	if v := envs["LIMITS_QUOTA_MESSAGES_DAILY"]; v != "" {
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			envCfg.Limits.Quotas.MessagesPerDay = n
+		}
+	}
+	if v := envs["LIMITS_QUOTA_THREADS_DAILY"]; v != "" {
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			envCfg.Limits.Quotas.ThreadsPerDay = n
+		}
+	}
+
+	// audit env overrides
+	if v := envs["AUDIT_RETENTION"]; v != "" {
+		envCfg.Audit.Retention = parseDuration(v)
+	}
+	if v := envs["AUDIT_AUTH_FAILURES_RPS"]; v != "" {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			envCfg.Audit.AuthFailures.RPS = f
+		}
+	}
+	if v := envs["AUDIT_AUTH_FAILURES_BURST"]; v != "" {
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			envCfg.Audit.AuthFailures.Burst = n
+		}
+	}
 	return envCfg, EnvResult{BackendKeys: backendKeys, SigningKeys: signingKeys, EnvUsed: envUsed}
 }
 
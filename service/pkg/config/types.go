@@ -14,6 +14,7 @@ import (
 type RuntimeConfig struct {
 	BackendKeys    map[string]struct{}
 	SigningKeys    map[string]struct{}
+	SigningKeyList []string // configured order; the first signs until a key is rotated in
 	MaxPayloadSize int64
 }
 
@@ -23,10 +24,18 @@ type Config struct {
 	Storage    StorageConfig    `yaml:"storage"`
 	Logging    LoggingConfig    `yaml:"logging"`
 	Retention  RetentionConfig  `yaml:"retention"`
+	Scheduler  SchedulerConfig  `yaml:"scheduler"`
 	Ingest     IngestConfig     `yaml:"ingest"`
 	Telemetry  TelemetryConfig  `yaml:"telemetry"`
 	Sensor     SensorConfig     `yaml:"sensor"`
 	Encryption EncryptionConfig `yaml:"encryption"`
+	Mentions   MentionsConfig   `yaml:"mentions"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Limits     LimitsConfig     `yaml:"limits"`
+	Audit      AuditConfig      `yaml:"audit"`
+
+	ContentPolicy ContentPolicyConfig `yaml:"content_policy"`
+	Namespaces    []NamespaceConfig   `yaml:"namespaces"`
 }
 
 // ServerConfig holds http and security settings.
@@ -37,8 +46,43 @@ type ServerConfig struct {
 	MaxPayloadSize SizeBytes    `yaml:"max_payload_size,default=100KB"`
 	CORS           CORSConfig   `yaml:"cors"`
 	RateLimit      RateConfig   `yaml:"rate_limit"`
-	IPWhitelist    []string     `yaml:"ip_whitelist"`
+	IPWhitelist    []string     `yaml:"ip_whitelist"` // ips or cidr ranges allowed for every role
 	APIKeys        APIKeyConfig `yaml:"api_keys"`
+	TLS            TLSConfig    `yaml:"tls"`
+
+	IPAllowlists   RoleIPAllowlists `yaml:"ip_allowlists"`
+	TrustedProxies []string         `yaml:"trusted_proxies"` // ips or cidr ranges whose X-Forwarded-For is believed
+}
+
+// RoleIPAllowlists narrows each role to ips or cidr ranges; an empty list allows any.
+type RoleIPAllowlists struct {
+	Admin    []string `yaml:"admin"`
+	Backend  []string `yaml:"backend"`
+	Frontend []string `yaml:"frontend"`
+}
+
+// TLSConfig serves https when cert_file and key_file are set. Client certificates are
+// verified against client_ca_file; a verified certificate whose common name or a DNS name
+// is listed under client_roles authenticates as that role without an API key.
+type TLSConfig struct {
+	CertFile          string          `yaml:"cert_file"`
+	KeyFile           string          `yaml:"key_file"`
+	MinVersion        string          `yaml:"min_version,default=1.2"`     // 1.2 or 1.3
+	ReloadInterval    Duration        `yaml:"reload_interval,default=10s"` // how often cert_file and key_file are checked for changes
+	ClientCAFile      string          `yaml:"client_ca_file"`
+	RequireClientCert bool            `yaml:"require_client_cert,default=false"` // reject connections without a verified client certificate
+	ClientRoles       ClientCertRoles `yaml:"client_roles"`
+}
+
+// ClientCertRoles lists the certificate names granted each role.
+type ClientCertRoles struct {
+	Admin   []string `yaml:"admin"`
+	Backend []string `yaml:"backend"`
+}
+
+// Enabled reports whether the server serves https.
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
 }
 
 // StorageConfig holds database-specific settings.
@@ -75,6 +119,13 @@ type RetentionConfig struct {
 	Cron    string        `yaml:"cron,default=0 2 * * *"` // Default to daily at 02:00
 	MTTL    time.Duration `yaml:"mttl,default=720h"`      // Message TTL after delete (30 days)
 	TTTL    time.Duration `yaml:"tttl,default=720h"`      // Thread TTL after delete (30 days)
+	MaxAge  time.Duration `yaml:"max_age"`                // Hard delete messages older than this, deleted or not (0 disables)
+	MaxIdle time.Duration `yaml:"max_idle"`               // Hard delete whole threads without activity for this long (0 disables)
+}
+
+// SchedulerConfig controls release of scheduled messages.
+type SchedulerConfig struct {
+	PollInterval Duration `yaml:"poll_interval,default=1s"` // how often due messages are released
 }
 
 // IngestConfig holds intake, compute, and apply configuration.
@@ -215,3 +266,121 @@ type EncryptionConfig struct {
 		MasterKeyHex  string `yaml:"master_key_hex"`
 	} `yaml:"kms"`
 }
+
+// MentionsConfig controls @mention extraction from message bodies.
+type MentionsConfig struct {
+	Enabled         bool   `yaml:"enabled,default=false"`
+	Field           string `yaml:"field,default=body.content"`     // body path scanned for @user tokens
+	AutoParticipate bool   `yaml:"auto_participate,default=false"` // add mentioned non-participants as members
+}
+
+// LimitsConfig layers rate limits on top of the per-key server.rate_limit and adds daily
+// quotas. A limit with zero rps is disabled.
+type LimitsConfig struct {
+	IP     RateLimitConfig  `yaml:"ip"`     // per client ip, before authentication
+	User   RateLimitConfig  `yaml:"user"`   // per signed user id (or asserted X-User-ID for backends)
+	Routes RouteRateConfig  `yaml:"routes"` // per user, or per key without a user, and route class
+	Quotas DailyQuotaConfig `yaml:"quotas"`
+}
+
+// RateLimitConfig is a token bucket; burst defaults to rps (at least 1).
+type RateLimitConfig struct {
+	RPS   float64 `yaml:"rps"`
+	Burst int     `yaml:"burst"`
+}
+
+// RouteRateConfig holds one bucket per route class.
+type RouteRateConfig struct {
+	Read          RateLimitConfig `yaml:"read"`           // GET and HEAD
+	MessageCreate RateLimitConfig `yaml:"message_create"` // message creates, single and batch
+	Write         RateLimitConfig `yaml:"write"`          // every other mutation
+}
+
+// DailyQuotaConfig caps what one user may create per UTC day; 0 is unlimited.
+type DailyQuotaConfig struct {
+	MessagesPerDay int `yaml:"messages_per_day"`
+	ThreadsPerDay  int `yaml:"threads_per_day"`
+}
+
+// AuditConfig bounds the audit trail. Zero values take the defaults.
+type AuditConfig struct {
+	Retention    Duration        `yaml:"retention,default=2160h"` // records older than this are pruned (90 days)
+	AuthFailures RateLimitConfig `yaml:"auth_failures"`           // per client ip, default 1 rps and a burst of 10; failures past it are only counted
+}
+
+// NamespaceConfig declares a tenant sharing this server. Its data is kept under keys
+// prefixed with n:<id>: and only its own api keys reach it. The top-level settings make
+// up the default namespace, whose keys are unprefixed.
+type NamespaceConfig struct {
+	ID         string                    `yaml:"id"`
+	APIKeys    APIKeyConfig              `yaml:"api_keys"` // signing keys are required; admin keys are confined to the namespace
+	Encryption NamespaceEncryptionConfig `yaml:"encryption"`
+	Retention  NamespaceRetentionConfig  `yaml:"retention"`
+}
+
+// NamespaceEncryptionConfig is a namespace's own encryption policy; the KMS is shared.
+type NamespaceEncryptionConfig struct {
+	Enabled bool     `yaml:"enabled,default=false"`
+	Fields  []string `yaml:"fields"`
+}
+
+// NamespaceRetentionConfig overrides the top-level retention periods; zero keeps them.
+// Whether and when the purge runner runs stays a top-level setting.
+type NamespaceRetentionConfig struct {
+	MTTL    time.Duration `yaml:"mttl"`
+	TTTL    time.Duration `yaml:"tttl"`
+	MaxAge  time.Duration `yaml:"max_age"`
+	MaxIdle time.Duration `yaml:"max_idle"`
+}
+
+// AuthConfig controls how frontend users are authenticated.
+type AuthConfig struct {
+	RequireTokens bool      `yaml:"require_tokens,default=false"` // reject plain user-id signatures, accepting only expiring tokens
+	TokenTTL      Duration  `yaml:"token_ttl,default=1h"`         // lifetime of tokens issued without expires_in
+	MaxTokenTTL   Duration  `yaml:"max_token_ttl,default=24h"`    // longest lifetime a token may be issued for
+	JWT           JWTConfig `yaml:"jwt"`
+}
+
+// JWTConfig accepts identity-provider JWTs (RS256, ES256, EdDSA) as frontend identity.
+// Keys come from a JWKS file or URL, cached and reloaded on change or on an unknown key id.
+type JWTConfig struct {
+	Enabled         bool     `yaml:"enabled,default=false"`
+	JWKSFile        string   `yaml:"jwks_file"`
+	JWKSURL         string   `yaml:"jwks_url"`
+	RefreshInterval Duration `yaml:"refresh_interval,default=5m"` // how long fetched keys are cached
+	Issuer          string   `yaml:"issuer"`                      // required iss when set
+	Audience        string   `yaml:"audience"`                    // required aud when set
+	UserClaim       string   `yaml:"user_claim,default=sub"`      // claim holding the author's user id
+	Leeway          Duration `yaml:"leeway,default=0s"`           // clock skew allowed on exp and nbf
+}
+
+// ContentPolicyConfig declares the hooks run on writes before they are enqueued.
+// Each policy applies to the listed handlers, or to message.create and message.update when none are given.
+type ContentPolicyConfig struct {
+	Redact   []RedactPolicyConfig   `yaml:"redact"`
+	MaxLinks []MaxLinksPolicyConfig `yaml:"max_links"`
+	HTTP     []HTTPPolicyConfig     `yaml:"http"`
+}
+
+// RedactPolicyConfig replaces regex and word-list matches, or rejects the write when Reject is set.
+type RedactPolicyConfig struct {
+	Handlers    []string `yaml:"handlers"`
+	Patterns    []string `yaml:"patterns"`
+	Words       []string `yaml:"words"` // matched as whole words, case-insensitive
+	Replacement string   `yaml:"replacement,default=***"`
+	Reject      bool     `yaml:"reject,default=false"`
+}
+
+// MaxLinksPolicyConfig rejects writes containing more than Limit links.
+type MaxLinksPolicyConfig struct {
+	Handlers []string `yaml:"handlers"`
+	Limit    int      `yaml:"limit"`
+}
+
+// HTTPPolicyConfig delegates decisions to an external service.
+type HTTPPolicyConfig struct {
+	Handlers []string `yaml:"handlers"`
+	URL      string   `yaml:"url"`
+	Timeout  Duration `yaml:"timeout,default=2s"`
+	FailOpen bool     `yaml:"fail_open,default=false"` // allow writes when the service errors or times out
+}
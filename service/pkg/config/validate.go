@@ -3,7 +3,11 @@ package config
 import (
 	"encoding/hex"
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
+	"regexp"
+	"strings"
 
 	"github.com/adhocore/gronx"
 )
@@ -24,6 +28,12 @@ func ValidateConfig(eff EffectiveConfigResult) error {
 		return fmt.Errorf("signing keys are required: set server.api_keys.signing in config or PROGRESSDB_API_SIGNING_KEYS env")
 	}
 
+	// Namespace validation: ids are key-safe and unique, each namespace signs with its own
+	// keys, and no api key belongs to two namespaces.
+	if err := validateNamespaces(cfg); err != nil {
+		return err
+	}
+
 	// If encryption is enabled (either in config or via env), ensure a master key is provided
 	useEnc := cfg.Encryption.Enabled
 	if ev := os.Getenv("PROGRESSDB_ENCRYPTION_ENABLED"); ev != "" {
@@ -34,6 +44,9 @@ func ValidateConfig(eff EffectiveConfigResult) error {
 			useEnc = false
 		}
 	}
+	for _, ns := range cfg.Namespaces {
+		useEnc = useEnc || ns.Encryption.Enabled
+	}
 	if useEnc {
 		mkFile := cfg.Encryption.KMS.MasterKeyFile
 		mkHex := cfg.Encryption.KMS.MasterKeyHex
@@ -62,7 +75,183 @@ func ValidateConfig(eff EffectiveConfigResult) error {
 		if ret.TTTL <= 0 {
 			return fmt.Errorf("invalid retention.tttl: must be positive duration")
 		}
+		if ret.MaxAge < 0 {
+			return fmt.Errorf("invalid retention.max_age: cannot be negative")
+		}
+		if ret.MaxIdle < 0 {
+			return fmt.Errorf("invalid retention.max_idle: cannot be negative")
+		}
+	}
+
+	// Mentions validation: the scanned field must live inside the message body.
+	if m := cfg.Mentions; m.Enabled && m.Field != "" {
+		if m.Field != "body" && !strings.HasPrefix(m.Field, "body.") {
+			return fmt.Errorf("invalid mentions.field: must start with 'body': %q", m.Field)
+		}
+	}
+
+	// Auth validation: token lifetimes must be usable.
+	if cfg.Auth.TokenTTL < 0 {
+		return fmt.Errorf("invalid auth.token_ttl: must not be negative")
+	}
+	if cfg.Auth.MaxTokenTTL < 0 {
+		return fmt.Errorf("invalid auth.max_token_ttl: must not be negative")
+	}
+
+	if j := cfg.Auth.JWT; j.Enabled {
+		if (j.JWKSFile == "") == (j.JWKSURL == "") {
+			return fmt.Errorf("invalid auth.jwt: set exactly one of jwks_file or jwks_url")
+		}
+		if j.JWKSURL != "" {
+			u, err := url.Parse(j.JWKSURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("invalid auth.jwt.jwks_url: must be an http(s) URL")
+			}
+		}
+		if j.RefreshInterval < 0 || j.Leeway < 0 {
+			return fmt.Errorf("invalid auth.jwt: refresh_interval and leeway must not be negative")
+		}
+	}
+
+	// IP list validation: every entry is an ip or a cidr range.
+	srv := cfg.Server
+	for name, list := range map[string][]string{
+		"ip_whitelist":           srv.IPWhitelist,
+		"ip_allowlists.admin":    srv.IPAllowlists.Admin,
+		"ip_allowlists.backend":  srv.IPAllowlists.Backend,
+		"ip_allowlists.frontend": srv.IPAllowlists.Frontend,
+		"trusted_proxies":        srv.TrustedProxies,
+	} {
+		for _, entry := range list {
+			if !validIPOrCIDR(entry) {
+				return fmt.Errorf("invalid server.%s: %q is not an ip or cidr range", name, entry)
+			}
+		}
+	}
+
+	// TLS validation: cert and key come together; client settings need a client ca.
+	tc := cfg.Server.TLS
+	if tc.Enabled() {
+		if tc.CertFile == "" || tc.KeyFile == "" {
+			return fmt.Errorf("invalid server.tls: cert_file and key_file must both be set")
+		}
+		switch tc.MinVersion {
+		case "", "1.2", "1.3":
+		default:
+			return fmt.Errorf("invalid server.tls.min_version: must be 1.2 or 1.3")
+		}
+		if tc.ReloadInterval < 0 {
+			return fmt.Errorf("invalid server.tls.reload_interval: must not be negative")
+		}
+	}
+	if tc.ClientCAFile != "" && !tc.Enabled() {
+		return fmt.Errorf("invalid server.tls.client_ca_file: requires cert_file and key_file")
+	}
+	if tc.ClientCAFile == "" && (tc.RequireClientCert || len(tc.ClientRoles.Admin) > 0 || len(tc.ClientRoles.Backend) > 0) {
+		return fmt.Errorf("invalid server.tls: require_client_cert and client_roles need client_ca_file")
+	}
+
+	// Limits validation: rates and quotas must not be negative.
+	l := cfg.Limits
+	for name, r := range map[string]RateLimitConfig{"ip": l.IP, "user": l.User, "routes.read": l.Routes.Read, "routes.message_create": l.Routes.MessageCreate, "routes.write": l.Routes.Write} {
+		if r.RPS < 0 || r.Burst < 0 {
+			return fmt.Errorf("invalid limits.%s: rps and burst must not be negative", name)
+		}
+	}
+	if l.Quotas.MessagesPerDay < 0 || l.Quotas.ThreadsPerDay < 0 {
+		return fmt.Errorf("invalid limits.quotas: must not be negative")
+	}
+
+	// Audit validation: bounds must not be negative.
+	if cfg.Audit.Retention < 0 {
+		return fmt.Errorf("invalid audit.retention: must not be negative")
+	}
+	if cfg.Audit.AuthFailures.RPS < 0 || cfg.Audit.AuthFailures.Burst < 0 {
+		return fmt.Errorf("invalid audit.auth_failures: rps and burst must not be negative")
+	}
+
+	// Content policy validation: patterns must compile and limits must be usable.
+	for i, r := range cfg.ContentPolicy.Redact {
+		if len(r.Patterns) == 0 && len(r.Words) == 0 {
+			return fmt.Errorf("invalid content_policy.redact[%d]: patterns or words required", i)
+		}
+		for _, p := range r.Patterns {
+			if _, err := regexp.Compile(p); err != nil {
+				return fmt.Errorf("invalid content_policy.redact[%d] pattern %q: %w", i, p, err)
+			}
+		}
+	}
+	for i, l := range cfg.ContentPolicy.MaxLinks {
+		if l.Limit < 0 {
+			return fmt.Errorf("invalid content_policy.max_links[%d].limit: must not be negative", i)
+		}
+	}
+	for i, h := range cfg.ContentPolicy.HTTP {
+		u, err := url.Parse(h.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid content_policy.http[%d].url: must be an http(s) URL", i)
+		}
+		if h.Timeout < 0 {
+			return fmt.Errorf("invalid content_policy.http[%d].timeout: must not be negative", i)
+		}
 	}
 
 	return nil
 }
+
+var namespaceIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+func validateNamespaces(cfg *Config) error {
+	owners := make(map[string]string) // api key -> namespace
+	claim := func(namespace string, list []string) error {
+		for _, k := range list {
+			if owner, ok := owners[k]; ok && owner != namespace {
+				return fmt.Errorf("invalid namespaces: an api key is used by both %q and %q", owner, namespace)
+			}
+			owners[k] = namespace
+		}
+		return nil
+	}
+	top := cfg.Server.APIKeys
+	if err := claim("", append(append(append([]string{}, top.Backend...), top.Frontend...), top.Admin...)); err != nil {
+		return err
+	}
+
+	seen := make(map[string]struct{})
+	for i, ns := range cfg.Namespaces {
+		if !namespaceIDPattern.MatchString(ns.ID) {
+			return fmt.Errorf("invalid namespaces[%d].id: must be 1-63 lowercase letters, digits, '_' or '-'", i)
+		}
+		if _, dup := seen[ns.ID]; dup {
+			return fmt.Errorf("invalid namespaces[%d].id: %q is declared twice", i, ns.ID)
+		}
+		seen[ns.ID] = struct{}{}
+		if len(ns.APIKeys.Signing) == 0 {
+			return fmt.Errorf("invalid namespaces[%d]: signing keys are required", i)
+		}
+		k := ns.APIKeys
+		if err := claim(ns.ID, append(append(append([]string{}, k.Backend...), k.Frontend...), k.Admin...)); err != nil {
+			return err
+		}
+		for _, f := range ns.Encryption.Fields {
+			if f = strings.TrimSpace(f); f != "" && f != "body" && !strings.HasPrefix(f, "body.") {
+				return fmt.Errorf("invalid namespaces[%d].encryption.fields: must start with 'body': %q", i, f)
+			}
+		}
+		r := ns.Retention
+		if r.MTTL < 0 || r.TTTL < 0 || r.MaxAge < 0 || r.MaxIdle < 0 {
+			return fmt.Errorf("invalid namespaces[%d].retention: durations must not be negative", i)
+		}
+	}
+	return nil
+}
+
+func validIPOrCIDR(s string) bool {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		_, err := netip.ParsePrefix(s)
+		return err == nil
+	}
+	_, err := netip.ParseAddr(s)
+	return err == nil
+}
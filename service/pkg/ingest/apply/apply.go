@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"sort"
+	"strconv"
 
 	"progressdb/pkg/ingest/tracking"
 	"progressdb/pkg/ingest/types"
@@ -11,6 +12,7 @@ import (
 	"progressdb/pkg/state"
 	"progressdb/pkg/state/logger"
 	"progressdb/pkg/store/db/storedb"
+	"progressdb/pkg/store/features/audit"
 	"progressdb/pkg/store/keys"
 
 	"github.com/cockroachdb/pebble"
@@ -71,18 +73,22 @@ func groupOperationsByThreadKey(entries []types.BatchEntry) map[string][]types.B
 
 func getOperationPriority(handler types.HandlerID) int {
 	switch handler {
-	case types.HandlerThreadCreate:
+	case types.HandlerThreadCreate, types.HandlerBatch:
 		return 1
 	case types.HandlerThreadUpdate:
 		return 2
-	case types.HandlerThreadDelete:
+	case types.HandlerThreadParticipant:
 		return 3
-	case types.HandlerMessageCreate:
+	case types.HandlerThreadTransfer:
 		return 4
-	case types.HandlerMessageUpdate:
+	case types.HandlerThreadDelete:
 		return 5
-	case types.HandlerMessageDelete:
+	case types.HandlerMessageCreate, types.HandlerMessageBatch, types.HandlerMessageMove:
 		return 6
+	case types.HandlerMessageUpdate:
+		return 7
+	case types.HandlerMessageDelete:
+		return 8
 	default:
 		state.Crash("get_operation_priority_failed", fmt.Errorf("getOperationPriority: unsupported handler type: %v", handler))
 		return 10
@@ -101,10 +107,26 @@ func extractTS(entry types.BatchEntry) int64 {
 		}
 	case types.HandlerThreadDelete:
 		return 0
+	case types.HandlerThreadParticipant:
+		if update, ok := entry.Payload.(*models.ThreadParticipantPartial); ok {
+			return update.UpdatedTS
+		}
+	case types.HandlerThreadTransfer:
+		if transfer, ok := entry.Payload.(*models.ThreadTransferPartial); ok {
+			return transfer.UpdatedTS
+		}
 	case types.HandlerMessageCreate:
 		if m, ok := entry.Payload.(*models.Message); ok {
 			return m.CreatedTS
 		}
+	case types.HandlerMessageBatch:
+		if batch, ok := entry.Payload.(*models.MessageBatchPartial); ok && len(batch.Messages) > 0 {
+			return batch.Messages[0].CreatedTS
+		}
+	case types.HandlerMessageMove:
+		if move, ok := entry.Payload.(*models.MessageMovePartial); ok {
+			return move.UpdatedTS
+		}
 	case types.HandlerMessageUpdate:
 		if update, ok := entry.Payload.(*models.MessageUpdatePartial); ok && update.UpdatedTS != 0 {
 			return update.UpdatedTS
@@ -113,6 +135,8 @@ func extractTS(entry types.BatchEntry) int64 {
 		if del, ok := entry.Payload.(*models.MessageDeletePartial); ok {
 			return del.UpdatedTS
 		}
+	case types.HandlerBatch:
+		return entry.TS
 	}
 
 	state.Crash("index_state_init_failed", fmt.Errorf("extractTS: unsupported operation or handler"))
@@ -129,16 +153,26 @@ func extractAuthor(entry types.BatchEntry) string {
 		return entry.QueueOp.Extras.UserID
 	case types.HandlerThreadDelete:
 		return entry.QueueOp.Extras.UserID
+	case types.HandlerThreadParticipant, types.HandlerThreadTransfer:
+		return entry.QueueOp.Extras.UserID
 	case types.HandlerMessageCreate:
 		if m, ok := entry.Payload.(*models.Message); ok {
 			return m.Author
 		}
-	case types.HandlerMessageUpdate:
+	case types.HandlerMessageBatch:
+		if batch, ok := entry.Payload.(*models.MessageBatchPartial); ok {
+			return batch.Author
+		}
+	case types.HandlerMessageUpdate, types.HandlerMessageMove:
 		return entry.QueueOp.Extras.UserID
 	case types.HandlerMessageDelete:
 		if del, ok := entry.Payload.(*models.MessageDeletePartial); ok {
 			return del.Author
 		}
+	case types.HandlerBatch:
+		if batch, ok := entry.Payload.(*models.BatchPartial); ok {
+			return batch.Author
+		}
 	}
 
 	state.Crash("index_state_init_failed", fmt.Errorf("extractAuthor: unsupported operation or handler"))
@@ -159,10 +193,26 @@ func ExtractTKey(qop *types.QueueOp) string {
 		if del, ok := qop.Payload.(*models.ThreadDeletePartial); ok && del.Key != "" {
 			return del.Key
 		}
+	case types.HandlerThreadParticipant:
+		if update, ok := qop.Payload.(*models.ThreadParticipantPartial); ok && update.Thread != "" {
+			return update.Thread
+		}
+	case types.HandlerThreadTransfer:
+		if transfer, ok := qop.Payload.(*models.ThreadTransferPartial); ok && transfer.Thread != "" {
+			return transfer.Thread
+		}
 	case types.HandlerMessageCreate:
 		if msg, ok := qop.Payload.(*models.Message); ok {
 			return msg.Thread
 		}
+	case types.HandlerMessageBatch:
+		if batch, ok := qop.Payload.(*models.MessageBatchPartial); ok {
+			return batch.Thread
+		}
+	case types.HandlerMessageMove:
+		if move, ok := qop.Payload.(*models.MessageMovePartial); ok {
+			return move.Thread
+		}
 	case types.HandlerMessageUpdate:
 		if update, ok := qop.Payload.(*models.MessageUpdatePartial); ok && update.Thread != "" {
 			return update.Thread
@@ -171,6 +221,11 @@ func ExtractTKey(qop *types.QueueOp) string {
 		if del, ok := qop.Payload.(*models.MessageDeletePartial); ok && del.Thread != "" {
 			return del.Thread
 		}
+	case types.HandlerBatch:
+		// grouped with its first thread so it applies ahead of single ops on that thread
+		if ops := batchOpEntries(qop); len(ops) > 0 {
+			return ExtractTKey(ops[0].QueueOp)
+		}
 	}
 
 	state.Crash("index_state_init_failed", fmt.Errorf("ExtractTKey: unsupported operation or handler"))
@@ -179,8 +234,10 @@ func ExtractTKey(qop *types.QueueOp) string {
 
 func ExtractMKey(qop *types.QueueOp) string {
 	switch qop.Handler {
-	case types.HandlerThreadCreate, types.HandlerThreadUpdate, types.HandlerThreadDelete:
+	case types.HandlerThreadCreate, types.HandlerThreadUpdate, types.HandlerThreadDelete, types.HandlerThreadParticipant, types.HandlerThreadTransfer:
 		return ""
+	case types.HandlerMessageBatch, types.HandlerBatch, types.HandlerMessageMove:
+		return "" // one key per message in the payload
 	case types.HandlerMessageCreate:
 		if m, ok := qop.Payload.(*models.Message); ok {
 			return m.Key
@@ -199,6 +256,28 @@ func ExtractMKey(qop *types.QueueOp) string {
 	return ""
 }
 
+// batchOpEntries expands a multi-operation batch into one entry per operation, in order.
+func batchOpEntries(qop *types.QueueOp) []types.BatchEntry {
+	batch, ok := qop.Payload.(*models.BatchPartial)
+	if !ok {
+		return nil
+	}
+	entries := make([]types.BatchEntry, len(batch.Ops))
+	for i := range batch.Ops {
+		op := &batch.Ops[i]
+		entries[i] = types.BatchEntry{
+			QueueOp: &types.QueueOp{
+				Handler: types.HandlerID(op.Handler),
+				Payload: op.Payload(),
+				TS:      op.TS,
+				EnqSeq:  qop.EnqSeq,
+				Extras:  qop.Extras,
+			},
+		}
+	}
+	return entries
+}
+
 func sortOperationsByType(entries []types.BatchEntry) []types.BatchEntry {
 	sorted := make([]types.BatchEntry, len(entries))
 	copy(sorted, entries)
@@ -227,11 +306,38 @@ func extractUniqueThreadKeys(threadGroups map[string][]types.BatchEntry) []strin
 
 func collectProvisionalMessageKeys(entries []types.BatchEntry) []string {
 	provKeyMap := make(map[string]bool)
+	add := func(msg *models.Message) {
+		if msg.Key == "" {
+			return
+		}
+		if parsed, err := keys.ParseKey(msg.Key); err == nil && parsed.Type == keys.KeyTypeMessageProvisional {
+			provKeyMap[msg.Key] = true
+		}
+	}
+	addMove := func(move *models.MessageMovePartial) {
+		for _, key := range append(append([]string(nil), move.Keys...), move.TargetKeys...) {
+			add(&models.Message{Key: key})
+		}
+	}
 	for _, entry := range entries {
-		if msg, ok := entry.Payload.(*models.Message); ok && msg.Key != "" {
-			if parsed, err := keys.ParseKey(msg.Key); err == nil && parsed.Type == keys.KeyTypeMessageProvisional {
-				provKeyMap[msg.Key] = true
+		switch p := entry.Payload.(type) {
+		case *models.Message:
+			add(p)
+		case *models.MessageBatchPartial:
+			for i := range p.Messages {
+				add(&p.Messages[i])
+			}
+		case *models.BatchPartial:
+			for _, op := range p.Ops {
+				if op.Message != nil {
+					add(op.Message)
+				}
+				if op.Move != nil {
+					addMove(op.Move)
+				}
 			}
+		case *models.MessageMovePartial:
+			addMove(p)
 		}
 	}
 	provKeys := make([]string, 0, len(provKeyMap))
@@ -252,8 +358,12 @@ func bulkLookupProvisionalKeys(provKeys []string) (map[string]string, error) {
 			threadToProvKeys[parsed.ThreadKey] = append(threadToProvKeys[parsed.ThreadKey], provKey)
 		}
 	}
-	for thread, keys := range threadToProvKeys {
-		prefix := []byte("t:" + thread + ":m:")
+	for thread, provs := range threadToProvKeys {
+		threadPrefix, err := keys.GenAllThreadMessagesPrefix(thread)
+		if err != nil {
+			continue
+		}
+		prefix := []byte(threadPrefix)
 		upper := nextPrefix(prefix)
 		iter, err := storedb.Client.NewIter(&pebble.IterOptions{
 			LowerBound: prefix,
@@ -262,7 +372,7 @@ func bulkLookupProvisionalKeys(provKeys []string) (map[string]string, error) {
 		if err != nil {
 			return mappings, err
 		}
-		for _, provKey := range keys {
+		for _, provKey := range provs {
 			seekKey := []byte(provKey + ":")
 			iter.SeekGE(seekKey)
 			if iter.Valid() && bytes.HasPrefix(iter.Key(), seekKey) {
@@ -327,15 +437,27 @@ func ApplyBatchToDB(entries []types.BatchEntry) error {
 	inflightKeys := collectInflightKeys(entries)
 
 	// process per thread groupings
+	var trail []models.AuditRecord
 	for _, threadEntries := range threadGroups {
 		sortedOps := sortOperationsByType(threadEntries)
 		for _, op := range sortedOps {
-			if err := BProcOperation(op, batchProcessor); err != nil {
+			err := BProcOperation(op, batchProcessor)
+			if err != nil {
 				logger.Error("operation_processing_failed", "err", err, "handler", op.Handler)
 			}
+			trail = append(trail, auditRecords(op, err)...)
 		}
 	}
 
+	// the trail lives in its own store, so its records are staged with the writes they
+	// describe: once flushed, the WAL can let go of the batch and a record that fails to
+	// reach the trail is retried from the index without applying the batch again
+	if key, data, err := audit.Stage(trail); err != nil {
+		logger.Error("audit_stage_failed", "err", err, "record_count", len(trail))
+	} else if key != "" {
+		batchProcessor.KV.SetIndexKV(key, data)
+	}
+
 	// commit to database
 	if err := batchProcessor.Flush(); err != nil {
 		return fmt.Errorf("batch flush failed: %w", err)
@@ -353,9 +475,54 @@ func ApplyBatchToDB(entries []types.BatchEntry) error {
 		logger.Error("wal_truncate_failed", "err", err, "seq_count", len(seqs))
 	}
 
+	// audit only what was committed
+	if err := audit.Sync(); err != nil {
+		logger.Error("audit_record_failed", "err", err, "record_count", len(trail))
+	}
+
 	return nil
 }
 
+// auditRecords describes an applied operation for the audit trail, one record per
+// operation of a multi-operation batch.
+func auditRecords(entry types.BatchEntry, err error) []models.AuditRecord {
+	if entry.Handler == types.HandlerBatch {
+		var out []models.AuditRecord
+		for _, op := range batchOpEntries(entry.QueueOp) {
+			out = append(out, auditRecords(op, err)...)
+		}
+		return out
+	}
+	meta := entry.QueueOp.Extras
+	rec := models.AuditRecord{
+		Action: string(entry.Handler),
+		Role:   meta.ApiRole,
+		UserID: extractAuthor(entry),
+		Thread: ExtractTKey(entry.QueueOp),
+		Target: ExtractMKey(entry.QueueOp),
+		ReqID:  meta.ReqID,
+		IP:     meta.ReqIP,
+	}
+	rec.Namespace = keys.NamespaceOf(rec.Thread)
+	if rec.UserID == "" {
+		rec.UserID = meta.UserID
+	}
+	switch p := entry.Payload.(type) {
+	case *models.MessageBatchPartial:
+		rec.Detail = map[string]string{"count": strconv.Itoa(len(p.Messages))}
+	case *models.MessageMovePartial:
+		rec.Detail = map[string]string{"count": strconv.Itoa(len(p.Keys)), "target_thread": p.Target}
+	}
+	if err != nil {
+		rec.Outcome = audit.OutcomeFailed
+		if rec.Detail == nil {
+			rec.Detail = map[string]string{}
+		}
+		rec.Detail["error"] = err.Error()
+	}
+	return []models.AuditRecord{rec}
+}
+
 func collectInflightKeys(entries []types.BatchEntry) []string {
 	var inflightKeys []string
 	for _, entry := range entries {
@@ -370,6 +537,19 @@ func collectInflightKeys(entries []types.BatchEntry) []string {
 				inflightKeys = append(inflightKeys, msg.Key)
 				logger.Debug("collecting_inflight_message_key", "key", msg.Key)
 			}
+		case types.HandlerMessageBatch:
+			if batch, ok := entry.Payload.(*models.MessageBatchPartial); ok {
+				for _, msg := range batch.Messages {
+					inflightKeys = append(inflightKeys, msg.Key)
+				}
+				logger.Debug("collecting_inflight_message_batch_keys", "count", len(batch.Messages))
+			}
+		case types.HandlerBatch:
+			inflightKeys = append(inflightKeys, collectInflightKeys(batchOpEntries(entry.QueueOp))...)
+		case types.HandlerMessageMove:
+			if move, ok := entry.Payload.(*models.MessageMovePartial); ok {
+				inflightKeys = append(inflightKeys, move.TargetKeys...)
+			}
 		}
 	}
 	logger.Debug("collected_inflight_keys", "count", len(inflightKeys), "keys", inflightKeys)
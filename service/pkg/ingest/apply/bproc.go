@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"progressdb/pkg/config"
 	"progressdb/pkg/ingest/types"
 	"progressdb/pkg/models"
 	"progressdb/pkg/state"
@@ -20,12 +21,22 @@ func BProcOperation(entry types.BatchEntry, batchProcessor *BatchProcessor) erro
 		return BProcThreadUpdate(entry, batchProcessor)
 	case types.HandlerThreadDelete:
 		return BProcThreadDelete(entry, batchProcessor)
+	case types.HandlerThreadParticipant:
+		return BProcThreadParticipant(entry, batchProcessor)
+	case types.HandlerThreadTransfer:
+		return BProcThreadTransfer(entry, batchProcessor)
 	case types.HandlerMessageCreate:
 		return BProcMessageCreate(entry, batchProcessor)
+	case types.HandlerMessageBatch:
+		return BProcMessageBatch(entry, batchProcessor)
+	case types.HandlerBatch:
+		return BProcBatch(entry, batchProcessor)
 	case types.HandlerMessageUpdate:
 		return BProcMessageUpdate(entry, batchProcessor)
 	case types.HandlerMessageDelete:
 		return BProcMessageDelete(entry, batchProcessor)
+	case types.HandlerMessageMove:
+		return BProcMessageMove(entry, batchProcessor)
 	}
 
 	// this is not going to happen
@@ -69,8 +80,22 @@ func BProcThreadCreate(entry types.BatchEntry, batchProcessor *BatchProcessor) e
 
 	// index
 	// thread <> message indexes are inited already
-	batchProcessor.Index.SetUserOwnership(author, threadKey, 1)      // user, thread, 1
-	batchProcessor.Index.SetThreadParticipants(author, threadKey, 1) // user, thread, 1
+	batchProcessor.Index.SetUserOwnership(author, threadKey, 1)                              // user, thread, 1
+	batchProcessor.Index.SetThreadParticipantRole(author, threadKey, models.ThreadRoleOwner) // user, thread, owner
+
+	if thread.ExternalID != "" {
+		batchProcessor.Index.SetThreadExternalID(author, thread.ExternalID, threadKey)
+	}
+
+	// direct-message threads: every other participant joins as a member
+	if thread.IsDirect() {
+		for _, userID := range thread.DM {
+			if userID != author {
+				batchProcessor.Index.SetThreadParticipantRole(userID, threadKey, models.ThreadRoleMember)
+			}
+		}
+		batchProcessor.Index.SetDirectThread(thread.DM, threadKey)
+	}
 	return nil
 }
 
@@ -93,18 +118,8 @@ func BProcThreadDelete(entry types.BatchEntry, batchProcessor *BatchProcessor) e
 	}
 
 	// check access
-	hasOwnership, err := batchProcessor.Index.DoesUserOwnThread(author, threadKey)
-	if err != nil {
-		return fmt.Errorf("failed to check thread ownership: %w", err)
-	}
-
-	hasParticipation, err := batchProcessor.Index.DoesThreadHaveUser(threadKey, author)
-	if err != nil {
-		return fmt.Errorf("failed to check thread participation: %w", err)
-	}
-
-	if !hasOwnership && !hasParticipation {
-		return fmt.Errorf("access denied: user %s does not have access to thread %s", author, threadKey)
+	if err := authorizeThreadAction(batchProcessor, threadKey, author, models.ThreadActionDeleteThread); err != nil {
+		return err
 	}
 
 	// fetch existing
@@ -159,13 +174,8 @@ func BProcThreadUpdate(entry types.BatchEntry, batchProcessor *BatchProcessor) e
 	}
 
 	// check access
-	hasOwnership, err := batchProcessor.Index.DoesUserOwnThread(author, threadKey)
-	if err != nil {
-		return fmt.Errorf("failed to check thread ownership: %w", err)
-	}
-
-	if !hasOwnership {
-		return fmt.Errorf("access denied: only thread owner can update thread %s", threadKey)
+	if err := authorizeThreadAction(batchProcessor, threadKey, author, models.ThreadActionUpdateThread); err != nil {
+		return err
 	}
 
 	// fetch existing
@@ -181,6 +191,12 @@ func BProcThreadUpdate(entry types.BatchEntry, batchProcessor *BatchProcessor) e
 	}
 
 	// apply updates
+	if update.Title != "" {
+		thread.Title = update.Title
+	}
+	if update.Tags != nil {
+		thread.Tags = update.Tags
+	}
 	if update.UpdatedTS != 0 {
 		thread.UpdatedTS = update.UpdatedTS
 	}
@@ -193,6 +209,136 @@ func BProcThreadUpdate(entry types.BatchEntry, batchProcessor *BatchProcessor) e
 	return nil
 }
 
+func BProcThreadParticipant(entry types.BatchEntry, batchProcessor *BatchProcessor) error {
+	// extract
+	author := extractAuthor(entry)
+	if author == "" {
+		return fmt.Errorf("author required for participant update")
+	}
+
+	// validate
+	if entry.Payload == nil {
+		return fmt.Errorf("payload required for participant update")
+	}
+
+	// parse
+	update, ok := entry.Payload.(*models.ThreadParticipantPartial)
+	if !ok {
+		return fmt.Errorf("invalid payload type for participant update")
+	}
+
+	// resolve
+	threadKey := ExtractTKey(entry.QueueOp)
+	if _, err := keys.ParseKey(threadKey); err != nil {
+		return fmt.Errorf("invalid thread key format: %s - expected t:<threadKey>", threadKey)
+	}
+
+	currentRole, err := batchProcessor.Index.GetThreadUserRole(threadKey, update.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve participant role: %w", err)
+	}
+	if currentRole == models.ThreadRoleOwner {
+		return fmt.Errorf("%w: thread owner role cannot be changed", models.ErrThreadAccessDenied)
+	}
+
+	// check access - participants may always leave a thread themselves
+	selfLeave := update.Removed && update.UserID == author
+	if !selfLeave {
+		action := models.ThreadActionManageParticipants
+		if currentRole == models.ThreadRoleAdmin || update.Role == models.ThreadRoleAdmin {
+			action = models.ThreadActionManageAdmins
+		}
+		if err := authorizeThreadAction(batchProcessor, threadKey, author, action); err != nil {
+			return err
+		}
+	}
+
+	// index
+	if update.Removed {
+		batchProcessor.Index.RemoveThreadParticipant(update.UserID, threadKey)
+		return nil
+	}
+	batchProcessor.Index.SetThreadParticipantRole(update.UserID, threadKey, update.Role)
+	return nil
+}
+
+func BProcThreadTransfer(entry types.BatchEntry, batchProcessor *BatchProcessor) error {
+	// validate
+	if entry.Payload == nil {
+		return fmt.Errorf("payload required for thread transfer")
+	}
+
+	// parse
+	transfer, ok := entry.Payload.(*models.ThreadTransferPartial)
+	if !ok {
+		return fmt.Errorf("invalid payload type for thread transfer")
+	}
+
+	// resolve
+	threadKey := ExtractTKey(entry.QueueOp)
+	if _, err := keys.ParseKey(threadKey); err != nil {
+		return fmt.Errorf("invalid thread key format: %s - expected t:<threadKey>", threadKey)
+	}
+
+	// fetch existing
+	existingData, err := batchProcessor.Data.GetThreadMetaCopy(threadKey)
+	if err != nil {
+		return fmt.Errorf("failed to get thread for transfer: %w", err)
+	}
+
+	// parse existing
+	var thread models.Thread
+	if err := json.Unmarshal(existingData, &thread); err != nil {
+		return fmt.Errorf("unmarshal existing thread: %w", err)
+	}
+
+	// a direct thread is keyed by its participant set, which a new owner would change
+	if thread.IsDirect() {
+		return fmt.Errorf("thread %s is a direct-message thread", threadKey)
+	}
+
+	// check owner - refuse if ownership moved since the transfer was requested
+	if thread.Author != transfer.From {
+		return fmt.Errorf("thread %s is owned by %s, not %s", threadKey, thread.Author, transfer.From)
+	}
+	if transfer.To == transfer.From {
+		return nil
+	}
+
+	// index
+	batchProcessor.Index.RemoveUserOwnership(transfer.From, threadKey)
+	batchProcessor.Index.SetUserOwnership(transfer.To, threadKey, 1)
+	batchProcessor.Index.SetThreadParticipantRole(transfer.To, threadKey, models.ThreadRoleOwner)
+	if transfer.KeepPreviousOwner {
+		batchProcessor.Index.SetThreadParticipantRole(transfer.From, threadKey, transfer.PreviousOwnerRole)
+	} else {
+		batchProcessor.Index.RemoveThreadParticipant(transfer.From, threadKey)
+	}
+
+	// external ids are unique per author; the thread drops its id if the new owner uses it
+	if thread.ExternalID != "" {
+		moved, err := batchProcessor.Index.MoveThreadExternalID(transfer.From, transfer.To, thread.ExternalID, threadKey)
+		if err != nil {
+			return fmt.Errorf("move thread external id: %w", err)
+		}
+		if !moved {
+			logger.Warn("thread_transfer_external_id_dropped", "thread", threadKey, "to", transfer.To, "external_id", thread.ExternalID)
+			thread.ExternalID = ""
+		}
+	}
+
+	// apply transfer
+	thread.Author = transfer.To
+	thread.UpdatedTS = transfer.UpdatedTS
+
+	// store
+	if err := batchProcessor.Data.SetThreadData(threadKey, &thread); err != nil {
+		return fmt.Errorf("set thread meta: %w", err)
+	}
+
+	return nil
+}
+
 // Messages
 func BProcMessageCreate(entry types.BatchEntry, batchProcessor *BatchProcessor) error {
 	// extract
@@ -219,20 +365,88 @@ func BProcMessageCreate(entry types.BatchEntry, batchProcessor *BatchProcessor)
 	}
 
 	// check access
-	hasOwnership, err := batchProcessor.Index.DoesUserOwnThread(author, threadKey)
-	if err != nil {
-		return fmt.Errorf("failed to check thread ownership: %w", err)
+	if err := authorizeThreadAction(batchProcessor, threadKey, author, models.ThreadActionPostMessage); err != nil {
+		return err
 	}
 
-	hasParticipation, err := batchProcessor.Index.DoesThreadHaveUser(threadKey, author)
-	if err != nil {
-		return fmt.Errorf("failed to check thread participation: %w", err)
+	return createMessage(batchProcessor, threadKey, author, msg, entry.TS)
+}
+
+// BProcMessageBatch creates every message of a batch in order; the thread group is
+// applied sequentially, so the messages receive contiguous sequences. The batch lands
+// whole or not at all: a message that fails discards those staged before it.
+func BProcMessageBatch(entry types.BatchEntry, batchProcessor *BatchProcessor) error {
+	// extract
+	author := extractAuthor(entry)
+	if author == "" {
+		return fmt.Errorf("author required for message batch")
+	}
+
+	// resolve
+	threadKey := ExtractTKey(entry.QueueOp)
+	if threadKey == "" {
+		return fmt.Errorf("thread ID required for message batch")
+	}
+
+	// parse
+	batch, ok := entry.Payload.(*models.MessageBatchPartial)
+	if !ok {
+		return fmt.Errorf("invalid payload type for message batch")
+	}
+
+	// check access
+	if err := authorizeThreadAction(batchProcessor, threadKey, author, models.ThreadActionPostMessage); err != nil {
+		return err
+	}
+
+	batchProcessor.KV.Begin()
+	for i := range batch.Messages {
+		msg := &batch.Messages[i]
+		if err := createMessage(batchProcessor, threadKey, author, msg, msg.CreatedTS); err != nil {
+			batchProcessor.KV.Rollback()
+			return fmt.Errorf("messages[%d]: %w", i, err)
+		}
+	}
+	batchProcessor.KV.Commit()
+	return nil
+}
+
+// BProcBatch applies the operations of a multi-operation batch in order. Later operations
+// see the staged writes of earlier ones; the first that fails discards them all, so the
+// batch lands whole or not at all.
+func BProcBatch(entry types.BatchEntry, batchProcessor *BatchProcessor) error {
+	// extract
+	author := extractAuthor(entry)
+	if author == "" {
+		return fmt.Errorf("author required for batch")
+	}
+
+	batchProcessor.KV.Begin()
+	for i, op := range batchOpEntries(entry.QueueOp) {
+		if err := applyBatchOp(op, batchProcessor); err != nil {
+			batchProcessor.KV.Rollback()
+			return fmt.Errorf("ops[%d]: %w", i, err)
+		}
+	}
+	batchProcessor.KV.Commit()
+	return nil
+}
+
+func applyBatchOp(op types.BatchEntry, batchProcessor *BatchProcessor) error {
+	if op.Payload == nil {
+		return fmt.Errorf("payload required for %s", op.Handler)
 	}
 
-	if !hasOwnership && !hasParticipation {
-		return fmt.Errorf("access denied: user %s does not have access to thread %s", author, threadKey)
+	// sequences - ops may touch threads other than the one the batch is grouped under
+	if threadKey := ExtractTKey(op.QueueOp); threadKey != "" {
+		if err := batchProcessor.Index.InitializeThreadSequencesFromDB([]string{threadKey}); err != nil {
+			return fmt.Errorf("init thread sequences: %w", err)
+		}
 	}
+	return BProcOperation(op, batchProcessor)
+}
 
+func createMessage(batchProcessor *BatchProcessor, threadKey, author string, msg *models.Message, ts int64) error {
 	// resolve message key
 	finalMessageKey, err := batchProcessor.Index.ResolveMessageKey(msg.Key)
 	if err != nil {
@@ -243,15 +457,26 @@ func BProcMessageCreate(entry types.BatchEntry, batchProcessor *BatchProcessor)
 	// sync message fields
 	msg.Thread = threadKey
 	msg.Author = author
-	msg.CreatedTS = entry.TS
-	msg.UpdatedTS = entry.TS
+	msg.CreatedTS = ts
+	msg.UpdatedTS = ts
 	msg.Key = finalMessageKey
 
 	// index
 	batchProcessor.Index.UpdateThreadMessageIndexes(threadKey, msg)
+	if msg.ExternalID != "" {
+		batchProcessor.Index.SetMessageExternalID(threadKey, msg.ExternalID, finalMessageKey)
+	}
+	if msg.ExpiresAt != 0 {
+		batchProcessor.Index.SetMessageExpiry(finalMessageKey, msg.ExpiresAt)
+	}
+
+	// mentions - extracted before the body is encrypted on store
+	if err := indexMentions(batchProcessor, threadKey, author, msg); err != nil {
+		logger.Error("mention_index_failed", "msg_key", finalMessageKey, "error", err)
+	}
 
 	// store
-	if err := batchProcessor.Data.SetMessageData(finalMessageKey, msg, entry.TS); err != nil {
+	if err := batchProcessor.Data.SetMessageData(finalMessageKey, msg, ts); err != nil {
 		return fmt.Errorf("set message data: %w", err)
 	}
 	return nil
@@ -282,21 +507,6 @@ func BProcMessageUpdate(entry types.BatchEntry, batchProcessor *BatchProcessor)
 		return fmt.Errorf("invalid payload type for message update")
 	}
 
-	// check access
-	hasOwnership, err := batchProcessor.Index.DoesUserOwnThread(author, threadKey)
-	if err != nil {
-		return fmt.Errorf("failed to check thread ownership: %w", err)
-	}
-
-	hasParticipation, err := batchProcessor.Index.DoesThreadHaveUser(threadKey, author)
-	if err != nil {
-		return fmt.Errorf("failed to check thread participation: %w", err)
-	}
-
-	if !hasOwnership && !hasParticipation {
-		return fmt.Errorf("access denied: user %s does not have access to thread %s", author, threadKey)
-	}
-
 	// resolve message key
 	finalMessageKey, err := batchProcessor.Index.ResolveMessageKey(messageKey)
 	if err != nil {
@@ -315,6 +525,24 @@ func BProcMessageUpdate(entry types.BatchEntry, batchProcessor *BatchProcessor)
 		return fmt.Errorf("unmarshal existing message: %w", err)
 	}
 
+	// validate - the message must belong to the thread it is edited through
+	if msg.Thread != threadKey {
+		return fmt.Errorf("message %s not found in thread %s", finalMessageKey, threadKey)
+	}
+
+	// check access
+	if err := authorizeThreadAction(batchProcessor, threadKey, author, messageEditAction(msg.Author, author)); err != nil {
+		return err
+	}
+
+	// previous body, to retract mentions the edit removes
+	var previous *models.Message
+	if update.Body != nil && config.MentionField() != "" {
+		if previous, err = batchProcessor.Data.GetMessageCopy(finalMessageKey); err != nil {
+			logger.Error("mention_previous_body_failed", "msg_key", finalMessageKey, "error", err)
+		}
+	}
+
 	// apply updates
 	if update.Body != nil {
 		msg.Body = update.Body
@@ -323,6 +551,14 @@ func BProcMessageUpdate(entry types.BatchEntry, batchProcessor *BatchProcessor)
 		msg.UpdatedTS = update.UpdatedTS
 	}
 
+	// mentions - extracted before the body is encrypted on store
+	if update.Body != nil {
+		dropStaleMentions(batchProcessor, previous, &msg)
+		if err := indexMentions(batchProcessor, threadKey, author, &msg); err != nil {
+			logger.Error("mention_index_failed", "msg_key", finalMessageKey, "error", err)
+		}
+	}
+
 	// store
 	if err := batchProcessor.Data.SetMessageData(finalMessageKey, &msg, entry.TS); err != nil {
 		return fmt.Errorf("set message data: %w", err)
@@ -364,21 +600,6 @@ func BProcMessageDelete(entry types.BatchEntry, batchProcessor *BatchProcessor)
 		return fmt.Errorf("thread key required for message deletion")
 	}
 
-	// check access
-	hasOwnership, err := batchProcessor.Index.DoesUserOwnThread(author, finalThreadKey)
-	if err != nil {
-		return fmt.Errorf("failed to check thread ownership: %w", err)
-	}
-
-	hasParticipation, err := batchProcessor.Index.DoesThreadHaveUser(finalThreadKey, author)
-	if err != nil {
-		return fmt.Errorf("failed to check thread participation: %w", err)
-	}
-
-	if !hasOwnership && !hasParticipation {
-		return fmt.Errorf("access denied: user %s does not have access to thread %s", author, finalThreadKey)
-	}
-
 	var msgKey string
 	if entry.Payload != nil {
 		switch p := entry.Payload.(type) {
@@ -407,6 +628,16 @@ func BProcMessageDelete(entry types.BatchEntry, batchProcessor *BatchProcessor)
 		return fmt.Errorf("unmarshal message for delete: %w", err)
 	}
 
+	// validate - the message must belong to the thread it is deleted through
+	if existingMessage.Thread != finalThreadKey {
+		return fmt.Errorf("message %s not found in thread %s", finalMessageKey, finalThreadKey)
+	}
+
+	// check access
+	if err := authorizeThreadAction(batchProcessor, finalThreadKey, author, messageEditAction(existingMessage.Author, author)); err != nil {
+		return err
+	}
+
 	// mark deleted
 	existingMessage.Deleted = true
 	existingMessage.UpdatedTS = entry.TS
@@ -434,3 +665,194 @@ func BProcMessageDelete(entry types.BatchEntry, batchProcessor *BatchProcessor)
 
 	return nil
 }
+
+// BProcMessageMove moves messages into another thread under fresh sequenced keys, in the
+// order given. The source keeps a tombstone and a redirect so old keys still resolve;
+// a copy leaves the source untouched. When one message fails, none of the set moves.
+func BProcMessageMove(entry types.BatchEntry, batchProcessor *BatchProcessor) error {
+	// extract
+	author := extractAuthor(entry)
+	if author == "" {
+		return fmt.Errorf("author required for message move")
+	}
+
+	// parse
+	move, ok := entry.Payload.(*models.MessageMovePartial)
+	if !ok {
+		return fmt.Errorf("invalid payload type for message move")
+	}
+
+	// check access - moving takes messages away from the source, copying only reads them
+	sourceAction := models.ThreadActionModerateMessages
+	if move.Copy {
+		sourceAction = models.ThreadActionRead
+	}
+	if err := authorizeThreadAction(batchProcessor, move.Thread, author, sourceAction); err != nil {
+		return err
+	}
+	if err := authorizeThreadAction(batchProcessor, move.Target, author, models.ThreadActionPostMessage); err != nil {
+		return err
+	}
+
+	// sequences - the batch is grouped under the source thread only
+	if err := batchProcessor.Index.InitializeThreadSequencesFromDB([]string{move.Target}); err != nil {
+		return fmt.Errorf("init target thread sequences: %w", err)
+	}
+
+	// the set moves whole or not at all; a batch already rolls back everything it staged
+	owned := !batchProcessor.KV.InTransaction()
+	if owned {
+		batchProcessor.KV.Begin()
+	}
+	for i := range move.Keys {
+		if err := moveMessage(batchProcessor, move, i, author, entry.TS); err != nil {
+			if owned {
+				batchProcessor.KV.Rollback()
+			}
+			return fmt.Errorf("keys[%d]: %w", i, err)
+		}
+	}
+	if owned {
+		batchProcessor.KV.Commit()
+	}
+	return nil
+}
+
+func moveMessage(batchProcessor *BatchProcessor, move *models.MessageMovePartial, i int, author string, ts int64) error {
+	key, targetKey := move.Keys[i], move.TargetKeys[i]
+
+	// resolve message keys
+	sourceKey, err := batchProcessor.Index.ResolveMessageKey(key)
+	if err != nil {
+		return fmt.Errorf("resolve message key %s: %w", key, err)
+	}
+
+	// fetch existing
+	msg, err := batchProcessor.Data.GetMessageCopy(sourceKey)
+	if err != nil {
+		return fmt.Errorf("failed to get message for move: %w", err)
+	}
+	if msg.Deleted {
+		return fmt.Errorf("message deleted: %s", sourceKey)
+	}
+	if msg.Thread != move.Thread {
+		return fmt.Errorf("message %s does not belong to thread %s", sourceKey, move.Thread)
+	}
+
+	finalKey, err := batchProcessor.Index.ResolveMessageKey(targetKey)
+	if err != nil {
+		return fmt.Errorf("resolve message key %s: %w", targetKey, err)
+	}
+
+	// sync message fields
+	moved := *msg
+	moved.Key = finalKey
+	moved.Thread = move.Target
+	moved.UpdatedTS = move.UpdatedTS
+	if i < len(move.Bodies) {
+		moved.Body = move.Bodies[i]
+	}
+
+	// index
+	if msg.ExternalID != "" {
+		if move.Copy {
+			moved.ExternalID = "" // the source keeps its id
+		} else if kept, err := batchProcessor.Index.MoveMessageExternalID(move.Thread, move.Target, msg.ExternalID, finalKey); err != nil {
+			return fmt.Errorf("move external id: %w", err)
+		} else if !kept {
+			logger.Warn("message_external_id_dropped", "msg_key", finalKey, "external_id", msg.ExternalID)
+			moved.ExternalID = ""
+		}
+	}
+	if moved.ExpiresAt != 0 {
+		batchProcessor.Index.SetMessageExpiry(finalKey, moved.ExpiresAt)
+	}
+	batchProcessor.Index.UpdateThreadMessageIndexes(move.Target, &moved)
+
+	// mentions - extracted before the body is encrypted on store
+	if err := indexMentions(batchProcessor, move.Target, author, &moved); err != nil {
+		logger.Error("mention_index_failed", "msg_key", finalKey, "error", err)
+	}
+
+	// store
+	if err := batchProcessor.Data.SetMessageData(finalKey, &moved, ts); err != nil {
+		return fmt.Errorf("set message data: %w", err)
+	}
+	if err := copyMessageVersions(batchProcessor, sourceKey, &moved); err != nil {
+		return err
+	}
+	if move.Copy {
+		return nil
+	}
+
+	// tombstone the source, leaving a redirect to the new key
+	msg.Deleted = true
+	msg.UpdatedTS = move.UpdatedTS
+	if err := batchProcessor.Data.SetMessageData(sourceKey, msg, ts); err != nil {
+		return fmt.Errorf("set moved message data: %w", err)
+	}
+	batchProcessor.Index.TouchThreadMessageIndexes(move.Thread, move.UpdatedTS)
+	batchProcessor.Index.SetSoftDeletedMessages(msg.Author, sourceKey, 1)
+	batchProcessor.Index.SetMovedMessage(sourceKey, finalKey)
+	if field := config.MentionField(); field != "" {
+		for _, userID := range extractMentions(msg.Body, field) {
+			batchProcessor.Index.RemoveUserMention(userID, sourceKey)
+		}
+	}
+	return nil
+}
+
+// copyMessageVersions carries the edit history of a message over to its new key, including
+// edits staged earlier in the batch.
+func copyMessageVersions(batchProcessor *BatchProcessor, sourceKey string, moved *models.Message) error {
+	versions, err := batchProcessor.Data.ListMessageVersions(sourceKey)
+	if err != nil {
+		return fmt.Errorf("list message versions: %w", err)
+	}
+	if len(versions) == 0 {
+		return nil
+	}
+
+	parts, err := keys.ParseMessageKey(moved.Key)
+	if err != nil {
+		return fmt.Errorf("failed to parse message key %s: %w", moved.Key, err)
+	}
+	seq, err := keys.KeySequenceNumbered(parts.Seq)
+	if err != nil {
+		return fmt.Errorf("failed to convert sequence %s to uint64: %w", parts.Seq, err)
+	}
+
+	for _, raw := range versions {
+		var version models.Message
+		if err := json.Unmarshal([]byte(raw), &version); err != nil {
+			return fmt.Errorf("unmarshal message version: %w", err)
+		}
+		version.Key = moved.Key
+		version.Thread = moved.Thread
+		version.ExternalID = moved.ExternalID
+		versionKey := keys.GenMessageVersionKey(moved.Key, version.UpdatedTS, seq)
+		if err := batchProcessor.Data.SetVersionKey(versionKey, &version); err != nil {
+			return fmt.Errorf("set version key: %w", err)
+		}
+	}
+	return nil
+}
+
+// helpers
+func authorizeThreadAction(batchProcessor *BatchProcessor, threadKey, author string, action models.ThreadAction) error {
+	role, err := batchProcessor.Index.GetThreadUserRole(threadKey, author)
+	if err != nil {
+		return fmt.Errorf("failed to resolve thread role: %w", err)
+	}
+	if err := models.AuthorizeThreadAction(role, action); err != nil {
+		return fmt.Errorf("%w (user %s, thread %s)", err, author, threadKey)
+	}
+	return nil
+}
+
+func messageEditAction(messageAuthor, author string) models.ThreadAction {
+	if messageAuthor == author {
+		return models.ThreadActionEditOwnMessage
+	}
+	return models.ThreadActionModerateMessages
+}
@@ -60,7 +60,7 @@ func (dm *DataManager) SetMessageData(messageKey string, data interface{}, ts in
 
 	// Encrypt if it's a message (not for partials or other types)
 	if _, ok := data.(*models.Message); ok {
-		marshaled, err = encryption.EncryptMessageData(parsed.ThreadKey, marshaled)
+		marshaled, err = dm.encryptMessageData(parsed.ThreadKey, marshaled)
 		if err != nil {
 			return fmt.Errorf("failed to encrypt message data: %w", err)
 		}
@@ -70,6 +70,105 @@ func (dm *DataManager) SetMessageData(messageKey string, data interface{}, ts in
 	return nil
 }
 
+// encryptMessageData encrypts with the thread's DEK, taken from the batch when the thread
+// was created in it and is not yet in the store.
+func (dm *DataManager) encryptMessageData(threadKey string, data []byte) ([]byte, error) {
+	staged, ok := dm.kv.GetStoreKV(keys.GenThreadKey(threadKey))
+	if !ok || staged == nil {
+		return encryption.EncryptMessageData(threadKey, data)
+	}
+	kmsMeta, err := stagedThreadKMS(staged)
+	if err != nil {
+		return nil, err
+	}
+	return encryption.EncryptMessageDataWithKMS(keys.NamespaceOf(threadKey), kmsMeta, data)
+}
+
+// GetMessageCopy returns the message with its body decrypted, for operations that
+// re-encrypt it under another thread.
+func (dm *DataManager) GetMessageCopy(messageKey string) (*models.Message, error) {
+	parsed, err := keys.ParseMessageKey(messageKey)
+	if err != nil {
+		return nil, fmt.Errorf("parse message key: %w", err)
+	}
+	data, err := dm.GetMessageDataCopy(messageKey)
+	if err != nil {
+		return nil, err
+	}
+
+	kmsMeta, err := dm.threadKMS(parsed.ThreadKey)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := encryption.DecryptMessageData(keys.NamespaceOf(messageKey), kmsMeta, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt message data: %w", err)
+	}
+
+	var msg models.Message
+	if err := json.Unmarshal(decrypted, &msg); err != nil {
+		return nil, fmt.Errorf("unmarshal message: %w", err)
+	}
+	return &msg, nil
+}
+
+// ListMessageVersions returns the versions of a message decrypted and keyed by version
+// key, with the ones staged in the batch over those already stored.
+func (dm *DataManager) ListMessageVersions(messageKey string) (map[string]string, error) {
+	parsed, err := keys.ParseMessageKey(messageKey)
+	if err != nil {
+		return nil, fmt.Errorf("parse message key: %w", err)
+	}
+	prefix, err := keys.GenAllMessageVersionsPrefix(messageKey)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := messages.ListMessageVersions(messageKey)
+	if err != nil {
+		return nil, err
+	}
+
+	staged := dm.kv.IndexKVWithPrefix(prefix)
+	if len(staged) == 0 {
+		return versions, nil
+	}
+	kmsMeta, err := dm.threadKMS(parsed.ThreadKey)
+	if err != nil {
+		return nil, err
+	}
+	for key, data := range staged {
+		if data == nil {
+			delete(versions, key)
+			continue
+		}
+		decrypted, err := encryption.DecryptMessageData(keys.NamespaceOf(messageKey), kmsMeta, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt version data: %w", err)
+		}
+		versions[key] = string(decrypted)
+	}
+	return versions, nil
+}
+
+// threadKMS returns the thread's KMS metadata, taken from the batch when the thread was
+// created in it.
+func (dm *DataManager) threadKMS(threadKey string) (*models.KMSMeta, error) {
+	if staged, ok := dm.kv.GetStoreKV(keys.GenThreadKey(threadKey)); ok && staged != nil {
+		return stagedThreadKMS(staged)
+	}
+	return encryption.GetThreadKMS(threadKey)
+}
+
+func stagedThreadKMS(staged []byte) (*models.KMSMeta, error) {
+	var thread struct {
+		KMS *models.KMSMeta `json:"kms"`
+	}
+	if err := json.Unmarshal(staged, &thread); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal thread KMS: %w", err)
+	}
+	return thread.KMS, nil
+}
+
 func (dm *DataManager) SetVersionKey(versionKey string, data interface{}) error {
 	if versionKey == "" {
 		return fmt.Errorf("versionKey cannot be empty")
@@ -84,7 +183,7 @@ func (dm *DataManager) SetVersionKey(versionKey string, data interface{}) error
 
 	// Encrypt if it's a message
 	if msg, ok := data.(*models.Message); ok {
-		marshaled, err = encryption.EncryptMessageData(msg.Thread, marshaled)
+		marshaled, err = dm.encryptMessageData(msg.Thread, marshaled)
 		if err != nil {
 			return fmt.Errorf("failed to encrypt version data: %w", err)
 		}
@@ -1,6 +1,7 @@
 package apply
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -113,6 +114,23 @@ func (im *IndexManager) UpdateThreadMessageIndexes(threadKey string, message *mo
 	}
 }
 
+// TouchThreadMessageIndexes records a change to the thread's messages that adds none, such
+// as a message moving out of it.
+func (im *IndexManager) TouchThreadMessageIndexes(threadKey string, updatedAt int64) {
+	idx, err := im.loadThreadIndex(threadKey)
+	if err != nil {
+		logger.Error("failed to load thread index", "error", err)
+		return
+	}
+	if updatedAt <= idx.LastUpdatedAt {
+		return
+	}
+	idx.LastUpdatedAt = updatedAt
+	if err := im.saveThreadIndex(threadKey, idx); err != nil {
+		logger.Error("failed to save thread index", "error", err)
+	}
+}
+
 func (im *IndexManager) InitializeThreadSequencesFromDB(threadKeys []string) error {
 	for _, threadKey := range threadKeys {
 		// Check if already in batch
@@ -181,7 +199,7 @@ func (m *IndexManager) generateNewSequencedKey(messageKey string) (string, error
 		return "", fmt.Errorf("expected message key, got %s", parsed.Type)
 	}
 
-	threadKey := keys.GenThreadKey(parsed.ThreadTS)
+	threadKey := keys.GenThreadKey(parsed.ThreadKey)
 
 	// Get the next available sequence number for this thread
 	sequence, err := m.GetNextMessageSequence(threadKey)
@@ -189,7 +207,7 @@ func (m *IndexManager) generateNewSequencedKey(messageKey string) (string, error
 		return "", fmt.Errorf("get next message sequence: %w", err)
 	}
 
-	finalKey := keys.GenMessageKey(parsed.ThreadTS, parsed.MessageTS, sequence)
+	finalKey := keys.GenMessageKey(parsed.ThreadKey, parsed.MessageTS, sequence)
 
 	// set state
 	m.kv.SetStateKV(messageKey, finalKey)
@@ -237,9 +255,112 @@ func (im *IndexManager) SetUserOwnership(userID, threadKey string, value int) {
 	im.kv.SetIndexKV(key, []byte(strconv.Itoa(value)))
 }
 
-func (im *IndexManager) SetThreadParticipants(userID, threadKey string, value int) {
+func (im *IndexManager) RemoveUserOwnership(userID, threadKey string) {
+	im.kv.DeleteIndexKV(keys.GenUserOwnsThreadKey(userID, threadKey))
+}
+
+func (im *IndexManager) SetThreadParticipantRole(userID, threadKey string, role models.ThreadRole) {
 	key := keys.GenThreadHasUserKey(threadKey, userID)
-	im.kv.SetIndexKV(key, []byte(strconv.Itoa(value)))
+	im.kv.SetIndexKV(key, []byte(role))
+}
+
+func (im *IndexManager) RemoveThreadParticipant(userID, threadKey string) {
+	key := keys.GenThreadHasUserKey(threadKey, userID)
+	im.kv.DeleteIndexKV(key)
+}
+
+// external ids
+func (im *IndexManager) SetThreadExternalID(author, externalID, threadKey string) {
+	im.kv.SetIndexKV(keys.GenThreadExternalIDKey(keys.NamespaceOf(threadKey), author, externalID), []byte(threadKey))
+}
+
+func (im *IndexManager) SetMessageExternalID(threadKey, externalID, messageKey string) {
+	im.kv.SetIndexKV(keys.GenMessageExternalIDKey(threadKey, externalID), []byte(messageKey))
+}
+
+// MoveThreadExternalID re-keys a transferred thread's external id to its new owner.
+// Returns false, dropping the previous owner's lookup, when the new owner already uses the id.
+func (im *IndexManager) MoveThreadExternalID(from, to, externalID, threadKey string) (bool, error) {
+	namespace := keys.NamespaceOf(threadKey)
+	toKey := keys.GenThreadExternalIDKey(namespace, to, externalID)
+	taken := false
+	if data, ok := im.kv.GetIndexKV(toKey); ok {
+		taken = data != nil
+	} else {
+		existing, err := indexdb.GetThreadByExternalID(namespace, to, externalID)
+		if err != nil {
+			return false, err
+		}
+		taken = existing != ""
+	}
+
+	im.kv.DeleteIndexKV(keys.GenThreadExternalIDKey(namespace, from, externalID))
+	if taken {
+		return false, nil
+	}
+	im.kv.SetIndexKV(toKey, []byte(threadKey))
+	return true, nil
+}
+
+// MoveMessageExternalID re-keys a moved message's external id to the target thread.
+// Returns false, dropping the source lookup, when the target already uses the id.
+func (im *IndexManager) MoveMessageExternalID(from, to, externalID, messageKey string) (bool, error) {
+	toKey := keys.GenMessageExternalIDKey(to, externalID)
+	taken := false
+	if data, ok := im.kv.GetIndexKV(toKey); ok {
+		taken = data != nil
+	} else {
+		existing, err := indexdb.GetMessageByExternalID(to, externalID)
+		if err != nil {
+			return false, err
+		}
+		taken = existing != ""
+	}
+
+	im.kv.DeleteIndexKV(keys.GenMessageExternalIDKey(from, externalID))
+	if taken {
+		return false, nil
+	}
+	im.kv.SetIndexKV(toKey, []byte(messageKey))
+	return true, nil
+}
+
+// moves
+func (im *IndexManager) SetMovedMessage(messageKey, movedTo string) {
+	im.kv.SetIndexKV(keys.GenMovedMessageKey(messageKey), []byte(movedTo))
+}
+
+// direct-message threads
+func (im *IndexManager) SetDirectThread(participants []string, threadKey string) {
+	im.kv.SetIndexKV(keys.GenDirectThreadKey(keys.NamespaceOf(threadKey), participants), []byte(threadKey))
+}
+
+// mentions
+func (im *IndexManager) SetUserMention(userID string, mention models.Mention) {
+	data, err := json.Marshal(mention)
+	if err != nil {
+		logger.Error("set_user_mention_failed", "user", userID, "message", mention.MessageKey, "error", err)
+		return
+	}
+	im.kv.SetIndexKV(keys.GenUserMentionKey(userID, mention.MessageKey), data)
+}
+
+func (im *IndexManager) RemoveUserMention(userID, messageKey string) {
+	im.kv.DeleteIndexKV(keys.GenUserMentionKey(userID, messageKey))
+}
+
+func (im *IndexManager) HasUserMention(userID, messageKey string) (bool, error) {
+	key := keys.GenUserMentionKey(userID, messageKey)
+	if data, ok := im.kv.GetIndexKV(key); ok {
+		return data != nil, nil
+	}
+	if _, err := indexdb.GetKey(key); err != nil {
+		if indexdb.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
 }
 
 // deletes
@@ -254,6 +375,11 @@ func (im *IndexManager) SetSoftDeletedMessages(userID, messageKey string, value
 	im.kv.SetIndexKV(key, []byte(strconv.Itoa(value)))
 }
 
+// expiry
+func (im *IndexManager) SetMessageExpiry(messageKey string, expiresAt int64) {
+	im.kv.SetIndexKV(keys.GenExpiryMarkerKey(messageKey), []byte(strconv.FormatInt(expiresAt, 10)))
+}
+
 // Checks
 func (im *IndexManager) DoesUserOwnThread(userID, threadKey string) (bool, error) {
 	key := keys.GenUserOwnsThreadKey(userID, threadKey)
@@ -265,10 +391,31 @@ func (im *IndexManager) DoesUserOwnThread(userID, threadKey string) (bool, error
 }
 
 func (im *IndexManager) DoesThreadHaveUser(threadKey, userID string) (bool, error) {
+	role, err := im.GetThreadParticipantRole(threadKey, userID)
+	if err != nil {
+		return false, err
+	}
+	return role != models.ThreadRoleNone, nil
+}
+
+func (im *IndexManager) GetThreadParticipantRole(threadKey, userID string) (models.ThreadRole, error) {
 	key := keys.GenThreadHasUserKey(threadKey, userID)
 	if data, ok := im.kv.GetIndexKV(key); ok {
-		return string(data) == "1", nil
+		role, _ := models.ParseThreadRole(string(data))
+		return role, nil
 	}
 	// Not in batch, query DB
-	return indexdb.DoesThreadHaveUser(threadKey, userID)
+	return indexdb.GetThreadParticipantRole(threadKey, userID)
+}
+
+// GetThreadUserRole resolves the effective role of a user, with ownership taking precedence.
+func (im *IndexManager) GetThreadUserRole(threadKey, userID string) (models.ThreadRole, error) {
+	isOwner, err := im.DoesUserOwnThread(userID, threadKey)
+	if err != nil {
+		return models.ThreadRoleNone, err
+	}
+	if isOwner {
+		return models.ThreadRoleOwner, nil
+	}
+	return im.GetThreadParticipantRole(threadKey, userID)
 }
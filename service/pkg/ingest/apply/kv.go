@@ -1,6 +1,7 @@
 package apply
 
 import (
+	"strings"
 	"sync"
 
 	"progressdb/pkg/state/logger"
@@ -15,6 +16,19 @@ type KVManager struct {
 	storeKV map[string][]byte
 	indexKV map[string][]byte
 	stateKV map[string]string
+	undo    *undoLog
+}
+
+// undoLog holds what each key staged before Begin, recorded on its first write since.
+type undoLog struct {
+	storeKV map[string]stagedValue
+	indexKV map[string]stagedValue
+	stateKV map[string]stagedValue
+}
+
+type stagedValue struct {
+	value   []byte
+	present bool
 }
 
 func NewKVManager() *KVManager {
@@ -29,6 +43,9 @@ func (kvm *KVManager) SetStoreKV(key string, value []byte) {
 	logger.Debug("[KVManager] SetStoreKV", "key", key)
 	kvm.mu.Lock()
 	defer kvm.mu.Unlock()
+	if kvm.undo != nil {
+		remember(kvm.undo.storeKV, kvm.storeKV, key)
+	}
 	kvm.storeKV[key] = value
 }
 
@@ -36,9 +53,23 @@ func (kvm *KVManager) SetIndexKV(key string, value []byte) {
 	logger.Debug("[KVManager] SetIndexKV", "key", key)
 	kvm.mu.Lock()
 	defer kvm.mu.Unlock()
+	if kvm.undo != nil {
+		remember(kvm.undo.indexKV, kvm.indexKV, key)
+	}
 	kvm.indexKV[key] = value
 }
 
+// DeleteIndexKV stages a delete; the key reads back as present with a nil value until flushed.
+func (kvm *KVManager) DeleteIndexKV(key string) {
+	logger.Debug("[KVManager] DeleteIndexKV", "key", key)
+	kvm.mu.Lock()
+	defer kvm.mu.Unlock()
+	if kvm.undo != nil {
+		remember(kvm.undo.indexKV, kvm.indexKV, key)
+	}
+	kvm.indexKV[key] = nil
+}
+
 func (kvm *KVManager) GetStoreKV(key string) ([]byte, bool) {
 	kvm.mu.RLock()
 	defer kvm.mu.RUnlock()
@@ -59,10 +90,29 @@ func (kvm *KVManager) GetIndexKV(key string) ([]byte, bool) {
 	return nil, false
 }
 
+// IndexKVWithPrefix returns the staged index entries under prefix, staged deletes included as nil.
+func (kvm *KVManager) IndexKVWithPrefix(prefix string) map[string][]byte {
+	kvm.mu.RLock()
+	defer kvm.mu.RUnlock()
+	out := make(map[string][]byte)
+	for key, val := range kvm.indexKV {
+		if strings.HasPrefix(key, prefix) {
+			out[key] = val
+		}
+	}
+	return out
+}
+
 func (kvm *KVManager) SetStateKV(key string, value string) {
 	logger.Debug("[KVManager] SetStateKV", "key", key)
 	kvm.mu.Lock()
 	defer kvm.mu.Unlock()
+	if kvm.undo != nil {
+		if _, seen := kvm.undo.stateKV[key]; !seen {
+			prev, ok := kvm.stateKV[key]
+			kvm.undo.stateKV[key] = stagedValue{value: []byte(prev), present: ok}
+		}
+	}
 	kvm.stateKV[key] = value
 }
 
@@ -73,6 +123,71 @@ func (kvm *KVManager) GetStateKV(key string) (string, bool) {
 	return val, ok
 }
 
+// Begin starts recording what staged writes replace, so Rollback can discard every write
+// made since. It is not nested: an operation that stages many writes calls Begin once and
+// ends with Commit or Rollback.
+func (kvm *KVManager) Begin() {
+	kvm.mu.Lock()
+	defer kvm.mu.Unlock()
+	kvm.undo = &undoLog{
+		storeKV: make(map[string]stagedValue),
+		indexKV: make(map[string]stagedValue),
+		stateKV: make(map[string]stagedValue),
+	}
+}
+
+// InTransaction reports whether a Begin is open, so an operation that can also run inside
+// a batch only starts one of its own when the batch has not.
+func (kvm *KVManager) InTransaction() bool {
+	kvm.mu.RLock()
+	defer kvm.mu.RUnlock()
+	return kvm.undo != nil
+}
+
+// Commit keeps the writes staged since Begin.
+func (kvm *KVManager) Commit() {
+	kvm.mu.Lock()
+	defer kvm.mu.Unlock()
+	kvm.undo = nil
+}
+
+// Rollback restores every key written since Begin to what it staged before.
+func (kvm *KVManager) Rollback() {
+	kvm.mu.Lock()
+	defer kvm.mu.Unlock()
+	if kvm.undo == nil {
+		return
+	}
+	restore(kvm.storeKV, kvm.undo.storeKV)
+	restore(kvm.indexKV, kvm.undo.indexKV)
+	for key, prev := range kvm.undo.stateKV {
+		if prev.present {
+			kvm.stateKV[key] = string(prev.value)
+		} else {
+			delete(kvm.stateKV, key)
+		}
+	}
+	kvm.undo = nil
+}
+
+func remember(undo map[string]stagedValue, staged map[string][]byte, key string) {
+	if _, seen := undo[key]; seen {
+		return
+	}
+	prev, ok := staged[key]
+	undo[key] = stagedValue{value: prev, present: ok}
+}
+
+func restore(staged map[string][]byte, undo map[string]stagedValue) {
+	for key, prev := range undo {
+		if prev.present {
+			staged[key] = prev.value
+		} else {
+			delete(staged, key)
+		}
+	}
+}
+
 func (kvm *KVManager) Flush() error {
 	kvm.mu.Lock()
 	defer kvm.mu.Unlock()
@@ -101,6 +216,13 @@ func (kvm *KVManager) Flush() error {
 		defer indexBatch.Close()
 
 		for key, value := range kvm.indexKV {
+			if value == nil {
+				logger.Debug("[KVManager] Deleting indexKV", "key", key)
+				if err := indexBatch.Delete([]byte(key), nil); err != nil {
+					return err
+				}
+				continue
+			}
 			logger.Debug("[KVManager] Writing indexKV", "key", key, "len", len(value))
 			if err := indexBatch.Set([]byte(key), value, nil); err != nil {
 				return err
@@ -125,4 +247,5 @@ func (kvm *KVManager) Reset() {
 	kvm.storeKV = make(map[string][]byte)
 	kvm.indexKV = make(map[string][]byte)
 	kvm.stateKV = make(map[string]string)
+	kvm.undo = nil
 }
@@ -0,0 +1,132 @@
+package apply
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"progressdb/pkg/config"
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+)
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_.\-]{1,36})`)
+
+// indexMentions writes mention indexes for the users referenced in the configured body field.
+// It must run on the plaintext message, before the message is handed to SetMessageData.
+// actor is the user performing the write; mentioned non-participants are only added when
+// the actor may manage participants, and never to a direct thread.
+func indexMentions(batchProcessor *BatchProcessor, threadKey, actor string, msg *models.Message) error {
+	field := config.MentionField()
+	if field == "" || msg.Body == nil {
+		return nil
+	}
+
+	autoParticipate := config.GetConfig().Mentions.AutoParticipate
+	if autoParticipate && authorizeThreadAction(batchProcessor, threadKey, actor, models.ThreadActionManageParticipants) != nil {
+		autoParticipate = false
+	}
+	if autoParticipate {
+		// a direct thread keeps the participant set it was created for
+		data, err := batchProcessor.Data.GetThreadMetaCopy(threadKey)
+		if err != nil {
+			return fmt.Errorf("failed to get thread for mentions: %w", err)
+		}
+		var thread models.Thread
+		if err := json.Unmarshal(data, &thread); err != nil {
+			return fmt.Errorf("unmarshal thread for mentions: %w", err)
+		}
+		autoParticipate = !thread.IsDirect()
+	}
+	for _, userID := range extractMentions(msg.Body, field) {
+		if userID == msg.Author {
+			continue
+		}
+
+		role, err := batchProcessor.Index.GetThreadUserRole(threadKey, userID)
+		if err != nil {
+			return fmt.Errorf("failed to resolve mentioned user role: %w", err)
+		}
+		if role == models.ThreadRoleNone {
+			if !autoParticipate {
+				logger.Debug("mention_skipped_non_participant", "thread", threadKey, "user", userID)
+				continue
+			}
+			batchProcessor.Index.SetThreadParticipantRole(userID, threadKey, models.ThreadRoleMember)
+		}
+
+		// keep read state when an edit repeats an existing mention
+		exists, err := batchProcessor.Index.HasUserMention(userID, msg.Key)
+		if err != nil {
+			return fmt.Errorf("failed to check mention: %w", err)
+		}
+		if exists {
+			continue
+		}
+
+		batchProcessor.Index.SetUserMention(userID, models.Mention{
+			MessageKey: msg.Key,
+			Thread:     threadKey,
+			Author:     msg.Author,
+			CreatedTS:  msg.CreatedTS,
+		})
+	}
+	return nil
+}
+
+// dropStaleMentions removes the mentions of users the previous body named and the
+// edited body no longer does.
+func dropStaleMentions(batchProcessor *BatchProcessor, previous, msg *models.Message) {
+	field := config.MentionField()
+	if field == "" || previous == nil {
+		return
+	}
+	current := make(map[string]struct{})
+	for _, userID := range extractMentions(msg.Body, field) {
+		current[userID] = struct{}{}
+	}
+	for _, userID := range extractMentions(previous.Body, field) {
+		if _, kept := current[userID]; !kept {
+			batchProcessor.Index.RemoveUserMention(userID, msg.Key)
+		}
+	}
+}
+
+// extractMentions returns the distinct user IDs mentioned in the string at the given body path.
+func extractMentions(body interface{}, field string) []string {
+	// normalise to generic JSON types so struct bodies resolve the same way
+	var node interface{}
+	if raw, err := json.Marshal(body); err != nil || json.Unmarshal(raw, &node) != nil {
+		return nil
+	}
+
+	segments := strings.Split(field, ".")
+	for _, segment := range segments[1:] {
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = obj[segment]
+	}
+
+	text, ok := node.(string)
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	var users []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		userID := strings.TrimRight(match[1], ".-")
+		if userID == "" {
+			continue
+		}
+		if _, dup := seen[userID]; dup {
+			continue
+		}
+		seen[userID] = struct{}{}
+		users = append(users, userID)
+	}
+	return users
+}
@@ -61,9 +61,9 @@ func ComputeThreadUpdate(ctx context.Context, op *types.QueueOp) ([]types.BatchE
 		return nil, fmt.Errorf("failed to unmarshal thread: %w", err)
 	}
 
-	// check if user owns the thread
-	if existingThread.Author != op.Extras.UserID {
-		return nil, fmt.Errorf("user not authorized to update this thread")
+	// thread roles are enforced at apply time
+	if existingThread.Deleted {
+		return nil, fmt.Errorf("thread is deleted")
 	}
 
 	// validate
@@ -103,6 +103,40 @@ func ComputeThreadDelete(ctx context.Context, op *types.QueueOp) ([]types.BatchE
 	return []types.BatchEntry{be}, nil
 }
 
+func ComputeThreadParticipant(ctx context.Context, op *types.QueueOp) ([]types.BatchEntry, error) {
+	// parse
+	update, ok := op.Payload.(*models.ThreadParticipantPartial)
+	if !ok {
+		return nil, fmt.Errorf("invalid payload type for thread participant")
+	}
+
+	// validate
+	if err := ValidateReadyForBatchEntry(update); err != nil {
+		return nil, fmt.Errorf("thread participant validation failed: %w", err)
+	}
+
+	// done
+	be := types.BatchEntry{QueueOp: op, Enq: op.EnqSeq}
+	return []types.BatchEntry{be}, nil
+}
+
+func ComputeThreadTransfer(ctx context.Context, op *types.QueueOp) ([]types.BatchEntry, error) {
+	// parse
+	transfer, ok := op.Payload.(*models.ThreadTransferPartial)
+	if !ok {
+		return nil, fmt.Errorf("invalid payload type for thread transfer")
+	}
+
+	// validate
+	if err := ValidateReadyForBatchEntry(transfer); err != nil {
+		return nil, fmt.Errorf("thread transfer validation failed: %w", err)
+	}
+
+	// done
+	be := types.BatchEntry{QueueOp: op, Enq: op.EnqSeq}
+	return []types.BatchEntry{be}, nil
+}
+
 // message op methods
 func ComputeMessageCreate(ctx context.Context, op *types.QueueOp) ([]types.BatchEntry, error) {
 	if op.Payload == nil {
@@ -124,6 +158,56 @@ func ComputeMessageCreate(ctx context.Context, op *types.QueueOp) ([]types.Batch
 	be := types.BatchEntry{QueueOp: op, Enq: op.EnqSeq}
 	return []types.BatchEntry{be}, nil
 }
+func ComputeMessageBatch(ctx context.Context, op *types.QueueOp) ([]types.BatchEntry, error) {
+	if op.Payload == nil {
+		return nil, fmt.Errorf("empty payload for message batch")
+	}
+
+	// parse
+	batch, ok := op.Payload.(*models.MessageBatchPartial)
+	if !ok {
+		return nil, fmt.Errorf("invalid payload type for message batch")
+	}
+
+	// validate
+	if err := ValidateReadyForBatchEntry(batch); err != nil {
+		return nil, fmt.Errorf("message batch validation failed: %w", err)
+	}
+
+	// done - one entry so the whole group applies together
+	be := types.BatchEntry{QueueOp: op, Enq: op.EnqSeq}
+	return []types.BatchEntry{be}, nil
+}
+func ComputeBatch(ctx context.Context, op *types.QueueOp) ([]types.BatchEntry, error) {
+	if op.Payload == nil {
+		return nil, fmt.Errorf("empty payload for batch")
+	}
+
+	// parse
+	batch, ok := op.Payload.(*models.BatchPartial)
+	if !ok {
+		return nil, fmt.Errorf("invalid payload type for batch")
+	}
+
+	// sync - threads created in the batch need their keys provisioned like single creates
+	for i, batchOp := range batch.Ops {
+		if batchOp.Thread == nil || types.HandlerID(batchOp.Handler) != types.HandlerThreadCreate {
+			continue
+		}
+		if _, err := ComputeThreadCreate(ctx, &types.QueueOp{Handler: types.HandlerThreadCreate, Payload: batchOp.Thread}); err != nil {
+			return nil, fmt.Errorf("ops[%d]: %w", i, err)
+		}
+	}
+
+	// validate
+	if err := ValidateReadyForBatchEntry(batch); err != nil {
+		return nil, fmt.Errorf("batch validation failed: %w", err)
+	}
+
+	// done - one entry so the operations apply in order
+	be := types.BatchEntry{QueueOp: op, Enq: op.EnqSeq}
+	return []types.BatchEntry{be}, nil
+}
 func ComputeMessageUpdate(ctx context.Context, op *types.QueueOp) ([]types.BatchEntry, error) {
 	if op.Payload == nil {
 		return nil, fmt.Errorf("empty payload for message update")
@@ -144,6 +228,22 @@ func ComputeMessageUpdate(ctx context.Context, op *types.QueueOp) ([]types.Batch
 	be := types.BatchEntry{QueueOp: op, Enq: op.EnqSeq}
 	return []types.BatchEntry{be}, nil
 }
+func ComputeMessageMove(ctx context.Context, op *types.QueueOp) ([]types.BatchEntry, error) {
+	// parse
+	move, ok := op.Payload.(*models.MessageMovePartial)
+	if !ok {
+		return nil, fmt.Errorf("invalid payload type for message move")
+	}
+
+	// validate
+	if err := ValidateReadyForBatchEntry(move); err != nil {
+		return nil, fmt.Errorf("message move validation failed: %w", err)
+	}
+
+	// done - one entry so the messages land in order
+	be := types.BatchEntry{QueueOp: op, Enq: op.EnqSeq}
+	return []types.BatchEntry{be}, nil
+}
 func ComputeMessageDelete(ctx context.Context, op *types.QueueOp) ([]types.BatchEntry, error) {
 	// parse
 	del, ok := op.Payload.(*models.MessageDeletePartial)
@@ -2,6 +2,7 @@ package compute
 
 import (
 	"fmt"
+	"progressdb/pkg/ingest/types"
 	"progressdb/pkg/models"
 	"strings"
 )
@@ -54,7 +55,7 @@ func ValidateReadyForBatchEntry(p interface{}) error {
 			if v.UpdatedTS == 0 {
 				errors = append(errors, "updated_ts: cannot be zero")
 			}
-			if v.Title == "" {
+			if v.Title == "" && v.Tags == nil {
 				errors = append(errors, "title: cannot be empty")
 			}
 
@@ -84,6 +85,102 @@ func ValidateReadyForBatchEntry(p interface{}) error {
 				errors = append(errors, "key: cannot be empty")
 			}
 		}
+	case *models.ThreadParticipantPartial:
+		if v == nil {
+			errors = append(errors, "ThreadParticipantPartial cannot be nil")
+		} else {
+			if v.Thread == "" {
+				errors = append(errors, "thread: cannot be empty")
+			}
+			if v.UserID == "" {
+				errors = append(errors, "user_id: cannot be empty")
+			}
+			if !v.Removed && v.Role == models.ThreadRoleNone {
+				errors = append(errors, "role: cannot be empty")
+			}
+		}
+	case *models.MessageBatchPartial:
+		if v == nil {
+			errors = append(errors, "MessageBatchPartial cannot be nil")
+		} else {
+			if v.Thread == "" {
+				errors = append(errors, "thread: cannot be empty")
+			}
+			if v.Author == "" {
+				errors = append(errors, "author: cannot be empty")
+			}
+			if len(v.Messages) == 0 {
+				errors = append(errors, "messages: cannot be empty")
+			}
+			for i := range v.Messages {
+				if err := ValidateReadyForBatchEntry(&v.Messages[i]); err != nil {
+					errors = append(errors, fmt.Sprintf("messages[%d]: %v", i, err))
+				}
+			}
+		}
+	case *models.MessageMovePartial:
+		if v == nil {
+			errors = append(errors, "MessageMovePartial cannot be nil")
+		} else {
+			if v.Thread == "" {
+				errors = append(errors, "thread: cannot be empty")
+			}
+			if v.Target == "" {
+				errors = append(errors, "target: cannot be empty")
+			} else if v.Target == v.Thread {
+				errors = append(errors, "target: must differ from thread")
+			}
+			if len(v.Keys) == 0 {
+				errors = append(errors, "keys: cannot be empty")
+			}
+			if len(v.TargetKeys) != len(v.Keys) {
+				errors = append(errors, "target_keys: must match keys")
+			}
+			if v.Bodies != nil && len(v.Bodies) != len(v.Keys) {
+				errors = append(errors, "bodies: must match keys")
+			}
+			if v.UpdatedTS == 0 {
+				errors = append(errors, "updated_ts: cannot be zero")
+			}
+		}
+	case *models.BatchPartial:
+		if v == nil {
+			errors = append(errors, "BatchPartial cannot be nil")
+		} else {
+			if v.Author == "" {
+				errors = append(errors, "author: cannot be empty")
+			}
+			if len(v.Ops) == 0 {
+				errors = append(errors, "ops: cannot be empty")
+			}
+			for i := range v.Ops {
+				op := &v.Ops[i]
+				if !batchOpPayloadMatches(op) {
+					errors = append(errors, fmt.Sprintf("ops[%d]: handler %q not supported in a batch", i, op.Handler))
+					continue
+				}
+				if err := ValidateReadyForBatchEntry(op.Payload()); err != nil {
+					errors = append(errors, fmt.Sprintf("ops[%d]: %v", i, err))
+				}
+			}
+		}
+	case *models.ThreadTransferPartial:
+		if v == nil {
+			errors = append(errors, "ThreadTransferPartial cannot be nil")
+		} else {
+			if v.Thread == "" {
+				errors = append(errors, "thread: cannot be empty")
+			}
+			if v.From == "" {
+				errors = append(errors, "from: cannot be empty")
+			}
+			if v.To == "" {
+				errors = append(errors, "to: cannot be empty")
+			}
+			if v.KeepPreviousOwner && v.PreviousOwnerRole == models.ThreadRoleNone {
+				errors = append(errors, "previous_owner_role: cannot be empty")
+			}
+		}
 	case *models.MessageDeletePartial:
 		if v == nil {
 			errors = append(errors, "MessageDeletePartial cannot be nil")
@@ -115,3 +212,21 @@ func ValidateReadyForBatchEntry(p interface{}) error {
 	}
 	return nil
 }
+
+// batchOpPayloadMatches reports whether a batch op names a handler allowed in a batch and
+// carries the payload that handler expects.
+func batchOpPayloadMatches(op *models.BatchOpPartial) bool {
+	switch types.HandlerID(op.Handler) {
+	case types.HandlerThreadCreate:
+		return op.Thread != nil
+	case types.HandlerThreadUpdate:
+		return op.ThreadUpdate != nil
+	case types.HandlerThreadParticipant:
+		return op.Participant != nil
+	case types.HandlerMessageCreate:
+		return op.Message != nil
+	case types.HandlerMessageMove:
+		return op.Move != nil
+	}
+	return false
+}
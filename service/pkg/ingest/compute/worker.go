@@ -81,16 +81,26 @@ func (cw *ComputeWorker) compute(op *types.QueueOp) ([]types.BatchEntry, error)
 	switch op.Handler {
 	case types.HandlerMessageCreate:
 		return ComputeMessageCreate(context.Background(), op)
+	case types.HandlerMessageBatch:
+		return ComputeMessageBatch(context.Background(), op)
+	case types.HandlerBatch:
+		return ComputeBatch(context.Background(), op)
 	case types.HandlerMessageUpdate:
 		return ComputeMessageUpdate(context.Background(), op)
 	case types.HandlerMessageDelete:
 		return ComputeMessageDelete(context.Background(), op)
+	case types.HandlerMessageMove:
+		return ComputeMessageMove(context.Background(), op)
 	case types.HandlerThreadCreate:
 		return ComputeThreadCreate(context.Background(), op)
 	case types.HandlerThreadUpdate:
 		return ComputeThreadUpdate(context.Background(), op)
 	case types.HandlerThreadDelete:
 		return ComputeThreadDelete(context.Background(), op)
+	case types.HandlerThreadParticipant:
+		return ComputeThreadParticipant(context.Background(), op)
+	case types.HandlerThreadTransfer:
+		return ComputeThreadTransfer(context.Background(), op)
 	default:
 		return nil, fmt.Errorf("unknown handler: %s", op.Handler)
 	}
@@ -0,0 +1,84 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"progressdb/pkg/ingest/types"
+)
+
+// RedactHook replaces matches of its patterns and words, or rejects the write
+// outright when configured to.
+type RedactHook struct {
+	patterns    []*regexp.Regexp
+	replacement string
+	reject      bool
+}
+
+// NewRedactHook compiles the regex patterns and whole-word, case-insensitive word list.
+func NewRedactHook(patterns, words []string, replacement string, reject bool) (*RedactHook, error) {
+	h := &RedactHook{replacement: replacement, reject: reject}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		h.patterns = append(h.patterns, re)
+	}
+	if len(words) > 0 {
+		quoted := make([]string, 0, len(words))
+		for _, w := range words {
+			if w = strings.TrimSpace(w); w != "" {
+				quoted = append(quoted, regexp.QuoteMeta(w))
+			}
+		}
+		if len(quoted) > 0 {
+			h.patterns = append(h.patterns, regexp.MustCompile(`(?i)\b(?:`+strings.Join(quoted, "|")+`)\b`))
+		}
+	}
+	return h, nil
+}
+
+func (h *RedactHook) Name() string { return "redact" }
+
+func (h *RedactHook) Run(ctx context.Context, op *types.QueueOp) error {
+	if h.reject {
+		for _, text := range collectText(op) {
+			for _, re := range h.patterns {
+				if re.MatchString(text) {
+					return &RejectError{Hook: h.Name(), Reason: "content contains blocked terms"}
+				}
+			}
+		}
+		return nil
+	}
+	rewriteText(op, func(s string) string {
+		for _, re := range h.patterns {
+			s = re.ReplaceAllLiteralString(s, h.replacement)
+		}
+		return s
+	})
+	return nil
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// MaxLinksHook rejects writes whose content contains more than Limit links.
+type MaxLinksHook struct {
+	Limit int
+}
+
+func (h *MaxLinksHook) Name() string { return "max_links" }
+
+func (h *MaxLinksHook) Run(ctx context.Context, op *types.QueueOp) error {
+	count := 0
+	for _, text := range collectText(op) {
+		count += len(linkPattern.FindAllStringIndex(text, -1))
+	}
+	if count > h.Limit {
+		return &RejectError{Hook: h.Name(), Reason: fmt.Sprintf("too many links: %d (maximum %d)", count, h.Limit)}
+	}
+	return nil
+}
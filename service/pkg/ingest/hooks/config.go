@@ -0,0 +1,68 @@
+package hooks
+
+import (
+	"fmt"
+	"time"
+
+	"progressdb/pkg/config"
+	"progressdb/pkg/ingest/types"
+	"progressdb/pkg/state/logger"
+)
+
+const (
+	defaultRedactReplacement = "***"
+	defaultHTTPTimeout       = 2 * time.Second
+)
+
+var defaultHandlers = []string{string(types.HandlerMessageCreate), string(types.HandlerMessageUpdate)}
+
+// Configure registers the built-in and HTTP hooks declared in the content policy config.
+func Configure(cfg config.ContentPolicyConfig) error {
+	for i, r := range cfg.Redact {
+		replacement := r.Replacement
+		if replacement == "" {
+			replacement = defaultRedactReplacement
+		}
+		hook, err := NewRedactHook(r.Patterns, r.Words, replacement, r.Reject)
+		if err != nil {
+			return fmt.Errorf("redact[%d]: %w", i, err)
+		}
+		if err := registerFor(r.Handlers, hook); err != nil {
+			return fmt.Errorf("redact[%d]: %w", i, err)
+		}
+	}
+	for i, l := range cfg.MaxLinks {
+		if err := registerFor(l.Handlers, &MaxLinksHook{Limit: l.Limit}); err != nil {
+			return fmt.Errorf("max_links[%d]: %w", i, err)
+		}
+	}
+	for i, h := range cfg.HTTP {
+		timeout := h.Timeout.Duration()
+		if timeout <= 0 {
+			timeout = defaultHTTPTimeout
+		}
+		if err := registerFor(h.Handlers, NewHTTPHook(h.URL, timeout, h.FailOpen)); err != nil {
+			return fmt.Errorf("http[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func registerFor(handlers []string, hook Hook) error {
+	if len(handlers) == 0 {
+		handlers = defaultHandlers
+	}
+	ids := make([]types.HandlerID, 0, len(handlers))
+	for _, h := range handlers {
+		id := types.HandlerID(h)
+		if !contentHandlers[id] {
+			return fmt.Errorf("handler %q does not carry content", h)
+		}
+		ids = append(ids, id)
+	}
+	for _, id := range ids {
+		Register(id, hook)
+		logger.Info("content_policy_registered", "hook", hook.Name(), "handler", id)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package hooks
+
+import (
+	"encoding/json"
+
+	"progressdb/pkg/ingest/types"
+	"progressdb/pkg/models"
+)
+
+// contentHandlers are the handlers whose payloads carry user content.
+var contentHandlers = map[types.HandlerID]bool{
+	types.HandlerMessageCreate: true,
+	types.HandlerMessageUpdate: true,
+	types.HandlerThreadCreate:  true,
+	types.HandlerThreadUpdate:  true,
+}
+
+// rewriteText replaces every string in the op's user content with fn(s).
+func rewriteText(op *types.QueueOp, fn func(string) string) {
+	switch p := op.Payload.(type) {
+	case *models.Message:
+		p.Body = rewriteStrings(normalize(p.Body), fn)
+	case *models.MessageUpdatePartial:
+		p.Body = rewriteStrings(normalize(p.Body), fn)
+	case *models.Thread:
+		p.Title = fn(p.Title)
+	case *models.ThreadUpdatePartial:
+		p.Title = fn(p.Title)
+	}
+}
+
+// collectText returns every string in the op's user content.
+func collectText(op *types.QueueOp) []string {
+	var out []string
+	collect := func(s string) string {
+		out = append(out, s)
+		return s
+	}
+	switch p := op.Payload.(type) {
+	case *models.Message:
+		rewriteStrings(normalize(p.Body), collect)
+	case *models.MessageUpdatePartial:
+		rewriteStrings(normalize(p.Body), collect)
+	case *models.Thread:
+		collect(p.Title)
+	case *models.ThreadUpdatePartial:
+		collect(p.Title)
+	}
+	return out
+}
+
+// normalize converts a body to generic JSON types so struct bodies walk the same way.
+func normalize(body interface{}) interface{} {
+	switch body.(type) {
+	case nil, string, map[string]interface{}, []interface{}:
+		return body
+	}
+	var node interface{}
+	if raw, err := json.Marshal(body); err != nil || json.Unmarshal(raw, &node) != nil {
+		return body
+	}
+	return node
+}
+
+func rewriteStrings(node interface{}, fn func(string) string) interface{} {
+	switch v := node.(type) {
+	case string:
+		return fn(v)
+	case map[string]interface{}:
+		for k, child := range v {
+			v[k] = rewriteStrings(child, fn)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = rewriteStrings(child, fn)
+		}
+		return v
+	default:
+		return node
+	}
+}
@@ -0,0 +1,91 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"progressdb/pkg/ingest/types"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/state/telemetry"
+)
+
+// Hook inspects an operation before it is enqueued. It may rewrite op.Payload in
+// place to redact or transform content, or return a *RejectError to refuse the write.
+type Hook interface {
+	Name() string
+	Run(ctx context.Context, op *types.QueueOp) error
+}
+
+// RejectError is returned when a hook refuses a write.
+type RejectError struct {
+	Hook   string
+	Reason string
+}
+
+func (e *RejectError) Error() string {
+	return fmt.Sprintf("rejected by %s: %s", e.Hook, e.Reason)
+}
+
+// ErrPolicyUnavailable is returned when a fail-closed hook cannot reach a decision.
+var ErrPolicyUnavailable = errors.New("content policy unavailable")
+
+// Registry holds the hooks registered for each handler, run in registration order.
+type Registry struct {
+	mu    sync.RWMutex
+	hooks map[types.HandlerID][]Hook
+}
+
+func NewRegistry() *Registry {
+	return &Registry{hooks: make(map[types.HandlerID][]Hook)}
+}
+
+var GlobalRegistry = NewRegistry()
+
+func (r *Registry) Register(handler types.HandlerID, hook Hook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks[handler] = append(r.hooks[handler], hook)
+}
+
+// Reset removes every registered hook.
+func (r *Registry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = make(map[types.HandlerID][]Hook)
+}
+
+// Run applies the hooks for op.Handler, stopping at the first error.
+func (r *Registry) Run(ctx context.Context, op *types.QueueOp) error {
+	r.mu.RLock()
+	hooks := r.hooks[op.Handler]
+	r.mu.RUnlock()
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	tr := telemetry.Track("ingest.content_policy")
+	defer tr.Finish()
+
+	for _, hook := range hooks {
+		if err := hook.Run(ctx, op); err != nil {
+			var rejected *RejectError
+			if errors.As(err, &rejected) {
+				logger.Info("content_policy_rejected", "hook", hook.Name(), "handler", op.Handler, "user", op.Extras.UserID, "reason", rejected.Reason)
+			} else {
+				logger.Error("content_policy_failed", "hook", hook.Name(), "handler", op.Handler, "error", err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func Register(handler types.HandlerID, hook Hook) {
+	GlobalRegistry.Register(handler, hook)
+}
+
+func Run(ctx context.Context, op *types.QueueOp) error {
+	return GlobalRegistry.Run(ctx, op)
+}
@@ -0,0 +1,143 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"progressdb/pkg/ingest/types"
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+)
+
+const maxHookResponseSize = 1 << 20
+
+// HTTPHook delegates the decision to an external service.
+//
+// The service receives {"handler", "user_id", "payload"} and answers with
+// {"action": "allow" | "reject" | "modify", "reason", "body", "title"}. A modify
+// answer replaces the message body and/or thread title with the returned values.
+type HTTPHook struct {
+	URL      string
+	Timeout  time.Duration
+	FailOpen bool
+	client   *http.Client
+}
+
+type httpHookRequest struct {
+	Handler types.HandlerID `json:"handler"`
+	UserID  string          `json:"user_id,omitempty"`
+	Payload interface{}     `json:"payload"`
+}
+
+type httpHookResponse struct {
+	Action string          `json:"action"`
+	Reason string          `json:"reason,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Title  *string         `json:"title,omitempty"`
+}
+
+func NewHTTPHook(url string, timeout time.Duration, failOpen bool) *HTTPHook {
+	return &HTTPHook{
+		URL:      url,
+		Timeout:  timeout,
+		FailOpen: failOpen,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (h *HTTPHook) Name() string { return "http" }
+
+func (h *HTTPHook) Run(ctx context.Context, op *types.QueueOp) error {
+	decision, err := h.call(ctx, op)
+	if err != nil {
+		if h.FailOpen {
+			logger.Warn("content_policy_http_fail_open", "url", h.URL, "handler", op.Handler, "error", err)
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrPolicyUnavailable, err)
+	}
+
+	switch decision.Action {
+	case "", "allow":
+		return nil
+	case "reject":
+		reason := decision.Reason
+		if reason == "" {
+			reason = "content rejected"
+		}
+		return &RejectError{Hook: h.Name(), Reason: reason}
+	case "modify":
+		return h.apply(op, decision)
+	default:
+		err := fmt.Errorf("unknown action %q", decision.Action)
+		if h.FailOpen {
+			logger.Warn("content_policy_http_fail_open", "url", h.URL, "handler", op.Handler, "error", err)
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrPolicyUnavailable, err)
+	}
+}
+
+func (h *HTTPHook) call(ctx context.Context, op *types.QueueOp) (*httpHookResponse, error) {
+	reqBody, err := json.Marshal(httpHookRequest{Handler: op.Handler, UserID: op.Extras.UserID, Payload: op.Payload})
+	if err != nil {
+		return nil, fmt.Errorf("marshal hook request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("hook returned status %d", resp.StatusCode)
+	}
+
+	var decision httpHookResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxHookResponseSize)).Decode(&decision); err != nil {
+		return nil, fmt.Errorf("decode hook response: %w", err)
+	}
+	return &decision, nil
+}
+
+func (h *HTTPHook) apply(op *types.QueueOp, decision *httpHookResponse) error {
+	var body interface{}
+	if len(decision.Body) > 0 {
+		if err := json.Unmarshal(decision.Body, &body); err != nil || body == nil {
+			return &RejectError{Hook: h.Name(), Reason: "hook returned an invalid body"}
+		}
+	}
+
+	switch p := op.Payload.(type) {
+	case *models.Message:
+		if body != nil {
+			p.Body = body
+		}
+	case *models.MessageUpdatePartial:
+		if body != nil {
+			p.Body = body
+		}
+	case *models.Thread:
+		if decision.Title != nil && *decision.Title != "" {
+			p.Title = *decision.Title
+		}
+	case *models.ThreadUpdatePartial:
+		if decision.Title != nil && *decision.Title != "" {
+			p.Title = *decision.Title
+		}
+	}
+	return nil
+}
@@ -1,10 +1,12 @@
 package queue
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"sync/atomic"
 
+	"progressdb/pkg/ingest/hooks"
 	"progressdb/pkg/ingest/types"
 	"progressdb/pkg/state/logger"
 )
@@ -22,6 +24,22 @@ var (
 )
 
 func (q *IngestQueue) Enqueue(op *types.QueueOp) error {
+	// content policies run before the op reaches the WAL
+	if err := hooks.Run(context.Background(), op); err != nil {
+		return err
+	}
+	if err := validateSchema(op); err != nil {
+		return err
+	}
+	return q.enqueue(op)
+}
+
+// EnqueueWithoutHooks enqueues an op whose content policies already ran, such as a
+// scheduled message screened when it was scheduled. Its schema is still checked.
+func (q *IngestQueue) EnqueueWithoutHooks(op *types.QueueOp) error {
+	if err := validateSchema(op); err != nil {
+		return err
+	}
 	return q.enqueue(op)
 }
 
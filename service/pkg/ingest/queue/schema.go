@@ -0,0 +1,44 @@
+package queue
+
+import (
+	"progressdb/pkg/ingest/tracking"
+	"progressdb/pkg/ingest/types"
+	"progressdb/pkg/models"
+	"progressdb/pkg/store/features/schemas"
+	"progressdb/pkg/store/keys"
+)
+
+// validateSchema checks a message body against its thread's schemas once hooks have
+// rewritten it, so the body that is stored is the one that was checked. Batches check
+// their messages per op when they are built.
+func validateSchema(op *types.QueueOp) error {
+	var threadKey string
+	var body interface{}
+	switch p := op.Payload.(type) {
+	case *models.Message:
+		threadKey, body = p.Thread, p.Body
+	case *models.MessageUpdatePartial:
+		threadKey, body = p.Thread, p.Body
+	default:
+		return nil
+	}
+
+	hasTagSchemas, err := schemas.HasTagSchemas(keys.NamespaceOf(threadKey))
+	if err != nil {
+		return err
+	}
+	var tags []string
+	if hasTagSchemas {
+		if tags, err = tracking.GlobalKeyMapper.ThreadTags(threadKey); err != nil {
+			return err
+		}
+	}
+	fieldErrs, err := schemas.ValidateBody(keys.NamespaceOf(threadKey), body, tags)
+	if err != nil {
+		return err
+	}
+	if len(fieldErrs) > 0 {
+		return &schemas.ValidationError{Fields: fieldErrs}
+	}
+	return nil
+}
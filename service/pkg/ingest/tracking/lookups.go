@@ -0,0 +1,142 @@
+package tracking
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"progressdb/pkg/models"
+	"progressdb/pkg/store/db/indexdb"
+	"progressdb/pkg/store/db/storedb"
+	thread_store "progressdb/pkg/store/features/threads"
+	"progressdb/pkg/store/keys"
+)
+
+// GetOrCreateDirectThread returns the live direct-message thread of a namespace for a
+// canonical participant set, calling create with candidateKey only when none exists.
+func (km *KeyMapper) GetOrCreateDirectThread(namespace string, participants []string, candidateKey string, create func() error) (string, bool, error) {
+	return km.GetOrReserve(keys.GenDirectThreadKey(namespace, participants), candidateKey, func() (string, error) {
+		existing, err := indexdb.GetDirectThread(namespace, participants)
+		if err != nil {
+			return "", fmt.Errorf("failed to look up direct thread: %w", err)
+		}
+		return liveThread(existing), nil
+	}, create)
+}
+
+// ReserveThreadExternalID reserves an author's external id in a namespace for
+// candidateKey, or returns the live thread already holding it.
+func (km *KeyMapper) ReserveThreadExternalID(namespace, author, externalID, candidateKey string, create func() error) (string, bool, error) {
+	return km.GetOrReserve(keys.GenThreadExternalIDKey(namespace, author, externalID), candidateKey, func() (string, error) {
+		return km.lookupThreadExternalID(namespace, author, externalID)
+	}, create)
+}
+
+// ReserveMessageExternalID reserves a thread's message external id for candidateKey, or
+// returns the live message already holding it.
+func (km *KeyMapper) ReserveMessageExternalID(threadKey, externalID, candidateKey string, create func() error) (string, bool, error) {
+	return km.GetOrReserve(keys.GenMessageExternalIDKey(threadKey, externalID), candidateKey, func() (string, error) {
+		return km.lookupMessageExternalID(threadKey, externalID)
+	}, create)
+}
+
+// ReserveMessageExternalIDs reserves a thread's message external ids for the matching
+// candidate keys behind one create. Returns the external ids already taken, if any.
+func (km *KeyMapper) ReserveMessageExternalIDs(threadKey string, externalIDs, candidateKeys []string, create func() error) (map[string]string, error) {
+	lookupKeys := make([]string, len(externalIDs))
+	byLookupKey := make(map[string]string, len(externalIDs))
+	for i, externalID := range externalIDs {
+		lookupKeys[i] = keys.GenMessageExternalIDKey(threadKey, externalID)
+		byLookupKey[lookupKeys[i]] = externalID
+	}
+
+	taken, err := km.ReserveAll(lookupKeys, candidateKeys, func(lookupKey string) (string, error) {
+		return km.lookupMessageExternalID(threadKey, byLookupKey[lookupKey])
+	}, create)
+	if err != nil || len(taken) == 0 {
+		return nil, err
+	}
+	out := make(map[string]string, len(taken))
+	for lookupKey, existing := range taken {
+		out[byLookupKey[lookupKey]] = existing
+	}
+	return out, nil
+}
+
+// ResolveThreadExternalID returns the thread key for an author's external id in a
+// namespace, or "".
+func (km *KeyMapper) ResolveThreadExternalID(namespace, author, externalID string) (string, error) {
+	return km.LookupReserved(keys.GenThreadExternalIDKey(namespace, author, externalID), func() (string, error) {
+		return km.lookupThreadExternalID(namespace, author, externalID)
+	})
+}
+
+// ResolveMessageExternalID returns the message key for a thread's external id, or "".
+func (km *KeyMapper) ResolveMessageExternalID(threadKey, externalID string) (string, error) {
+	return km.LookupReserved(keys.GenMessageExternalIDKey(threadKey, externalID), func() (string, error) {
+		return km.lookupMessageExternalID(threadKey, externalID)
+	})
+}
+
+func (km *KeyMapper) lookupThreadExternalID(namespace, author, externalID string) (string, error) {
+	existing, err := indexdb.GetThreadByExternalID(namespace, author, externalID)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up thread external id: %w", err)
+	}
+	return liveThread(existing), nil
+}
+
+func (km *KeyMapper) lookupMessageExternalID(threadKey, externalID string) (string, error) {
+	existing, err := indexdb.GetMessageByExternalID(threadKey, externalID)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up message external id: %w", err)
+	}
+	return liveMessage(existing), nil
+}
+
+// liveThread returns threadKey if it names a stored thread that is not deleted.
+func liveThread(threadKey string) string {
+	if threadKey == "" {
+		return ""
+	}
+	data, err := thread_store.GetThreadData(threadKey)
+	if err != nil {
+		return ""
+	}
+	var thread models.Thread
+	if err := json.Unmarshal([]byte(data), &thread); err != nil || thread.Deleted {
+		return ""
+	}
+	return threadKey
+}
+
+// liveMessage returns messageKey if it names a stored message that is not deleted.
+func liveMessage(messageKey string) string {
+	if messageKey == "" {
+		return ""
+	}
+	if _, err := storedb.GetKey(messageKey); err != nil {
+		return ""
+	}
+	if _, err := indexdb.GetKey(keys.GenSoftDeleteMarkerKey(messageKey)); err == nil {
+		return ""
+	}
+	return messageKey
+}
+
+// ThreadTags returns the tags of the thread threadKey names, waiting for it if it is
+// still in flight. Unknown threads have no tags; they are rejected later in the pipeline.
+func (km *KeyMapper) ThreadTags(threadKey string) ([]string, error) {
+	resolvedKey, err := km.ResolveKeyOrWait(threadKey)
+	if err != nil {
+		return nil, nil
+	}
+	data, err := thread_store.GetThreadData(resolvedKey)
+	if err != nil {
+		return nil, nil
+	}
+	var thread models.Thread
+	if err := json.Unmarshal([]byte(data), &thread); err != nil {
+		return nil, err
+	}
+	return thread.Tags, nil
+}
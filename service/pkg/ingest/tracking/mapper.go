@@ -16,12 +16,16 @@ type KeyMapper struct {
 	tracker    *InflightTracker
 	batchCache map[string]string // provisionalKey -> finalKey for current batch
 	mu         sync.RWMutex
+
+	reserved   map[string]string // lookupKey -> provisional key whose create is awaiting apply
+	reservedMu sync.Mutex
 }
 
 func NewKeyMapper(tracker *InflightTracker) *KeyMapper {
 	return &KeyMapper{
 		tracker:    tracker,
 		batchCache: make(map[string]string),
+		reserved:   make(map[string]string),
 	}
 }
 
@@ -72,6 +76,80 @@ func (km *KeyMapper) ResolveKeyOrWait(key string) (string, error) {
 	return finalKey, nil
 }
 
+// GetOrReserve returns the key indexed under lookupKey, calling create only when lookup
+// finds none. Calls are serialised so concurrent requests for the same lookup key share
+// candidateKey until its create has been applied and indexed.
+// Returns: (key, created, error)
+func (km *KeyMapper) GetOrReserve(lookupKey, candidateKey string, lookup func() (string, error), create func() error) (string, bool, error) {
+	km.reservedMu.Lock()
+	defer km.reservedMu.Unlock()
+
+	existing, err := km.lookupReserved(lookupKey, lookup)
+	if err != nil || existing != "" {
+		return existing, false, err
+	}
+
+	// hold the reservation until the create leaves the inflight tracker
+	if err := create(); err != nil {
+		return "", false, err
+	}
+	km.reserved[lookupKey] = candidateKey
+	return candidateKey, true, nil
+}
+
+// ReserveAll reserves several lookup keys for their candidate keys behind a single create.
+// Nothing is created when any lookup key is taken; the taken ones are returned instead.
+// Returns: (lookupKey -> existing key, error)
+func (km *KeyMapper) ReserveAll(lookupKeys, candidateKeys []string, lookup func(lookupKey string) (string, error), create func() error) (map[string]string, error) {
+	km.reservedMu.Lock()
+	defer km.reservedMu.Unlock()
+
+	taken := make(map[string]string)
+	for _, lookupKey := range lookupKeys {
+		existing, err := km.lookupReserved(lookupKey, func() (string, error) { return lookup(lookupKey) })
+		if err != nil {
+			return nil, err
+		}
+		if existing != "" {
+			taken[lookupKey] = existing
+		}
+	}
+	if len(taken) > 0 {
+		return taken, nil
+	}
+
+	if err := create(); err != nil {
+		return nil, err
+	}
+	for i, lookupKey := range lookupKeys {
+		km.reserved[lookupKey] = candidateKeys[i]
+	}
+	return nil, nil
+}
+
+// LookupReserved resolves lookupKey to a create still in flight or, failing that, through lookup.
+func (km *KeyMapper) LookupReserved(lookupKey string, lookup func() (string, error)) (string, error) {
+	km.reservedMu.Lock()
+	defer km.reservedMu.Unlock()
+
+	return km.lookupReserved(lookupKey, lookup)
+}
+
+func (km *KeyMapper) lookupReserved(lookupKey string, lookup func() (string, error)) (string, error) {
+	// 1. A create for this lookup key is still in flight? Share its key.
+	if pending, ok := km.reserved[lookupKey]; ok {
+		if km.tracker.IsInflight(pending) {
+			logger.Debug("lookup_reserved", "source", "inflight", "lookup", lookupKey, "key", pending)
+			return pending, nil
+		}
+		// applied (or dropped); the index is authoritative from here on
+		delete(km.reserved, lookupKey)
+	}
+
+	// 2. Fall back to the index
+	return lookup()
+}
+
 // PrepopulateBatchCache populates the batch cache with provisional->final mappings
 // Called by apply workers when processing a batch
 func (km *KeyMapper) PrepopulateBatchCache(mappings map[string]string) {
@@ -8,12 +8,17 @@ import (
 type HandlerID string
 
 const (
-	HandlerMessageCreate HandlerID = "message.create"
-	HandlerMessageUpdate HandlerID = "message.update"
-	HandlerMessageDelete HandlerID = "message.delete"
-	HandlerThreadCreate  HandlerID = "thread.create"
-	HandlerThreadUpdate  HandlerID = "thread.update"
-	HandlerThreadDelete  HandlerID = "thread.delete"
+	HandlerMessageCreate     HandlerID = "message.create"
+	HandlerMessageUpdate     HandlerID = "message.update"
+	HandlerMessageDelete     HandlerID = "message.delete"
+	HandlerMessageBatch      HandlerID = "message.batch"
+	HandlerMessageMove       HandlerID = "message.move"
+	HandlerThreadCreate      HandlerID = "thread.create"
+	HandlerThreadUpdate      HandlerID = "thread.update"
+	HandlerThreadDelete      HandlerID = "thread.delete"
+	HandlerThreadParticipant HandlerID = "thread.participant"
+	HandlerThreadTransfer    HandlerID = "thread.transfer"
+	HandlerBatch             HandlerID = "batch"
 )
 
 type RequestMetadata struct {
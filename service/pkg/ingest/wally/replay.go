@@ -280,6 +280,27 @@ func (r *WALReplayer) convertPayloadToType(op *types.QueueOp) error {
 		}
 		op.Payload = &msg
 
+	case types.HandlerMessageBatch:
+		var batch models.MessageBatchPartial
+		if err := json.Unmarshal(payloadJSON, &batch); err != nil {
+			return fmt.Errorf("failed to unmarshal payload as MessageBatchPartial: %w", err)
+		}
+		op.Payload = &batch
+
+	case types.HandlerMessageMove:
+		var move models.MessageMovePartial
+		if err := json.Unmarshal(payloadJSON, &move); err != nil {
+			return fmt.Errorf("failed to unmarshal payload as MessageMovePartial: %w", err)
+		}
+		op.Payload = &move
+
+	case types.HandlerBatch:
+		var batch models.BatchPartial
+		if err := json.Unmarshal(payloadJSON, &batch); err != nil {
+			return fmt.Errorf("failed to unmarshal payload as BatchPartial: %w", err)
+		}
+		op.Payload = &batch
+
 	case types.HandlerMessageDelete:
 		var msg models.MessageDeletePartial
 		if err := json.Unmarshal(payloadJSON, &msg); err != nil {
@@ -301,6 +322,20 @@ func (r *WALReplayer) convertPayloadToType(op *types.QueueOp) error {
 		}
 		op.Payload = &thread
 
+	case types.HandlerThreadParticipant:
+		var update models.ThreadParticipantPartial
+		if err := json.Unmarshal(payloadJSON, &update); err != nil {
+			return fmt.Errorf("failed to unmarshal payload as ThreadParticipantPartial: %w", err)
+		}
+		op.Payload = &update
+
+	case types.HandlerThreadTransfer:
+		var transfer models.ThreadTransferPartial
+		if err := json.Unmarshal(payloadJSON, &transfer); err != nil {
+			return fmt.Errorf("failed to unmarshal payload as ThreadTransferPartial: %w", err)
+		}
+		op.Payload = &transfer
+
 	default:
 		return fmt.Errorf("unknown handler type: %s", op.Handler)
 	}
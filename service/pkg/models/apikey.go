@@ -0,0 +1,131 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+type APIKeyRole string
+
+const (
+	APIKeyRoleAdmin    APIKeyRole = "admin"
+	APIKeyRoleBackend  APIKeyRole = "backend"
+	APIKeyRoleFrontend APIKeyRole = "frontend"
+)
+
+// API key permissions. A key without any may do everything its role allows.
+const (
+	APIKeyPermRead  = "read"  // GET and HEAD requests
+	APIKeyPermWrite = "write" // every other request except signing
+	APIKeyPermSign  = "sign"  // POST /backend/v1/sign
+)
+
+// APIKeyGrant narrows what a key may do within its role. Empty fields do not restrict.
+type APIKeyGrant struct {
+	Permissions []string `json:"permissions,omitempty"`
+	Paths       []string `json:"paths,omitempty"`       // allowed path prefixes, matched on segment boundaries
+	ThreadTags  []string `json:"thread_tags,omitempty"` // only threads carrying one of these tags
+	UserPrefix  string   `json:"user_prefix,omitempty"` // only users whose id starts with this
+}
+
+// APIKey is a key created at runtime. Only a salted hash of its secret is stored.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Namespace  string     `json:"namespace,omitempty"` // "" for the default namespace
+	Role       APIKeyRole `json:"role"`
+	Label      string     `json:"label,omitempty"`
+	Salt       string     `json:"salt,omitempty"`
+	Hash       string     `json:"hash,omitempty"`
+	Status     string     `json:"status,omitempty"` // derived for listings: active, expired or revoked
+	CreatedTS  int64      `json:"created_ts"`
+	ExpiresTS  int64      `json:"expires_ts,omitempty"` // 0 never expires
+	RevokedTS  int64      `json:"revoked_ts,omitempty"`
+	LastUsedTS int64      `json:"last_used_ts,omitempty"`
+
+	APIKeyGrant
+}
+
+// Usable reports whether the key may authenticate at now (unix ns).
+func (k *APIKey) Usable(now int64) bool {
+	return k.RevokedTS == 0 && (k.ExpiresTS == 0 || now < k.ExpiresTS)
+}
+
+func ParseAPIKeyRole(s string) (APIKeyRole, bool) {
+	switch APIKeyRole(s) {
+	case APIKeyRoleAdmin, APIKeyRoleBackend, APIKeyRoleFrontend:
+		return APIKeyRole(s), true
+	}
+	return "", false
+}
+
+// Validate checks permission names and path shapes.
+func (g *APIKeyGrant) Validate() error {
+	for _, p := range g.Permissions {
+		switch p {
+		case APIKeyPermRead, APIKeyPermWrite, APIKeyPermSign:
+		default:
+			return fmt.Errorf("permissions: %q is not one of read, write, sign", p)
+		}
+	}
+	for _, p := range g.Paths {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("paths: %q must start with /", p)
+		}
+	}
+	if len(g.UserPrefix) > 36 {
+		return fmt.Errorf("user_prefix: at most 36 characters allowed")
+	}
+	return nil
+}
+
+// Restricted reports whether the grant narrows the key's role at all.
+func (g *APIKeyGrant) Restricted() bool {
+	return len(g.Permissions) > 0 || len(g.Paths) > 0 || len(g.ThreadTags) > 0 || g.UserPrefix != ""
+}
+
+// Allows reports whether the grant includes permission.
+func (g *APIKeyGrant) Allows(permission string) bool {
+	if len(g.Permissions) == 0 {
+		return true
+	}
+	for _, p := range g.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsPath reports whether path falls under one of the allowed prefixes.
+func (g *APIKeyGrant) AllowsPath(path string) bool {
+	if len(g.Paths) == 0 {
+		return true
+	}
+	for _, p := range g.Paths {
+		p = strings.TrimSuffix(p, "/")
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsUser reports whether userID matches the user prefix.
+func (g *APIKeyGrant) AllowsUser(userID string) bool {
+	return strings.HasPrefix(userID, g.UserPrefix)
+}
+
+// AllowsTags reports whether a thread with tags is within the tag restriction.
+func (g *APIKeyGrant) AllowsTags(tags []string) bool {
+	if len(g.ThreadTags) == 0 {
+		return true
+	}
+	for _, want := range g.ThreadTags {
+		for _, tag := range tags {
+			if tag == want {
+				return true
+			}
+		}
+	}
+	return false
+}
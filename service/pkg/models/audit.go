@@ -0,0 +1,47 @@
+package models
+
+// Audit actions recorded outside the ingest pipeline. Applied writes use their
+// handler id (thread.create, message.update, ...) as the action.
+const (
+	AuditActionAdminRequest     = "admin.request"
+	AuditActionAuthFailure      = "auth.failure"
+	AuditActionAPIKeyCreate     = "api_key.create"
+	AuditActionAPIKeyUpdate     = "api_key.update"
+	AuditActionAPIKeyRevoke     = "api_key.revoke"
+	AuditActionSigningKeyRotate = "signing_key.rotate"
+	AuditActionSigningKeyState  = "signing_key.state"
+	AuditActionPurgeThread      = "retention.purge_thread"
+	AuditActionPurgeMessage     = "retention.purge_message"
+)
+
+// AuditRecord is one append-only entry in the audit trail. Hash covers every other
+// field, including PrevHash, so editing or removing a record breaks the chain.
+type AuditRecord struct {
+	Seq       uint64            `json:"seq"`
+	TS        int64             `json:"ts"`
+	Action    string            `json:"action"`
+	Outcome   string            `json:"outcome"` // ok or failed
+	Role      string            `json:"role,omitempty"`
+	Actor     string            `json:"actor,omitempty"` // api key id or fingerprint, or client certificate name
+	Namespace string            `json:"namespace,omitempty"`
+	UserID    string            `json:"user_id,omitempty"`
+	Thread    string            `json:"thread,omitempty"`
+	Target    string            `json:"target,omitempty"` // message key, api key id, request path, ...
+	ReqID     string            `json:"reqid,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	Detail    map[string]string `json:"detail,omitempty"`
+	PrevHash  string            `json:"prev_hash"`
+	Hash      string            `json:"hash"`
+}
+
+// AuditFilter selects audit records. Empty fields do not filter.
+type AuditFilter struct {
+	Namespace string // records of one namespace; "" is the default namespace
+	UserID    string
+	Thread    string
+	Action    string // exact action, or a family such as "message." when it ends in a dot
+	From      int64  // inclusive, unix nanoseconds
+	To        int64  // exclusive, unix nanoseconds
+	Before    uint64 // only records with a lower sequence; used to page backwards
+	Limit     int
+}
@@ -0,0 +1,9 @@
+package models
+
+type Mention struct {
+	MessageKey string `json:"message_key"`
+	Thread     string `json:"thread"`
+	Author     string `json:"author"`
+	CreatedTS  int64  `json:"created_ts"`
+	Read       bool   `json:"read"`
+}
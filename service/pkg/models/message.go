@@ -5,9 +5,14 @@ type Message struct {
 	Thread string `json:"thread"`
 	Author string `json:"author"`
 
+	ExternalID string `json:"external_id,omitempty"` // client-supplied, unique per thread
+
 	CreatedTS int64 `json:"created_ts,omitempty"`
 	UpdatedTS int64 `json:"updated_ts,omitempty"`
 
 	Body    interface{} `json:"body,omitempty"`
 	Deleted bool        `json:"deleted,omitempty"`
+
+	DeliverAt int64 `json:"deliver_at,omitempty"` // unix ns; set while the message is held back for scheduled delivery
+	ExpiresAt int64 `json:"expires_at,omitempty"` // unix ns; hidden once passed and purged by retention
 }
@@ -1,9 +1,10 @@
 package models
 
 type ThreadUpdatePartial struct {
-	Key       string `json:"key"`
-	UpdatedTS int64  `json:"updated_ts"`
-	Title     string `json:"title"`
+	Key       string   `json:"key"`
+	UpdatedTS int64    `json:"updated_ts"`
+	Title     string   `json:"title"`
+	Tags      []string `json:"tags,omitempty"`
 }
 
 type MessageUpdatePartial struct {
@@ -25,3 +26,78 @@ type MessageDeletePartial struct {
 	Thread    string `json:"thread"`
 	Author    string `json:"author"`
 }
+
+// MessageBatchPartial creates several messages in one thread, applied in order with
+// contiguous sequences.
+type MessageBatchPartial struct {
+	Thread   string    `json:"thread"`
+	Author   string    `json:"author"`
+	Messages []Message `json:"messages"`
+}
+
+// MessageMovePartial moves messages into another thread, or copies them when Copy is set.
+// TargetKeys holds the provisional key in the target for each source key, in order, and
+// Bodies the body each arrives with once the target's hooks ran; without Bodies the
+// source bodies are kept.
+type MessageMovePartial struct {
+	Thread     string        `json:"thread"`
+	Target     string        `json:"target"`
+	Keys       []string      `json:"keys"`
+	TargetKeys []string      `json:"target_keys"`
+	Bodies     []interface{} `json:"bodies,omitempty"`
+	Copy       bool          `json:"copy,omitempty"`
+	UpdatedTS  int64         `json:"updated_ts"`
+}
+
+// BatchPartial runs heterogeneous operations in order as one group.
+type BatchPartial struct {
+	Author string           `json:"author"`
+	Ops    []BatchOpPartial `json:"ops"`
+}
+
+// BatchOpPartial is one operation of a BatchPartial; Handler names the ingest handler
+// and exactly one payload field is set.
+type BatchOpPartial struct {
+	Handler      string                    `json:"handler"`
+	TS           int64                     `json:"ts"`
+	Thread       *Thread                   `json:"thread,omitempty"`
+	ThreadUpdate *ThreadUpdatePartial      `json:"thread_update,omitempty"`
+	Participant  *ThreadParticipantPartial `json:"participant,omitempty"`
+	Message      *Message                  `json:"message,omitempty"`
+	Move         *MessageMovePartial       `json:"move,omitempty"`
+}
+
+// Payload returns the operation's payload, or nil when none is set.
+func (op *BatchOpPartial) Payload() interface{} {
+	switch {
+	case op.Thread != nil:
+		return op.Thread
+	case op.ThreadUpdate != nil:
+		return op.ThreadUpdate
+	case op.Participant != nil:
+		return op.Participant
+	case op.Message != nil:
+		return op.Message
+	case op.Move != nil:
+		return op.Move
+	}
+	return nil
+}
+
+// ThreadTransferPartial moves ownership of a thread from one user to another.
+type ThreadTransferPartial struct {
+	Thread            string     `json:"thread"`
+	From              string     `json:"from"`
+	To                string     `json:"to"`
+	KeepPreviousOwner bool       `json:"keep_previous_owner,omitempty"`
+	PreviousOwnerRole ThreadRole `json:"previous_owner_role,omitempty"`
+	UpdatedTS         int64      `json:"updated_ts"`
+}
+
+type ThreadParticipantPartial struct {
+	Thread    string     `json:"thread"`
+	UserID    string     `json:"user_id"`
+	Role      ThreadRole `json:"role"`
+	Removed   bool       `json:"removed,omitempty"`
+	UpdatedTS int64      `json:"updated_ts"`
+}
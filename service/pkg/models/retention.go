@@ -0,0 +1,40 @@
+package models
+
+import "errors"
+
+type RetentionScope string
+
+const (
+	RetentionScopeThread RetentionScope = "thread"
+	RetentionScopeTag    RetentionScope = "tag"
+	RetentionScopeOwner  RetentionScope = "owner"
+)
+
+// RetentionPolicy overrides the global retention ttls for the threads it targets.
+type RetentionPolicy struct {
+	Scope  RetentionScope `json:"scope"`
+	Target string         `json:"target"` // thread key, tag or owner user id
+
+	KeepDays         int  `json:"keep_days,omitempty"`          // messages older than this are purged; 0 keeps them
+	PurgeDeletedDays int  `json:"purge_deleted_days,omitempty"` // deleted threads and messages purged after this; 0 uses the global ttl
+	NeverPurge       bool `json:"never_purge,omitempty"`
+
+	UpdatedTS int64 `json:"updated_ts,omitempty"`
+}
+
+// LegalHold blocks every purge and hard delete of a thread until released.
+type LegalHold struct {
+	Thread    string `json:"thread"`
+	Reason    string `json:"reason,omitempty"`
+	CreatedTS int64  `json:"created_ts"`
+}
+
+var ErrLegalHold = errors.New("thread is under legal hold")
+
+func ParseRetentionScope(s string) (RetentionScope, bool) {
+	switch RetentionScope(s) {
+	case RetentionScopeThread, RetentionScopeTag, RetentionScopeOwner:
+		return RetentionScope(s), true
+	}
+	return "", false
+}
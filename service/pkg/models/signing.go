@@ -0,0 +1,31 @@
+package models
+
+type SigningKeyState string
+
+const (
+	SigningKeyActive  SigningKeyState = "active"  // signs new signatures and tokens
+	SigningKeyVerify  SigningKeyState = "verify"  // only verifies what it signed before
+	SigningKeyRetired SigningKeyState = "retired" // rejected everywhere
+)
+
+// SigningKey is a named user-signing secret. Keys from the config file are stored
+// without their secret, only to remember the state an admin gave them. Runtime keys
+// are stored with the secret sealed under a KMS data key when encryption is enabled.
+type SigningKey struct {
+	ID           string          `json:"id"`
+	Secret       string          `json:"secret,omitempty"`
+	SealedSecret string          `json:"sealed_secret,omitempty"` // base64 ciphertext of Secret
+	SecretKeyID  string          `json:"secret_key_id,omitempty"` // KMS data key sealing it
+	State        SigningKeyState `json:"state"`
+	Source       string          `json:"source"` // config or runtime
+	CreatedTS    int64           `json:"created_ts,omitempty"`
+	UpdatedTS    int64           `json:"updated_ts,omitempty"`
+}
+
+func ParseSigningKeyState(s string) (SigningKeyState, bool) {
+	switch SigningKeyState(s) {
+	case SigningKeyActive, SigningKeyVerify, SigningKeyRetired:
+		return SigningKeyState(s), true
+	}
+	return "", false
+}
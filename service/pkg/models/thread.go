@@ -1,13 +1,39 @@
 package models
 
+import "sort"
+
 type Thread struct {
-	Key       string   `json:"key"`
-	Title     string   `json:"title,omitempty"`
-	Author    string   `json:"author"`
-	CreatedTS int64    `json:"created_ts,omitempty"`
-	UpdatedTS int64    `json:"updated_ts,omitempty"`
-	Deleted   bool     `json:"deleted,omitempty"`
-	KMS       *KMSMeta `json:"kms,omitempty"`
+	Key        string   `json:"key"`
+	Title      string   `json:"title,omitempty"`
+	ExternalID string   `json:"external_id,omitempty"` // client-supplied, unique per author
+	Tags       []string `json:"tags,omitempty"`
+	Author     string   `json:"author"`
+	CreatedTS  int64    `json:"created_ts,omitempty"`
+	UpdatedTS  int64    `json:"updated_ts,omitempty"`
+	Deleted    bool     `json:"deleted,omitempty"`
+	DM         []string `json:"dm,omitempty"` // canonical participant set of a direct-message thread
+	KMS        *KMSMeta `json:"kms,omitempty"`
+}
+
+// IsDirect reports whether the thread is a direct-message thread.
+func (t *Thread) IsDirect() bool {
+	return len(t.DM) > 0
+}
+
+// CanonicalDMParticipants sorts and de-duplicates a participant set so that every
+// ordering of the same users maps to the same direct-message thread.
+func CanonicalDMParticipants(userIDs []string) []string {
+	seen := make(map[string]bool, len(userIDs))
+	out := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	sort.Strings(out)
+	return out
 }
 
 type KMSMeta struct {
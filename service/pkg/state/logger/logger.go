@@ -1,16 +1,13 @@
 package logger
 
 import (
-	"fmt"
 	"log/slog"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 )
 
 var Log *slog.Logger
-var Audit *slog.Logger
 
 type asyncWriter struct {
 	ch chan []byte
@@ -48,28 +45,6 @@ func Init(level string, dbPath string) {
 
 	// Simple console logger - no files, no async complications
 	Log = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slogLevel}))
-
-	// Initialize audit logger (simplified)
-	// attachAuditLogger(filepath.Join(dbPath, "state", "logs"))
-}
-
-func attachAuditLogger(logsDir string) {
-	fname := filepath.Join(logsDir, "audit.log")
-	if fi, err := os.Stat(fname); err == nil {
-		const maxSize = 10 * 1024 * 1024
-		if fi.Size() > maxSize {
-			bak := fname + "." + fi.ModTime().UTC().Format("20060102T150405Z")
-			_ = os.Rename(fname, bak)
-		}
-	}
-	f, err := os.OpenFile(fname, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to open audit log file: %v\n", err)
-		return
-	}
-	h := slog.NewJSONHandler(f, &slog.HandlerOptions{Level: slog.LevelInfo})
-	Audit = slog.New(h)
-	Audit.Info("audit_sink_attached", "path", fname)
 }
 
 func Sync() {
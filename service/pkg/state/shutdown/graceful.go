@@ -14,13 +14,14 @@ import (
 	"progressdb/pkg/state/telemetry"
 	"progressdb/pkg/store/db/indexdb"
 	storedb "progressdb/pkg/store/db/storedb"
+	"progressdb/pkg/store/features/audit"
 
 	"github.com/valyala/fasthttp"
 )
 
 // ShutdownApp performs graceful shutdown of all app components.
 // This consolidates shutdown logic from both app.go and shutdown.go.
-func ShutdownApp(ctx context.Context, srvFast *fasthttp.Server, retentionCancel context.CancelFunc, ingestIngestor *ingest.Ingestor, hwSensor *sensor.Sensor) error {
+func ShutdownApp(ctx context.Context, srvFast *fasthttp.Server, retentionCancel context.CancelFunc, schedulerCancel context.CancelFunc, ingestIngestor *ingest.Ingestor, hwSensor *sensor.Sensor) error {
 	logger.Info("shutdown: requested")
 
 	// stop accepting new requests
@@ -37,6 +38,12 @@ func ShutdownApp(ctx context.Context, srvFast *fasthttp.Server, retentionCancel
 		retentionCancel()
 	}
 
+	// cancel scheduled message delivery before the queue closes
+	if schedulerCancel != nil {
+		logger.Info("shutdown: stopping message scheduler")
+		schedulerCancel()
+	}
+
 	// ensure ingest queue drains before closing store and stop ingest processor
 	if queue.GlobalIngestQueue != nil {
 		queue.GlobalIngestQueue.Close()
@@ -64,6 +71,12 @@ func ShutdownApp(ctx context.Context, srvFast *fasthttp.Server, retentionCancel
 		logger.Error("shutdown: index close error", "error", err)
 	}
 
+	// close audit trail; every append is already synced
+	logger.Info("shutdown: closing audit")
+	if err := audit.Close(); err != nil {
+		logger.Error("shutdown: audit close error", "error", err)
+	}
+
 	// force sync storage to disc before closing
 	logger.Info("shutdown: syncing storage to disc")
 	if err := storedb.Client.Flush(); err != nil {
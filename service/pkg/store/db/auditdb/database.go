@@ -0,0 +1,140 @@
+package auditdb
+
+import (
+	"errors"
+	"fmt"
+
+	"progressdb/pkg/state/logger"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Client holds the audit trail. It is kept apart from the store and index so that
+// retention, purges and restores of user data never touch it.
+var Client *pebble.DB
+var StorePath string
+
+func Open(path string) error {
+	var err error
+	Client, err = pebble.Open(path, &pebble.Options{})
+	if err != nil {
+		logger.Error("pebble_open_failed", "path", path, "error", err)
+		return err
+	}
+	StorePath = path
+	return nil
+}
+
+func Close() error {
+	if Client == nil {
+		return nil
+	}
+	if err := Client.Close(); err != nil {
+		return err
+	}
+	Client = nil
+	return nil
+}
+
+func Ready() bool {
+	return Client != nil
+}
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, pebble.ErrNotFound)
+}
+
+func GetKey(key string) ([]byte, error) {
+	if Client == nil {
+		return nil, fmt.Errorf("pebble not opened; call Open first")
+	}
+	v, closer, err := Client.Get([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	out := make([]byte, len(v))
+	copy(out, v)
+	return out, nil
+}
+
+// Append writes a record and its index entries in one synced batch.
+func Append(key string, value []byte, indexKeys []string) error {
+	if Client == nil {
+		return fmt.Errorf("pebble not opened; call Open first")
+	}
+	b := Client.NewBatch()
+	defer b.Close()
+	if err := b.Set([]byte(key), value, nil); err != nil {
+		return err
+	}
+	for _, k := range indexKeys {
+		if err := b.Set([]byte(k), nil, nil); err != nil {
+			return err
+		}
+	}
+	if err := b.Commit(pebble.Sync); err != nil {
+		logger.Error("audit_append_failed", "key", key, "error", err)
+		return err
+	}
+	return nil
+}
+
+// Trim deletes pruned records and their index entries and saves the new floor, in one
+// synced batch.
+func Trim(deleteKeys []string, floorKey string, floor []byte) error {
+	if Client == nil {
+		return fmt.Errorf("pebble not opened; call Open first")
+	}
+	b := Client.NewBatch()
+	defer b.Close()
+	for _, k := range deleteKeys {
+		if err := b.Delete([]byte(k), nil); err != nil {
+			return err
+		}
+	}
+	if err := b.Set([]byte(floorKey), floor, nil); err != nil {
+		return err
+	}
+	if err := b.Commit(pebble.Sync); err != nil {
+		logger.Error("audit_trim_failed", "count", len(deleteKeys), "error", err)
+		return err
+	}
+	return nil
+}
+
+// PrefixIter returns an iterator bounded to keys starting with prefix.
+func PrefixIter(prefix string) (*pebble.Iterator, error) {
+	if Client == nil {
+		return nil, fmt.Errorf("pebble not opened; call Open first")
+	}
+	lower := []byte(prefix)
+	return Client.NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: upperBound(lower)})
+}
+
+// NewSnapshot returns a consistent view of the trail, for reads that must not see a
+// prune halfway.
+func NewSnapshot() (*pebble.Snapshot, error) {
+	if Client == nil {
+		return nil, fmt.Errorf("pebble not opened; call Open first")
+	}
+	return Client.NewSnapshot(), nil
+}
+
+// SnapshotPrefixIter is PrefixIter over a snapshot.
+func SnapshotPrefixIter(snap *pebble.Snapshot, prefix string) (*pebble.Iterator, error) {
+	lower := []byte(prefix)
+	return snap.NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: upperBound(lower)})
+}
+
+func upperBound(prefix []byte) []byte {
+	out := make([]byte, len(prefix))
+	copy(out, prefix)
+	for i := len(out) - 1; i >= 0; i-- {
+		if out[i] < 0xFF {
+			out[i]++
+			return out[:i+1]
+		}
+	}
+	return nil
+}
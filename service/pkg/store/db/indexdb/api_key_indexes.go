@@ -0,0 +1,47 @@
+package indexdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/state/telemetry"
+	"progressdb/pkg/store/keys"
+)
+
+func SaveAPIKey(key models.APIKey) error {
+	tr := telemetry.Track("indexdb.save_api_key")
+	defer tr.Finish()
+
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("marshal api key: %w", err)
+	}
+	return SaveKey(keys.GenAPIKeyKey(key.Namespace, key.ID), data)
+}
+
+func ListAPIKeys(namespace string) ([]models.APIKey, error) {
+	iter, err := DBIter()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	defer iter.Close()
+
+	var apiKeys []models.APIKey
+	prefix := keys.Namespaced(namespace, keys.APIKeyPrefix)
+	for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid(); ok = iter.Next() {
+		key := string(iter.Key())
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+		var apiKey models.APIKey
+		if err := json.Unmarshal(iter.Value(), &apiKey); err != nil {
+			logger.Warn("api_key_corrupt", "key", key, "error", err)
+			continue
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+	return apiKeys, nil
+}
@@ -0,0 +1,32 @@
+package indexdb
+
+import (
+	"progressdb/pkg/state/telemetry"
+	"progressdb/pkg/store/keys"
+)
+
+// GetDirectThread returns the thread key indexed for a canonical participant set,
+// or "" if no direct-message thread has been created for it.
+func GetDirectThread(namespace string, participants []string) (string, error) {
+	tr := telemetry.Track("indexdb.get_direct_thread")
+	defer tr.Finish()
+
+	val, err := GetKey(keys.GenDirectThreadKey(namespace, participants))
+	if err != nil {
+		if IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return val, nil
+}
+
+// DeleteDirectThread removes the participant-set lookup if it still points at threadKey.
+func DeleteDirectThread(participants []string, threadKey string) error {
+	namespace := keys.NamespaceOf(threadKey)
+	current, err := GetDirectThread(namespace, participants)
+	if err != nil || current != threadKey {
+		return err
+	}
+	return DeleteKey(keys.GenDirectThreadKey(namespace, participants))
+}
@@ -0,0 +1,35 @@
+package indexdb
+
+import (
+	"strconv"
+
+	"progressdb/pkg/state/telemetry"
+	"progressdb/pkg/store/keys"
+)
+
+// GetMessageExpiry returns when messageKey expires (unix ns), or 0 if it never does.
+func GetMessageExpiry(messageKey string) (int64, error) {
+	val, err := GetKey(keys.GenExpiryMarkerKey(messageKey))
+	if err != nil {
+		if IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
+
+func IsExpired(messageKey string, now int64) (bool, error) {
+	expiresAt, err := GetMessageExpiry(messageKey)
+	if err != nil {
+		return false, err
+	}
+	return expiresAt != 0 && expiresAt <= now, nil
+}
+
+func DeleteExpiryMarker(messageKey string) error {
+	tr := telemetry.Track("indexdb.delete_expiry_marker")
+	defer tr.Finish()
+
+	return DeleteKey(keys.GenExpiryMarkerKey(messageKey))
+}
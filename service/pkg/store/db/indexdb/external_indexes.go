@@ -0,0 +1,76 @@
+package indexdb
+
+import (
+	"fmt"
+	"strings"
+
+	"progressdb/pkg/state/telemetry"
+	"progressdb/pkg/store/keys"
+)
+
+// GetThreadByExternalID returns the thread key an author registered under externalID in a
+// namespace, or "".
+func GetThreadByExternalID(namespace, author, externalID string) (string, error) {
+	tr := telemetry.Track("indexdb.get_thread_by_external_id")
+	defer tr.Finish()
+
+	return getExternalID(keys.GenThreadExternalIDKey(namespace, author, externalID))
+}
+
+// GetMessageByExternalID returns the message key registered under externalID in a thread, or "".
+func GetMessageByExternalID(threadKey, externalID string) (string, error) {
+	tr := telemetry.Track("indexdb.get_message_by_external_id")
+	defer tr.Finish()
+
+	return getExternalID(keys.GenMessageExternalIDKey(threadKey, externalID))
+}
+
+// DeleteExternalID removes an external id lookup if it still points at key.
+func DeleteExternalID(lookupKey, key string) error {
+	current, err := getExternalID(lookupKey)
+	if err != nil || current != key {
+		return err
+	}
+	return DeleteKey(lookupKey)
+}
+
+func getExternalID(lookupKey string) (string, error) {
+	val, err := GetKey(lookupKey)
+	if err != nil {
+		if IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return val, nil
+}
+
+// DeleteMessageExternalIDs removes the thread's external id lookups that point at messageKey.
+func DeleteMessageExternalIDs(threadKey, messageKey string) error {
+	prefix, err := keys.GenThreadExternalIDsPrefix(threadKey)
+	if err != nil {
+		return err
+	}
+	iter, err := DBIter()
+	if err != nil {
+		return fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	var lookupKeys []string
+	for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid(); ok = iter.Next() {
+		key := string(iter.Key())
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+		if string(iter.Value()) == messageKey {
+			lookupKeys = append(lookupKeys, key)
+		}
+	}
+	iter.Close()
+
+	for _, key := range lookupKeys {
+		if err := DeleteKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package indexdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/state/telemetry"
+	"progressdb/pkg/store/keys"
+)
+
+func ListUserMentions(namespace, userID string) ([]models.Mention, error) {
+	prefix, err := keys.GenUserMentionRelPrefix(namespace, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate user mention prefix: %w", err)
+	}
+	iter, err := DBIter()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	defer iter.Close()
+
+	var mentions []models.Mention
+	for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid(); ok = iter.Next() {
+		key := string(iter.Key())
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+		var mention models.Mention
+		if err := json.Unmarshal(iter.Value(), &mention); err != nil {
+			logger.Warn("mention_index_corrupt", "key", key, "error", err)
+			continue
+		}
+		mentions = append(mentions, mention)
+	}
+	return mentions, nil
+}
+
+func MarkUserMentionRead(userID, messageKey string) error {
+	tr := telemetry.Track("indexdb.mark_user_mention_read")
+	defer tr.Finish()
+
+	key := keys.GenUserMentionKey(userID, messageKey)
+	val, err := GetKey(key)
+	if err != nil {
+		return err
+	}
+	var mention models.Mention
+	if err := json.Unmarshal([]byte(val), &mention); err != nil {
+		return fmt.Errorf("unmarshal mention: %w", err)
+	}
+	if mention.Read {
+		return nil
+	}
+	mention.Read = true
+	data, err := json.Marshal(mention)
+	if err != nil {
+		return fmt.Errorf("marshal mention: %w", err)
+	}
+	return SaveKey(key, data)
+}
+
+// DeleteMessageMentions drops every user's mention of the given messages.
+func DeleteMessageMentions(messageKeys map[string]bool) error {
+	tr := telemetry.Track("indexdb.delete_message_mentions")
+	defer tr.Finish()
+
+	if len(messageKeys) == 0 {
+		return nil
+	}
+	// mentions live in the namespace of the message they point at
+	namespaces := make(map[string]bool)
+	for messageKey := range messageKeys {
+		namespaces[keys.NamespaceOf(messageKey)] = true
+	}
+	iter, err := DBIter()
+	if err != nil {
+		return fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	var stale []string
+	for namespace := range namespaces {
+		prefix := keys.Namespaced(namespace, keys.UserThreadsRelPrefix)
+		for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid(); ok = iter.Next() {
+			key := string(iter.Key())
+			if !strings.HasPrefix(key, prefix) {
+				break
+			}
+			if i := strings.Index(key, ":mention:"); i >= 0 && messageKeys[keys.Namespaced(namespace, key[i+len(":mention:"):])] {
+				stale = append(stale, key)
+			}
+		}
+	}
+	iter.Close()
+
+	for _, key := range stale {
+		if err := DeleteKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
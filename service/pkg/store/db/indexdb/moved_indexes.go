@@ -0,0 +1,36 @@
+package indexdb
+
+import (
+	"progressdb/pkg/state/telemetry"
+	"progressdb/pkg/store/keys"
+)
+
+// maxMoveHops bounds how many redirects are followed for a message moved more than once.
+const maxMoveHops = 16
+
+// ResolveMovedMessage follows the redirects left by message moves and returns the key the
+// message lives under now, or messageKey itself when it never moved.
+func ResolveMovedMessage(messageKey string) (string, error) {
+	tr := telemetry.Track("indexdb.resolve_moved_message")
+	defer tr.Finish()
+
+	for i := 0; i < maxMoveHops; i++ {
+		next, err := GetKey(keys.GenMovedMessageKey(messageKey))
+		if err != nil {
+			if IsNotFound(err) {
+				return messageKey, nil
+			}
+			return "", err
+		}
+		messageKey = next
+	}
+	return messageKey, nil
+}
+
+// DeleteMovedMessage drops the redirect left when messageKey moved, once its tombstone is purged.
+func DeleteMovedMessage(messageKey string) error {
+	tr := telemetry.Track("indexdb.delete_moved_message")
+	defer tr.Finish()
+
+	return DeleteKey(keys.GenMovedMessageKey(messageKey))
+}
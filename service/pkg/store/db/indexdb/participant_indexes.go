@@ -0,0 +1,79 @@
+package indexdb
+
+import (
+	"fmt"
+	"strings"
+
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/telemetry"
+	"progressdb/pkg/store/keys"
+)
+
+type ThreadParticipant struct {
+	UserID string            `json:"user_id"`
+	Role   models.ThreadRole `json:"role"`
+}
+
+func SetThreadUserRole(threadKey, userID string, role models.ThreadRole) error {
+	tr := telemetry.Track("indexdb.set_thread_user_role")
+	defer tr.Finish()
+
+	key := keys.GenThreadHasUserKey(threadKey, userID)
+	return SaveKey(key, []byte(role))
+}
+
+// GetThreadParticipantRole returns the role stored on the participant relationship only.
+func GetThreadParticipantRole(threadKey, userID string) (models.ThreadRole, error) {
+	key := keys.GenThreadHasUserKey(threadKey, userID)
+	val, err := GetKey(key)
+	if err != nil {
+		if IsNotFound(err) {
+			return models.ThreadRoleNone, nil
+		}
+		return models.ThreadRoleNone, err
+	}
+	role, _ := models.ParseThreadRole(val)
+	return role, nil
+}
+
+// GetThreadUserRole resolves the effective role of a user, with ownership taking precedence.
+func GetThreadUserRole(threadKey, userID string) (models.ThreadRole, error) {
+	isOwner, err := DoesUserOwnThread(userID, threadKey)
+	if err != nil {
+		return models.ThreadRoleNone, err
+	}
+	if isOwner {
+		return models.ThreadRoleOwner, nil
+	}
+	return GetThreadParticipantRole(threadKey, userID)
+}
+
+func ListThreadParticipants(threadKey string) ([]ThreadParticipant, error) {
+	prefix, err := keys.GenThreadUserRelPrefix(threadKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate thread user prefix: %w", err)
+	}
+	iter, err := DBIter()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	defer iter.Close()
+
+	var participants []ThreadParticipant
+	for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid(); ok = iter.Next() {
+		key := string(iter.Key())
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+		userID := strings.TrimPrefix(key, prefix)
+		role, _ := models.ParseThreadRole(string(iter.Value()))
+		if role != models.ThreadRoleOwner {
+			// legacy owners are stored as plain participants
+			if isOwner, err := DoesUserOwnThread(userID, threadKey); err == nil && isOwner {
+				role = models.ThreadRoleOwner
+			}
+		}
+		participants = append(participants, ThreadParticipant{UserID: userID, Role: role})
+	}
+	return participants, nil
+}
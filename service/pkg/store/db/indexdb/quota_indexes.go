@@ -0,0 +1,61 @@
+package indexdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"progressdb/pkg/state/telemetry"
+	"progressdb/pkg/store/keys"
+)
+
+func GetQuotaCount(namespace, day, kind, userID string) (int, error) {
+	v, err := GetKey(keys.GenQuotaKey(namespace, day, kind, userID))
+	if err != nil {
+		if IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("corrupt quota count: %w", err)
+	}
+	return n, nil
+}
+
+func SaveQuotaCount(namespace, day, kind, userID string, count int) error {
+	tr := telemetry.Track("indexdb.save_quota_count")
+	defer tr.Finish()
+
+	return SaveKey(keys.GenQuotaKey(namespace, day, kind, userID), []byte(strconv.Itoa(count)))
+}
+
+// DeleteQuotaCountsBefore removes a namespace's counters of every day before day.
+func DeleteQuotaCountsBefore(namespace, day string) (int, error) {
+	tr := telemetry.Track("indexdb.delete_quota_counts")
+	defer tr.Finish()
+
+	iter, err := DBIter()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	var stale []string
+	prefix := keys.Namespaced(namespace, keys.QuotaPrefix)
+	end := prefix + day
+	for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid(); ok = iter.Next() {
+		key := string(iter.Key())
+		if !strings.HasPrefix(key, prefix) || key >= end {
+			break
+		}
+		stale = append(stale, key)
+	}
+	iter.Close()
+
+	for _, key := range stale {
+		if err := DeleteKey(key); err != nil {
+			return 0, err
+		}
+	}
+	return len(stale), nil
+}
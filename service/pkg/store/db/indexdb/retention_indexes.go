@@ -0,0 +1,139 @@
+package indexdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/state/telemetry"
+	"progressdb/pkg/store/keys"
+)
+
+func SaveRetentionPolicy(namespace string, policy models.RetentionPolicy) error {
+	tr := telemetry.Track("indexdb.save_retention_policy")
+	defer tr.Finish()
+
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("marshal retention policy: %w", err)
+	}
+	return SaveKey(keys.GenRetentionPolicyKey(namespace, string(policy.Scope), policy.Target), data)
+}
+
+// GetRetentionPolicy returns a namespace's policy for scope and target, or nil if none is set.
+func GetRetentionPolicy(namespace string, scope models.RetentionScope, target string) (*models.RetentionPolicy, error) {
+	val, err := GetKey(keys.GenRetentionPolicyKey(namespace, string(scope), target))
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var policy models.RetentionPolicy
+	if err := json.Unmarshal([]byte(val), &policy); err != nil {
+		return nil, fmt.Errorf("unmarshal retention policy: %w", err)
+	}
+	return &policy, nil
+}
+
+func DeleteRetentionPolicy(namespace string, scope models.RetentionScope, target string) error {
+	tr := telemetry.Track("indexdb.delete_retention_policy")
+	defer tr.Finish()
+
+	return DeleteKey(keys.GenRetentionPolicyKey(namespace, string(scope), target))
+}
+
+func ListRetentionPolicies(namespace string) ([]models.RetentionPolicy, error) {
+	iter, err := DBIter()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	defer iter.Close()
+
+	var policies []models.RetentionPolicy
+	prefix := keys.Namespaced(namespace, keys.RetentionPolicyPrefix)
+	for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid(); ok = iter.Next() {
+		key := string(iter.Key())
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+		var policy models.RetentionPolicy
+		if err := json.Unmarshal(iter.Value(), &policy); err != nil {
+			logger.Warn("retention_policy_corrupt", "key", key, "error", err)
+			continue
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+func SaveLegalHold(hold models.LegalHold) error {
+	tr := telemetry.Track("indexdb.save_legal_hold")
+	defer tr.Finish()
+
+	data, err := json.Marshal(hold)
+	if err != nil {
+		return fmt.Errorf("marshal legal hold: %w", err)
+	}
+	return SaveKey(keys.GenLegalHoldKey(hold.Thread), data)
+}
+
+// GetLegalHold returns the hold on threadKey, or nil if the thread is not held.
+func GetLegalHold(threadKey string) (*models.LegalHold, error) {
+	val, err := GetKey(keys.GenLegalHoldKey(threadKey))
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var hold models.LegalHold
+	if err := json.Unmarshal([]byte(val), &hold); err != nil {
+		return nil, fmt.Errorf("unmarshal legal hold: %w", err)
+	}
+	return &hold, nil
+}
+
+func IsOnLegalHold(threadKey string) (bool, error) {
+	_, err := GetKey(keys.GenLegalHoldKey(threadKey))
+	if err != nil {
+		if IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func DeleteLegalHold(threadKey string) error {
+	tr := telemetry.Track("indexdb.delete_legal_hold")
+	defer tr.Finish()
+
+	return DeleteKey(keys.GenLegalHoldKey(threadKey))
+}
+
+func ListLegalHolds(namespace string) ([]models.LegalHold, error) {
+	iter, err := DBIter()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	defer iter.Close()
+
+	var holds []models.LegalHold
+	prefix := keys.Namespaced(namespace, keys.LegalHoldPrefix)
+	for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid(); ok = iter.Next() {
+		key := string(iter.Key())
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+		var hold models.LegalHold
+		if err := json.Unmarshal(iter.Value(), &hold); err != nil {
+			logger.Warn("legal_hold_corrupt", "key", key, "error", err)
+			continue
+		}
+		holds = append(holds, hold)
+	}
+	return holds, nil
+}
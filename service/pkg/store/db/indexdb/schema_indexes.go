@@ -0,0 +1,43 @@
+package indexdb
+
+import (
+	"fmt"
+	"strings"
+
+	"progressdb/pkg/state/telemetry"
+	"progressdb/pkg/store/keys"
+)
+
+func SaveSchema(key string, schema []byte) error {
+	tr := telemetry.Track("indexdb.save_schema")
+	defer tr.Finish()
+
+	return SaveKey(key, schema)
+}
+
+func DeleteSchema(key string) error {
+	tr := telemetry.Track("indexdb.delete_schema")
+	defer tr.Finish()
+
+	return DeleteKey(key)
+}
+
+// ListSchemas returns every schema document registered in a namespace keyed by its storage key.
+func ListSchemas(namespace string) (map[string][]byte, error) {
+	iter, err := DBIter()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	defer iter.Close()
+
+	schemas := make(map[string][]byte)
+	prefix := keys.Namespaced(namespace, keys.SchemaPrefix)
+	for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid(); ok = iter.Next() {
+		key := string(iter.Key())
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+		schemas[key] = append([]byte(nil), iter.Value()...)
+	}
+	return schemas, nil
+}
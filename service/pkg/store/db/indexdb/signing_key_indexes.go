@@ -0,0 +1,47 @@
+package indexdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/state/telemetry"
+	"progressdb/pkg/store/keys"
+)
+
+func SaveSigningKey(namespace string, key models.SigningKey) error {
+	tr := telemetry.Track("indexdb.save_signing_key")
+	defer tr.Finish()
+
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("marshal signing key: %w", err)
+	}
+	return SaveKey(keys.GenSigningKeyKey(namespace, key.ID), data)
+}
+
+func ListSigningKeys(namespace string) ([]models.SigningKey, error) {
+	iter, err := DBIter()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	defer iter.Close()
+
+	var signingKeys []models.SigningKey
+	prefix := keys.Namespaced(namespace, keys.SigningKeyPrefix)
+	for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid(); ok = iter.Next() {
+		key := string(iter.Key())
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+		var signingKey models.SigningKey
+		if err := json.Unmarshal(iter.Value(), &signingKey); err != nil {
+			logger.Warn("signing_key_corrupt", "key", key, "error", err)
+			continue
+		}
+		signingKeys = append(signingKeys, signingKey)
+	}
+	return signingKeys, nil
+}
@@ -7,8 +7,8 @@ import (
 	"progressdb/pkg/store/keys"
 )
 
-func ListUserThreadKeys(userID string) ([]string, error) {
-	prefix, err := keys.GenUserThreadRelPrefix(userID)
+func ListUserThreadKeys(namespace, userID string) ([]string, error) {
+	prefix, err := keys.GenUserThreadRelPrefix(namespace, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate user thread prefix: %w", err)
 	}
@@ -11,6 +11,7 @@ import (
 	"progressdb/pkg/models"
 	"progressdb/pkg/state/logger"
 	"progressdb/pkg/state/telemetry"
+	"progressdb/pkg/store/keys"
 )
 
 var key []byte
@@ -49,7 +50,7 @@ func EncryptionEnabled() bool {
 }
 
 func EncryptMessageData(threadKey string, data []byte) ([]byte, error) {
-	if !EncryptionEnabled() {
+	if !threadEncrypted(threadKey) {
 		return data, nil
 	}
 
@@ -57,8 +58,20 @@ func EncryptMessageData(threadKey string, data []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	return EncryptMessageDataWithKMS(keys.NamespaceOf(threadKey), kmsMeta, data)
+}
+
+// EncryptMessageDataWithKMS is EncryptMessageData for a thread whose KMS metadata is
+// already at hand, such as one created earlier in the same apply batch.
+func EncryptMessageDataWithKMS(namespace string, kmsMeta *models.KMSMeta, data []byte) ([]byte, error) {
+	if !NamespaceEncrypted(namespace) {
+		return data, nil
+	}
+	if kmsMeta == nil || kmsMeta.KeyID == "" {
+		return nil, fmt.Errorf("no KMS key ID for thread")
+	}
 
-	if EncryptionHasFieldPolicy() {
+	if EncryptionHasFieldPolicy(namespace) {
 		var msg models.Message
 		if err := json.Unmarshal(data, &msg); err != nil {
 			return nil, err
@@ -76,8 +89,8 @@ func EncryptMessageData(threadKey string, data []byte) ([]byte, error) {
 	}
 }
 
-func DecryptMessageData(kmsMeta *models.KMSMeta, data []byte) ([]byte, error) {
-	if !EncryptionEnabled() {
+func DecryptMessageData(namespace string, kmsMeta *models.KMSMeta, data []byte) ([]byte, error) {
+	if !NamespaceEncrypted(namespace) {
 		return data, nil
 	}
 
@@ -85,7 +98,7 @@ func DecryptMessageData(kmsMeta *models.KMSMeta, data []byte) ([]byte, error) {
 		return nil, fmt.Errorf("no KMS key ID for thread")
 	}
 
-	if EncryptionHasFieldPolicy() {
+	if EncryptionHasFieldPolicy(namespace) {
 		var msg models.Message
 		if err := json.Unmarshal(data, &msg); err != nil {
 			return nil, err
@@ -154,7 +167,8 @@ func EncryptMessageBody(m *models.Message, thread models.Thread) (any, error) {
 		return nil, errors.New("nil message")
 	}
 
-	if !EncryptionEnabled() {
+	namespace := keys.NamespaceOf(m.Thread)
+	if !NamespaceEncrypted(namespace) {
 		return m.Body, nil
 	}
 
@@ -163,7 +177,7 @@ func EncryptMessageBody(m *models.Message, thread models.Thread) (any, error) {
 	}
 	keyID := thread.KMS.KeyID
 
-	if EncryptionHasFieldPolicy() {
+	if EncryptionHasFieldPolicy(namespace) {
 		tr.Mark("encrypt_fields")
 		b, err := json.Marshal(m)
 		if err != nil {
@@ -179,7 +193,7 @@ func EncryptMessageBody(m *models.Message, thread models.Thread) (any, error) {
 			return nil, err
 		}
 
-		for _, rule := range fieldRules {
+		for _, rule := range fieldRules[namespace] {
 			v = encryptBodyPath(v, rule.segments, keyID)
 		}
 
@@ -290,7 +304,8 @@ func DecryptMessageBody(m *models.Message, threadKeyID string) (any, error) {
 		return nil, errors.New("nil message")
 	}
 
-	if !EncryptionEnabled() {
+	namespace := keys.NamespaceOf(m.Thread)
+	if !NamespaceEncrypted(namespace) {
 		return m.Body, nil
 	}
 
@@ -298,7 +313,7 @@ func DecryptMessageBody(m *models.Message, threadKeyID string) (any, error) {
 		return nil, errors.New("no thread key id provided")
 	}
 
-	if EncryptionHasFieldPolicy() {
+	if EncryptionHasFieldPolicy(namespace) {
 		tr.Mark("decrypt_fields")
 		b, err := json.Marshal(m)
 		if err != nil {
@@ -311,7 +326,7 @@ func DecryptMessageBody(m *models.Message, threadKeyID string) (any, error) {
 		}
 
 		var firstErr error
-		for _, rule := range fieldRules {
+		for _, rule := range fieldRules[namespace] {
 			res, err := decryptBodyPath(v, rule.segments, threadKeyID)
 			if err != nil && firstErr == nil {
 				firstErr = err
@@ -25,7 +25,7 @@ var (
 func SetupKMS(ctx context.Context) error {
 	cfg := config.GetConfig()
 
-	if !cfg.Encryption.Enabled {
+	if !config.EncryptionInUse() {
 		logger.Info("kms: encryption disabled")
 		return nil
 	}
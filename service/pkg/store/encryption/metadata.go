@@ -12,7 +12,7 @@ import (
 )
 
 func ProvisionThreadKMS(threadKey string) (*models.KMSMeta, error) {
-	if !EncryptionEnabled() {
+	if !threadEncrypted(threadKey) {
 		return nil, nil
 	}
 
@@ -39,7 +39,7 @@ func ProvisionThreadKMS(threadKey string) (*models.KMSMeta, error) {
 }
 
 func GetThreadKMS(threadKey string) (*models.KMSMeta, error) {
-	if !EncryptionEnabled() {
+	if !threadEncrypted(threadKey) {
 		return nil, nil
 	}
 
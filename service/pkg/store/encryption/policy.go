@@ -3,16 +3,21 @@ package encryption
 import (
 	"fmt"
 	"strings"
+
+	"progressdb/pkg/config"
+	"progressdb/pkg/store/keys"
 )
 
 type fieldRule struct {
 	segments []string
 }
 
-var fieldRules []fieldRule
+// fieldRules holds the body fields each namespace encrypts; a namespace without rules
+// encrypts whole messages.
+var fieldRules = map[string][]fieldRule{}
 
-func SetEncryptionFieldPolicy(fields []string) error {
-	fieldRules = fieldRules[:0]
+func SetEncryptionFieldPolicy(namespace string, fields []string) error {
+	var rules []fieldRule
 	for _, p := range fields {
 		p = strings.TrimSpace(p)
 		if p == "" {
@@ -22,11 +27,26 @@ func SetEncryptionFieldPolicy(fields []string) error {
 		if len(segments) == 0 || segments[0] != "body" {
 			return fmt.Errorf("encryption field path must start with 'body': %q", p)
 		}
-		fieldRules = append(fieldRules, fieldRule{segments: segments})
+		rules = append(rules, fieldRule{segments: segments})
 	}
+	fieldRules[namespace] = rules
 	return nil
 }
 
-func EncryptionHasFieldPolicy() bool {
-	return len(fieldRules) > 0
+func EncryptionHasFieldPolicy(namespace string) bool {
+	return len(fieldRules[namespace]) > 0
+}
+
+// NamespaceEncrypted reports whether the threads of a namespace are encrypted: the KMS is
+// shared, but each namespace turns encryption on for itself.
+func NamespaceEncrypted(namespace string) bool {
+	if !EncryptionEnabled() {
+		return false
+	}
+	enabled, _ := config.EncryptionPolicy(namespace)
+	return enabled
+}
+
+func threadEncrypted(threadKey string) bool {
+	return NamespaceEncrypted(keys.NamespaceOf(threadKey))
 }
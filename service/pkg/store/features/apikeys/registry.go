@@ -0,0 +1,327 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"progressdb/pkg/config"
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/db/indexdb"
+	"progressdb/pkg/timeutil"
+)
+
+// keyPrefix marks runtime keys: pdb_<id>_<secret>. The id finds the record, the
+// secret is checked against its salted hash.
+const keyPrefix = "pdb_"
+
+// lastUsedFlushInterval bounds how often a key's last-used time is written to disk;
+// listings read the in-memory value.
+const lastUsedFlushInterval = time.Minute
+
+const (
+	StatusActive  = "active"
+	StatusExpired = "expired"
+	StatusRevoked = "revoked"
+)
+
+var (
+	ErrNotFound  = errors.New("api key not found")
+	errLabel     = errors.New("label: at most 128 characters allowed")
+	errExpiresIn = errors.New("expires_in: must not be negative")
+)
+
+// invalidError marks errors caused by the request rather than by storage.
+type invalidError struct{ error }
+
+// IsInvalid reports whether err rejects the request's input.
+func IsInvalid(err error) bool {
+	var invalid invalidError
+	return errors.As(err, &invalid)
+}
+
+type entry struct {
+	key     models.APIKey
+	flushed int64 // last-used time last written to disk
+}
+
+type registry struct {
+	mu     sync.RWMutex
+	loaded bool
+	keys   map[string]*entry
+}
+
+var reg = &registry{keys: make(map[string]*entry)}
+
+// IsRuntimeKey reports whether value has the shape of a runtime key.
+func IsRuntimeKey(value string) bool {
+	return strings.HasPrefix(value, keyPrefix)
+}
+
+// KeyID returns the id part of a runtime key, which is safe to log.
+func KeyID(value string) (string, bool) {
+	if !IsRuntimeKey(value) {
+		return "", false
+	}
+	id, _, ok := strings.Cut(value[len(keyPrefix):], "_")
+	return id, ok && id != ""
+}
+
+// Create stores a new key in a namespace and returns its record and the key itself,
+// which is not kept and cannot be shown again.
+func Create(namespace string, role models.APIKeyRole, label string, expiresIn time.Duration, grant models.APIKeyGrant) (models.APIKey, string, error) {
+	if _, ok := models.ParseAPIKeyRole(string(role)); !ok {
+		return models.APIKey{}, "", invalidError{errors.New("role: must be one of admin, backend, frontend")}
+	}
+	if len(label) > 128 {
+		return models.APIKey{}, "", invalidError{errLabel}
+	}
+	if expiresIn < 0 {
+		return models.APIKey{}, "", invalidError{errExpiresIn}
+	}
+	if err := grant.Validate(); err != nil {
+		return models.APIKey{}, "", invalidError{err}
+	}
+	if err := reg.ensureLoaded(); err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	salt, err := randomHex(16)
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	now := timeutil.Now().UnixNano()
+	key := models.APIKey{
+		ID:        id,
+		Namespace: namespace,
+		Role:      role,
+		Label:     label,
+		Salt:      salt,
+		Hash:      hashSecret(salt, secret),
+		CreatedTS: now,
+
+		APIKeyGrant: grant,
+	}
+	if expiresIn > 0 {
+		key.ExpiresTS = now + int64(expiresIn)
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if err := indexdb.SaveAPIKey(key); err != nil {
+		return models.APIKey{}, "", fmt.Errorf("failed to save api key: %w", err)
+	}
+	reg.keys[id] = &entry{key: key}
+	return view(key, now), keyPrefix + id + "_" + secret, nil
+}
+
+// Changes lists the fields of a key to update; nil fields are left alone.
+type Changes struct {
+	Label       *string
+	ExpiresIn   *time.Duration // from now; zero removes the expiry
+	Permissions *[]string
+	Paths       *[]string
+	ThreadTags  *[]string
+	UserPrefix  *string
+}
+
+// Update applies changes to a key of a namespace.
+func Update(namespace, id string, changes Changes) (models.APIKey, error) {
+	if changes.Label != nil && len(*changes.Label) > 128 {
+		return models.APIKey{}, invalidError{errLabel}
+	}
+	if changes.ExpiresIn != nil && *changes.ExpiresIn < 0 {
+		return models.APIKey{}, invalidError{errExpiresIn}
+	}
+	return mutate(namespace, id, func(k *models.APIKey, now int64) error {
+		if changes.Label != nil {
+			k.Label = *changes.Label
+		}
+		if changes.ExpiresIn != nil {
+			k.ExpiresTS = 0
+			if *changes.ExpiresIn > 0 {
+				k.ExpiresTS = now + int64(*changes.ExpiresIn)
+			}
+		}
+		if changes.Permissions != nil {
+			k.Permissions = *changes.Permissions
+		}
+		if changes.Paths != nil {
+			k.Paths = *changes.Paths
+		}
+		if changes.ThreadTags != nil {
+			k.ThreadTags = *changes.ThreadTags
+		}
+		if changes.UserPrefix != nil {
+			k.UserPrefix = *changes.UserPrefix
+		}
+		if err := k.APIKeyGrant.Validate(); err != nil {
+			return invalidError{err}
+		}
+		return nil
+	})
+}
+
+// Revoke disables a key of a namespace for good. The record is kept for listings.
+func Revoke(namespace, id string) (models.APIKey, error) {
+	return mutate(namespace, id, func(k *models.APIKey, now int64) error {
+		if k.RevokedTS == 0 {
+			k.RevokedTS = now
+		}
+		return nil
+	})
+}
+
+func mutate(namespace, id string, fn func(k *models.APIKey, now int64) error) (models.APIKey, error) {
+	if err := reg.ensureLoaded(); err != nil {
+		return models.APIKey{}, err
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	e, ok := reg.keys[id]
+	if !ok || e.key.Namespace != namespace {
+		return models.APIKey{}, ErrNotFound
+	}
+	now := timeutil.Now().UnixNano()
+	updated := e.key
+	if err := fn(&updated, now); err != nil {
+		return models.APIKey{}, err
+	}
+	if err := indexdb.SaveAPIKey(updated); err != nil {
+		return models.APIKey{}, fmt.Errorf("failed to save api key: %w", err)
+	}
+	e.key, e.flushed = updated, updated.LastUsedTS
+	return view(updated, now), nil
+}
+
+// List returns every runtime key of a namespace, newest first, without salts or hashes.
+func List(namespace string) ([]models.APIKey, error) {
+	if err := reg.ensureLoaded(); err != nil {
+		return nil, err
+	}
+	now := timeutil.Now().UnixNano()
+	reg.mu.RLock()
+	out := make([]models.APIKey, 0, len(reg.keys))
+	for _, e := range reg.keys {
+		if e.key.Namespace == namespace {
+			out = append(out, view(e.key, now))
+		}
+	}
+	reg.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedTS > out[j].CreatedTS })
+	return out, nil
+}
+
+// Authenticate resolves a runtime key to its record, without salt or hash, recording
+// when it was last used.
+func Authenticate(value string) (models.APIKey, bool) {
+	if !IsRuntimeKey(value) {
+		return models.APIKey{}, false
+	}
+	id, secret, ok := strings.Cut(value[len(keyPrefix):], "_")
+	if !ok || id == "" || secret == "" {
+		return models.APIKey{}, false
+	}
+	if err := reg.ensureLoaded(); err != nil {
+		logger.Error("api_keys_load_failed", "error", err)
+		return models.APIKey{}, false
+	}
+
+	now := timeutil.Now().UnixNano()
+	reg.mu.RLock()
+	e, ok := reg.keys[id]
+	var key models.APIKey
+	if ok {
+		key = e.key
+	}
+	reg.mu.RUnlock()
+	if !ok || !key.Usable(now) {
+		return models.APIKey{}, false
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(key.Salt, secret)), []byte(key.Hash)) != 1 {
+		return models.APIKey{}, false
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	e.key.LastUsedTS = now
+	if now-e.flushed >= int64(lastUsedFlushInterval) {
+		if err := indexdb.SaveAPIKey(e.key); err != nil {
+			logger.Warn("api_key_last_used_save_failed", "id", id, "error", err)
+		} else {
+			e.flushed = now
+		}
+	}
+	return view(key, now), true
+}
+
+func (r *registry) ensureLoaded() error {
+	r.mu.RLock()
+	loaded := r.loaded
+	r.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loaded {
+		return nil
+	}
+	for _, namespace := range config.Namespaces() {
+		stored, err := indexdb.ListAPIKeys(namespace)
+		if err != nil {
+			return fmt.Errorf("failed to load api keys: %w", err)
+		}
+		for _, k := range stored {
+			k.Namespace = namespace
+			r.keys[k.ID] = &entry{key: k, flushed: k.LastUsedTS}
+		}
+	}
+	r.loaded = true
+	return nil
+}
+
+// view strips the secret material and fills in the derived status.
+func view(k models.APIKey, now int64) models.APIKey {
+	k.Salt, k.Hash = "", ""
+	switch {
+	case k.RevokedTS != 0:
+		k.Status = StatusRevoked
+	case !k.Usable(now):
+		k.Status = StatusExpired
+	default:
+		k.Status = StatusActive
+	}
+	return k
+}
+
+func hashSecret(salt, secret string) string {
+	sum := sha256.Sum256([]byte(salt + secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
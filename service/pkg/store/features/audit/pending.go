@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/db/auditdb"
+	"progressdb/pkg/store/db/indexdb"
+	"progressdb/pkg/store/keys"
+	"progressdb/pkg/timeutil"
+)
+
+// pending hands out staging keys in order and keeps Sync from appending a set twice.
+var pending struct {
+	mu     sync.Mutex
+	lastTS int64
+	syncMu sync.Mutex
+}
+
+// Stage encodes records under a fresh index key, to be written in the same flush as the
+// writes they describe. Once that flush is synced the batch needs no replay: Sync moves
+// the records to the trail and keeps retrying them until it can. Nothing is staged when
+// the trail is not open.
+func Stage(recs []models.AuditRecord) (string, []byte, error) {
+	if len(recs) == 0 || !auditdb.Ready() {
+		return "", nil, nil
+	}
+	data, err := json.Marshal(recs)
+	if err != nil {
+		return "", nil, fmt.Errorf("encode pending audit records: %w", err)
+	}
+	pending.mu.Lock()
+	ts := timeutil.Now().UnixNano()
+	if ts <= pending.lastTS {
+		ts = pending.lastTS + 1
+	}
+	pending.lastTS = ts
+	pending.mu.Unlock()
+	return keys.GenAuditPendingKey(ts), data, nil
+}
+
+// Sync appends staged records to the trail in the order they were staged, removing each
+// set once it is recorded. It stops at the first set that fails, which stays staged for
+// the next call; a set recorded but not yet removed when the process dies is recorded
+// again, so the trail may repeat a write but never misses one.
+func Sync() error {
+	if !auditdb.Ready() {
+		return nil
+	}
+	pending.syncMu.Lock()
+	defer pending.syncMu.Unlock()
+
+	iter, err := indexdb.DBIter()
+	if err != nil {
+		return fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	type set struct {
+		key  string
+		data []byte
+	}
+	var sets []set
+	for ok := iter.SeekGE([]byte(keys.AuditPendingPrefix)); ok && iter.Valid(); ok = iter.Next() {
+		key := string(iter.Key())
+		if !strings.HasPrefix(key, keys.AuditPendingPrefix) {
+			break
+		}
+		sets = append(sets, set{key: key, data: append([]byte(nil), iter.Value()...)})
+	}
+	iter.Close()
+
+	for _, s := range sets {
+		var recs []models.AuditRecord
+		if err := json.Unmarshal(s.data, &recs); err != nil {
+			logger.Error("audit_pending_corrupt", "key", s.key, "error", err)
+			recs = nil
+		}
+		head.mu.Lock()
+		closed := !auditdb.Ready()
+		var err error
+		if !closed && len(recs) > 0 {
+			err = head.appendAll(recs)
+		}
+		head.mu.Unlock()
+		if closed {
+			return nil // shutting down; what is left is synced on the next start
+		}
+		if err != nil {
+			return err
+		}
+		if err := indexdb.DeleteKey(s.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"progressdb/pkg/config"
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/db/auditdb"
+	"progressdb/pkg/store/keys"
+	"progressdb/pkg/timeutil"
+
+	"github.com/cockroachdb/pebble"
+)
+
+const (
+	pruneInterval = time.Hour
+	pruneChunk    = 10000 // records dropped per pass; a backlog is worked off on the next appends
+)
+
+// floor is the last pruned record. The stored trail continues its chain.
+type floor struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+func readFloor(snap *pebble.Snapshot) (floor, error) {
+	var f floor
+	data, closer, err := snap.Get([]byte(keys.AuditFloorKey))
+	if err != nil {
+		if auditdb.IsNotFound(err) {
+			return f, nil
+		}
+		return f, err
+	}
+	defer closer.Close()
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("corrupt audit floor: %w", err)
+	}
+	return f, nil
+}
+
+// maybePrune drops records older than audit.retention, at most once an hour (or once
+// per retention when shorter) unless a backlog remains. The newest record is always
+// kept so the head survives restarts. Callers hold the lock.
+func (c *chain) maybePrune() {
+	now := timeutil.Now()
+	retention := config.AuditSettings().Retention.Duration()
+	if now.Sub(c.pruned) < min(pruneInterval, retention) {
+		return
+	}
+	n, err := c.prune(now.Add(-retention).UnixNano())
+	if err != nil {
+		logger.Error("audit_prune_failed", "error", err)
+	}
+	if err != nil || n < pruneChunk {
+		c.pruned = now
+	}
+	if n > 0 {
+		logger.Info("audit_pruned", "records", n)
+	}
+}
+
+func (c *chain) prune(cutoff int64) (int, error) {
+	iter, err := auditdb.PrefixIter(keys.AuditRecordPrefix)
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Close()
+
+	var last floor
+	var drop []string
+	n := 0
+	for ok := iter.First(); ok && n < pruneChunk; ok = iter.Next() {
+		var rec models.AuditRecord
+		if err := json.Unmarshal(iter.Value(), &rec); err != nil {
+			return 0, fmt.Errorf("corrupt audit record %s: %w", iter.Key(), err)
+		}
+		if rec.TS >= cutoff || rec.Seq >= c.seq {
+			break
+		}
+		drop = append(drop, string(iter.Key()))
+		if rec.UserID != "" {
+			drop = append(drop, keys.GenAuditUserIndexKey(rec.Namespace, rec.UserID, rec.Seq))
+		}
+		if rec.Thread != "" {
+			drop = append(drop, keys.GenAuditThreadIndexKey(rec.Thread, rec.Seq))
+		}
+		last = floor{Seq: rec.Seq, Hash: rec.Hash}
+		n++
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	data, err := json.Marshal(last)
+	if err != nil {
+		return 0, err
+	}
+	if err := auditdb.Trim(drop, keys.AuditFloorKey, data); err != nil {
+		return 0, fmt.Errorf("failed to prune audit trail: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,299 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/db/auditdb"
+	"progressdb/pkg/store/keys"
+	"progressdb/pkg/timeutil"
+)
+
+const (
+	OutcomeOK     = "ok"
+	OutcomeFailed = "failed"
+)
+
+// chain tracks the tail of the trail so appends need no read-back.
+type chain struct {
+	mu       sync.Mutex
+	loaded   bool
+	seq      uint64
+	hash     string
+	ts       int64
+	poisoned bool      // set when the head on disk could not be read; appends would fork the chain
+	pruned   time.Time // last prune pass
+}
+
+var head = &chain{}
+
+// Record appends one entry to the audit trail, assigning its sequence, timestamp and
+// hash. Timestamps never go backwards, so sequence order is also time order.
+func Record(rec models.AuditRecord) error {
+	return RecordAll([]models.AuditRecord{rec})
+}
+
+// RecordAll appends entries in order under a single lock, pruning records past
+// audit.retention from time to time.
+func RecordAll(recs []models.AuditRecord) error {
+	if len(recs) == 0 || !auditdb.Ready() {
+		return nil
+	}
+	head.mu.Lock()
+	defer head.mu.Unlock()
+	return head.appendAll(recs)
+}
+
+// Close closes the trail's store once no append is in progress. Appends after it are
+// dropped, so writers still winding down at shutdown never touch a closed store.
+func Close() error {
+	head.mu.Lock()
+	defer head.mu.Unlock()
+	return auditdb.Close()
+}
+
+// appendAll appends records in order. Callers hold the lock.
+func (c *chain) appendAll(recs []models.AuditRecord) error {
+	if err := c.load(); err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		if err := c.append(rec); err != nil {
+			return err
+		}
+	}
+	c.maybePrune()
+	return nil
+}
+
+// load reads the last record on first use. Callers hold the lock.
+func (c *chain) load() error {
+	if c.poisoned {
+		return fmt.Errorf("audit trail head unreadable")
+	}
+	if c.loaded {
+		return nil
+	}
+	iter, err := auditdb.PrefixIter(keys.AuditRecordPrefix)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	if iter.Last() {
+		var last models.AuditRecord
+		if err := json.Unmarshal(iter.Value(), &last); err != nil {
+			c.poisoned = true
+			return fmt.Errorf("corrupt audit record %s: %w", iter.Key(), err)
+		}
+		c.seq, c.hash, c.ts = last.Seq, last.Hash, last.TS
+	}
+	c.loaded = true
+	return nil
+}
+
+func (c *chain) append(rec models.AuditRecord) error {
+	rec.Seq = c.seq + 1
+	rec.TS = timeutil.Now().UnixNano()
+	if rec.TS <= c.ts {
+		rec.TS = c.ts + 1
+	}
+	if rec.Outcome == "" {
+		rec.Outcome = OutcomeOK
+	}
+	rec.PrevHash = c.hash
+	rec.Hash = ""
+	hash, err := hashRecord(rec)
+	if err != nil {
+		return err
+	}
+	rec.Hash = hash
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	var indexKeys []string
+	if rec.UserID != "" {
+		indexKeys = append(indexKeys, keys.GenAuditUserIndexKey(rec.Namespace, rec.UserID, rec.Seq))
+	}
+	if rec.Thread != "" {
+		indexKeys = append(indexKeys, keys.GenAuditThreadIndexKey(rec.Thread, rec.Seq))
+	}
+	if err := auditdb.Append(keys.GenAuditRecordKey(rec.Seq), data, indexKeys); err != nil {
+		return fmt.Errorf("failed to append audit record: %w", err)
+	}
+	c.seq, c.hash, c.ts = rec.Seq, rec.Hash, rec.TS
+	return nil
+}
+
+// hashRecord returns the hex sha256 of the record's JSON with Hash left empty.
+func hashRecord(rec models.AuditRecord) (string, error) {
+	rec.Hash = ""
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Query returns matching records newest first. Thread and user filters walk their
+// index; everything else scans the trail backwards from Before.
+func Query(f models.AuditFilter) ([]models.AuditRecord, error) {
+	prefix := keys.AuditRecordPrefix
+	indexed := true
+	switch {
+	case f.Thread != "":
+		prefix = keys.GenAuditThreadPrefix(f.Thread)
+	case f.UserID != "":
+		prefix = keys.GenAuditUserPrefix(f.Namespace, f.UserID)
+	default:
+		indexed = false
+	}
+
+	iter, err := auditdb.PrefixIter(prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var ok bool
+	if f.Before > 0 {
+		ok = iter.SeekLT([]byte(prefix + keys.PadAuditSeq(f.Before)))
+	} else {
+		ok = iter.Last()
+	}
+
+	out := []models.AuditRecord{}
+	for ; ok && len(out) < f.Limit; ok = iter.Prev() {
+		var rec models.AuditRecord
+		if indexed {
+			seq, valid := parseIndexSeq(string(iter.Key()), prefix)
+			if !valid {
+				continue // a longer id sharing this prefix
+			}
+			data, err := auditdb.GetKey(keys.GenAuditRecordKey(seq))
+			if auditdb.IsNotFound(err) {
+				continue // pruned since the index was read
+			}
+			if err != nil {
+				return nil, fmt.Errorf("audit index points at missing record %d: %w", seq, err)
+			}
+			if err := json.Unmarshal(data, &rec); err != nil {
+				return nil, fmt.Errorf("corrupt audit record %d: %w", seq, err)
+			}
+		} else if err := json.Unmarshal(iter.Value(), &rec); err != nil {
+			return nil, fmt.Errorf("corrupt audit record %s: %w", iter.Key(), err)
+		}
+
+		if f.From != 0 && rec.TS < f.From {
+			break // older records only get older
+		}
+		if matches(rec, f) {
+			out = append(out, rec)
+		}
+	}
+	return out, nil
+}
+
+func parseIndexSeq(key, prefix string) (uint64, bool) {
+	suffix := strings.TrimPrefix(key, prefix)
+	if len(suffix) != keys.AuditSeqPadWidth {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(suffix, 10, 64)
+	return seq, err == nil
+}
+
+func matches(rec models.AuditRecord, f models.AuditFilter) bool {
+	if rec.Namespace != f.Namespace {
+		return false
+	}
+	if f.UserID != "" && rec.UserID != f.UserID {
+		return false
+	}
+	if f.Thread != "" && rec.Thread != f.Thread {
+		return false
+	}
+	if f.Action != "" {
+		if strings.HasSuffix(f.Action, ".") {
+			if !strings.HasPrefix(rec.Action, f.Action) {
+				return false
+			}
+		} else if rec.Action != f.Action {
+			return false
+		}
+	}
+	if f.To != 0 && rec.TS >= f.To {
+		return false
+	}
+	return true
+}
+
+// VerifyResult reports whether the stored trail is an unbroken hash chain.
+type VerifyResult struct {
+	Records       uint64 `json:"records"`
+	PrunedThrough uint64 `json:"pruned_through,omitempty"`
+	Valid         bool   `json:"valid"`
+	BrokenAt      uint64 `json:"broken_at,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// Verify walks the whole trail, recomputing every hash and checking that sequences
+// are contiguous and each record links to the one before it. A pruned trail starts
+// from the last record pruned.
+func Verify() (VerifyResult, error) {
+	snap, err := auditdb.NewSnapshot()
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	defer snap.Close()
+	pruned, err := readFloor(snap)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	iter, err := auditdb.SnapshotPrefixIter(snap, keys.AuditRecordPrefix)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	defer iter.Close()
+
+	res := VerifyResult{Valid: true, PrunedThrough: pruned.Seq}
+	prevHash := pruned.Hash
+	broken := func(seq uint64, reason string) (VerifyResult, error) {
+		res.Valid, res.BrokenAt, res.Reason = false, seq, reason
+		logger.Warn("audit_chain_broken", "seq", seq, "reason", reason)
+		return res, nil
+	}
+	for ok := iter.First(); ok; ok = iter.Next() {
+		expected := pruned.Seq + res.Records + 1
+		var rec models.AuditRecord
+		if err := json.Unmarshal(iter.Value(), &rec); err != nil {
+			return broken(expected, "undecodable record")
+		}
+		if rec.Seq != expected || string(iter.Key()) != keys.GenAuditRecordKey(rec.Seq) {
+			return broken(expected, "missing or reordered record")
+		}
+		if rec.PrevHash != prevHash {
+			return broken(rec.Seq, "prev_hash does not match the previous record")
+		}
+		hash, err := hashRecord(rec)
+		if err != nil {
+			return VerifyResult{}, err
+		}
+		if hash != rec.Hash {
+			return broken(rec.Seq, "hash does not match record contents")
+		}
+		prevHash = rec.Hash
+		res.Records++
+	}
+	return res, nil
+}
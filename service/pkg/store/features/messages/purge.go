@@ -2,76 +2,128 @@ package messages
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"progressdb/pkg/models"
 	"progressdb/pkg/state/logger"
+	"progressdb/pkg/state/telemetry"
 	"progressdb/pkg/store/db/indexdb"
 	"progressdb/pkg/store/db/storedb"
 	"progressdb/pkg/store/keys"
-
-	"github.com/cockroachdb/pebble"
 )
 
+// PurgeMessagePermanently hard deletes a message with its versions, markers and move
+// redirect. When it was the oldest message of its thread the start index moves past it.
+// Held threads are refused.
 func PurgeMessagePermanently(messageKey string) error {
-	if storedb.Client == nil {
-		return fmt.Errorf("pebble not opened; call storedb.Open first")
+	tr := telemetry.Track("messages.purge")
+	defer tr.Finish()
+
+	parsed, err := keys.ParseMessageKey(messageKey)
+	if err != nil {
+		return fmt.Errorf("invalid message key %s: %w", messageKey, err)
 	}
-	if indexdb.Client == nil {
-		return fmt.Errorf("pebble not opened; call Open first")
+	if held, err := indexdb.IsOnLegalHold(parsed.ThreadKey); err != nil {
+		return err
+	} else if held {
+		return fmt.Errorf("%w: %s", models.ErrLegalHold, parsed.ThreadKey)
+	}
+	seq, err := keys.KeySequenceNumbered(parsed.Seq)
+	if err != nil {
+		return fmt.Errorf("invalid message sequence %s: %w", parsed.Seq, err)
 	}
+
 	vprefix, err := keys.GenAllMessageVersionsPrefix(messageKey)
 	if err != nil {
 		return fmt.Errorf("failed to generate versions prefix: %w", err)
 	}
-	vi, err := indexdb.Client.NewIter(&pebble.IterOptions{})
+	vi, err := indexdb.DBIter()
 	if err != nil {
 		return err
 	}
-	defer vi.Close()
-
-	var threadKey string
-	var seq int64
-	var versionKeys [][]byte
-	found := false
-	for vi.SeekGE([]byte(vprefix)); vi.Valid(); vi.Next() {
+	var versionKeys []string
+	for ok := vi.SeekGE([]byte(vprefix)); ok && vi.Valid(); ok = vi.Next() {
 		if !bytes.HasPrefix(vi.Key(), []byte(vprefix)) {
 			break
 		}
-		if !found {
-			parsed, err := keys.ParseKey(string(vi.Key()))
-			if err == nil && parsed.Type == keys.KeyTypeVersion {
-				if s, err := keys.KeySequenceNumbered(parsed.Seq); err == nil {
-					seq = int64(s)
-				}
-			}
-			v := append([]byte(nil), vi.Value()...)
-			var msg models.Message
-			if err := json.Unmarshal(v, &msg); err == nil {
-				threadKey = msg.Thread
-				found = true
-			}
-		}
-		versionKeys = append(versionKeys, append([]byte(nil), vi.Key()...))
+		versionKeys = append(versionKeys, string(vi.Key()))
 	}
+	vi.Close()
 
-	if found && threadKey != "" {
-		msgKey := keys.GenMessageKey(threadKey, "", uint64(seq))
-		if err := storedb.Client.Delete([]byte(msgKey), storedb.WriteOpt(true)); err != nil {
-			logger.Error("purge_main_message_failed", "key", msgKey, "error", err)
-		}
-		if err := indexdb.DeleteSoftDeleteMarker(messageKey); err != nil {
-			logger.Error("unmark_soft_deleted_purge_failed", "msg", messageKey, "error", err)
+	for _, k := range versionKeys {
+		if err := indexdb.DeleteKey(k); err != nil {
+			logger.Error("purge_message_version_failed", "key", k, "error", err)
 		}
 	}
+	if err := storedb.DeleteKey(messageKey); err != nil {
+		return fmt.Errorf("delete message %s: %w", messageKey, err)
+	}
+	if err := indexdb.DeleteSoftDeleteMarker(messageKey); err != nil {
+		logger.Error("purge_message_unmark_deleted_failed", "msg", messageKey, "error", err)
+	}
+	if err := indexdb.DeleteExpiryMarker(messageKey); err != nil {
+		return fmt.Errorf("delete expiry marker %s: %w", messageKey, err)
+	}
+	if err := indexdb.DeleteMessageExternalIDs(parsed.ThreadKey, messageKey); err != nil {
+		logger.Error("purge_message_external_id_failed", "msg", messageKey, "error", err)
+	}
+	if err := indexdb.DeleteMovedMessage(messageKey); err != nil {
+		logger.Error("purge_message_redirect_failed", "msg", messageKey, "error", err)
+	}
 
-	for _, k := range versionKeys {
-		if err := indexdb.Client.Delete(k, indexdb.WriteOpt(true)); err != nil {
-			logger.Error("purge_version_delete_failed", "key", string(k), "error", err)
-		}
+	threadKey := parsed.ThreadKey
+	if err := advanceThreadStart(threadKey, seq); err != nil {
+		logger.Error("purge_message_index_failed", "thread", threadKey, "error", err)
 	}
 
-	logger.Info("purge_message_completed", "msg", messageKey, "deleted_keys", len(versionKeys))
+	logger.Info("purge_message_completed", "msg", messageKey, "versions", len(versionKeys))
 	return nil
 }
+
+// advanceThreadStart moves the start index to the oldest remaining message once
+// the message at purgedSeq is gone.
+func advanceThreadStart(threadKey string, purgedSeq uint64) error {
+	startRaw, err := indexdb.GetThreadIndexData(threadKey, "start")
+	if err != nil {
+		return err
+	}
+	start, err := strconv.ParseUint(startRaw, 10, 64)
+	if err != nil {
+		return fmt.Errorf("parse start index: %w", err)
+	}
+	if purgedSeq > start {
+		return nil
+	}
+
+	endRaw, err := indexdb.GetThreadIndexData(threadKey, "end")
+	if err != nil {
+		return err
+	}
+	next, err := strconv.ParseUint(endRaw, 10, 64)
+	if err != nil {
+		return fmt.Errorf("parse end index: %w", err)
+	}
+
+	prefix, err := keys.GenAllThreadMessagesPrefix(threadKey)
+	if err != nil {
+		return err
+	}
+	iter, err := storedb.Iter()
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid(); ok = iter.Next() {
+		if !bytes.HasPrefix(iter.Key(), []byte(prefix)) {
+			break
+		}
+		if parts, err := keys.ParseMessageKey(string(iter.Key())); err == nil {
+			if s, err := keys.KeySequenceNumbered(parts.Seq); err == nil && s < next {
+				next = s
+			}
+		}
+	}
+
+	return indexdb.SaveKey(keys.GenThreadMessageStart(threadKey), []byte(strconv.FormatUint(next, 10)))
+}
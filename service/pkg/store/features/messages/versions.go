@@ -13,7 +13,9 @@ import (
 	"github.com/cockroachdb/pebble"
 )
 
-func ListMessageVersions(messageKey string) ([]string, error) {
+// ListMessageVersions returns the stored versions of a message, decrypted and keyed by
+// version key.
+func ListMessageVersions(messageKey string) (map[string]string, error) {
 	if indexdb.Client == nil {
 		return nil, fmt.Errorf("pebble not opened; call Open first")
 	}
@@ -27,7 +29,7 @@ func ListMessageVersions(messageKey string) ([]string, error) {
 	}
 	defer iter.Close()
 
-	var out []string
+	out := map[string]string{}
 	var kmsMeta *models.KMSMeta
 
 	for iter.SeekGE([]byte(prefix)); iter.Valid(); iter.Next() {
@@ -52,12 +54,12 @@ func ListMessageVersions(messageKey string) ([]string, error) {
 			}
 		}
 
-		decrypted, err := encryption.DecryptMessageData(kmsMeta, v)
+		decrypted, err := encryption.DecryptMessageData(keys.NamespaceOf(messageKey), kmsMeta, v)
 		if err != nil {
 			return nil, fmt.Errorf("decryption failed: %w", err)
 		}
 
-		out = append(out, string(decrypted))
+		out[string(iter.Key())] = string(decrypted)
 	}
 	return out, iter.Error()
 }
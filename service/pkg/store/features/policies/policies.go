@@ -0,0 +1,215 @@
+package policies
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"progressdb/pkg/config"
+	"progressdb/pkg/models"
+	"progressdb/pkg/store/db/indexdb"
+	"progressdb/pkg/store/features/threads"
+	"progressdb/pkg/store/keys"
+	"progressdb/pkg/timeutil"
+)
+
+const day = 24 * time.Hour
+
+// Effective is the retention that applies to one thread once policies are resolved.
+type Effective struct {
+	MessageTTL        time.Duration // age after which messages are purged; 0 keeps them
+	ThreadIdleTTL     time.Duration // inactivity after which the whole thread is purged; 0 keeps it
+	DeletedMessageTTL time.Duration
+	DeletedThreadTTL  time.Duration
+	NeverPurge        bool
+	Held              bool
+}
+
+// CanPurge reports whether anything in the thread may be purged at all.
+func (e Effective) CanPurge() bool {
+	return !e.NeverPurge && !e.Held
+}
+
+// SetPolicy validates and stores a namespace's retention policy, replacing any for the
+// same target.
+func SetPolicy(namespace string, policy models.RetentionPolicy) error {
+	if _, ok := models.ParseRetentionScope(string(policy.Scope)); !ok {
+		return fmt.Errorf("scope: must be one of thread, tag, owner")
+	}
+	if policy.Target == "" {
+		return fmt.Errorf("target: cannot be empty")
+	}
+	if policy.KeepDays < 0 || policy.PurgeDeletedDays < 0 {
+		return fmt.Errorf("keep_days and purge_deleted_days: cannot be negative")
+	}
+	if policy.NeverPurge && (policy.KeepDays != 0 || policy.PurgeDeletedDays != 0) {
+		return fmt.Errorf("never_purge: cannot be combined with keep_days or purge_deleted_days")
+	}
+	if !policy.NeverPurge && policy.KeepDays == 0 && policy.PurgeDeletedDays == 0 {
+		return fmt.Errorf("policy must set keep_days, purge_deleted_days or never_purge")
+	}
+	policy.UpdatedTS = timeutil.Now().UnixNano()
+	return indexdb.SaveRetentionPolicy(namespace, policy)
+}
+
+// DeletePolicy removes a policy. It reports whether one was set.
+func DeletePolicy(namespace string, scope models.RetentionScope, target string) (bool, error) {
+	existing, err := indexdb.GetRetentionPolicy(namespace, scope, target)
+	if err != nil || existing == nil {
+		return false, err
+	}
+	return true, indexdb.DeleteRetentionPolicy(namespace, scope, target)
+}
+
+// ListPolicies returns every policy of a namespace ordered by scope and target.
+func ListPolicies(namespace string) ([]models.RetentionPolicy, error) {
+	policies, err := indexdb.ListRetentionPolicies(namespace)
+	if err != nil {
+		return nil, err
+	}
+	if policies == nil {
+		policies = []models.RetentionPolicy{}
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		if policies[i].Scope != policies[j].Scope {
+			return policies[i].Scope < policies[j].Scope
+		}
+		return policies[i].Target < policies[j].Target
+	})
+	return policies, nil
+}
+
+// HasAgePolicies reports whether any policy in a namespace purges messages by age, so
+// retention can skip scanning its live threads otherwise.
+func HasAgePolicies(namespace string) (bool, error) {
+	policies, err := indexdb.ListRetentionPolicies(namespace)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range policies {
+		if p.KeepDays > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Resolve returns the retention for thread. A thread policy replaces tag and owner
+// policies outright; otherwise those combine with the longest retention winning. Any
+// keep_days set replaces the configured max age, so a policy can extend or shorten it.
+// Policies are looked up in the thread's own namespace.
+func Resolve(thread *models.Thread, defaults config.RetentionConfig) (Effective, error) {
+	namespace := keys.NamespaceOf(thread.Key)
+	eff := Effective{
+		MessageTTL:        defaults.MaxAge,
+		ThreadIdleTTL:     defaults.MaxIdle,
+		DeletedMessageTTL: defaults.MTTL,
+		DeletedThreadTTL:  defaults.TTTL,
+	}
+
+	held, err := indexdb.IsOnLegalHold(thread.Key)
+	if err != nil {
+		return eff, err
+	}
+	eff.Held = held
+
+	own, err := indexdb.GetRetentionPolicy(namespace, models.RetentionScopeThread, thread.Key)
+	if err != nil {
+		return eff, err
+	}
+	if own != nil {
+		apply(&eff, []models.RetentionPolicy{*own})
+		return eff, nil
+	}
+
+	var matched []models.RetentionPolicy
+	for _, tag := range thread.Tags {
+		p, err := indexdb.GetRetentionPolicy(namespace, models.RetentionScopeTag, tag)
+		if err != nil {
+			return eff, err
+		}
+		if p != nil {
+			matched = append(matched, *p)
+		}
+	}
+	if thread.Author != "" {
+		p, err := indexdb.GetRetentionPolicy(namespace, models.RetentionScopeOwner, thread.Author)
+		if err != nil {
+			return eff, err
+		}
+		if p != nil {
+			matched = append(matched, *p)
+		}
+	}
+	apply(&eff, matched)
+	return eff, nil
+}
+
+func apply(eff *Effective, matched []models.RetentionPolicy) {
+	var keep, purgeDeleted time.Duration
+	for _, p := range matched {
+		if p.NeverPurge {
+			eff.NeverPurge = true
+		}
+		if d := time.Duration(p.KeepDays) * day; d > keep {
+			keep = d
+		}
+		if d := time.Duration(p.PurgeDeletedDays) * day; d > purgeDeleted {
+			purgeDeleted = d
+		}
+	}
+	if keep > 0 {
+		eff.MessageTTL = keep
+		// an idle thread must not take messages the policy still keeps with it
+		if eff.ThreadIdleTTL > 0 && eff.ThreadIdleTTL < keep {
+			eff.ThreadIdleTTL = keep
+		}
+	}
+	if purgeDeleted > 0 {
+		eff.DeletedMessageTTL = purgeDeleted
+		eff.DeletedThreadTTL = purgeDeleted
+	}
+}
+
+// PlaceHold puts threadKey under legal hold, replacing the reason of an existing hold.
+func PlaceHold(threadKey, reason string) (*models.LegalHold, error) {
+	exists, err := threads.CheckThreadExists(threadKey)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("thread not found: %s", threadKey)
+	}
+	hold := models.LegalHold{Thread: threadKey, Reason: reason, CreatedTS: timeutil.Now().UnixNano()}
+	if existing, err := indexdb.GetLegalHold(threadKey); err != nil {
+		return nil, err
+	} else if existing != nil {
+		hold.CreatedTS = existing.CreatedTS
+	}
+	if err := indexdb.SaveLegalHold(hold); err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// ReleaseHold lifts the hold on threadKey. It reports whether one was in place.
+func ReleaseHold(threadKey string) (bool, error) {
+	held, err := indexdb.IsOnLegalHold(threadKey)
+	if err != nil || !held {
+		return false, err
+	}
+	return true, indexdb.DeleteLegalHold(threadKey)
+}
+
+// ListHolds returns every active hold in a namespace, oldest first.
+func ListHolds(namespace string) ([]models.LegalHold, error) {
+	holds, err := indexdb.ListLegalHolds(namespace)
+	if err != nil {
+		return nil, err
+	}
+	if holds == nil {
+		holds = []models.LegalHold{}
+	}
+	sort.Slice(holds, func(i, j int) bool { return holds[i].CreatedTS < holds[j].CreatedTS })
+	return holds, nil
+}
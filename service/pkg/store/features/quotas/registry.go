@@ -0,0 +1,108 @@
+package quotas
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"progressdb/pkg/config"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/db/indexdb"
+	"progressdb/pkg/timeutil"
+)
+
+const (
+	KindMessages = "messages"
+	KindThreads  = "threads"
+)
+
+// dayFormat names a UTC day in counter keys; it sorts chronologically.
+const dayFormat = "20060102"
+
+type registry struct {
+	mu     sync.Mutex
+	day    string
+	counts map[string]int // <namespace>:<kind>:<user_id> -> today's count, read through from disk
+}
+
+var reg = &registry{}
+
+// Consume counts n creations by userID in a namespace against today's limit. When they
+// would exceed it nothing is counted and the time until the quota resets at UTC
+// midnight is returned. A limit of 0 is unlimited.
+func Consume(namespace, kind, userID string, n, limit int) (bool, time.Duration, error) {
+	if limit <= 0 || n <= 0 || userID == "" {
+		return true, 0, nil
+	}
+	now := timeutil.Now().UTC()
+	day := now.Format(dayFormat)
+	reset := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC).Sub(now)
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.day != day {
+		reg.rollover(day)
+	}
+	k := namespace + ":" + kind + ":" + userID
+	count, ok := reg.counts[k]
+	if !ok {
+		var err error
+		if count, err = indexdb.GetQuotaCount(namespace, day, kind, userID); err != nil {
+			return false, 0, fmt.Errorf("failed to read quota: %w", err)
+		}
+		reg.counts[k] = count
+	}
+	if count+n > limit {
+		return false, reset, nil
+	}
+	if err := indexdb.SaveQuotaCount(namespace, day, kind, userID, count+n); err != nil {
+		return false, 0, fmt.Errorf("failed to save quota: %w", err)
+	}
+	reg.counts[k] = count + n
+	return true, 0, nil
+}
+
+// Refund gives back n creations counted today by Consume for requests that created
+// nothing. Counts from an earlier day are already gone and stay so.
+func Refund(namespace, kind, userID string, n, limit int) error {
+	if limit <= 0 || n <= 0 || userID == "" {
+		return nil
+	}
+	day := timeutil.Now().UTC().Format(dayFormat)
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.day != day {
+		return nil
+	}
+	k := namespace + ":" + kind + ":" + userID
+	count, ok := reg.counts[k]
+	if !ok {
+		return nil
+	}
+	count -= n
+	if count < 0 {
+		count = 0
+	}
+	if err := indexdb.SaveQuotaCount(namespace, day, kind, userID, count); err != nil {
+		return fmt.Errorf("failed to save quota: %w", err)
+	}
+	reg.counts[k] = count
+	return nil
+}
+
+// rollover starts a new day and drops the counters of earlier days. Callers hold the lock.
+func (r *registry) rollover(day string) {
+	r.day = day
+	r.counts = make(map[string]int)
+	for _, namespace := range config.Namespaces() {
+		n, err := indexdb.DeleteQuotaCountsBefore(namespace, day)
+		if err != nil {
+			logger.Warn("quota_prune_failed", "namespace", namespace, "error", err)
+			continue
+		}
+		if n > 0 {
+			logger.Info("quota_pruned", "namespace", namespace, "day", day, "counters", n)
+		}
+	}
+}
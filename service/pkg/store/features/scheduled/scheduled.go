@@ -0,0 +1,292 @@
+package scheduled
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"progressdb/pkg/ingest/types"
+	"progressdb/pkg/models"
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/state/telemetry"
+	"progressdb/pkg/store/db/indexdb"
+	storedb "progressdb/pkg/store/db/storedb"
+	"progressdb/pkg/store/encryption"
+	"progressdb/pkg/store/keys"
+	"progressdb/pkg/timeutil"
+)
+
+// Scheduled messages are held outside the thread's t:<thread>:m: range, so they are
+// invisible to message iterators until the scheduler releases them through ingest.
+//
+//   sched:t:<thread>:m:<ts>                 (storedb) -> message, encrypted like stored messages
+//   idx:sched:<deliverAt>:t:<thread>:m:<ts> (indexdb) -> due entry, ordered by delivery time
+
+var ErrNotFound = errors.New("scheduled message not found")
+
+// serialises release and cancel so a message is never both delivered and cancelled
+var mu sync.Mutex
+
+// dueEntry is the value of a due index key: the request that scheduled the message and,
+// once its release has begun, the timestamp its delivered key is built from.
+type dueEntry struct {
+	Key        string                `json:"key"`
+	Request    types.RequestMetadata `json:"request"`
+	ReleasedTS int64                 `json:"released_ts,omitempty"`
+}
+
+// Schedule stores msg until msg.DeliverAt, with the metadata of the request that
+// scheduled it for the release. msg.Key must be a provisional message key.
+func Schedule(msg *models.Message, req types.RequestMetadata) error {
+	tr := telemetry.Track("scheduled.schedule")
+	defer tr.Finish()
+
+	parsed, err := keys.ParseKey(msg.Key)
+	if err != nil || parsed.Type != keys.KeyTypeMessageProvisional {
+		return fmt.Errorf("invalid scheduled message key: %s", msg.Key)
+	}
+	if msg.DeliverAt == 0 {
+		return fmt.Errorf("deliver_at required for scheduled message")
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal scheduled message: %w", err)
+	}
+	data, err = encryption.EncryptMessageData(parsed.ThreadKey, data)
+	if err != nil {
+		return fmt.Errorf("encrypt scheduled message: %w", err)
+	}
+
+	// data first; a due entry without data is dropped on release
+	if err := storedb.SaveKey(keys.GenScheduledMessageKey(parsed.ThreadKey, parsed.MessageTS), data); err != nil {
+		return fmt.Errorf("save scheduled message: %w", err)
+	}
+	dueKey := keys.GenScheduledDueIndexKey(msg.DeliverAt, parsed.ThreadKey, parsed.MessageTS)
+	return saveDue(dueKey, dueEntry{Key: msg.Key, Request: req})
+}
+
+// Get returns the pending scheduled message with the given provisional key.
+func Get(messageKey string) (*models.Message, error) {
+	parsed, err := keys.ParseKey(messageKey)
+	if err != nil || parsed.Type != keys.KeyTypeMessageProvisional {
+		return nil, ErrNotFound
+	}
+	data, err := storedb.GetKey(keys.GenScheduledMessageKey(parsed.ThreadKey, parsed.MessageTS))
+	if err != nil {
+		if storedb.IsNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	kmsMeta, err := encryption.GetThreadKMS(parsed.ThreadKey)
+	if err != nil {
+		return nil, err
+	}
+	return decode(parsed.Namespace, kmsMeta, []byte(data))
+}
+
+// List returns the pending scheduled messages of a thread, soonest delivery first.
+func List(threadKey string) ([]models.Message, error) {
+	prefix, err := keys.GenScheduledThreadPrefix(threadKey)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := storedb.Iter()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	defer iter.Close()
+
+	var raw [][]byte
+	for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid(); ok = iter.Next() {
+		if !strings.HasPrefix(string(iter.Key()), prefix) {
+			break
+		}
+		raw = append(raw, append([]byte(nil), iter.Value()...))
+	}
+
+	messages := make([]models.Message, 0, len(raw))
+	if len(raw) == 0 {
+		return messages, nil
+	}
+	kmsMeta, err := encryption.GetThreadKMS(threadKey)
+	if err != nil {
+		return nil, err
+	}
+	for _, data := range raw {
+		msg, err := decode(keys.NamespaceOf(threadKey), kmsMeta, data)
+		if err != nil {
+			logger.Warn("scheduled_message_corrupt", "thread", threadKey, "error", err)
+			continue
+		}
+		messages = append(messages, *msg)
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].DeliverAt < messages[j].DeliverAt })
+	return messages, nil
+}
+
+// Cancel removes a pending scheduled message. It returns ErrNotFound once the
+// message has been released or cancelled.
+func Cancel(messageKey string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	msg, err := Get(messageKey)
+	if err != nil {
+		return err
+	}
+	return remove(msg)
+}
+
+// Due returns the due index keys of a namespace's messages whose delivery time is at or
+// before now.
+func Due(namespace string, now int64, limit int) ([]string, error) {
+	iter, err := indexdb.DBIter()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	defer iter.Close()
+
+	prefix := keys.GenScheduledDuePrefix(namespace)
+	upper := prefix + keys.PadTS(now) + ";"
+	var due []string
+	for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid() && len(due) < limit; ok = iter.Next() {
+		key := string(iter.Key())
+		if !strings.HasPrefix(key, prefix) || key >= upper {
+			break
+		}
+		due = append(due, key)
+	}
+	return due, nil
+}
+
+// Release hands the message behind a due index key to deliver and removes it once
+// deliver succeeds. A deliver error leaves the message pending for the next attempt.
+// The delivered key's timestamp is fixed before the first attempt, so a release retried
+// after its message was enqueued delivers under the same key instead of a second copy.
+func Release(dueKey string, deliver func(msg *models.Message, req types.RequestMetadata, releasedTS int64) error) error {
+	parsed, err := keys.ParseKey(dueKey)
+	if err != nil || parsed.Type != keys.KeyTypeScheduledDueIndex {
+		return fmt.Errorf("invalid scheduled due key: %s", dueKey)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	msg, err := Get(parsed.MessageProvKey)
+	if errors.Is(err, ErrNotFound) {
+		// cancelled or purged with its thread
+		return indexdb.DeleteKey(dueKey)
+	}
+	if err != nil {
+		return err
+	}
+	due, err := getDue(dueKey, msg)
+	if err != nil {
+		return err
+	}
+	if due.ReleasedTS == 0 {
+		due.ReleasedTS = timeutil.Now().UnixNano()
+		if err := saveDue(dueKey, due); err != nil {
+			return err
+		}
+	}
+	if err := deliver(msg, due.Request, due.ReleasedTS); err != nil {
+		return err
+	}
+	return remove(msg)
+}
+
+// DeleteThread drops every pending scheduled message of a thread.
+func DeleteThread(threadKey string) error {
+	parsedThread, err := keys.ParseKey(threadKey)
+	if err != nil || parsedThread.Type != keys.KeyTypeThread {
+		return fmt.Errorf("invalid thread key: %s", threadKey)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	iter, err := indexdb.DBIter()
+	if err != nil {
+		return fmt.Errorf("failed to create DB iterator: %w", err)
+	}
+	prefix := keys.GenScheduledDuePrefix(parsedThread.Namespace)
+	var dueKeys []string
+	var dataKeys []string
+	for ok := iter.SeekGE([]byte(prefix)); ok && iter.Valid(); ok = iter.Next() {
+		key := string(iter.Key())
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+		parsed, err := keys.ParseKey(key)
+		if err != nil || parsed.ThreadTS != parsedThread.ThreadTS {
+			continue
+		}
+		dueKeys = append(dueKeys, key)
+		dataKeys = append(dataKeys, keys.GenScheduledMessageKey(parsed.ThreadKey, parsed.MessageTS))
+	}
+	iter.Close()
+
+	for i := range dueKeys {
+		if err := indexdb.DeleteKey(dueKeys[i]); err != nil {
+			return err
+		}
+		if err := storedb.DeleteKey(dataKeys[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func remove(msg *models.Message) error {
+	parsed, err := keys.ParseKey(msg.Key)
+	if err != nil {
+		return fmt.Errorf("invalid scheduled message key: %s", msg.Key)
+	}
+	if err := indexdb.DeleteKey(keys.GenScheduledDueIndexKey(msg.DeliverAt, parsed.ThreadKey, parsed.MessageTS)); err != nil {
+		return err
+	}
+	return storedb.DeleteKey(keys.GenScheduledMessageKey(parsed.ThreadKey, parsed.MessageTS))
+}
+
+func saveDue(dueKey string, due dueEntry) error {
+	data, err := json.Marshal(due)
+	if err != nil {
+		return fmt.Errorf("marshal scheduled due index: %w", err)
+	}
+	if err := indexdb.SaveKey(dueKey, data); err != nil {
+		return fmt.Errorf("save scheduled due index: %w", err)
+	}
+	return nil
+}
+
+// getDue reads the due entry of msg. Entries written before it carried the request hold
+// only the message key; the author stands in for the request.
+func getDue(dueKey string, msg *models.Message) (dueEntry, error) {
+	data, err := indexdb.GetKey(dueKey)
+	if err != nil && !indexdb.IsNotFound(err) {
+		return dueEntry{}, err
+	}
+	var due dueEntry
+	if err != nil || json.Unmarshal([]byte(data), &due) != nil {
+		due = dueEntry{Key: msg.Key, Request: types.RequestMetadata{UserID: msg.Author}}
+	}
+	return due, nil
+}
+
+func decode(namespace string, kmsMeta *models.KMSMeta, data []byte) (*models.Message, error) {
+	decrypted, err := encryption.DecryptMessageData(namespace, kmsMeta, data)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt scheduled message: %w", err)
+	}
+	var msg models.Message
+	if err := json.Unmarshal(decrypted, &msg); err != nil {
+		return nil, fmt.Errorf("unmarshal scheduled message: %w", err)
+	}
+	return &msg, nil
+}
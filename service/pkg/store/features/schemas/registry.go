@@ -0,0 +1,221 @@
+package schemas
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"progressdb/pkg/state/logger"
+	"progressdb/pkg/store/db/indexdb"
+	"progressdb/pkg/store/keys"
+)
+
+const (
+	ScopeGlobal = "global"
+	ScopeTag    = "tag"
+)
+
+// Entry is a registered schema as returned to admins.
+type Entry struct {
+	Scope  string          `json:"scope"`
+	Tag    string          `json:"tag,omitempty"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// registry holds the schemas of one namespace.
+type registry struct {
+	mu        sync.RWMutex
+	namespace string
+	loaded    bool
+	global    *Schema
+	tags      map[string]*Schema
+}
+
+var (
+	regsMu sync.Mutex
+	regs   = make(map[string]*registry)
+)
+
+func registryFor(namespace string) *registry {
+	regsMu.Lock()
+	defer regsMu.Unlock()
+	reg, ok := regs[namespace]
+	if !ok {
+		reg = &registry{namespace: namespace, tags: make(map[string]*Schema)}
+		regs[namespace] = reg
+	}
+	return reg
+}
+
+// SetGlobal compiles and stores the schema every message body of a namespace must satisfy.
+func SetGlobal(namespace string, raw []byte) error {
+	return set(namespace, keys.GenSchemaGlobalKey(namespace), "", raw)
+}
+
+// SetTag compiles and stores the schema for message bodies in threads carrying tag.
+func SetTag(namespace, tag string, raw []byte) error {
+	return set(namespace, keys.GenSchemaTagKey(namespace, tag), tag, raw)
+}
+
+func set(namespace, key, tag string, raw []byte) error {
+	schema, err := Compile(raw)
+	if err != nil {
+		return err
+	}
+	reg := registryFor(namespace)
+	if err := reg.ensureLoaded(); err != nil {
+		return err
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if err := indexdb.SaveSchema(key, raw); err != nil {
+		return fmt.Errorf("failed to save schema: %w", err)
+	}
+	if tag == "" {
+		reg.global = schema
+	} else {
+		reg.tags[tag] = schema
+	}
+	return nil
+}
+
+// DeleteGlobal removes a namespace's global schema. It reports whether one was registered.
+func DeleteGlobal(namespace string) (bool, error) {
+	return remove(namespace, keys.GenSchemaGlobalKey(namespace), "")
+}
+
+// DeleteTag removes the schema for tag. It reports whether one was registered.
+func DeleteTag(namespace, tag string) (bool, error) {
+	return remove(namespace, keys.GenSchemaTagKey(namespace, tag), tag)
+}
+
+func remove(namespace, key, tag string) (bool, error) {
+	reg := registryFor(namespace)
+	if err := reg.ensureLoaded(); err != nil {
+		return false, err
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	existed := reg.global != nil
+	if tag != "" {
+		_, existed = reg.tags[tag]
+	}
+	if !existed {
+		return false, nil
+	}
+	if err := indexdb.DeleteSchema(key); err != nil {
+		return false, fmt.Errorf("failed to delete schema: %w", err)
+	}
+	if tag == "" {
+		reg.global = nil
+	} else {
+		delete(reg.tags, tag)
+	}
+	return true, nil
+}
+
+// List returns a namespace's stored schema documents, global first and then by tag.
+func List(namespace string) ([]Entry, error) {
+	stored, err := indexdb.ListSchemas(namespace)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(stored))
+	for key, raw := range stored {
+		entry := Entry{Scope: ScopeGlobal, Schema: json.RawMessage(raw)}
+		if key != keys.GenSchemaGlobalKey(namespace) {
+			entry.Scope = ScopeTag
+			entry.Tag = strings.TrimPrefix(key, keys.GenSchemaTagKey(namespace, ""))
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Scope != entries[j].Scope {
+			return entries[i].Scope == ScopeGlobal
+		}
+		return entries[i].Tag < entries[j].Tag
+	})
+	return entries, nil
+}
+
+// HasTagSchemas reports whether any per-tag schema is registered in a namespace, so
+// callers can skip loading thread metadata when tags cannot affect the outcome.
+func HasTagSchemas(namespace string) (bool, error) {
+	reg := registryFor(namespace)
+	if err := reg.ensureLoaded(); err != nil {
+		return false, err
+	}
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return len(reg.tags) > 0, nil
+}
+
+// ValidationError is returned when a body does not match its schemas.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("body does not match schema: %d field errors", len(e.Fields))
+}
+
+// ValidateBody checks a decoded message body against a namespace's global schema and
+// the schemas of every tag on the thread.
+func ValidateBody(namespace string, body interface{}, tags []string) ([]FieldError, error) {
+	reg := registryFor(namespace)
+	if err := reg.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	var errs []FieldError
+	if reg.global != nil {
+		errs = append(errs, reg.global.Validate("body", body)...)
+	}
+	for _, tag := range tags {
+		if schema, ok := reg.tags[tag]; ok {
+			errs = append(errs, schema.Validate("body", body)...)
+		}
+	}
+	return errs, nil
+}
+
+func (r *registry) ensureLoaded() error {
+	r.mu.RLock()
+	loaded := r.loaded
+	r.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loaded {
+		return nil
+	}
+	stored, err := indexdb.ListSchemas(r.namespace)
+	if err != nil {
+		return fmt.Errorf("failed to load schemas: %w", err)
+	}
+	tagPrefix := keys.GenSchemaTagKey(r.namespace, "")
+	for key, raw := range stored {
+		schema, err := Compile(raw)
+		if err != nil {
+			// keep serving; a broken document should not block every write
+			logger.Error("schema_load_failed", "key", key, "error", err)
+			continue
+		}
+		if key == keys.GenSchemaGlobalKey(r.namespace) {
+			r.global = schema
+		} else if strings.HasPrefix(key, tagPrefix) {
+			r.tags[strings.TrimPrefix(key, tagPrefix)] = schema
+		}
+	}
+	r.loaded = true
+	return nil
+}
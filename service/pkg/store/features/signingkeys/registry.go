@@ -0,0 +1,377 @@
+package signingkeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"progressdb/pkg/config"
+	"progressdb/pkg/models"
+	"progressdb/pkg/store/db/indexdb"
+	"progressdb/pkg/store/encryption"
+	"progressdb/pkg/timeutil"
+)
+
+const (
+	SourceConfig  = "config"
+	SourceRuntime = "runtime"
+)
+
+var (
+	ErrNotFound    = errors.New("signing key not found")
+	ErrExists      = errors.New("signing key already exists")
+	ErrInvalidID   = errors.New("id: must be 1-64 letters, digits, '.', '_' or '-'")
+	ErrNoActiveKey = errors.New("no active signing key")
+	ErrActiveKey   = errors.New("the active key cannot be demoted; activate another key first")
+)
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ConfigKeyID derives the stable, non-secret ID of a key from the config file.
+func ConfigKeyID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
+}
+
+// registry holds the signing keys of one namespace.
+type registry struct {
+	mu        sync.RWMutex
+	namespace string
+	loaded    bool
+	keys      []*models.SigningKey // config keys in file order, then runtime keys by creation
+}
+
+var (
+	regsMu sync.Mutex
+	regs   = make(map[string]*registry)
+)
+
+func registryFor(namespace string) *registry {
+	regsMu.Lock()
+	defer regsMu.Unlock()
+	reg, ok := regs[namespace]
+	if !ok {
+		reg = &registry{namespace: namespace}
+		regs[namespace] = reg
+	}
+	return reg
+}
+
+// Active returns the key a namespace's new signatures and tokens are signed with.
+func Active(namespace string) (models.SigningKey, error) {
+	reg := registryFor(namespace)
+	if err := reg.ensureLoaded(); err != nil {
+		return models.SigningKey{}, err
+	}
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	for _, k := range reg.keys {
+		if k.State == models.SigningKeyActive {
+			return *k, nil
+		}
+	}
+	return models.SigningKey{}, ErrNoActiveKey
+}
+
+// Lookup returns the secret of a namespace's key that may still verify, i.e. is not
+// retired.
+func Lookup(namespace, id string) (string, bool) {
+	reg := registryFor(namespace)
+	if reg.ensureLoaded() != nil {
+		return "", false
+	}
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	k := reg.find(id)
+	if k == nil || k.State == models.SigningKeyRetired {
+		return "", false
+	}
+	return k.Secret, true
+}
+
+// Verifiable returns the secrets of every key of a namespace that is not retired, for
+// signatures that do not name their key.
+func Verifiable(namespace string) []string {
+	reg := registryFor(namespace)
+	if reg.ensureLoaded() != nil {
+		return nil
+	}
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	secrets := make([]string, 0, len(reg.keys))
+	for _, k := range reg.keys {
+		if k.State != models.SigningKeyRetired {
+			secrets = append(secrets, k.Secret)
+		}
+	}
+	return secrets
+}
+
+// List returns every key of a namespace without its secret.
+func List(namespace string) ([]models.SigningKey, error) {
+	reg := registryFor(namespace)
+	if err := reg.ensureLoaded(); err != nil {
+		return nil, err
+	}
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	out := make([]models.SigningKey, len(reg.keys))
+	for i, k := range reg.keys {
+		out[i] = withoutSecret(k)
+	}
+	return out, nil
+}
+
+// Rotate generates a new key and makes it active; the previously active key is kept
+// for verification. An empty id is replaced with a generated one.
+func Rotate(namespace, id string) (models.SigningKey, error) {
+	reg := registryFor(namespace)
+	if err := reg.ensureLoaded(); err != nil {
+		return models.SigningKey{}, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return models.SigningKey{}, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	now := timeutil.Now().UnixNano()
+	if id == "" {
+		id = fmt.Sprintf("key-%d", now)
+	}
+	if !keyIDPattern.MatchString(id) {
+		return models.SigningKey{}, ErrInvalidID
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.find(id) != nil {
+		return models.SigningKey{}, ErrExists
+	}
+	key := &models.SigningKey{
+		ID:        id,
+		Secret:    hex.EncodeToString(secret),
+		State:     models.SigningKeyActive,
+		Source:    SourceRuntime,
+		CreatedTS: now,
+		UpdatedTS: now,
+	}
+	if err := seal(key); err != nil {
+		return models.SigningKey{}, err
+	}
+	if err := reg.demoteActive(now); err != nil {
+		return models.SigningKey{}, err
+	}
+	if err := indexdb.SaveSigningKey(namespace, stored(key)); err != nil {
+		return models.SigningKey{}, fmt.Errorf("failed to save signing key: %w", err)
+	}
+	reg.keys = append(reg.keys, key)
+	return withoutSecret(key), nil
+}
+
+// SetState moves a key between states. Activating a key demotes the current active
+// key to verify-only.
+func SetState(namespace, id string, state models.SigningKeyState) (models.SigningKey, error) {
+	reg := registryFor(namespace)
+	if err := reg.ensureLoaded(); err != nil {
+		return models.SigningKey{}, err
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	key := reg.find(id)
+	if key == nil {
+		return models.SigningKey{}, ErrNotFound
+	}
+	if key.State == state {
+		return withoutSecret(key), nil
+	}
+	if key.State == models.SigningKeyActive {
+		return models.SigningKey{}, ErrActiveKey
+	}
+
+	now := timeutil.Now().UnixNano()
+	if state == models.SigningKeyActive {
+		if err := reg.demoteActive(now); err != nil {
+			return models.SigningKey{}, err
+		}
+	}
+	if err := reg.save(key, state, now); err != nil {
+		return models.SigningKey{}, err
+	}
+	return withoutSecret(key), nil
+}
+
+func (r *registry) find(id string) *models.SigningKey {
+	for _, k := range r.keys {
+		if k.ID == id {
+			return k
+		}
+	}
+	return nil
+}
+
+// demoteActive moves the active key, if any, to verify-only. Callers hold the lock.
+func (r *registry) demoteActive(now int64) error {
+	for _, k := range r.keys {
+		if k.State == models.SigningKeyActive {
+			return r.save(k, models.SigningKeyVerify, now)
+		}
+	}
+	return nil
+}
+
+// save persists a state change.
+func (r *registry) save(key *models.SigningKey, state models.SigningKeyState, now int64) error {
+	record := stored(key)
+	record.State, record.UpdatedTS = state, now
+	if err := indexdb.SaveSigningKey(r.namespace, record); err != nil {
+		return fmt.Errorf("failed to save signing key: %w", err)
+	}
+	key.State, key.UpdatedTS = state, now
+	return nil
+}
+
+func (r *registry) ensureLoaded() error {
+	r.mu.RLock()
+	loaded := r.loaded
+	r.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loaded {
+		return nil
+	}
+	saved, err := indexdb.ListSigningKeys(r.namespace)
+	if err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+	records := make(map[string]models.SigningKey, len(saved))
+	for _, k := range saved {
+		records[k.ID] = k
+	}
+
+	// config keys: the first signs and the rest verify unless an admin changed them
+	var keys []*models.SigningKey
+	for i, secret := range config.GetSigningKeyList(r.namespace) {
+		key := &models.SigningKey{ID: ConfigKeyID(secret), Secret: secret, State: models.SigningKeyVerify, Source: SourceConfig}
+		if i == 0 {
+			key.State = models.SigningKeyActive
+		}
+		if rec, ok := records[key.ID]; ok {
+			key.State, key.CreatedTS, key.UpdatedTS = rec.State, rec.CreatedTS, rec.UpdatedTS
+		}
+		keys = append(keys, key)
+	}
+	var runtime []*models.SigningKey
+	for _, rec := range records {
+		if rec.Source != SourceRuntime {
+			continue
+		}
+		rec := rec
+		if err := unseal(r.namespace, &rec); err != nil {
+			return fmt.Errorf("failed to unseal signing key %s: %w", rec.ID, err)
+		}
+		if rec.Secret != "" {
+			runtime = append(runtime, &rec)
+		}
+	}
+	sort.Slice(runtime, func(i, j int) bool { return runtime[i].CreatedTS < runtime[j].CreatedTS })
+	keys = append(keys, runtime...)
+
+	// the most recently activated key wins; if none is active the first usable key signs
+	var active *models.SigningKey
+	for _, k := range keys {
+		if k.State != models.SigningKeyActive {
+			continue
+		}
+		if active == nil || k.UpdatedTS > active.UpdatedTS {
+			if active != nil {
+				active.State = models.SigningKeyVerify
+			}
+			active = k
+		} else {
+			k.State = models.SigningKeyVerify
+		}
+	}
+	if active == nil {
+		for _, k := range keys {
+			if k.State != models.SigningKeyRetired {
+				k.State = models.SigningKeyActive
+				break
+			}
+		}
+	}
+
+	r.keys = keys
+	r.loaded = true
+	return nil
+}
+
+// seal encrypts a runtime key's secret under a data key of its own, so the stored record
+// never holds it in the clear. Without encryption the secret is stored as is.
+func seal(key *models.SigningKey) error {
+	if key.Source != SourceRuntime || !encryption.EncryptionEnabled() {
+		return nil
+	}
+	if key.SecretKeyID == "" {
+		keyID, _, _, _, err := encryption.CreateDEK()
+		if err != nil {
+			return fmt.Errorf("failed to create signing key DEK: %w", err)
+		}
+		key.SecretKeyID = keyID
+	}
+	sealed, _, err := encryption.EncryptWithDEK(key.SecretKeyID, []byte(key.Secret), []byte(key.ID))
+	if err != nil {
+		return fmt.Errorf("failed to seal signing key: %w", err)
+	}
+	key.SealedSecret = base64.StdEncoding.EncodeToString(sealed)
+	return nil
+}
+
+// unseal restores the secret of a stored runtime key. Records written before secrets
+// were sealed are sealed and saved again.
+func unseal(namespace string, rec *models.SigningKey) error {
+	if rec.SealedSecret == "" {
+		if rec.Secret == "" || !encryption.EncryptionEnabled() {
+			return nil
+		}
+		if err := seal(rec); err != nil {
+			return err
+		}
+		return indexdb.SaveSigningKey(namespace, stored(rec))
+	}
+	sealed, err := base64.StdEncoding.DecodeString(rec.SealedSecret)
+	if err != nil {
+		return err
+	}
+	secret, err := encryption.DecryptWithDEK(rec.SecretKeyID, sealed, []byte(rec.ID))
+	if err != nil {
+		return err
+	}
+	rec.Secret = string(secret)
+	return nil
+}
+
+// stored returns the record persisted for a key: config keys keep no secret, sealed
+// runtime keys only their sealed one.
+func stored(key *models.SigningKey) models.SigningKey {
+	record := *key
+	if record.Source == SourceConfig || record.SealedSecret != "" {
+		record.Secret = ""
+	}
+	return record
+}
+
+func withoutSecret(key *models.SigningKey) models.SigningKey {
+	out := *key
+	out.Secret, out.SealedSecret, out.SecretKeyID = "", "", ""
+	return out
+}
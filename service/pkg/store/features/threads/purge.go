@@ -18,6 +18,11 @@ func PurgeThreadPermanently(threadKey string) error {
 	if threadKey == "" {
 		return fmt.Errorf("threadKey cannot be empty")
 	}
+	if held, err := indexdb.IsOnLegalHold(threadKey); err != nil {
+		return err
+	} else if held {
+		return fmt.Errorf("%w: %s", models.ErrLegalHold, threadKey)
+	}
 
 	// Store: delete all messages in thread
 	if err := deleteAllMessagesInThread(threadKey); err != nil {
@@ -129,7 +134,11 @@ func DeleteAllThreadIndexes(threadKey string) error {
 		return fmt.Errorf("pebble not opened; call Open first")
 	}
 
-	prefix := fmt.Sprintf("idx:t:%s:", threadKey)
+	parsed, err := keys.ParseKey(threadKey)
+	if err != nil || parsed.Type != keys.KeyTypeThread {
+		return fmt.Errorf("invalid thread key: %s", threadKey)
+	}
+	prefix := keys.Namespaced(parsed.Namespace, fmt.Sprintf("idx:t:%s:", parsed.ThreadTS))
 	iter, err := indexdb.Client.NewIter(&pebble.IterOptions{
 		LowerBound: []byte(prefix),
 		UpperBound: calculateUpperBound(prefix),
@@ -23,7 +23,7 @@ func NewThreadIterator(db *pebble.DB) *ThreadIterator {
 	}
 }
 
-func (ti *ThreadIterator) ExecuteThreadQuery(userID string, req pagination.PaginationRequest) ([]string, pagination.PaginationResponse, error) {
+func (ti *ThreadIterator) ExecuteThreadQuery(namespace, userID string, req pagination.PaginationRequest) ([]string, pagination.PaginationResponse, error) {
 	logger.Debug("Admin ThreadIterator query started",
 		"userID", userID,
 		"limit", req.Limit,
@@ -32,7 +32,7 @@ func (ti *ThreadIterator) ExecuteThreadQuery(userID string, req pagination.Pagin
 		"sortBy", req.SortBy)
 
 	// Generate user thread relationship prefix
-	userThreadPrefix, err := keys.GenUserThreadRelPrefix(userID)
+	userThreadPrefix, err := keys.GenUserThreadRelPrefix(namespace, userID)
 	if err != nil {
 		logger.Error("Admin ThreadIterator failed to generate prefix", "userID", userID, "error", err)
 		return nil, pagination.PaginationResponse{}, fmt.Errorf("failed to generate user thread prefix: %w", err)
@@ -52,7 +52,7 @@ func (ti *ThreadIterator) ExecuteThreadQuery(userID string, req pagination.Pagin
 		"hasBefore", response.HasBefore)
 
 	// Get total count of all threads for this user
-	total, err := ti.getTotalThreadCount(userID)
+	total, err := ti.getTotalThreadCount(namespace, userID)
 	if err != nil {
 		// Log error but don't fail request
 		total = 0
@@ -76,8 +76,8 @@ func (ti *ThreadIterator) ExecuteThreadQuery(userID string, req pagination.Pagin
 	return threadKeys, response, nil
 }
 
-func (ti *ThreadIterator) getTotalThreadCount(userID string) (int, error) {
-	userThreadPrefix, err := keys.GenUserThreadRelPrefix(userID)
+func (ti *ThreadIterator) getTotalThreadCount(namespace, userID string) (int, error) {
+	userThreadPrefix, err := keys.GenUserThreadRelPrefix(namespace, userID)
 	if err != nil {
 		return 0, err
 	}
@@ -6,6 +6,7 @@ import (
 	"progressdb/pkg/models"
 	"progressdb/pkg/store/db/storedb"
 	"progressdb/pkg/store/encryption"
+	"progressdb/pkg/store/keys"
 )
 
 type MessageFetcher struct{}
@@ -45,7 +46,7 @@ func (mf *MessageFetcher) FetchMessages(messageKeys []string) ([]models.Message,
 		}
 		defer closer.Close()
 
-		decryptedData, err := encryption.DecryptMessageData(kmsMeta, value)
+		decryptedData, err := encryption.DecryptMessageData(keys.NamespaceOf(firstMessage.Thread), kmsMeta, value)
 		if err != nil {
 			println("[mi/fetcher] Failed to decrypt message key:", messageKey, "err:", err.Error())
 			continue
@@ -3,12 +3,13 @@ package mi
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/cockroachdb/pebble"
 	"progressdb/pkg/store/db/indexdb"
-	"progressdb/pkg/store/db/storedb"
 	"progressdb/pkg/store/keys"
 	"progressdb/pkg/store/pagination"
+	"progressdb/pkg/timeutil"
 )
 
 type MessageIterator struct {
@@ -93,13 +94,19 @@ func (mi *MessageIterator) GetMessageCountExcludingDeleted(threadKey string) (in
 		return mi.countMessagesByIteration(threadKey)
 	}
 
-	// Count deleted messages only
-	deletedCount, err := mi.getDeletedMessagesCount(threadKey)
+	// Deleted messages
+	deleted, err := mi.getDeletedMessageKeys(threadKey)
 	if err != nil {
 		return 0, err
 	}
 
-	activeCount := totalCount - deletedCount
+	// Expired messages stay hidden until retention purges them; deleted ones are already counted
+	expiredCount, err := mi.getExpiredMessagesCount(threadKey, deleted)
+	if err != nil {
+		return 0, err
+	}
+
+	activeCount := totalCount - len(deleted) - expiredCount
 	if activeCount < 0 {
 		activeCount = 0 // Safety check
 	}
@@ -138,28 +145,60 @@ func (mi *MessageIterator) getTotalMessageCountFromIndex(threadKey string) (int,
 	return totalCount, nil
 }
 
-func (mi *MessageIterator) getDeletedMessagesCount(threadKey string) (int, error) {
-	// Count delete markers with prefix "del:{thread_key}:m:"
+func (mi *MessageIterator) getDeletedMessageKeys(threadKey string) (map[string]bool, error) {
+	// Collect delete markers with prefix "del:{thread_key}:m:"
 	threadMessagePrefix, err := keys.GenAllThreadMessagesPrefix(threadKey)
 	if err != nil {
-		return 0, fmt.Errorf("failed to generate thread message prefix: %w", err)
+		return nil, fmt.Errorf("failed to generate thread message prefix: %w", err)
 	}
-	deletePrefix := keys.GenSoftDeletePrefix() + threadMessagePrefix
+	namespace := keys.NamespaceOf(threadKey)
+	deletePrefix := keys.GenSoftDeleteMarkerKey(threadMessagePrefix)
 
-	// Use StoreDB iterator to scan delete markers directly
-	iter, err := storedb.Client.NewIter(&pebble.IterOptions{
+	iter, err := indexdb.Client.NewIter(&pebble.IterOptions{
 		LowerBound: []byte(deletePrefix),
 		UpperBound: nextPrefix([]byte(deletePrefix)),
 	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to create iterator for delete markers: %w", err)
+		return nil, fmt.Errorf("failed to create iterator for delete markers: %w", err)
 	}
 	defer iter.Close()
 
+	deleted := make(map[string]bool)
+	for iter.First(); iter.Valid(); iter.Next() {
+		deleted[keys.Namespaced(namespace, strings.TrimPrefix(string(iter.Key()), keys.GenSoftDeletePrefix(namespace)))] = true
+	}
+
+	return deleted, nil
+}
+
+func (mi *MessageIterator) getExpiredMessagesCount(threadKey string, skip map[string]bool) (int, error) {
+	// Count expiry markers "exp:{thread_key}:m:" that have passed
+	threadMessagePrefix, err := keys.GenAllThreadMessagesPrefix(threadKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate thread message prefix: %w", err)
+	}
+	namespace := keys.NamespaceOf(threadKey)
+	expiryPrefix := keys.GenExpiryMarkerKey(threadMessagePrefix)
+
+	iter, err := indexdb.Client.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(expiryPrefix),
+		UpperBound: nextPrefix([]byte(expiryPrefix)),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create iterator for expiry markers: %w", err)
+	}
+	defer iter.Close()
+
+	now := timeutil.Now().UnixNano()
 	count := 0
-	for iter.Valid() {
-		iter.Next()
-		count++
+	for iter.First(); iter.Valid(); iter.Next() {
+		if skip[keys.Namespaced(namespace, strings.TrimPrefix(string(iter.Key()), keys.GenExpiryPrefix(namespace)))] {
+			continue
+		}
+		expiresAt, err := strconv.ParseInt(string(iter.Value()), 10, 64)
+		if err == nil && expiresAt <= now {
+			count++
+		}
 	}
 
 	return count, nil
@@ -6,6 +6,7 @@ import (
 	"progressdb/pkg/store/db/storedb"
 	"progressdb/pkg/store/keys"
 	"progressdb/pkg/store/pagination"
+	"progressdb/pkg/timeutil"
 
 	"github.com/cockroachdb/pebble"
 )
@@ -19,10 +20,9 @@ func NewKeyManager() *KeyManager {
 }
 
 func (km *KeyManager) ExecuteKeyQuery(threadKey, prefix string, req pagination.PaginationRequest) ([]string, error) {
+	now := timeutil.Now().UnixNano()
 	isDeleted := func(messageKey string) bool {
-		deleteMarkerKey := keys.GenSoftDeleteMarkerKey(messageKey)
-		_, err := indexdb.GetKey(deleteMarkerKey)
-		return err == nil // Marker exists = message deleted
+		return isHidden(messageKey, now)
 	}
 
 	logger.Debug("[MI KeyManager] Query",
@@ -280,10 +280,9 @@ func (km *KeyManager) checkHasKeysBefore(prefix, reference string) bool {
 		valid = iter.Prev()
 	}
 
+	now := timeutil.Now().UnixNano()
 	isDeleted := func(messageKey string) bool {
-		deleteMarkerKey := keys.GenSoftDeleteMarkerKey(messageKey)
-		_, err := indexdb.GetKey(deleteMarkerKey)
-		return err == nil
+		return isHidden(messageKey, now)
 	}
 
 	checks := 0
@@ -322,10 +321,9 @@ func (km *KeyManager) checkHasKeysAfter(prefix, reference string) bool {
 		valid = iter.Next()
 	}
 
+	now := timeutil.Now().UnixNano()
 	isDeleted := func(messageKey string) bool {
-		deleteMarkerKey := keys.GenSoftDeleteMarkerKey(messageKey)
-		_, err := indexdb.GetKey(deleteMarkerKey)
-		return err == nil
+		return isHidden(messageKey, now)
 	}
 
 	checks := 0
@@ -343,6 +341,16 @@ func (km *KeyManager) checkHasKeysAfter(prefix, reference string) bool {
 	return false
 }
 
+// isHidden reports whether a message is soft deleted or has expired
+func isHidden(messageKey string, now int64) bool {
+	deleteMarkerKey := keys.GenSoftDeleteMarkerKey(messageKey)
+	if _, err := indexdb.GetKey(deleteMarkerKey); err == nil {
+		return true // Marker exists = message deleted
+	}
+	expired, err := indexdb.IsExpired(messageKey, now)
+	return err == nil && expired
+}
+
 // reverses a slice of keys to handle database iteration direction
 func reverseKeys(keys []string) []string {
 	for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
@@ -33,9 +33,9 @@ func NewThreadIterator(db *pebble.DB) *ThreadIterator {
 	}
 }
 
-func (ti *ThreadIterator) ExecuteThreadQuery(userID string, req pagination.PaginationRequest) ([]string, pagination.PaginationResponse, error) {
+func (ti *ThreadIterator) ExecuteThreadQuery(namespace, userID string, req pagination.PaginationRequest) ([]string, pagination.PaginationResponse, error) {
 	// 1. Generate user thread prefix
-	userThreadPrefix, err := keys.GenUserThreadRelPrefix(userID)
+	userThreadPrefix, err := keys.GenUserThreadRelPrefix(namespace, userID)
 	if err != nil {
 		return nil, pagination.PaginationResponse{}, fmt.Errorf("failed to generate user thread prefix: %w", err)
 	}
@@ -68,12 +68,12 @@ func (ti *ThreadIterator) ExecuteThreadQuery(userID string, req pagination.Pagin
 	threads = ti.sorter.SortThreads(threads, req.SortBy)
 
 	// 8. Calculate pagination metadata
-	total, err := ti.getTotalThreadCount(userID)
+	total, err := ti.getTotalThreadCount(namespace, userID)
 	if err != nil {
 		total = 0
 	}
 
-	paginationResp := ti.paging.CalculatePagination(threads, total, req, userID)
+	paginationResp := ti.paging.CalculatePagination(threads, total, req, namespace, userID)
 
 	// 9. Return thread keys for API response
 	finalThreadKeys := make([]string, len(threads))
@@ -92,43 +92,29 @@ func (ti *ThreadIterator) ExecuteThreadQuery(userID string, req pagination.Pagin
 func (ti *ThreadIterator) transformRequestKeys(userID string, req pagination.PaginationRequest) pagination.PaginationRequest {
 	transformed := req
 
+	// thread keys are converted to relationship keys
 	if req.Before != "" {
-		if !strings.HasPrefix(req.Before, "rel:u:") {
-			// Convert thread key to relationship key
-			transformed.Before = ti.threadKeyToRelKey(userID, req.Before)
-		}
+		transformed.Before = userThreadRelKey(userID, req.Before)
 	}
 	if req.After != "" {
-		if !strings.HasPrefix(req.After, "rel:u:") {
-			transformed.After = ti.threadKeyToRelKey(userID, req.After)
-		}
+		transformed.After = userThreadRelKey(userID, req.After)
 	}
 	if req.Anchor != "" {
-		if !strings.HasPrefix(req.Anchor, "rel:u:") {
-			transformed.Anchor = ti.threadKeyToRelKey(userID, req.Anchor)
-		}
+		transformed.Anchor = userThreadRelKey(userID, req.Anchor)
 	}
 
 	return transformed
 }
 
-func (ti *ThreadIterator) threadKeyToRelKey(userID, threadKey string) string {
-	if strings.HasPrefix(threadKey, "t:") {
-		threadTS := strings.TrimPrefix(threadKey, "t:")
-		return fmt.Sprintf(keys.RelUserOwnsThread, userID, threadTS)
-	}
-	return threadKey
-}
-
-func (ti *ThreadIterator) getTotalThreadCount(userID string) (int, error) {
+func (ti *ThreadIterator) getTotalThreadCount(namespace, userID string) (int, error) {
 	// Get total relationship keys count (includes deleted threads)
-	totalRelKeys, err := ti.getTotalRelationshipKeysCount(userID)
+	totalRelKeys, err := ti.getTotalRelationshipKeysCount(namespace, userID)
 	if err != nil {
 		return 0, err
 	}
 
 	// Count deleted threads for this user
-	deletedCount, err := ti.getDeletedThreadCount(userID)
+	deletedCount, err := ti.getDeletedThreadCount(namespace, userID)
 	if err != nil {
 		return 0, err
 	}
@@ -142,8 +128,8 @@ func (ti *ThreadIterator) getTotalThreadCount(userID string) (int, error) {
 }
 
 // getTotalRelationshipKeysCount counts all user-thread relationship keys
-func (ti *ThreadIterator) getTotalRelationshipKeysCount(userID string) (int, error) {
-	userThreadPrefix, err := keys.GenUserThreadRelPrefix(userID)
+func (ti *ThreadIterator) getTotalRelationshipKeysCount(namespace, userID string) (int, error) {
+	userThreadPrefix, err := keys.GenUserThreadRelPrefix(namespace, userID)
 	if err != nil {
 		return 0, err
 	}
@@ -177,10 +163,10 @@ func (ti *ThreadIterator) getTotalRelationshipKeysCount(userID string) (int, err
 	return totalCount, nil
 }
 
-func (ti *ThreadIterator) getDeletedThreadCount(userID string) (int, error) {
+func (ti *ThreadIterator) getDeletedThreadCount(namespace, userID string) (int, error) {
 	// Count delete markers with prefix "del:t:" for threads owned by this user
 	// We need to scan all thread delete markers and check if they belong to this user
-	deletePrefix := keys.GenSoftDeletePrefix() + keys.GenThreadMetadataPrefix()
+	deletePrefix := keys.GenSoftDeleteMarkerKey(keys.GenThreadMetadataPrefix(namespace))
 
 	// Use StoreDB iterator to scan thread delete markers directly
 	iter, err := storedb.Client.NewIter(&pebble.IterOptions{
@@ -197,10 +183,10 @@ func (ti *ThreadIterator) getDeletedThreadCount(userID string) (int, error) {
 		deleteMarkerKey := string(iter.Key())
 
 		// Extract thread key from delete marker (del:t:{thread_key} -> t:{thread_key})
-		threadKey := deleteMarkerKey[4:] // Remove "del:" prefix
+		threadKey := strings.TrimPrefix(deleteMarkerKey, keys.GenSoftDeletePrefix(namespace))
 
 		// Check if this deleted thread belongs to user by checking relationship
-		userThreadRelKey, err := keys.GenUserThreadRelPrefix(userID)
+		userThreadRelKey, err := keys.GenUserThreadRelPrefix(namespace, userID)
 		if err == nil {
 			userThreadRelKey += threadKey
 			_, err = indexdb.GetKey(userThreadRelKey)
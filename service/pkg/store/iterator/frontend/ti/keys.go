@@ -2,7 +2,6 @@ package ti
 
 import (
 	"fmt"
-	"strings"
 
 	"progressdb/pkg/state/logger"
 	"progressdb/pkg/store/db/indexdb"
@@ -74,15 +73,13 @@ func (km *KeyManager) ExecuteKeyQuery(userID, prefix string, req pagination.Pagi
 func (km *KeyManager) fetchAnchorWindowKeys(userID, prefix string, req pagination.PaginationRequest, isDeleted func(string) bool) ([]string, error) {
 	var anchorRelKey string
 	if req.Anchor != "" {
-		if strings.HasPrefix(req.Anchor, "rel:u:") {
+		parsed, err := keys.ParseKey(req.Anchor)
+		switch {
+		case err == nil && parsed.Type == keys.KeyTypeUserOwnsThread:
 			anchorRelKey = req.Anchor
-		} else if strings.HasPrefix(req.Anchor, "t:") {
-			parsed, err := keys.ParseKey(req.Anchor)
-			if err != nil || parsed.Type != keys.KeyTypeThread {
-				return nil, fmt.Errorf("invalid anchor thread key: %s", req.Anchor)
-			}
-			anchorRelKey = fmt.Sprintf(keys.RelUserOwnsThread, userID, parsed.ThreadTS)
-		} else {
+		case err == nil && parsed.Type == keys.KeyTypeThread:
+			anchorRelKey = keys.GenUserOwnsThreadKey(userID, req.Anchor)
+		default:
 			return nil, fmt.Errorf("invalid anchor format: %s", req.Anchor)
 		}
 	}
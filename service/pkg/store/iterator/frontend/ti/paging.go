@@ -1,9 +1,6 @@
 package ti
 
 import (
-	"fmt"
-	"strings"
-
 	"progressdb/pkg/models"
 	"progressdb/pkg/state/logger"
 	"progressdb/pkg/store/keys"
@@ -22,7 +19,7 @@ func (pm *PageManager) CalculatePagination(
 	threads []models.Thread,
 	total int,
 	req pagination.PaginationRequest,
-	userID string,
+	namespace, userID string,
 ) pagination.PaginationResponse {
 
 	response := pagination.PaginationResponse{
@@ -45,8 +42,8 @@ func (pm *PageManager) CalculatePagination(
 		oldestThread := threads[len(threads)-1]
 
 		// Calculate flags - check from appropriate boundaries
-		response.HasAfter = pm.hasThreadsAfterAnchor(oldestThread, userID)   // check from oldest boundary
-		response.HasBefore = pm.hasThreadsBeforeAnchor(newestThread, userID) // check from newest boundary
+		response.HasAfter = pm.hasThreadsAfterAnchor(oldestThread, namespace, userID)   // check from oldest boundary
+		response.HasBefore = pm.hasThreadsBeforeAnchor(newestThread, namespace, userID) // check from newest boundary
 
 		// Set anchor points - for anchor queries, before_anchor is newest, after_anchor is oldest
 		if len(threads) > 0 {
@@ -56,31 +53,22 @@ func (pm *PageManager) CalculatePagination(
 
 	case req.Before != "":
 		// Before query: get threads newer than reference point
-		userThreadPrefix, _ := keys.GenUserThreadRelPrefix(userID)
+		userThreadPrefix, _ := keys.GenUserThreadRelPrefix(namespace, userID)
 
 		// Convert reference to relationship key format for comparison
-		originalRefRelKey := req.Before
-		if !strings.HasPrefix(req.Before, "rel:u:") {
-			originalRefRelKey = fmt.Sprintf(keys.RelUserOwnsThread, userID, strings.TrimPrefix(req.Before, "t:"))
-		}
+		originalRefRelKey := userThreadRelKey(userID, req.Before)
 
 		// Calculate flags relative to window boundaries
 		if len(threads) > 0 {
 			// For before queries, check if there are threads newer than newest thread in current window
 			newestThreadKey := threads[0].Key
-			newestRelKey, err := keys.GenUserThreadRelPrefix(userID)
-			if err == nil {
-				newestRelKey += strings.TrimPrefix(newestThreadKey, "t:")
-				response.HasBefore = pm.keys.checkHasKeysBefore(userThreadPrefix, newestRelKey)
-			}
+			newestRelKey := userThreadRelKey(userID, newestThreadKey)
+			response.HasBefore = pm.keys.checkHasKeysBefore(userThreadPrefix, newestRelKey)
 
 			// Check if there are threads older than oldest thread in current window
 			oldestThreadKey := threads[len(threads)-1].Key
-			oldestRelKey, err := keys.GenUserThreadRelPrefix(userID)
-			if err == nil {
-				oldestRelKey += strings.TrimPrefix(oldestThreadKey, "t:")
-				response.HasAfter = pm.keys.checkHasKeysAfter(userThreadPrefix, oldestRelKey)
-			}
+			oldestRelKey := userThreadRelKey(userID, oldestThreadKey)
+			response.HasAfter = pm.keys.checkHasKeysAfter(userThreadPrefix, oldestRelKey)
 		} else {
 			// No threads returned, use original reference for consistency
 			response.HasBefore = pm.keys.checkHasKeysBefore(userThreadPrefix, originalRefRelKey)
@@ -95,31 +83,22 @@ func (pm *PageManager) CalculatePagination(
 
 	case req.After != "":
 		// After query: get threads older than reference point
-		userThreadPrefix, _ := keys.GenUserThreadRelPrefix(userID)
+		userThreadPrefix, _ := keys.GenUserThreadRelPrefix(namespace, userID)
 
 		// Convert reference to relationship key format for comparison
-		originalRefRelKey := req.After
-		if !strings.HasPrefix(req.After, "rel:u:") {
-			originalRefRelKey = fmt.Sprintf(keys.RelUserOwnsThread, userID, strings.TrimPrefix(req.After, "t:"))
-		}
+		originalRefRelKey := userThreadRelKey(userID, req.After)
 
 		// Calculate flags relative to window boundaries
 		if len(threads) > 0 {
 			// For after queries, check if there are threads newer than newest thread in current window
 			newestThreadKey := threads[0].Key
-			newestRelKey, err := keys.GenUserThreadRelPrefix(userID)
-			if err == nil {
-				newestRelKey += strings.TrimPrefix(newestThreadKey, "t:")
-				response.HasBefore = pm.keys.checkHasKeysBefore(userThreadPrefix, newestRelKey)
-			}
+			newestRelKey := userThreadRelKey(userID, newestThreadKey)
+			response.HasBefore = pm.keys.checkHasKeysBefore(userThreadPrefix, newestRelKey)
 
 			// Check if there are threads older than oldest thread in current window
 			oldestThreadKey := threads[len(threads)-1].Key
-			oldestRelKey, err := keys.GenUserThreadRelPrefix(userID)
-			if err == nil {
-				oldestRelKey += strings.TrimPrefix(oldestThreadKey, "t:")
-				response.HasAfter = pm.keys.checkHasKeysAfter(userThreadPrefix, oldestRelKey)
-			}
+			oldestRelKey := userThreadRelKey(userID, oldestThreadKey)
+			response.HasAfter = pm.keys.checkHasKeysAfter(userThreadPrefix, oldestRelKey)
 		} else {
 			// No threads returned, use original reference for consistency
 			response.HasBefore = pm.keys.checkHasKeysBefore(userThreadPrefix, originalRefRelKey)
@@ -147,9 +126,9 @@ func (pm *PageManager) CalculatePagination(
 	return response
 }
 
-func (pm *PageManager) hasThreadsBeforeAnchor(thread models.Thread, userID string) bool {
-	userThreadPrefix, _ := keys.GenUserThreadRelPrefix(userID)
-	relKey := fmt.Sprintf(keys.RelUserOwnsThread, userID, strings.TrimPrefix(thread.Key, "t:"))
+func (pm *PageManager) hasThreadsBeforeAnchor(thread models.Thread, namespace, userID string) bool {
+	userThreadPrefix, _ := keys.GenUserThreadRelPrefix(namespace, userID)
+	relKey := userThreadRelKey(userID, thread.Key)
 
 	hasBefore := pm.keys.checkHasKeysBefore(userThreadPrefix, relKey)
 
@@ -157,12 +136,21 @@ func (pm *PageManager) hasThreadsBeforeAnchor(thread models.Thread, userID strin
 	return hasBefore
 }
 
-func (pm *PageManager) hasThreadsAfterAnchor(thread models.Thread, userID string) bool {
-	userThreadPrefix, _ := keys.GenUserThreadRelPrefix(userID)
-	relKey := fmt.Sprintf(keys.RelUserOwnsThread, userID, strings.TrimPrefix(thread.Key, "t:"))
+func (pm *PageManager) hasThreadsAfterAnchor(thread models.Thread, namespace, userID string) bool {
+	userThreadPrefix, _ := keys.GenUserThreadRelPrefix(namespace, userID)
+	relKey := userThreadRelKey(userID, thread.Key)
 
 	hasAfter := pm.keys.checkHasKeysAfter(userThreadPrefix, relKey)
 
 	logger.Debug("[hasThreadsAfterAnchor]", "userID", userID, "threadKey", thread.Key, "relKey", relKey, "hasAfter", hasAfter)
 	return hasAfter
 }
+
+// userThreadRelKey returns the relationship key of userID owning ref, which is a thread
+// key or already a relationship key.
+func userThreadRelKey(userID, ref string) string {
+	if parsed, err := keys.ParseKey(ref); err == nil && parsed.Type == keys.KeyTypeThread {
+		return keys.GenUserOwnsThreadKey(userID, ref)
+	}
+	return ref
+}
@@ -10,10 +10,18 @@ const (
 	// u   = user
 	// p   = participant
 	// del = soft delete marker
+	// exp = expiry marker
 	// rel = relationship marker
+	// sched = scheduled (not yet delivered) message
+	// sk  = signing key
+	// ak  = api key
+	// n   = namespace
 	// All keys are lowercase; segments are separated by ":"
 	// <...> = variable segment (e.g. <thread_key>, <message_key>)
 
+	// namespaces
+	NamespacePrefix = "n:%s:" // n:<namespace>:<key> for every key of a named namespace; the default namespace is unprefixed
+
 	// provisional
 	ThreadPrvKey  = "t:%s"      // t:<threadTS>
 	MessagePrvKey = "t:%s:m:%s" // t:<threadTS>
@@ -32,12 +40,58 @@ const (
 	// soft delete markers
 	SoftDeleteMarker = "del:%s" // del:<original_key> -> key
 
+	// expiry markers
+	ExpiryMarker = "exp:%s" // exp:<message_key> -> expires at (unix ns)
+
+	// moved message redirects
+	MovedMessageKey = "mv:%s" // mv:<message_key> -> key the message moved to
+
 	// relationship markers
-	RelUserOwnsThread = "rel:u:%s:t:%s" // rel:u:<user_id>:t:<thread_key>
-	RelThreadHasUser  = "rel:t:%s:u:%s" // rel:t:<thread_key>:u:<user_id>
+	RelUserOwnsThread = "rel:u:%s:t:%s"       // rel:u:<user_id>:t:<thread_key>
+	RelThreadHasUser  = "rel:t:%s:u:%s"       // rel:t:<thread_key>:u:<user_id>
+	RelUserMention    = "rel:u:%s:mention:%s" // rel:u:<user_id>:mention:<message_key> -> mention
+
+	// message body schemas
+	SchemaGlobalKey = "schema:global" // schema:global -> json schema
+	SchemaTagKey    = "schema:tag:%s" // schema:tag:<tag> -> json schema
+
+	// retention policies and legal holds
+	RetentionPolicyKey = "retention:%s:%s" // retention:<scope>:<target> -> policy
+	LegalHoldKey       = "hold:%s"         // hold:<thread_key> -> hold
+
+	// client-supplied external ids
+	ThreadExternalIDKey  = "ext:u:%s:t:%s" // ext:u:<author>:t:<external_id> -> thread key
+	MessageExternalIDKey = "ext:t:%s:m:%s" // ext:t:<threadTS>:m:<external_id> -> message key
+
+	// direct-message threads
+	DirectThreadKey = "dm:%s" // dm:<participant_set_hash> -> thread key
+
+	// scheduled messages
+	ScheduledMessageKey = "sched:t:%s:m:%s"        // sched:t:<threadTS>:m:<messageTS> -> message
+	ScheduledDueIndex   = "idx:sched:%s:t:%s:m:%s" // idx:sched:<deliverAt>:t:<threadTS>:m:<messageTS> -> message key
+
+	// signing keys
+	SigningKeyKey = "sk:%s" // sk:<key_id> -> signing key record
+
+	// runtime api keys
+	APIKeyKey = "ak:%s" // ak:<key_id> -> api key record (salted hash, never the key)
+
+	// daily quotas
+	QuotaKey = "quota:%s:%s:%s" // quota:<yyyymmdd>:<kind>:<user_id> -> count
+
+	// audit trail (audit store)
+	AuditRecordKey   = "audit:%s"          // audit:<seq> -> audit record
+	AuditUserIndex   = "idx:audit:u:%s:%s" // idx:audit:u:<user_id>:<seq> -> empty
+	AuditThreadIndex = "idx:audit:t:%s:%s" // idx:audit:t:<thread_key>:<seq> -> empty
+	AuditFloorKey    = "audit_floor"       // audit_floor -> seq and hash of the last pruned record
+
+	// audit records of an applied batch not yet in the trail (index store)
+	AuditPendingKey = "audit_pending:%s" // audit_pending:<ts> -> audit records
 
 	// padding widths (fixed for lexicographic ordering)
-	SeqPadWidth = 9 // e.g. %09d
+	SeqPadWidth      = 9  // e.g. %09d
+	TSPadWidth       = 19 // unix nanoseconds, e.g. %019d
+	AuditSeqPadWidth = 20 // full uint64 range, e.g. %020d
 
 	// system keys
 	SystemVersionKey    = "system:version"
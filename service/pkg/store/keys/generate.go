@@ -1,90 +1,190 @@
 package keys
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"strings"
 )
 
+// Keys derived from a thread or message key inherit its namespace; the rest take the
+// namespace explicitly.
+
 // general
 func GenThreadPrvKey(threadTS string) string {
 	return fmt.Sprintf(ThreadPrvKey, threadTS)
 }
 
 func GenMessagePrvKey(threadTS string, messageKey string) string {
-	if parsed, err := ParseKey(threadTS); err == nil && parsed.Type == KeyTypeThread {
-		threadTS = parsed.ThreadTS
-	}
-	return fmt.Sprintf(MessagePrvKey, threadTS, messageKey)
+	namespace, threadTS := splitThread(threadTS)
+	return Namespaced(namespace, fmt.Sprintf(MessagePrvKey, threadTS, messageKey))
 }
 
 func GenMessageKey(threadTS, messageTS string, seq uint64) string {
-	if parsed, err := ParseKey(threadTS); err == nil && parsed.Type == KeyTypeThread {
-		threadTS = parsed.ThreadTS
-	}
+	namespace, threadTS := splitThread(threadTS)
 	if parsed, err := ParseKey(messageTS); err == nil && parsed.Type == KeyTypeVersion {
 		messageTS = parsed.MessageTS
 	}
-	return fmt.Sprintf(MessageKey, threadTS, messageTS, PadSeq(seq))
+	return Namespaced(namespace, fmt.Sprintf(MessageKey, threadTS, messageTS, PadSeq(seq)))
 }
 
 func GenMessageVersionKey(messageKey string, ts int64, versionSeq uint64) string {
-	return fmt.Sprintf(VersionKey, messageKey, fmt.Sprintf("%d", ts), PadSeq(versionSeq))
+	namespace, messageKey := SplitNamespace(messageKey)
+	return Namespaced(namespace, fmt.Sprintf(VersionKey, messageKey, fmt.Sprintf("%d", ts), PadSeq(versionSeq)))
 }
 
 func GenThreadKey(threadTS string) string {
-	if parsed, err := ParseKey(threadTS); err == nil && parsed.Type == KeyTypeThread {
-		threadTS = parsed.ThreadTS
-	}
-	return fmt.Sprintf(ThreadKey, threadTS)
+	namespace, threadTS := splitThread(threadTS)
+	return Namespaced(namespace, fmt.Sprintf(ThreadKey, threadTS))
 }
 
 // threading
 func GenThreadMessageStart(threadTS string) string {
-	if parsed, err := ParseKey(threadTS); err == nil && parsed.Type == KeyTypeThread {
-		threadTS = parsed.ThreadTS
-	}
-	return fmt.Sprintf(ThreadMessageStart, threadTS)
+	namespace, threadTS := splitThread(threadTS)
+	return Namespaced(namespace, fmt.Sprintf(ThreadMessageStart, threadTS))
 }
 func GenThreadMessageEnd(threadTS string) string {
-	if parsed, err := ParseKey(threadTS); err == nil && parsed.Type == KeyTypeThread {
-		threadTS = parsed.ThreadTS
-	}
-	return fmt.Sprintf(ThreadMessageEnd, threadTS)
+	namespace, threadTS := splitThread(threadTS)
+	return Namespaced(namespace, fmt.Sprintf(ThreadMessageEnd, threadTS))
 }
 
 func GenThreadMessageLC(threadTS string) string {
-	if parsed, err := ParseKey(threadTS); err == nil && parsed.Type == KeyTypeThread {
-		threadTS = parsed.ThreadTS
-	}
-	return fmt.Sprintf(ThreadMessageLC, threadTS)
+	namespace, threadTS := splitThread(threadTS)
+	return Namespaced(namespace, fmt.Sprintf(ThreadMessageLC, threadTS))
 }
 func GenThreadMessageLU(threadTS string) string {
-	if parsed, err := ParseKey(threadTS); err == nil && parsed.Type == KeyTypeThread {
-		threadTS = parsed.ThreadTS
-	}
-	return fmt.Sprintf(ThreadMessageLU, threadTS)
+	namespace, threadTS := splitThread(threadTS)
+	return Namespaced(namespace, fmt.Sprintf(ThreadMessageLU, threadTS))
 }
 
 // deletes
 func GenSoftDeleteMarkerKey(originalKey string) string {
-	return fmt.Sprintf(SoftDeleteMarker, originalKey)
+	namespace, originalKey := SplitNamespace(originalKey)
+	return Namespaced(namespace, fmt.Sprintf(SoftDeleteMarker, originalKey))
+}
+
+// expiry
+func GenExpiryMarkerKey(messageKey string) string {
+	namespace, messageKey := SplitNamespace(messageKey)
+	return Namespaced(namespace, fmt.Sprintf(ExpiryMarker, messageKey))
+}
+
+// moves
+func GenMovedMessageKey(messageKey string) string {
+	namespace, messageKey := SplitNamespace(messageKey)
+	return Namespaced(namespace, fmt.Sprintf(MovedMessageKey, messageKey))
 }
 
 // relationships
 func GenUserOwnsThreadKey(userID, threadTS string) string {
-	if parsed, err := ParseKey(threadTS); err == nil && parsed.Type == KeyTypeThread {
-		threadTS = parsed.ThreadTS
-	}
-	return fmt.Sprintf(RelUserOwnsThread, userID, threadTS)
+	namespace, threadTS := splitThread(threadTS)
+	return Namespaced(namespace, fmt.Sprintf(RelUserOwnsThread, userID, threadTS))
 }
 
 func GenThreadHasUserKey(threadTS, userID string) string {
-	if parsed, err := ParseKey(threadTS); err == nil && parsed.Type == KeyTypeThread {
-		threadTS = parsed.ThreadTS
+	namespace, threadTS := splitThread(threadTS)
+	return Namespaced(namespace, fmt.Sprintf(RelThreadHasUser, threadTS, userID))
+}
+
+func GenUserMentionKey(userID, messageKey string) string {
+	namespace, messageKey := SplitNamespace(messageKey)
+	return Namespaced(namespace, fmt.Sprintf(RelUserMention, userID, messageKey))
+}
+
+// schemas
+func GenSchemaGlobalKey(namespace string) string {
+	return Namespaced(namespace, SchemaGlobalKey)
+}
+
+func GenSchemaTagKey(namespace, tag string) string {
+	return Namespaced(namespace, fmt.Sprintf(SchemaTagKey, tag))
+}
+
+// signing keys
+func GenSigningKeyKey(namespace, keyID string) string {
+	return Namespaced(namespace, fmt.Sprintf(SigningKeyKey, keyID))
+}
+
+// api keys
+func GenAPIKeyKey(namespace, keyID string) string {
+	return Namespaced(namespace, fmt.Sprintf(APIKeyKey, keyID))
+}
+
+// quotas
+func GenQuotaKey(namespace, day, kind, userID string) string {
+	return Namespaced(namespace, fmt.Sprintf(QuotaKey, day, kind, userID))
+}
+
+// audit
+func GenAuditRecordKey(seq uint64) string {
+	return fmt.Sprintf(AuditRecordKey, PadAuditSeq(seq))
+}
+
+func GenAuditUserIndexKey(namespace, userID string, seq uint64) string {
+	return Namespaced(namespace, fmt.Sprintf(AuditUserIndex, userID, PadAuditSeq(seq)))
+}
+
+func GenAuditThreadIndexKey(threadKey string, seq uint64) string {
+	namespace, threadKey := SplitNamespace(threadKey)
+	return Namespaced(namespace, fmt.Sprintf(AuditThreadIndex, threadKey, PadAuditSeq(seq)))
+}
+
+func GenAuditPendingKey(ts int64) string {
+	return fmt.Sprintf(AuditPendingKey, PadTS(ts))
+}
+
+// retention
+func GenRetentionPolicyKey(namespace, scope, target string) string {
+	return Namespaced(namespace, fmt.Sprintf(RetentionPolicyKey, scope, target))
+}
+
+func GenLegalHoldKey(threadKey string) string {
+	if parsed, err := ParseKey(threadKey); err == nil && parsed.Type == KeyTypeThread {
+		threadKey = parsed.ThreadKey
 	}
-	return fmt.Sprintf(RelThreadHasUser, threadTS, userID)
+	namespace, threadKey := SplitNamespace(threadKey)
+	return Namespaced(namespace, fmt.Sprintf(LegalHoldKey, threadKey))
+}
+
+// external ids
+func GenThreadExternalIDKey(namespace, author, externalID string) string {
+	return Namespaced(namespace, fmt.Sprintf(ThreadExternalIDKey, author, externalID))
+}
+
+func GenMessageExternalIDKey(threadTS, externalID string) string {
+	namespace, threadTS := splitThread(threadTS)
+	return Namespaced(namespace, fmt.Sprintf(MessageExternalIDKey, threadTS, externalID))
+}
+
+// direct-message threads
+
+// GenDirectThreadKey hashes a canonical participant set (see models.CanonicalDMParticipants)
+// so the key length stays bounded for group DMs.
+func GenDirectThreadKey(namespace string, participants []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(participants, "\x00")))
+	return Namespaced(namespace, fmt.Sprintf(DirectThreadKey, hex.EncodeToString(sum[:])))
+}
+
+// scheduled messages
+func GenScheduledMessageKey(threadTS, messageTS string) string {
+	namespace, threadTS := splitThread(threadTS)
+	return Namespaced(namespace, fmt.Sprintf(ScheduledMessageKey, threadTS, messageTS))
+}
+
+func GenScheduledDueIndexKey(deliverAt int64, threadTS, messageTS string) string {
+	namespace, threadTS := splitThread(threadTS)
+	return Namespaced(namespace, fmt.Sprintf(ScheduledDueIndex, PadTS(deliverAt), threadTS, messageTS))
 }
 
 // helpers
 func PadSeq(seq uint64) string {
 	return fmt.Sprintf("%0*d", SeqPadWidth, seq)
 }
+
+func PadTS(ts int64) string {
+	return fmt.Sprintf("%0*d", TSPadWidth, ts)
+}
+
+func PadAuditSeq(seq uint64) string {
+	return fmt.Sprintf("%0*d", AuditSeqPadWidth, seq)
+}
@@ -0,0 +1,43 @@
+package keys
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Namespaced prefixes key with the namespace it belongs to. Keys of the default
+// namespace ("") stay unprefixed, so data written before namespaces keeps its keys.
+func Namespaced(namespace, key string) string {
+	if namespace == "" {
+		return key
+	}
+	return fmt.Sprintf(NamespacePrefix, namespace) + key
+}
+
+// SplitNamespace returns the namespace of key and the key without its prefix.
+func SplitNamespace(key string) (string, string) {
+	rest, ok := strings.CutPrefix(key, "n:")
+	if !ok {
+		return "", key
+	}
+	namespace, rest, ok := strings.Cut(rest, ":")
+	if !ok || namespace == "" || rest == "" {
+		return "", key
+	}
+	return namespace, rest
+}
+
+// NamespaceOf returns the namespace key belongs to, "" for the default one.
+func NamespaceOf(key string) string {
+	namespace, _ := SplitNamespace(key)
+	return namespace
+}
+
+// splitThread returns the namespace and timestamp of a thread given either by its key
+// or by its bare timestamp, which belongs to the default namespace.
+func splitThread(thread string) (string, string) {
+	if parsed, err := ParseKey(thread); err == nil && parsed.Type == KeyTypeThread {
+		return parsed.Namespace, parsed.ThreadTS
+	}
+	return "", thread
+}
@@ -254,10 +254,8 @@ func ParseSoftDeleteMarker(key string) (*SoftDeleteMarkerParts, error) {
 		return nil, fmt.Errorf("expected soft delete marker key, got %s", parsed.Type)
 	}
 
-	// Extract original key by removing "del:" prefix
-	originalKey := strings.TrimPrefix(key, "del:")
 	return &SoftDeleteMarkerParts{
-		OriginalKey: originalKey,
+		OriginalKey: parsed.OriginalKey,
 	}, nil
 }
 
@@ -290,6 +288,7 @@ const (
 	KeyTypeVersion            KeyType = "version"
 	KeyTypeUserOwnsThread     KeyType = "user_owns_thread"
 	KeyTypeThreadHasUser      KeyType = "thread_has_user"
+	KeyTypeUserMention        KeyType = "user_mention"
 	KeyTypeThreadMessageStart KeyType = "thread_message_start"
 	KeyTypeThreadMessageEnd   KeyType = "thread_message_end"
 	KeyTypeThreadMessageLC    KeyType = "thread_message_lc"
@@ -298,11 +297,16 @@ const (
 	KeyTypeDeletedThreadsIndex  KeyType = "deleted_threads_index"
 	KeyTypeDeletedMessagesIndex KeyType = "deleted_messages_index"
 	KeyTypeSoftDeleteMarker     KeyType = "soft_delete_marker"
+	KeyTypeExpiryMarker         KeyType = "expiry_marker"
+
+	KeyTypeScheduledMessage  KeyType = "scheduled_message"
+	KeyTypeScheduledDueIndex KeyType = "scheduled_due_index"
 )
 
 // KeyParts represents the parsed parts of any key
 type KeyParts struct {
 	Type           KeyType
+	Namespace      string // "" for the default namespace
 	ThreadKey      string // Full: "t:1761739879505665000"
 	ThreadTS       string // Just: "1761739879505665000"
 	MessageKey     string // Full: "t:1761739879505665000:m:msg123:001"
@@ -312,15 +316,33 @@ type KeyParts struct {
 	UserID         string // For relationship keys
 	IndexType      string // "start", "end", "lc", "lu", etc.
 	VersionTS      string // For version keys: timestamp from v:{messageKey}:{ts}:{seq}
-	OriginalKey    string // For soft delete markers: original key without "del:" prefix
+	OriginalKey    string // For soft delete and expiry markers: original key without the marker prefix
+	DeliverTS      string // For scheduled due index keys: padded delivery time
 }
 
-// ParseKey is the unified key parser that can handle all key formats
+// ParseKey is the unified key parser that can handle all key formats. The full keys it
+// returns keep the namespace of the parsed key; timestamps and ids are bare.
 func ParseKey(key string) (*KeyParts, error) {
 	if key == "" {
 		return nil, fmt.Errorf("empty key")
 	}
 
+	namespace, rest := SplitNamespace(key)
+	parsed, err := parseKey(rest)
+	if err != nil || namespace == "" {
+		return parsed, err
+	}
+	parsed.Namespace = namespace
+	for _, k := range []*string{&parsed.ThreadKey, &parsed.MessageKey, &parsed.MessageProvKey, &parsed.OriginalKey} {
+		if *k != "" {
+			*k = Namespaced(namespace, *k)
+		}
+	}
+	return parsed, nil
+}
+
+// parseKey parses a key without its namespace prefix.
+func parseKey(key string) (*KeyParts, error) {
 	parts := strings.Split(key, ":")
 	if len(parts) < 2 {
 		return nil, fmt.Errorf("invalid key format: %s", key)
@@ -338,6 +360,10 @@ func ParseKey(key string) (*KeyParts, error) {
 		return parseIndexKey(key, parts)
 	case "del":
 		return parseSoftDeleteKey(key, parts)
+	case "exp":
+		return parseExpiryKey(key, parts)
+	case "sched":
+		return parseScheduledKey(key, parts)
 	default:
 		return nil, fmt.Errorf("unknown key prefix: %s", parts[0])
 	}
@@ -442,6 +468,24 @@ func parseRelationKey(key string, parts []string) (*KeyParts, error) {
 		}, nil
 	}
 
+	// rel:u:{userID}:mention:{messageKey}
+	if len(parts) >= 6 && parts[1] == "u" && parts[3] == "mention" {
+		messageKey := strings.Join(parts[4:], ":")
+		msgParts, err := ParseKey(messageKey)
+		if err != nil || msgParts.Type != KeyTypeMessage {
+			return nil, fmt.Errorf("invalid mention key format: %s", key)
+		}
+		return &KeyParts{
+			Type:       KeyTypeUserMention,
+			UserID:     parts[2],
+			ThreadKey:  msgParts.ThreadKey,
+			ThreadTS:   msgParts.ThreadTS,
+			MessageKey: msgParts.MessageKey,
+			MessageTS:  msgParts.MessageTS,
+			Seq:        msgParts.Seq,
+		}, nil
+	}
+
 	return nil, fmt.Errorf("invalid relation key format: %s", key)
 }
 
@@ -480,6 +524,19 @@ func parseIndexKey(key string, parts []string) (*KeyParts, error) {
 		}, nil
 	}
 
+	// idx:sched:{deliverAt}:t:{threadTS}:m:{messageTS}
+	if len(parts) == 7 && parts[1] == "sched" && parts[3] == "t" && parts[5] == "m" {
+		threadTS := parts[4]
+		return &KeyParts{
+			Type:           KeyTypeScheduledDueIndex,
+			ThreadKey:      "t:" + threadTS,
+			ThreadTS:       threadTS,
+			MessageProvKey: fmt.Sprintf("t:%s:m:%s", threadTS, parts[6]),
+			MessageTS:      parts[6],
+			DeliverTS:      parts[2],
+		}, nil
+	}
+
 	// idx:t:deleted:u:{userID}:list
 	if len(parts) == 6 && parts[1] == "t" && parts[2] == "deleted" && parts[3] == "u" && parts[5] == "list" {
 		return &KeyParts{
@@ -512,3 +569,33 @@ func parseSoftDeleteKey(key string, parts []string) (*KeyParts, error) {
 		OriginalKey: originalKey,
 	}, nil
 }
+
+// parseExpiryKey handles keys starting with "exp:"
+func parseExpiryKey(key string, parts []string) (*KeyParts, error) {
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid expiry key format: %s", key)
+	}
+
+	// Extract message key by removing "exp:" prefix
+	originalKey := strings.TrimPrefix(key, "exp:")
+	return &KeyParts{
+		Type:        KeyTypeExpiryMarker,
+		OriginalKey: originalKey,
+	}, nil
+}
+
+// parseScheduledKey handles keys starting with "sched:"
+func parseScheduledKey(key string, parts []string) (*KeyParts, error) {
+	// sched:t:{threadTS}:m:{messageTS}
+	if len(parts) != 5 || parts[1] != "t" || parts[3] != "m" {
+		return nil, fmt.Errorf("invalid scheduled key format: %s", key)
+	}
+	threadTS := parts[2]
+	return &KeyParts{
+		Type:           KeyTypeScheduledMessage,
+		ThreadKey:      "t:" + threadTS,
+		ThreadTS:       threadTS,
+		MessageProvKey: fmt.Sprintf("t:%s:m:%s", threadTS, parts[4]),
+		MessageTS:      parts[4],
+	}, nil
+}
@@ -11,6 +11,9 @@ const (
 	// Used to generate a prefix for all messages in a specific thread.
 	ThreadMessagePrefix = "t:%s:m:"
 
+	// Used to generate a prefix for the versions of every message in a thread (v:t:{thread}:m:).
+	ThreadVersionsPrefix = "v:t:%s:m:"
+
 	// Used as a simple prefix for thread metadata (e.g., for scanning all threads).
 	ThreadMetadataPrefix = "t:"
 
@@ -26,6 +29,51 @@ const (
 	// Used as a prefix for looking up users in a thread (rel:t:{thread}:u:).
 	ThreadUserRelPrefix = "rel:t:%s:u:"
 
+	// Used as a prefix for looking up messages that mention a user (rel:u:{userID}:mention:).
+	UserMentionRelPrefix = "rel:u:%s:mention:"
+
+	// Used for scanning all registered message body schemas (global and per tag).
+	SchemaPrefix = "schema:"
+
+	// Used for scanning all persisted signing keys (sk:{key_id}).
+	SigningKeyPrefix = "sk:"
+
+	// Used for scanning all runtime api keys (ak:{key_id}).
+	APIKeyPrefix = "ak:"
+
+	// Used for scanning all daily quota counters (quota:{day}:{kind}:{user_id}).
+	QuotaPrefix = "quota:"
+
+	// Used for scanning all retention policies (retention:{scope}:{target}).
+	RetentionPolicyPrefix = "retention:"
+
+	// Used for scanning all legal holds (hold:{thread}).
+	LegalHoldPrefix = "hold:"
+
+	// Used as a prefix for scanning the message external ids of a thread (ext:t:{thread}:m:).
+	ThreadExternalIDsPrefix = "ext:t:%s:m:"
+
+	// Used for scanning all direct-message thread lookups (dm:{participant_set_hash}).
+	DirectThreadPrefix = "dm:"
+
+	// Used as a prefix for scanning the scheduled messages of a thread (sched:t:{thread}:m:).
+	ScheduledThreadPrefix = "sched:t:%s:m:"
+
+	// Used for scanning scheduled messages in delivery order (idx:sched:{deliverAt}:...).
+	ScheduledDuePrefix = "idx:sched:"
+
+	// Used for scanning the audit trail in sequence order (audit:{seq}).
+	AuditRecordPrefix = "audit:"
+
+	// Used as a prefix for scanning the audit records of a user (idx:audit:u:{user_id}:).
+	AuditUserPrefix = "idx:audit:u:%s:"
+
+	// Used as a prefix for scanning the audit records of a thread (idx:audit:t:{thread}:).
+	AuditThreadPrefix = "idx:audit:t:%s:"
+
+	// Used for scanning audit records still to be appended to the trail (audit_pending:{ts}).
+	AuditPendingPrefix = "audit_pending:"
+
 	// Prefix used when storing keys related to backup encryption.
 	BackupEncryptPrefix = "backup:encrypt:"
 
@@ -46,6 +94,9 @@ const (
 
 	// Used for scanning all soft delete markers.
 	SoftDeletePrefix = "del:"
+
+	// Used for scanning all message expiry markers.
+	ExpiryPrefix = "exp:"
 )
 
 func GenAllMessageVersionsPrefix(messageKey string) (string, error) {
@@ -56,7 +107,8 @@ func GenAllMessageVersionsPrefix(messageKey string) (string, error) {
 	if parsed.Type != KeyTypeMessage && parsed.Type != KeyTypeMessageProvisional {
 		return "", fmt.Errorf("expected message key, got %s", parsed.Type)
 	}
-	return fmt.Sprintf(VersionPrefix, messageKey), nil
+	namespace, messageKey := SplitNamespace(messageKey)
+	return Namespaced(namespace, fmt.Sprintf(VersionPrefix, messageKey)), nil
 }
 
 func GenAllThreadMessagesPrefix(threadKey string) (string, error) {
@@ -67,11 +119,33 @@ func GenAllThreadMessagesPrefix(threadKey string) (string, error) {
 	if parsed.Type != KeyTypeThread {
 		return "", fmt.Errorf("expected thread key, got %s", parsed.Type)
 	}
-	return fmt.Sprintf(ThreadMessagePrefix, parsed.ThreadTS), nil
+	return Namespaced(parsed.Namespace, fmt.Sprintf(ThreadMessagePrefix, parsed.ThreadTS)), nil
+}
+
+func GenAllThreadVersionsPrefix(threadKey string) (string, error) {
+	parsed, err := ParseKey(threadKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid thread key: %w", err)
+	}
+	if parsed.Type != KeyTypeThread {
+		return "", fmt.Errorf("expected thread key, got %s", parsed.Type)
+	}
+	return Namespaced(parsed.Namespace, fmt.Sprintf(ThreadVersionsPrefix, parsed.ThreadTS)), nil
+}
+
+func GenThreadExternalIDsPrefix(threadKey string) (string, error) {
+	parsed, err := ParseKey(threadKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid thread key: %w", err)
+	}
+	if parsed.Type != KeyTypeThread {
+		return "", fmt.Errorf("expected thread key, got %s", parsed.Type)
+	}
+	return Namespaced(parsed.Namespace, fmt.Sprintf(ThreadExternalIDsPrefix, parsed.ThreadTS)), nil
 }
 
-func GenThreadMetadataPrefix() string {
-	return ThreadMetadataPrefix
+func GenThreadMetadataPrefix(namespace string) string {
+	return Namespaced(namespace, ThreadMetadataPrefix)
 }
 
 func GenThreadMessagesGEPrefix(threadKey string, seq uint64) (string, error) {
@@ -82,15 +156,22 @@ func GenThreadMessagesGEPrefix(threadKey string, seq uint64) (string, error) {
 	if parsed.Type != KeyTypeThread {
 		return "", fmt.Errorf("expected thread key, got %s", parsed.Type)
 	}
-	return fmt.Sprintf(ThreadMessageGEPrefix, threadKey, PadSeq(seq)), nil
+	return Namespaced(parsed.Namespace, fmt.Sprintf(ThreadMessageGEPrefix, parsed.ThreadTS, PadSeq(seq))), nil
 }
 
-func GenUserThreadRelPrefix(userID string) (string, error) {
+func GenUserThreadRelPrefix(namespace, userID string) (string, error) {
 	// Simple user ID validation - non-empty and reasonable length
 	if userID == "" || len(userID) > 256 {
 		return "", fmt.Errorf("invalid user ID: %q", userID)
 	}
-	return fmt.Sprintf(UserThreadRelPrefix, userID), nil
+	return Namespaced(namespace, fmt.Sprintf(UserThreadRelPrefix, userID)), nil
+}
+
+func GenUserMentionRelPrefix(namespace, userID string) (string, error) {
+	if userID == "" || len(userID) > 256 {
+		return "", fmt.Errorf("invalid user ID: %q", userID)
+	}
+	return Namespaced(namespace, fmt.Sprintf(UserMentionRelPrefix, userID)), nil
 }
 
 func GenThreadUserRelPrefix(threadKey string) (string, error) {
@@ -101,11 +182,39 @@ func GenThreadUserRelPrefix(threadKey string) (string, error) {
 	if parsed.Type != KeyTypeThread {
 		return "", fmt.Errorf("expected thread key, got %s", parsed.Type)
 	}
-	return fmt.Sprintf(ThreadUserRelPrefix, threadKey), nil
+	return Namespaced(parsed.Namespace, fmt.Sprintf(ThreadUserRelPrefix, parsed.ThreadTS)), nil
 }
 
-func GenSoftDeletePrefix() string {
-	return SoftDeletePrefix
+func GenScheduledThreadPrefix(threadKey string) (string, error) {
+	parsed, err := ParseKey(threadKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid thread key: %w", err)
+	}
+	if parsed.Type != KeyTypeThread {
+		return "", fmt.Errorf("expected thread key, got %s", parsed.Type)
+	}
+	return Namespaced(parsed.Namespace, fmt.Sprintf(ScheduledThreadPrefix, parsed.ThreadTS)), nil
+}
+
+func GenScheduledDuePrefix(namespace string) string {
+	return Namespaced(namespace, ScheduledDuePrefix)
+}
+
+func GenAuditUserPrefix(namespace, userID string) string {
+	return Namespaced(namespace, fmt.Sprintf(AuditUserPrefix, userID))
+}
+
+func GenAuditThreadPrefix(threadKey string) string {
+	namespace, threadKey := SplitNamespace(threadKey)
+	return Namespaced(namespace, fmt.Sprintf(AuditThreadPrefix, threadKey))
+}
+
+func GenSoftDeletePrefix(namespace string) string {
+	return Namespaced(namespace, SoftDeletePrefix)
+}
+
+func GenExpiryPrefix(namespace string) string {
+	return Namespaced(namespace, ExpiryPrefix)
 }
 
 func ExtractThreadKeyFromMessage(messageKey string) (string, error) {
@@ -116,7 +225,7 @@ func ExtractThreadKeyFromMessage(messageKey string) (string, error) {
 	if parsed.Type != KeyTypeMessage && parsed.Type != KeyTypeMessageProvisional {
 		return "", fmt.Errorf("expected message key, got %s", parsed.Type)
 	}
-	return parsed.ThreadTS, nil
+	return parsed.ThreadKey, nil
 }
 
 func ExtractMessageKeyFromVersion(versionKey string) (string, error) {
@@ -129,7 +238,7 @@ func ExtractMessageKeyFromVersion(versionKey string) (string, error) {
 	}
 	threadTS := parsed.ThreadTS
 	messageTS := parsed.MessageTS
-	return fmt.Sprintf("t:%s:m:%s", threadTS, messageTS), nil
+	return Namespaced(parsed.Namespace, fmt.Sprintf("t:%s:m:%s", threadTS, messageTS)), nil
 }
 
 func IsThreadKey(key string) bool {
@@ -149,7 +258,7 @@ func IsVersionKey(key string) bool {
 
 func IsRelationKey(key string) bool {
 	parsed, err := ParseKey(key)
-	return err == nil && (parsed.Type == KeyTypeUserOwnsThread || parsed.Type == KeyTypeThreadHasUser)
+	return err == nil && (parsed.Type == KeyTypeUserOwnsThread || parsed.Type == KeyTypeThreadHasUser || parsed.Type == KeyTypeUserMention)
 }
 
 func IsIndexKey(key string) bool {
@@ -179,32 +288,35 @@ func NormalizeKey(key string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("invalid key: %w", err)
 	}
+	var normalized string
 	switch parsed.Type {
 	case KeyTypeThread:
-		return fmt.Sprintf("t:%s", parsed.ThreadTS), nil
+		return parsed.ThreadKey, nil
 	case KeyTypeMessage:
-		return fmt.Sprintf("t:%s:m:%s:%s",
+		normalized = fmt.Sprintf("t:%s:m:%s:%s",
 			parsed.ThreadTS,
 			parsed.MessageTS,
-			parsed.Seq), nil
+			parsed.Seq)
 	case KeyTypeMessageProvisional:
-		return fmt.Sprintf("t:%s:m:%s",
+		normalized = fmt.Sprintf("t:%s:m:%s",
 			parsed.ThreadTS,
-			parsed.MessageTS), nil
+			parsed.MessageTS)
 	case KeyTypeVersion:
-		return fmt.Sprintf("v:%s:m:%s",
+		normalized = fmt.Sprintf("v:%s:m:%s",
 			parsed.ThreadTS,
-			parsed.MessageTS), nil
+			parsed.MessageTS)
 	case KeyTypeUserOwnsThread:
-		return fmt.Sprintf("rel:u:%s:t:%s",
+		normalized = fmt.Sprintf("rel:u:%s:t:%s",
 			parsed.UserID,
-			parsed.ThreadTS), nil
+			parsed.ThreadTS)
 	case KeyTypeThreadHasUser:
-		return fmt.Sprintf("rel:t:%s:u:%s",
+		normalized = fmt.Sprintf("rel:t:%s:u:%s",
 			parsed.ThreadTS,
-			parsed.UserID), nil
+			parsed.UserID)
+	default:
+		return key, nil
 	}
-	return key, nil
+	return Namespaced(parsed.Namespace, normalized), nil
 }
 
 // GenThreadIndexPrefix generates the prefix for all thread-related indexes
@@ -216,5 +328,5 @@ func GenThreadIndexPrefix(threadKey string) (string, error) {
 	if parsed.Type != KeyTypeThread {
 		return "", fmt.Errorf("expected thread key, got %s", parsed.Type)
 	}
-	return fmt.Sprintf("idx:t:%s:", parsed.ThreadTS), nil
+	return Namespaced(parsed.Namespace, fmt.Sprintf("idx:t:%s:", parsed.ThreadTS)), nil
 }
@@ -190,7 +190,7 @@ func writeRecordsToStore(records *MigrationRecords, storeDB *pebble.DB) error {
 		}
 
 		// Provision KMS for thread if encryption is enabled
-		if encryption.EncryptionEnabled() {
+		if encryption.NamespaceEncrypted("") {
 			kmsMeta, err := encryption.ProvisionThreadKMS(thread.Key)
 			if err != nil {
 				return fmt.Errorf("failed to provision KMS for thread %s: %w", thread.Key, err)
@@ -229,13 +229,13 @@ func writeRecordsToStore(records *MigrationRecords, storeDB *pebble.DB) error {
 			return fmt.Errorf("failed to marshal message %s: %w", message.Key, err)
 		}
 
-		if encryption.EncryptionEnabled() {
+		if encryption.NamespaceEncrypted("") {
 			threadKMS := threadKMSMap[message.Thread]
 			if threadKMS == nil {
 				return fmt.Errorf("no KMS metadata found for thread %s when encrypting message %s", message.Thread, message.Key)
 			}
 
-			if encryption.EncryptionHasFieldPolicy() {
+			if encryption.EncryptionHasFieldPolicy("") {
 				// Use field-level encryption
 				encBody, err := encryption.EncryptMessageBody(messageModel, models.Thread{KMS: threadKMS})
 				if err != nil {
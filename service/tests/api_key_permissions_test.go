@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createAPIKey(t *testing.T, body map[string]interface{}) string {
+	t.Helper()
+	payload, _ := json.Marshal(body)
+	resp, err := DoRequest(t, "POST", strings.TrimSuffix(EndpointAdminHealth, "/health")+"/api-keys", payload, AuthHeaders(TestAdminKey))
+	if err != nil {
+		t.Fatalf("create api key failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Key string `json:"key"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	if resp.StatusCode != http.StatusCreated || out.Key == "" {
+		t.Fatalf("Expected an api key, got %d", resp.StatusCode)
+	}
+	return out.Key
+}
+
+func TestAPIKeyPermissions_Suite(t *testing.T) {
+	WithTestServer(t, func() {
+		adminURL := strings.TrimSuffix(EndpointAdminHealth, "/health")
+		asUser := func(key, user string) map[string]string {
+			return map[string]string{"Authorization": "Bearer " + key, "X-User-ID": user}
+		}
+		signBody := func(user string) []byte {
+			return []byte(`{"userId":"` + user + `"}`)
+		}
+
+		t.Run("ReadOnlyAdmin", func(t *testing.T) {
+			analytics := AuthHeaders(createAPIKey(t, map[string]interface{}{"role": "admin", "label": "analytics", "permissions": []string{"read"}}))
+			if status := requestStatus(t, "GET", adminURL+"/stats", nil, analytics); status != http.StatusOK {
+				t.Errorf("Expected status 200 reading stats, got %d", status)
+			}
+			if status := requestStatus(t, "POST", adminURL+"/jobs/purge", nil, analytics); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 purging, got %d", status)
+			}
+			if status := requestStatus(t, "POST", adminURL+"/encryption/encrypt-threads", []byte(`{}`), analytics); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 encrypting, got %d", status)
+			}
+		})
+
+		t.Run("AdminPaths", func(t *testing.T) {
+			stats := AuthHeaders(createAPIKey(t, map[string]interface{}{"role": "admin", "permissions": []string{"read"}, "paths": []string{"/admin/stats", "/admin/health"}}))
+			if status := requestStatus(t, "GET", adminURL+"/stats", nil, stats); status != http.StatusOK {
+				t.Errorf("Expected status 200 reading stats, got %d", status)
+			}
+			if status := requestStatus(t, "GET", adminURL+"/health", nil, stats); status != http.StatusOK {
+				t.Errorf("Expected status 200 reading health, got %d", status)
+			}
+			if status := requestStatus(t, "GET", adminURL+"/users", nil, stats); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 outside the allowed paths, got %d", status)
+			}
+			if status := requestStatus(t, "GET", adminURL+"/statsx", nil, stats); status != http.StatusForbidden {
+				t.Errorf("Expected paths to match whole segments, got %d", status)
+			}
+		})
+
+		t.Run("SignOnlyBackend", func(t *testing.T) {
+			key := createAPIKey(t, map[string]interface{}{"role": "backend", "permissions": []string{"sign"}})
+			if status := requestStatus(t, "POST", EndpointBackendSign, signBody("perm_user"), AuthHeaders(key)); status != http.StatusOK {
+				t.Errorf("Expected status 200 signing, got %d", status)
+			}
+			if status := requestStatus(t, "POST", EndpointFrontendThreads, []byte(`{"title":"x"}`), asUser(key, "perm_user")); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 creating a thread, got %d", status)
+			}
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, asUser(key, "perm_user")); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 reading threads, got %d", status)
+			}
+		})
+
+		t.Run("ReadOnlyFrontend", func(t *testing.T) {
+			headers, err := SignedAuthHeaders(createAPIKey(t, map[string]interface{}{"role": "frontend", "permissions": []string{"read"}}), "perm_reader")
+			if err != nil {
+				t.Fatalf("Failed to get signed auth headers: %v", err)
+			}
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, headers); status != http.StatusOK {
+				t.Errorf("Expected status 200 reading, got %d", status)
+			}
+			if status := requestStatus(t, "POST", EndpointFrontendThreads, []byte(`{"title":"x"}`), headers); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 writing, got %d", status)
+			}
+		})
+
+		t.Run("UserPrefix", func(t *testing.T) {
+			key := createAPIKey(t, map[string]interface{}{"role": "backend", "user_prefix": "tenant_a_"})
+			if status := requestStatus(t, "POST", EndpointBackendSign, signBody("tenant_a_bob"), AuthHeaders(key)); status != http.StatusOK {
+				t.Errorf("Expected status 200 signing a matching user, got %d", status)
+			}
+			if status := requestStatus(t, "POST", EndpointBackendSign, signBody("tenant_b_bob"), AuthHeaders(key)); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 signing another user, got %d", status)
+			}
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, asUser(key, "tenant_a_bob")); status != http.StatusOK {
+				t.Errorf("Expected status 200 acting as a matching user, got %d", status)
+			}
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, asUser(key, "tenant_b_bob")); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 acting as another user, got %d", status)
+			}
+		})
+
+		t.Run("ThreadTags", func(t *testing.T) {
+			user := "perm_tag_user"
+			key := createAPIKey(t, map[string]interface{}{"role": "backend", "thread_tags": []string{"Support"}})
+			headers := asUser(key, user)
+
+			resp, err := DoRequest(t, "POST", EndpointFrontendThreads, []byte(`{"title":"tagged","tags":["support"]}`), headers)
+			if err != nil {
+				t.Fatalf("create thread failed: %v", err)
+			}
+			var created struct {
+				Key string `json:"key"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&created)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusAccepted || created.Key == "" {
+				t.Fatalf("Expected status 202 creating a tagged thread, got %d", resp.StatusCode)
+			}
+			if status := requestStatus(t, "POST", EndpointFrontendThreads, []byte(`{"title":"untagged"}`), headers); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 creating an untagged thread, got %d", status)
+			}
+
+			Retry(t, 20, 200*time.Millisecond, func() bool {
+				return requestStatus(t, "GET", EndpointFrontendThreads+"/"+created.Key, nil, headers) == http.StatusOK
+			})
+			if status := requestStatus(t, "GET", EndpointFrontendThreads+"/"+created.Key, nil, headers); status != http.StatusOK {
+				t.Errorf("Expected status 200 reading the tagged thread, got %d", status)
+			}
+
+			other := createTestThreads(t, asUser(TestBackendKey, user), user, 1)[0]
+			Retry(t, 20, 200*time.Millisecond, func() bool {
+				return requestStatus(t, "GET", EndpointFrontendThreads+"/"+other, nil, asUser(TestBackendKey, user)) == http.StatusOK
+			})
+			if status := requestStatus(t, "GET", EndpointFrontendThreads+"/"+other, nil, headers); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 reading an untagged thread, got %d", status)
+			}
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, headers); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 listing threads, got %d", status)
+			}
+
+			// threads whose tags cannot be read are refused rather than passed on
+			if status := requestStatus(t, "GET", EndpointFrontendThreads+"/t:missing", nil, headers); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 reading an unknown thread, got %d", status)
+			}
+			move := []byte(`{"target":"t:missing","messages":["m:missing"]}`)
+			if status := requestStatus(t, "POST", ThreadMessagesURL(created.Key)+":move", move, headers); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 moving into an unknown thread, got %d", status)
+			}
+		})
+
+		t.Run("Validation", func(t *testing.T) {
+			payload, _ := json.Marshal(map[string]interface{}{"role": "admin", "permissions": []string{"purge"}})
+			if status := requestStatus(t, "POST", adminURL+"/api-keys", payload, AuthHeaders(TestAdminKey)); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for an unknown permission, got %d", status)
+			}
+			payload, _ = json.Marshal(map[string]interface{}{"role": "admin", "paths": []string{"admin/stats"}})
+			if status := requestStatus(t, "POST", adminURL+"/api-keys", payload, AuthHeaders(TestAdminKey)); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for a relative path, got %d", status)
+			}
+		})
+	})
+}
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type apiKeyResponse struct {
+	ID         string `json:"id"`
+	Role       string `json:"role"`
+	Label      string `json:"label"`
+	Salt       string `json:"salt"`
+	Hash       string `json:"hash"`
+	Status     string `json:"status"`
+	ExpiresTS  int64  `json:"expires_ts"`
+	LastUsedTS int64  `json:"last_used_ts"`
+}
+
+func TestAPIKeys_Suite(t *testing.T) {
+	WithTestServer(t, func() {
+		adminHeaders := AuthHeaders(TestAdminKey)
+		keysURL := strings.TrimSuffix(EndpointAdminHealth, "/health") + "/api-keys"
+
+		createKey := func(body map[string]interface{}) (int, string, apiKeyResponse) {
+			payload, _ := json.Marshal(body)
+			resp, err := DoRequest(t, "POST", keysURL, payload, adminHeaders)
+			if err != nil {
+				t.Fatalf("create api key failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out struct {
+				Key    string         `json:"key"`
+				APIKey apiKeyResponse `json:"api_key"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return resp.StatusCode, out.Key, out.APIKey
+		}
+		listKeys := func() map[string]apiKeyResponse {
+			resp, err := DoRequest(t, "GET", keysURL, nil, adminHeaders)
+			if err != nil {
+				t.Fatalf("list api keys failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out struct {
+				Keys []apiKeyResponse `json:"keys"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			byID := make(map[string]apiKeyResponse, len(out.Keys))
+			for _, k := range out.Keys {
+				if k.Salt != "" || k.Hash != "" {
+					t.Errorf("Expected listings to omit salts and hashes, got them for %s", k.ID)
+				}
+				byID[k.ID] = k
+			}
+			return byID
+		}
+		updateKey := func(id string, body map[string]interface{}) int {
+			payload, _ := json.Marshal(body)
+			return requestStatus(t, "PUT", keysURL+"/"+id, payload, adminHeaders)
+		}
+		signStatus := func(key string) int {
+			return requestStatus(t, "POST", EndpointBackendSign, []byte(`{"userId":"api_key_user"}`), AuthHeaders(key))
+		}
+
+		status, backendKey, backend := createKey(map[string]interface{}{"role": "backend", "label": "ci"})
+		if status != http.StatusCreated || backendKey == "" || backend.Role != "backend" || backend.Status != "active" {
+			t.Fatalf("Expected a backend key, got %d %q %+v", status, backendKey, backend)
+		}
+		if backend.Salt != "" || backend.Hash != "" {
+			t.Errorf("Expected the create response to omit the salt and hash")
+		}
+
+		t.Run("Authenticate", func(t *testing.T) {
+			if status := signStatus(backendKey); status != http.StatusOK {
+				t.Errorf("Expected status 200 signing with a runtime backend key, got %d", status)
+			}
+			if status := signStatus(backendKey + "x"); status != http.StatusUnauthorized {
+				t.Errorf("Expected status 401 for a wrong secret, got %d", status)
+			}
+			if got := listKeys()[backend.ID]; got.LastUsedTS == 0 || got.Label != "ci" {
+				t.Errorf("Expected a labelled key with a last-used time, got %+v", got)
+			}
+
+			// roles keep their path restrictions
+			if status := requestStatus(t, "GET", keysURL, nil, AuthHeaders(backendKey)); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 for a backend key on admin routes, got %d", status)
+			}
+			_, adminKey, _ := createKey(map[string]interface{}{"role": "admin"})
+			if status := requestStatus(t, "GET", keysURL, nil, AuthHeaders(adminKey)); status != http.StatusOK {
+				t.Errorf("Expected status 200 for a runtime admin key, got %d", status)
+			}
+			_, frontendKey, _ := createKey(map[string]interface{}{"role": "frontend"})
+			headers, err := SignedAuthHeaders(frontendKey, "api_key_user")
+			if err != nil {
+				t.Fatalf("Failed to get signed auth headers: %v", err)
+			}
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, headers); status != http.StatusOK {
+				t.Errorf("Expected status 200 for a runtime frontend key, got %d", status)
+			}
+
+			// config keys keep working alongside runtime keys
+			if status := signStatus(TestBackendKey); status != http.StatusOK {
+				t.Errorf("Expected status 200 for the config backend key, got %d", status)
+			}
+		})
+
+		t.Run("Validation", func(t *testing.T) {
+			if status, _, _ := createKey(map[string]interface{}{"role": "root"}); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for an unknown role, got %d", status)
+			}
+			if status, _, _ := createKey(map[string]interface{}{"role": "backend", "expires_in": -1}); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for a negative expiry, got %d", status)
+			}
+			if status := updateKey("missing", map[string]interface{}{"label": "x"}); status != http.StatusNotFound {
+				t.Errorf("Expected status 404 updating an unknown key, got %d", status)
+			}
+			if status := requestStatus(t, "DELETE", keysURL+"/missing", nil, adminHeaders); status != http.StatusNotFound {
+				t.Errorf("Expected status 404 revoking an unknown key, got %d", status)
+			}
+		})
+
+		t.Run("LabelAndExpire", func(t *testing.T) {
+			if status := updateKey(backend.ID, map[string]interface{}{"label": "ci-renamed", "expires_in": 1}); status != http.StatusOK {
+				t.Fatalf("Expected status 200 updating the key, got %d", status)
+			}
+			time.Sleep(2 * time.Second)
+			if status := signStatus(backendKey); status != http.StatusUnauthorized {
+				t.Errorf("Expected status 401 for an expired key, got %d", status)
+			}
+			if got := listKeys()[backend.ID]; got.Status != "expired" || got.Label != "ci-renamed" {
+				t.Errorf("Expected a renamed, expired key, got %+v", got)
+			}
+
+			if status := updateKey(backend.ID, map[string]interface{}{"expires_in": 0}); status != http.StatusOK {
+				t.Fatalf("Expected status 200 clearing the expiry, got %d", status)
+			}
+			if status := signStatus(backendKey); status != http.StatusOK {
+				t.Errorf("Expected status 200 once the expiry is cleared, got %d", status)
+			}
+		})
+
+		t.Run("Revoke", func(t *testing.T) {
+			if status := requestStatus(t, "DELETE", keysURL+"/"+backend.ID, nil, adminHeaders); status != http.StatusNoContent {
+				t.Fatalf("Expected status 204 revoking the key, got %d", status)
+			}
+			if status := signStatus(backendKey); status != http.StatusUnauthorized {
+				t.Errorf("Expected status 401 for a revoked key, got %d", status)
+			}
+			if got := listKeys()[backend.ID]; got.Status != "revoked" {
+				t.Errorf("Expected the key to be listed as revoked, got %+v", got)
+			}
+		})
+	})
+}
@@ -0,0 +1,291 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type auditRecord struct {
+	Seq      uint64            `json:"seq"`
+	TS       int64             `json:"ts"`
+	Action   string            `json:"action"`
+	Outcome  string            `json:"outcome"`
+	Role     string            `json:"role"`
+	Actor    string            `json:"actor"`
+	UserID   string            `json:"user_id"`
+	Thread   string            `json:"thread"`
+	Target   string            `json:"target"`
+	ReqID    string            `json:"reqid"`
+	IP       string            `json:"ip"`
+	Detail   map[string]string `json:"detail"`
+	PrevHash string            `json:"prev_hash"`
+	Hash     string            `json:"hash"`
+}
+
+type auditPage struct {
+	Records    []auditRecord `json:"records"`
+	NextBefore uint64        `json:"next_before"`
+}
+
+func TestAudit_Suite(t *testing.T) {
+	WithTestServer(t, func() {
+		adminHeaders := AuthHeaders(TestAdminKey)
+		adminURL := strings.TrimSuffix(EndpointAdminHealth, "/health")
+		auditURL := adminURL + "/audit"
+
+		query := func(params url.Values) (int, auditPage) {
+			resp, err := DoRequest(t, "GET", auditURL+"?"+params.Encode(), nil, adminHeaders)
+			if err != nil {
+				t.Fatalf("audit query failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var page auditPage
+			_ = json.NewDecoder(resp.Body).Decode(&page)
+			return resp.StatusCode, page
+		}
+		// waitFor polls until the filter returns at least want records; applied writes
+		// are recorded asynchronously.
+		waitFor := func(params url.Values, want int) []auditRecord {
+			var records []auditRecord
+			Retry(t, 20, 250*time.Millisecond, func() bool {
+				_, page := query(params)
+				records = page.Records
+				return len(records) >= want
+			})
+			return records
+		}
+
+		userID := "audit_user"
+		headers, err := SignedAuthHeaders(TestFrontendKey, userID)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		headers["X-Request-Id"] = "audit-req-1"
+		threadKey := createTestThreads(t, headers, userID, 1)[0]
+		createTestMessages(t, headers, threadKey, 2)
+
+		t.Run("Mutations", func(t *testing.T) {
+			records := waitFor(url.Values{"user": {userID}, "action": {"thread.create"}}, 1)
+			rec := records[0]
+			if rec.Thread != threadKey || rec.Role != "frontend" || rec.ReqID != "audit-req-1" || rec.IP == "" || rec.Outcome != "ok" {
+				t.Errorf("Expected the request metadata on the record, got %+v", rec)
+			}
+
+			records = waitFor(url.Values{"thread": {threadKey}, "action": {"message."}}, 2)
+			for _, r := range records {
+				if r.Action != "message.create" || r.Thread != threadKey || r.UserID != userID || r.Target == "" {
+					t.Errorf("Expected message creations in the thread, got %+v", r)
+				}
+			}
+			if records[0].Seq <= records[1].Seq {
+				t.Errorf("Expected newest first, got %d then %d", records[0].Seq, records[1].Seq)
+			}
+		})
+
+		t.Run("KeyOperations", func(t *testing.T) {
+			resp, err := DoRequest(t, "POST", adminURL+"/api-keys", []byte(`{"role":"backend","label":"audited"}`), adminHeaders)
+			if err != nil {
+				t.Fatalf("create api key failed: %v", err)
+			}
+			var created struct {
+				Key    string `json:"key"`
+				APIKey struct {
+					ID string `json:"id"`
+				} `json:"api_key"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&created)
+			resp.Body.Close()
+
+			records := waitFor(url.Values{"action": {"api_key.create"}}, 1)
+			rec := records[0]
+			if rec.Target != created.APIKey.ID || rec.Role != "admin" || rec.Detail["role"] != "backend" {
+				t.Errorf("Expected the created key on the record, got %+v", rec)
+			}
+			if !strings.HasPrefix(rec.Actor, "key:") || strings.Contains(rec.Actor, TestAdminKey) {
+				t.Errorf("Expected the admin to be named without its key, got %q", rec.Actor)
+			}
+			if _, page := query(url.Values{"action": {"admin.request"}}); len(page.Records) != 0 {
+				t.Errorf("Expected no generic record for an audited admin request, got %+v", page.Records)
+			}
+
+			// failed admin mutations still leave a trace
+			if status := requestStatus(t, "DELETE", adminURL+"/api-keys/missing", nil, adminHeaders); status != http.StatusNotFound {
+				t.Fatalf("Expected status 404 revoking an unknown key, got %d", status)
+			}
+			records = waitFor(url.Values{"action": {"admin.request"}}, 1)
+			if rec := records[0]; rec.Target != "/admin/api-keys/missing" || rec.Outcome != "failed" || rec.Detail["method"] != "DELETE" || rec.Detail["status"] != "404" {
+				t.Errorf("Expected a failed generic admin record, got %+v", rec)
+			}
+
+			// the new key's id names it once it is used
+			if status := requestStatus(t, "POST", EndpointBackendSign, []byte(`{"userId":"x"}`), AuthHeaders(created.Key)); status != http.StatusOK {
+				t.Fatalf("Expected the new key to work, got %d", status)
+			}
+			if status := requestStatus(t, "DELETE", adminURL+"/api-keys/"+created.APIKey.ID, nil, adminHeaders); status != http.StatusNoContent {
+				t.Fatalf("Expected status 204 revoking the key, got %d", status)
+			}
+			waitFor(url.Values{"action": {"api_key.revoke"}}, 1)
+		})
+
+		t.Run("AuthFailures", func(t *testing.T) {
+			bad := AuthHeaders("not-a-key")
+			bad["X-User-ID"] = "intruder"
+			bad["X-Role-Name"] = "admin"
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, bad); status != http.StatusUnauthorized {
+				t.Fatalf("Expected status 401 for an unknown key, got %d", status)
+			}
+			if status := requestStatus(t, "GET", adminURL+"/api-keys", nil, AuthHeaders(TestBackendKey)); status != http.StatusForbidden {
+				t.Fatalf("Expected status 403 for a backend key on admin routes, got %d", status)
+			}
+
+			records := waitFor(url.Values{"user": {"intruder"}, "action": {"auth.failure"}}, 1)
+			if rec := records[0]; rec.Outcome != "failed" || rec.Detail["status"] != "401" || rec.Target != "/frontend/v1/threads" {
+				t.Errorf("Expected an unauthorized attempt, got %+v", rec)
+			}
+			if rec := records[0]; rec.Role == "admin" {
+				t.Errorf("Expected the role claimed by the client to be ignored, got %+v", rec)
+			}
+			records = waitFor(url.Values{"action": {"auth.failure"}}, 2)
+			if rec := records[0]; rec.Detail["status"] != "403" || rec.Role != "backend" || rec.Detail["reason"] != "forbidden" {
+				t.Errorf("Expected a forbidden backend attempt, got %+v", rec)
+			}
+		})
+
+		t.Run("AuthFailureSampling", func(t *testing.T) {
+			flood := AuthHeaders("not-a-key")
+			flood["X-User-ID"] = "flooder"
+			for i := 0; i < 20; i++ {
+				requestStatus(t, "GET", EndpointFrontendThreads, nil, flood)
+			}
+			records := waitFor(url.Values{"user": {"flooder"}, "action": {"auth.failure"}}, 1)
+			if len(records) >= 20 {
+				t.Errorf("Expected a flood from one ip to be sampled, got %d records", len(records))
+			}
+
+			// once the bucket refills the next failure carries the count of those dropped
+			time.Sleep(1100 * time.Millisecond)
+			requestStatus(t, "GET", EndpointFrontendThreads, nil, flood)
+			records = waitFor(url.Values{"user": {"flooder"}, "action": {"auth.failure"}}, len(records)+1)
+			if n, err := strconv.Atoi(records[0].Detail["suppressed"]); err != nil || n < 1 {
+				t.Errorf("Expected the dropped failures to be counted, got %+v", records[0])
+			}
+		})
+
+		t.Run("FiltersAndPaging", func(t *testing.T) {
+			_, all := query(url.Values{"limit": {"1000"}})
+			if len(all.Records) < 5 {
+				t.Fatalf("Expected several records, got %d", len(all.Records))
+			}
+
+			_, first := query(url.Values{"limit": {"2"}})
+			if len(first.Records) != 2 || first.NextBefore != first.Records[1].Seq {
+				t.Fatalf("Expected a page of 2 with a cursor, got %+v", first)
+			}
+			_, second := query(url.Values{"limit": {"2"}, "before": {fmt.Sprint(first.NextBefore)}})
+			if len(second.Records) != 2 || second.Records[0].Seq != first.NextBefore-1 {
+				t.Errorf("Expected the next page to continue below the cursor, got %+v", second.Records)
+			}
+
+			// time bounds: from is inclusive, to exclusive
+			pivot := all.Records[len(all.Records)/2]
+			_, older := query(url.Values{"to": {fmt.Sprint(pivot.TS)}, "limit": {"1000"}})
+			_, newer := query(url.Values{"from": {time.Unix(0, pivot.TS).UTC().Format(time.RFC3339Nano)}, "limit": {"1000"}})
+			if len(older.Records)+len(newer.Records) < len(all.Records) || len(newer.Records) == 0 || newer.Records[len(newer.Records)-1].Seq != pivot.Seq {
+				t.Errorf("Expected from and to to split the trail at %d, got %d and %d of %d", pivot.Seq, len(newer.Records), len(older.Records), len(all.Records))
+			}
+
+			if status, page := query(url.Values{"user": {"nobody"}}); status != http.StatusOK || len(page.Records) != 0 {
+				t.Errorf("Expected an empty page for an unknown user, got %d %+v", status, page)
+			}
+			if status, _ := query(url.Values{"from": {"yesterday"}}); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for an unparseable time, got %d", status)
+			}
+			if status, _ := query(url.Values{"limit": {"0"}}); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for a zero limit, got %d", status)
+			}
+		})
+
+		t.Run("HashChain", func(t *testing.T) {
+			_, page := query(url.Values{"limit": {"3"}})
+			for i := 0; i+1 < len(page.Records); i++ {
+				if page.Records[i].PrevHash != page.Records[i+1].Hash {
+					t.Errorf("Expected record %d to link to %d", page.Records[i].Seq, page.Records[i+1].Seq)
+				}
+			}
+
+			resp, err := DoRequest(t, "GET", auditURL+"/verify", nil, adminHeaders)
+			if err != nil {
+				t.Fatalf("verify failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var res struct {
+				Records uint64 `json:"records"`
+				Valid   bool   `json:"valid"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&res)
+			if resp.StatusCode != http.StatusOK || !res.Valid || res.Records < page.Records[0].Seq {
+				t.Errorf("Expected an intact chain, got %d %+v", resp.StatusCode, res)
+			}
+		})
+
+		t.Run("AdminOnly", func(t *testing.T) {
+			if status := requestStatus(t, "GET", auditURL, nil, AuthHeaders(TestBackendKey)); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 for a backend key, got %d", status)
+			}
+		})
+	})
+}
+
+func TestAudit_Retention(t *testing.T) {
+	WithTestServerConfig(t, "audit:\n  retention: 1s\n", func() {
+		adminHeaders := AuthHeaders(TestAdminKey)
+		auditURL := strings.TrimSuffix(EndpointAdminHealth, "/health") + "/audit"
+		createKey := func(role string) {
+			if status := requestStatus(t, "POST", strings.TrimSuffix(auditURL, "/audit")+"/api-keys", []byte(`{"role":"`+role+`"}`), adminHeaders); status != http.StatusCreated {
+				t.Fatalf("Expected status 201 creating an api key, got %d", status)
+			}
+		}
+
+		createKey("backend")
+		createKey("backend")
+		time.Sleep(2 * time.Second)
+		createKey("frontend")
+
+		// records past the retention are pruned on the next append
+		var page auditPage
+		Retry(t, 20, 250*time.Millisecond, func() bool {
+			resp, err := DoRequest(t, "GET", auditURL+"?action=api_key.create", nil, adminHeaders)
+			if err != nil {
+				t.Fatalf("audit query failed: %v", err)
+			}
+			defer resp.Body.Close()
+			_ = json.NewDecoder(resp.Body).Decode(&page)
+			return len(page.Records) == 1
+		})
+		if len(page.Records) != 1 || page.Records[0].Detail["role"] != "frontend" {
+			t.Fatalf("Expected only the new key creation to remain, got %+v", page.Records)
+		}
+
+		// what remains still verifies, from the last record pruned
+		resp, err := DoRequest(t, "GET", auditURL+"/verify", nil, adminHeaders)
+		if err != nil {
+			t.Fatalf("verify failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var res struct {
+			Records       uint64 `json:"records"`
+			PrunedThrough uint64 `json:"pruned_through"`
+			Valid         bool   `json:"valid"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&res)
+		if !res.Valid || res.PrunedThrough == 0 || res.Records == 0 {
+			t.Errorf("Expected a valid pruned trail, got %+v", res)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type batchOpsResponse struct {
+	Results []struct {
+		Op     string `json:"op"`
+		Ref    string `json:"ref"`
+		Key    string `json:"key"`
+		UserID string `json:"user_id"`
+	} `json:"results"`
+}
+
+func TestBatchOps_Suite(t *testing.T) {
+	WithTestServer(t, func() {
+		owner, member := "batch_ops_owner", "batch_ops_member"
+		headers, err := SignedAuthHeaders(TestFrontendKey, owner)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		memberHeaders, err := SignedAuthHeaders(TestFrontendKey, member)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		batchURL := strings.TrimSuffix(EndpointFrontendThreads, "/threads") + "/batch"
+
+		postBatch := func(ops []map[string]interface{}) (int, batchOpsResponse) {
+			payload, _ := json.Marshal(map[string]interface{}{"ops": ops})
+			resp, err := DoRequest(t, "POST", batchURL, payload, headers)
+			if err != nil {
+				t.Fatalf("batch request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out batchOpsResponse
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return resp.StatusCode, out
+		}
+
+		t.Run("Validation", func(t *testing.T) {
+			if status, _ := postBatch(nil); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for an empty batch, got %d", status)
+			}
+			unknownOp := []map[string]interface{}{{"op": "thread.delete", "thread": "t:missing"}}
+			if status, _ := postBatch(unknownOp); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for an unsupported op, got %d", status)
+			}
+			unknownRef := []map[string]interface{}{
+				{"op": "message.create", "thread": "$nope", "payload": map[string]interface{}{"body": map[string]string{"text": "hi"}}},
+			}
+			if status, _ := postBatch(unknownRef); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for an unknown ref, got %d", status)
+			}
+			dupRef := []map[string]interface{}{
+				{"op": "thread.create", "ref": "a", "payload": map[string]string{"title": "one"}},
+				{"op": "thread.create", "ref": "a", "payload": map[string]string{"title": "two"}},
+			}
+			if status, _ := postBatch(dupRef); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for a duplicate ref, got %d", status)
+			}
+		})
+
+		t.Run("SeededConversation", func(t *testing.T) {
+			status, out := postBatch([]map[string]interface{}{
+				{"op": "thread.create", "ref": "conv", "payload": map[string]string{"title": "draft"}},
+				{"op": "message.create", "thread": "$conv", "payload": map[string]interface{}{"role": "system", "body": map[string]string{"text": "You are helpful."}}},
+				{"op": "message.create", "thread": "$conv", "payload": map[string]interface{}{"body": map[string]string{"text": "Hello"}}},
+				{"op": "thread.update", "thread": "$conv", "payload": map[string]string{"title": "Support chat"}},
+				{"op": "thread.participant", "thread": "$conv", "user_id": member, "payload": map[string]string{"role": "member"}},
+			})
+			if status != http.StatusAccepted || len(out.Results) != 5 {
+				t.Fatalf("Expected status 202 with 5 results, got %d %+v", status, out)
+			}
+			threadKey := out.Results[0].Key
+			for i, res := range out.Results {
+				if res.Key == "" {
+					t.Fatalf("Expected a key for op %d, got %+v", i, res)
+				}
+			}
+			if out.Results[3].Key != threadKey || out.Results[4].UserID != member {
+				t.Fatalf("Expected later ops to target %s, got %+v", threadKey, out.Results)
+			}
+
+			var list MessagesListResponse
+			Retry(t, 20, 300*time.Millisecond, func() bool {
+				resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey), nil, memberHeaders)
+				if err != nil {
+					return false
+				}
+				defer resp.Body.Close()
+				list = MessagesListResponse{}
+				_ = json.NewDecoder(resp.Body).Decode(&list)
+				return resp.StatusCode == http.StatusOK && len(list.Messages) == 2
+			})
+			if len(list.Messages) != 2 {
+				t.Fatalf("Expected the added participant to read 2 seeded messages, got %d", len(list.Messages))
+			}
+
+			resp, err := DoRequest(t, "GET", EndpointFrontendThreads+"/"+threadKey, nil, headers)
+			if err != nil {
+				t.Fatalf("read thread failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var thread ThreadResponse
+			_ = json.NewDecoder(resp.Body).Decode(&thread)
+			if thread.Thread.Title != "Support chat" {
+				t.Errorf("Expected updated title, got %q", thread.Thread.Title)
+			}
+		})
+
+		t.Run("AllOrNothing", func(t *testing.T) {
+			// the last op posts where the owner has no access, which only apply can tell
+			othersThread := createTestThreads(t, memberHeaders, member, 1)[0]
+			status, out := postBatch([]map[string]interface{}{
+				{"op": "thread.create", "ref": "doomed", "payload": map[string]string{"title": "doomed"}},
+				{"op": "message.create", "thread": "$doomed", "payload": map[string]interface{}{"body": map[string]string{"text": "first"}}},
+				{"op": "message.create", "thread": othersThread, "payload": map[string]interface{}{"body": map[string]string{"text": "intrusion"}}},
+			})
+			if status != http.StatusAccepted || len(out.Results) != 3 {
+				t.Fatalf("Expected status 202 with 3 results, got %d %+v", status, out)
+			}
+
+			time.Sleep(2 * time.Second)
+			resp, err := DoRequest(t, "GET", EndpointFrontendThreads+"?limit=100", nil, headers)
+			if err != nil {
+				t.Fatalf("list threads failed: %v", err)
+			}
+			var threads ThreadsListResponse
+			_ = json.NewDecoder(resp.Body).Decode(&threads)
+			resp.Body.Close()
+			for _, th := range threads.Threads {
+				if th.Title == "doomed" {
+					t.Errorf("Expected the thread of a failed batch not to exist, got %s", th.Key)
+				}
+			}
+
+			resp, err = DoRequest(t, "GET", ThreadMessagesURL(othersThread), nil, memberHeaders)
+			if err != nil {
+				t.Fatalf("list messages failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var list MessagesListResponse
+			_ = json.NewDecoder(resp.Body).Decode(&list)
+			if len(list.Messages) != 0 {
+				t.Errorf("Expected no messages from a failed batch, got %d", len(list.Messages))
+			}
+		})
+
+		t.Run("ExistingThread", func(t *testing.T) {
+			threadKey := createTestThreads(t, headers, owner, 1)[0]
+			status, out := postBatch([]map[string]interface{}{
+				{"op": "message.create", "ref": "q", "thread": threadKey, "payload": map[string]interface{}{"body": map[string]string{"text": "question"}}},
+				{"op": "message.create", "ref": "a", "thread": threadKey, "payload": map[string]interface{}{"body": map[string]string{"text": "answer"}}},
+			})
+			if status != http.StatusAccepted || len(out.Results) != 2 {
+				t.Fatalf("Expected status 202 with 2 results, got %d %+v", status, out)
+			}
+
+			// provisional keys resolve once applied
+			Retry(t, 20, 300*time.Millisecond, func() bool {
+				resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey)+"/"+out.Results[1].Key, nil, headers)
+				if err != nil {
+					return false
+				}
+				defer resp.Body.Close()
+				return resp.StatusCode == http.StatusOK
+			})
+		})
+	})
+}
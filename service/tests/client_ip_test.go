@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestClientIP_Suite(t *testing.T) {
+	serverYAML := "  trusted_proxies: [\"127.0.0.0/8\"]\n  ip_allowlists:\n    admin: [\"10.0.0.0/8\"]\n"
+	limits := "limits:\n  ip:\n    rps: 0.01\n    burst: 20\n"
+	WithTestServerServerConfig(t, serverYAML, limits, func() {
+		admin := func(forwardedFor string) map[string]string {
+			headers := AuthHeaders(TestAdminKey)
+			if forwardedFor != "" {
+				headers["X-Forwarded-For"] = forwardedFor
+			}
+			return headers
+		}
+
+		t.Run("RoleAllowlist", func(t *testing.T) {
+			if status := requestStatus(t, "GET", EndpointAdminHealth, nil, admin("10.1.2.3")); status != http.StatusOK {
+				t.Errorf("Expected status 200 from an allowed network, got %d", status)
+			}
+			if status := requestStatus(t, "GET", EndpointAdminHealth, nil, admin("")); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 from the proxy itself, got %d", status)
+			}
+			if status := requestStatus(t, "GET", EndpointAdminHealth, nil, admin("192.168.1.1")); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 from another network, got %d", status)
+			}
+			if status := requestStatus(t, "POST", EndpointBackendSign, []byte(`{"userId":"ip_user"}`), AuthHeaders(TestBackendKey)); status != http.StatusOK {
+				t.Errorf("Expected backends to be unrestricted, got %d", status)
+			}
+		})
+
+		t.Run("ForwardedChain", func(t *testing.T) {
+			// the proxy appends the real peer; addresses to its left are the client's claim
+			if status := requestStatus(t, "GET", EndpointAdminHealth, nil, admin("10.1.2.3, 192.168.1.1")); status != http.StatusForbidden {
+				t.Errorf("Expected a spoofed left-hand address to be ignored, got %d", status)
+			}
+			if status := requestStatus(t, "GET", EndpointAdminHealth, nil, admin("192.168.1.1, 10.1.2.3")); status != http.StatusOK {
+				t.Errorf("Expected the rightmost untrusted address to count, got %d", status)
+			}
+			if status := requestStatus(t, "GET", EndpointAdminHealth, nil, admin("10.1.2.3, 127.0.0.2")); status != http.StatusOK {
+				t.Errorf("Expected trusted hops to be skipped, got %d", status)
+			}
+		})
+
+		t.Run("RateLimitedByClient", func(t *testing.T) {
+			health := strings.TrimSuffix(EndpointAdminHealth, "/admin/health") + "/healthz"
+			limited := false
+			for i := 0; i < 25 && !limited; i++ {
+				limited = requestStatus(t, "GET", health, nil, map[string]string{"X-Forwarded-For": "10.7.7.7"}) == http.StatusTooManyRequests
+			}
+			if !limited {
+				t.Fatal("Expected the forwarded client to be rate limited")
+			}
+			if status := requestStatus(t, "GET", health, nil, map[string]string{"X-Forwarded-For": "10.7.7.8"}); status != http.StatusOK {
+				t.Errorf("Expected another client behind the proxy to be unaffected, got %d", status)
+			}
+		})
+	})
+
+	t.Run("UntrustedPeer", func(t *testing.T) {
+		WithTestServerServerConfig(t, "  ip_whitelist: [\"127.0.0.0/8\"]\n  ip_allowlists:\n    admin: [\"10.0.0.0/8\"]\n", "", func() {
+			headers := AuthHeaders(TestAdminKey)
+			headers["X-Forwarded-For"] = "10.1.2.3"
+			if status := requestStatus(t, "GET", EndpointAdminHealth, nil, headers); status != http.StatusForbidden {
+				t.Errorf("Expected X-Forwarded-For from an untrusted peer to be ignored, got %d", status)
+			}
+			if status := requestStatus(t, "POST", EndpointBackendSign, []byte(`{"userId":"ip_user"}`), AuthHeaders(TestBackendKey)); status != http.StatusOK {
+				t.Errorf("Expected the whitelist to match by cidr, got %d", status)
+			}
+		})
+	})
+}
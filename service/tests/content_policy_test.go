@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContentPolicy_Suite(t *testing.T) {
+	// external policy: rejects "spam", rewrites "shout" messages to upper case
+	hookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Handler string `json:"handler"`
+			Payload struct {
+				Body map[string]interface{} `json:"body"`
+			} `json:"payload"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		content, _ := req.Payload.Body["content"].(string)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(content, "spam"):
+			_ = json.NewEncoder(w).Encode(map[string]string{"action": "reject", "reason": "looks like spam"})
+		case strings.HasPrefix(content, "shout:"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"action": "modify",
+				"body":   map[string]string{"content": strings.ToUpper(content)},
+			})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]string{"action": "allow"})
+		}
+	}))
+	defer hookServer.Close()
+
+	policy := fmt.Sprintf(`content_policy:
+  redact:
+    - words: ["darn"]
+      patterns: ['\d{4}-\d{4}-\d{4}-\d{4}']
+      replacement: "[redacted]"
+  max_links:
+    - limit: 2
+  http:
+    - url: %s
+      timeout: 1s`, hookServer.URL)
+
+	WithTestServerConfig(t, policy, func() {
+		user := "policy_user"
+		headers, err := SignedAuthHeaders(TestFrontendKey, user)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		threadKey := createTestThreads(t, headers, user, 1)[0]
+
+		postMessage := func(content string) (int, string) {
+			body, _ := json.Marshal(map[string]interface{}{"body": map[string]string{"content": content}})
+			resp, err := DoRequest(t, "POST", ThreadMessagesURL(threadKey), body, headers)
+			if err != nil {
+				t.Fatalf("post message request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out map[string]string
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return resp.StatusCode, out["key"]
+		}
+
+		storedContent := func(messageKey string) string {
+			var content string
+			Retry(t, 10, 500*time.Millisecond, func() bool {
+				resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey)+"/"+messageKey, nil, headers)
+				if err != nil {
+					return false
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					return false
+				}
+				var out MessageResponse
+				if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+					return false
+				}
+				body, _ := out.Message.Body.(map[string]interface{})
+				content, _ = body["content"].(string)
+				return content != ""
+			})
+			return content
+		}
+
+		t.Run("RedactsWordsAndPatterns", func(t *testing.T) {
+			status, key := postMessage("Darn, my card is 1234-5678-1234-5678")
+			if status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+			if got := storedContent(key); got != "[redacted], my card is [redacted]" {
+				t.Errorf("Expected redacted content, got %q", got)
+			}
+		})
+
+		t.Run("MaxLinksRejected", func(t *testing.T) {
+			status, _ := postMessage("see https://a.example and https://b.example and www.c.example")
+			if status != http.StatusUnprocessableEntity {
+				t.Errorf("Expected status 422, got %d", status)
+			}
+			if status, _ := postMessage("see https://a.example"); status != http.StatusAccepted {
+				t.Errorf("Expected status 202, got %d", status)
+			}
+		})
+
+		t.Run("HTTPHookRejects", func(t *testing.T) {
+			if status, _ := postMessage("buy spam now"); status != http.StatusUnprocessableEntity {
+				t.Errorf("Expected status 422, got %d", status)
+			}
+		})
+
+		t.Run("HTTPHookModifies", func(t *testing.T) {
+			status, key := postMessage("shout: hello")
+			if status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+			if got := storedContent(key); got != "SHOUT: HELLO" {
+				t.Errorf("Expected modified content, got %q", got)
+			}
+		})
+
+		t.Run("SchemaCheckedAfterHooks", func(t *testing.T) {
+			// "darn" fits the schema; the redaction that replaces it does not
+			schemaURL := strings.TrimSuffix(EndpointAdminHealth, "/health") + "/schemas/global"
+			schema := []byte(`{"type":"object","properties":{"content":{"type":"string","maxLength":8}}}`)
+			if status := requestStatus(t, "PUT", schemaURL, schema, AuthHeaders(TestAdminKey)); status != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", status)
+			}
+			defer requestStatus(t, "DELETE", schemaURL, nil, AuthHeaders(TestAdminKey))
+
+			if status, _ := postMessage("darn"); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for a redacted body over the limit, got %d", status)
+			}
+			if status, _ := postMessage("fine"); status != http.StatusAccepted {
+				t.Errorf("Expected status 202, got %d", status)
+			}
+		})
+	})
+}
+
+func TestContentPolicy_FailModes(t *testing.T) {
+	// a listener that is closed immediately so every call fails
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close()
+
+	policy := fmt.Sprintf(`content_policy:
+  http:
+    - url: %s
+      handlers: ["message.create"]
+      timeout: 500ms
+    - url: %s
+      handlers: ["thread.create"]
+      timeout: 500ms
+      fail_open: true`, deadURL, deadURL)
+
+	WithTestServerConfig(t, policy, func() {
+		user := "policy_failmode_user"
+		headers, err := SignedAuthHeaders(TestFrontendKey, user)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+
+		// thread.create fails open
+		threadKey := createTestThreads(t, headers, user, 1)[0]
+
+		t.Run("FailClosed", func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{"body": map[string]string{"content": "hello"}})
+			resp, err := DoRequest(t, "POST", ThreadMessagesURL(threadKey), body, headers)
+			if err != nil {
+				t.Fatalf("post message request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("Expected status 503, got %d", resp.StatusCode)
+			}
+		})
+	})
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+type directThreadResponse struct {
+	Key          string   `json:"key"`
+	Created      bool     `json:"created"`
+	Participants []string `json:"participants"`
+}
+
+func TestDirectThreads_Suite(t *testing.T) {
+	WithTestServer(t, func() {
+		alice, bob, carol := "dm_alice", "dm_bob", "dm_carol"
+		aliceHeaders, err := SignedAuthHeaders(TestFrontendKey, alice)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		bobHeaders, err := SignedAuthHeaders(TestFrontendKey, bob)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+
+		getOrCreate := func(headers map[string]string, participants ...string) (int, directThreadResponse) {
+			payload, _ := json.Marshal(map[string]interface{}{"participants": participants})
+			resp, err := DoRequest(t, "POST", EndpointFrontendThreads+"/dm", payload, headers)
+			if err != nil {
+				t.Errorf("dm request failed: %v", err)
+				return 0, directThreadResponse{}
+			}
+			defer resp.Body.Close()
+			var out directThreadResponse
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return resp.StatusCode, out
+		}
+
+		t.Run("Validation", func(t *testing.T) {
+			if status, _ := getOrCreate(aliceHeaders); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for no participants, got %d", status)
+			}
+			if status, _ := getOrCreate(aliceHeaders, alice); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for only the caller, got %d", status)
+			}
+		})
+
+		var pairKey string
+		var ownerHeaders map[string]string
+		t.Run("ConcurrentRequestsShareOneThread", func(t *testing.T) {
+			const callers = 8
+			var wg sync.WaitGroup
+			results := make([]directThreadResponse, callers)
+			for i := 0; i < callers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					// both sides of the pair, in either order
+					if i%2 == 0 {
+						_, results[i] = getOrCreate(aliceHeaders, bob)
+					} else {
+						_, results[i] = getOrCreate(bobHeaders, alice, bob)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			created := 0
+			pairKey = results[0].Key
+			for i, res := range results {
+				if res.Key == "" || res.Key != pairKey {
+					t.Fatalf("Expected every caller to get %s, got %+v", pairKey, results)
+				}
+				if res.Created {
+					created++
+					ownerHeaders = bobHeaders
+					if i%2 == 0 {
+						ownerHeaders = aliceHeaders
+					}
+				}
+			}
+			if created != 1 {
+				t.Errorf("Expected exactly one create, got %d", created)
+			}
+		})
+
+		t.Run("LookupAfterApply", func(t *testing.T) {
+			Retry(t, 10, 300*time.Millisecond, func() bool {
+				resp, err := DoRequest(t, "GET", ThreadMessagesURL(pairKey), nil, bobHeaders)
+				if err != nil {
+					return false
+				}
+				resp.Body.Close()
+				return resp.StatusCode == http.StatusOK
+			})
+
+			status, out := getOrCreate(bobHeaders, alice)
+			if status != http.StatusOK || out.Created || out.Key != pairKey {
+				t.Errorf("Expected existing thread %s with status 200, got %d %+v", pairKey, status, out)
+			}
+			if len(out.Participants) != 2 || out.Participants[0] != alice || out.Participants[1] != bob {
+				t.Errorf("Expected canonical participants, got %v", out.Participants)
+			}
+
+			_, group := getOrCreate(aliceHeaders, bob, carol)
+			if group.Key == "" || group.Key == pairKey || !group.Created {
+				t.Errorf("Expected a new thread for a different participant set, got %+v", group)
+			}
+		})
+
+		t.Run("ParticipantsAreFixed", func(t *testing.T) {
+			payload, _ := json.Marshal(map[string]string{"role": "member"})
+			resp, err := DoRequest(t, "PUT", EndpointFrontendThreads+"/"+pairKey+"/participants/"+carol, payload, ownerHeaders)
+			if err != nil {
+				t.Fatalf("participant request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusConflict {
+				t.Errorf("Expected status 409, got %d", resp.StatusCode)
+			}
+		})
+
+		t.Run("DeletedThreadIsReplaced", func(t *testing.T) {
+			resp, err := DoRequest(t, "DELETE", EndpointFrontendThreads+"/"+pairKey, nil, ownerHeaders)
+			if err != nil {
+				t.Fatalf("delete thread failed: %v", err)
+			}
+			resp.Body.Close()
+
+			var out directThreadResponse
+			Retry(t, 20, 300*time.Millisecond, func() bool {
+				_, out = getOrCreate(aliceHeaders, bob)
+				return out.Key != "" && out.Key != pairKey
+			})
+			if !out.Created {
+				t.Errorf("Expected a new thread after delete, got %+v", out)
+			}
+		})
+	})
+}
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEphemeralMessages_Suite(t *testing.T) {
+	// cron far in the future; runs are triggered through the admin purge job
+	WithTestServerConfig(t, "retention:\n  enabled: true\n  cron: \"0 0 1 1 *\"\n  mttl: 720h\n  tttl: 720h", func() {
+		user := "ephemeral_user"
+		headers, err := SignedAuthHeaders(TestFrontendKey, user)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		threadKey := createTestThreads(t, headers, user, 1)[0]
+
+		post := func(body map[string]interface{}) int {
+			payload, _ := json.Marshal(body)
+			resp, err := DoRequest(t, "POST", ThreadMessagesURL(threadKey), payload, headers)
+			if err != nil {
+				t.Fatalf("create message request failed: %v", err)
+			}
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+
+		list := func() MessagesListResponse {
+			resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey), nil, headers)
+			if err != nil {
+				t.Fatalf("list messages request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out MessagesListResponse
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			return out
+		}
+
+		t.Run("InvalidExpiresInRejected", func(t *testing.T) {
+			for _, v := range []interface{}{"-5s", "soon", 0} {
+				status := post(map[string]interface{}{
+					"body":       map[string]string{"content": "bad ttl"},
+					"expires_in": v,
+				})
+				if status != http.StatusBadRequest {
+					t.Errorf("expires_in %v: expected status 400, got %d", v, status)
+				}
+			}
+		})
+
+		t.Run("HiddenOnExpiryAndPurged", func(t *testing.T) {
+			if status := post(map[string]interface{}{
+				"body":       map[string]string{"content": "self destruct"},
+				"expires_in": "3s",
+			}); status != http.StatusAccepted && status != http.StatusOK {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+			if status := post(map[string]interface{}{
+				"body": map[string]string{"content": "keep me"},
+			}); status != http.StatusAccepted && status != http.StatusOK {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+
+			var out MessagesListResponse
+			Retry(t, 10, 300*time.Millisecond, func() bool {
+				out = list()
+				return len(out.Messages) == 2
+			})
+			if len(out.Messages) != 2 {
+				t.Fatalf("Expected 2 messages before expiry, got %d", len(out.Messages))
+			}
+			ephemeral := out.Messages[0]
+			if ephemeral.ExpiresAt == 0 {
+				t.Fatalf("Expected expires_at on ephemeral message, got %+v", ephemeral)
+			}
+
+			time.Sleep(time.Until(time.Unix(0, ephemeral.ExpiresAt)) + 500*time.Millisecond)
+
+			out = list()
+			if len(out.Messages) != 1 || out.Messages[0].ExpiresAt != 0 {
+				t.Fatalf("Expected only the permanent message after expiry, got %+v", out.Messages)
+			}
+
+			resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey)+"/"+ephemeral.Key, nil, headers)
+			if err != nil {
+				t.Fatalf("get message request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNotFound {
+				t.Errorf("Expected status 404 for expired message, got %d", resp.StatusCode)
+			}
+
+			resp, err = DoRequest(t, "POST", strings.TrimSuffix(EndpointAdminHealth, "/health")+"/jobs/purge", nil, AuthHeaders(TestAdminKey))
+			if err != nil {
+				t.Fatalf("purge request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status 200 from purge, got %d", resp.StatusCode)
+			}
+
+			out = list()
+			if len(out.Messages) != 1 || out.Messages[0].ExpiresAt != 0 {
+				t.Errorf("Expected only the permanent message after purge, got %+v", out.Messages)
+			}
+			if out.Pagination != nil && out.Pagination.Total > 2 {
+				t.Errorf("Expected purged message to leave the total, got %d", out.Pagination.Total)
+			}
+
+			resp, err = DoRequest(t, "GET", ThreadMessagesURL(threadKey)+"/"+ephemeral.Key, nil, headers)
+			if err != nil {
+				t.Fatalf("get message request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNotFound {
+				t.Errorf("Expected status 404 for purged message, got %d", resp.StatusCode)
+			}
+		})
+
+		t.Run("DeletedAndExpiredCountedOnce", func(t *testing.T) {
+			otherThread := createTestThreads(t, headers, user, 1)[0]
+			payload, _ := json.Marshal(map[string]interface{}{
+				"body":       map[string]string{"content": "gone twice"},
+				"expires_in": "2s",
+			})
+			resp, err := DoRequest(t, "POST", ThreadMessagesURL(otherThread), payload, headers)
+			if err != nil {
+				t.Fatalf("create message request failed: %v", err)
+			}
+			var created map[string]string
+			_ = json.NewDecoder(resp.Body).Decode(&created)
+			resp.Body.Close()
+			createTestMessages(t, headers, otherThread, 2)
+
+			if status := requestStatus(t, "DELETE", ThreadMessagesURL(otherThread)+"/"+created["key"], nil, headers); status != http.StatusOK && status != http.StatusAccepted {
+				t.Fatalf("Expected the delete to be accepted, got %d", status)
+			}
+			time.Sleep(3 * time.Second)
+
+			resp, err = DoRequest(t, "GET", ThreadMessagesURL(otherThread), nil, headers)
+			if err != nil {
+				t.Fatalf("list messages request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out MessagesListResponse
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			if len(out.Messages) != 2 || out.Pagination == nil || out.Pagination.Total != 2 {
+				t.Errorf("Expected 2 messages in a total of 2, got %d in %+v", len(out.Messages), out.Pagination)
+			}
+		})
+	})
+}
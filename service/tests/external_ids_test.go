@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestExternalIDs_Suite(t *testing.T) {
+	WithTestServer(t, func() {
+		user, other := "external_id_user", "external_id_other"
+		headers, err := SignedAuthHeaders(TestFrontendKey, user)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		otherHeaders, err := SignedAuthHeaders(TestFrontendKey, other)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+
+		post := func(url string, body map[string]interface{}, headers map[string]string) (int, string) {
+			payload, _ := json.Marshal(body)
+			resp, err := DoRequest(t, "POST", url, payload, headers)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out struct {
+				Key string `json:"key"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return resp.StatusCode, out.Key
+		}
+		readThread := func(url string, headers map[string]string) (int, string) {
+			resp, err := DoRequest(t, "GET", url, nil, headers)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out ThreadResponse
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return resp.StatusCode, out.Thread.Key
+		}
+		readMessage := func(url string) (int, string) {
+			resp, err := DoRequest(t, "GET", url, nil, headers)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out MessageResponse
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return resp.StatusCode, out.Message.Key
+		}
+		message := func(externalID string) map[string]interface{} {
+			return map[string]interface{}{"external_id": externalID, "body": map[string]interface{}{"text": "hi"}}
+		}
+
+		var threadKey string
+		t.Run("ThreadExternalID", func(t *testing.T) {
+			status, key := post(EndpointFrontendThreads, map[string]interface{}{"title": "Conversation", "external_id": "conv-42"}, headers)
+			if status != http.StatusAccepted || key == "" {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+			threadKey = key
+
+			// resolves while the create may still be in flight
+			if status, resolved := readThread(EndpointFrontendThreads+"/ext:conv-42", headers); status != http.StatusOK || resolved != threadKey {
+				t.Errorf("Expected ext: path to resolve to %s, got %d %q", threadKey, status, resolved)
+			}
+			if status, resolved := readThread(EndpointFrontendThreads+"/by-external-id/conv-42", headers); status != http.StatusOK || resolved != threadKey {
+				t.Errorf("Expected by-external-id to resolve to %s, got %d %q", threadKey, status, resolved)
+			}
+
+			if status, existing := post(EndpointFrontendThreads, map[string]interface{}{"title": "Again", "external_id": "conv-42"}, headers); status != http.StatusConflict || existing != threadKey {
+				t.Errorf("Expected status 409 with %s, got %d %q", threadKey, status, existing)
+			}
+			if status, _ := post(EndpointFrontendThreads, map[string]interface{}{"title": "Other", "external_id": "conv-42"}, otherHeaders); status != http.StatusAccepted {
+				t.Errorf("Expected another author to reuse the id, got %d", status)
+			}
+			if status, _ := post(EndpointFrontendThreads, map[string]interface{}{"title": "Bad", "external_id": "a:b"}, headers); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for invalid external id, got %d", status)
+			}
+			if status, _ := readThread(EndpointFrontendThreads+"/by-external-id/missing", headers); status != http.StatusNotFound {
+				t.Errorf("Expected status 404 for unknown external id, got %d", status)
+			}
+		})
+
+		t.Run("MessageExternalID", func(t *testing.T) {
+			messagesURL := EndpointFrontendThreads + "/ext:conv-42/messages"
+			status, provisional := post(messagesURL, message("msg-1"), headers)
+			if status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+			if status, existing := post(messagesURL, message("msg-1"), headers); status != http.StatusConflict || existing == "" {
+				t.Errorf("Expected status 409 with the existing key, got %d %q", status, existing)
+			}
+
+			status, messageKey := readMessage(messagesURL + "/ext:msg-1")
+			if status != http.StatusOK || messageKey == "" || messageKey == provisional {
+				t.Fatalf("Expected ext: message path to resolve to a final key, got %d %q", status, messageKey)
+			}
+			if status, _ := readMessage(messagesURL + "/ext:unknown"); status != http.StatusNotFound {
+				t.Errorf("Expected status 404 for unknown message external id, got %d", status)
+			}
+
+			resp, err := DoRequest(t, "DELETE", messagesURL+"/ext:msg-1", nil, headers)
+			if err != nil {
+				t.Fatalf("delete failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected delete to succeed, got %d", resp.StatusCode)
+			}
+
+			// a deleted message frees its external id
+			Retry(t, 10, 300*time.Millisecond, func() bool {
+				status, _ := post(messagesURL, message("msg-1"), headers)
+				return status == http.StatusAccepted
+			})
+		})
+	})
+}
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"progressdb/pkg/api/auth"
+)
+
+type signTokenResponse struct {
+	UserID    string `json:"userId"`
+	Token     string `json:"token"`
+	KeyID     string `json:"keyId"`
+	ExpiresAt int64  `json:"expiresAt"`
+	Signature string `json:"signature"`
+}
+
+func signToken(t *testing.T, body map[string]interface{}) (int, signTokenResponse) {
+	t.Helper()
+	payload, _ := json.Marshal(body)
+	resp, err := DoRequest(t, "POST", EndpointBackendSign, payload, AuthHeaders(TestBackendKey))
+	if err != nil {
+		t.Fatalf("sign request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var out signTokenResponse
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+func tokenHeaders(token string) map[string]string {
+	return map[string]string{
+		"Authorization":    "Bearer " + TestFrontendKey,
+		"X-User-Signature": token,
+	}
+}
+
+func TestFrontendTokens_Suite(t *testing.T) {
+	WithTestServer(t, func() {
+		user := "token_user"
+		headers, err := SignedAuthHeaders(TestFrontendKey, user)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		threads := createTestThreads(t, headers, user, 2)
+		threadBody := []byte(`{"title":"from token"}`)
+
+		t.Run("IssueAndUse", func(t *testing.T) {
+			status, out := signToken(t, map[string]interface{}{"userId": user})
+			if status != http.StatusOK || out.Token == "" || out.KeyID == "" || out.Signature == "" {
+				t.Fatalf("Expected a token and legacy signature, got %d %+v", status, out)
+			}
+			if out.ExpiresAt <= time.Now().Unix() {
+				t.Errorf("Expected a future expiry, got %d", out.ExpiresAt)
+			}
+
+			// the token carries the user id, so X-User-ID is optional
+			if status := requestStatus(t, "GET", EndpointFrontendThreads+"/"+threads[0], nil, tokenHeaders(out.Token)); status != http.StatusOK {
+				t.Errorf("Expected status 200 reading with a token, got %d", status)
+			}
+			if status := requestStatus(t, "POST", EndpointFrontendThreads, threadBody, tokenHeaders(out.Token)); status != http.StatusAccepted {
+				t.Errorf("Expected status 202 writing with a token, got %d", status)
+			}
+
+			mismatched := tokenHeaders(out.Token)
+			mismatched["X-User-ID"] = "someone_else"
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, mismatched); status != http.StatusUnauthorized {
+				t.Errorf("Expected status 401 for a mismatched user id, got %d", status)
+			}
+
+			tampered := out.Token[:len(out.Token)-2] + "AA"
+			if tampered == out.Token {
+				tampered = out.Token[:len(out.Token)-2] + "BB"
+			}
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, tokenHeaders(tampered)); status != http.StatusUnauthorized {
+				t.Errorf("Expected status 401 for a tampered token, got %d", status)
+			}
+		})
+
+		t.Run("Validation", func(t *testing.T) {
+			if status, _ := signToken(t, map[string]interface{}{"userId": user, "expiresIn": 365 * 24 * 3600}); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for a ttl beyond the maximum, got %d", status)
+			}
+			if status, _ := signToken(t, map[string]interface{}{"userId": user, "scope": "admin"}); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for an unknown scope, got %d", status)
+			}
+			if status, _ := signToken(t, map[string]interface{}{"userId": user, "threads": []string{"not-a-thread"}}); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for an invalid thread scope, got %d", status)
+			}
+		})
+
+		t.Run("Expiry", func(t *testing.T) {
+			_, out := signToken(t, map[string]interface{}{"userId": user, "expiresIn": 1})
+			time.Sleep(2 * time.Second)
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, tokenHeaders(out.Token)); status != http.StatusUnauthorized {
+				t.Errorf("Expected status 401 for an expired token, got %d", status)
+			}
+		})
+
+		t.Run("ReadScope", func(t *testing.T) {
+			_, out := signToken(t, map[string]interface{}{"userId": user, "scope": "read"})
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, tokenHeaders(out.Token)); status != http.StatusOK {
+				t.Errorf("Expected status 200 reading with a read token, got %d", status)
+			}
+			if status := requestStatus(t, "POST", EndpointFrontendThreads, threadBody, tokenHeaders(out.Token)); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 writing with a read token, got %d", status)
+			}
+		})
+
+		t.Run("ThreadScope", func(t *testing.T) {
+			_, out := signToken(t, map[string]interface{}{"userId": user, "threads": []string{threads[0]}})
+			if status := requestStatus(t, "GET", ThreadMessagesURL(threads[0]), nil, tokenHeaders(out.Token)); status != http.StatusOK {
+				t.Errorf("Expected status 200 in a scoped thread, got %d", status)
+			}
+			if status := requestStatus(t, "GET", ThreadMessagesURL(threads[1]), nil, tokenHeaders(out.Token)); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 outside the scoped threads, got %d", status)
+			}
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, tokenHeaders(out.Token)); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 listing threads with a thread-scoped token, got %d", status)
+			}
+
+			// threads named in the body are held to the scope too
+			move := []byte(`{"target":"` + threads[1] + `","messages":["m:missing"]}`)
+			if status := requestStatus(t, "POST", ThreadMessagesURL(threads[0])+":move", move, tokenHeaders(out.Token)); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 moving into a thread outside the scope, got %d", status)
+			}
+			batchURL := strings.TrimSuffix(EndpointFrontendThreads, "/threads") + "/batch"
+			batch := []byte(`{"ops":[{"op":"message.create","thread":"` + threads[0] + `","payload":{"body":{"content":"hi"}}}]}`)
+			if status := requestStatus(t, "POST", batchURL, batch, tokenHeaders(out.Token)); status != http.StatusForbidden {
+				t.Errorf("Expected status 403 for a multi-operation batch, got %d", status)
+			}
+		})
+	})
+}
+
+func TestFrontendTokens_RequireTokens(t *testing.T) {
+	WithTestServerConfig(t, "auth:\n  require_tokens: true\n", func() {
+		user := "token_only_user"
+		status, out := signToken(t, map[string]interface{}{"userId": user})
+		if status != http.StatusOK || out.Token == "" {
+			t.Fatalf("Expected a token, got %d %+v", status, out)
+		}
+		if out.Signature != "" {
+			t.Errorf("Expected no legacy signature when tokens are required")
+		}
+		if !strings.HasPrefix(out.Token, "v1.") {
+			t.Errorf("Expected a v1 token, got %q", out.Token)
+		}
+
+		if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, tokenHeaders(out.Token)); status != http.StatusOK {
+			t.Errorf("Expected status 200 with a token, got %d", status)
+		}
+
+		sig, err := auth.CreateHMACSignature(user, TestSigningKey)
+		if err != nil {
+			t.Fatalf("Failed to create signature: %v", err)
+		}
+		legacy := map[string]string{
+			"Authorization":    "Bearer " + TestFrontendKey,
+			"X-User-ID":        user,
+			"X-User-Signature": sig,
+		}
+		if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, legacy); status != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 for a legacy signature, got %d", status)
+		}
+	})
+}
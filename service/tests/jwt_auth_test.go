@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type jwtTestKey struct {
+	kid  string
+	alg  string
+	priv crypto.Signer
+}
+
+func (k jwtTestKey) jwk() map[string]string {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch pub := k.priv.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": k.kid, "use": "sig", "n": b64(pub.N.Bytes()), "e": b64(big.NewInt(int64(pub.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kty": "EC", "kid": k.kid, "crv": "P-256", "x": b64(pub.X.FillBytes(make([]byte, 32))), "y": b64(pub.Y.FillBytes(make([]byte, 32)))}
+	case ed25519.PublicKey:
+		return map[string]string{"kty": "OKP", "kid": k.kid, "crv": "Ed25519", "x": b64(pub)}
+	}
+	return nil
+}
+
+func (k jwtTestKey) sign(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": k.alg, "kid": k.kid, "typ": "JWT"})
+	body, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+
+	var sig []byte
+	var err error
+	sum := sha256.Sum256([]byte(signed))
+	switch priv := k.priv.(type) {
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, sum[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		if r, s, err = ecdsa.Sign(rand.Reader, priv, sum[:]); err == nil {
+			sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(priv, []byte(signed))
+	}
+	if err != nil {
+		t.Fatalf("sign jwt: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func writeJWKS(t *testing.T, path string, keys ...jwtTestKey) {
+	t.Helper()
+	jwks := make([]map[string]string, len(keys))
+	for i, k := range keys {
+		jwks[i] = k.jwk()
+	}
+	raw, _ := json.Marshal(map[string]interface{}{"keys": jwks})
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatalf("write jwks: %v", err)
+	}
+}
+
+func TestJWTAuth_Suite(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ec key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	_, rotatedKey, _ := ed25519.GenerateKey(rand.Reader)
+	keys := []jwtTestKey{
+		{kid: "rsa-1", alg: "RS256", priv: rsaKey},
+		{kid: "ec-1", alg: "ES256", priv: ecKey},
+		{kid: "ed-1", alg: "EdDSA", priv: edKey},
+	}
+	rotated := jwtTestKey{kid: "ed-2", alg: "EdDSA", priv: rotatedKey}
+
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksPath, keys...)
+	extra := fmt.Sprintf("auth:\n  jwt:\n    enabled: true\n    jwks_file: %s\n    issuer: https://idp.test\n    audience: progressdb\n    user_claim: uid\n", jwksPath)
+
+	WithTestServerConfig(t, extra, func() {
+		claims := func(user string, mutate func(map[string]interface{})) map[string]interface{} {
+			c := map[string]interface{}{
+				"uid": user,
+				"iss": "https://idp.test",
+				"aud": []string{"other", "progressdb"},
+				"iat": time.Now().Unix(),
+				"exp": time.Now().Add(time.Hour).Unix(),
+			}
+			if mutate != nil {
+				mutate(c)
+			}
+			return c
+		}
+		jwtHeaders := func(token string) map[string]string {
+			return map[string]string{
+				"X-API-Key":     TestFrontendKey,
+				"Authorization": "Bearer " + token,
+			}
+		}
+
+		t.Run("Algorithms", func(t *testing.T) {
+			for _, k := range keys {
+				token := k.sign(t, claims("jwt_user_"+k.kid, nil))
+				if status := requestStatus(t, "POST", EndpointFrontendThreads, []byte(`{"title":"jwt"}`), jwtHeaders(token)); status != http.StatusAccepted {
+					t.Errorf("%s: expected status 202 creating a thread, got %d", k.alg, status)
+				}
+				if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, jwtHeaders(token)); status != http.StatusOK {
+					t.Errorf("%s: expected status 200 listing threads, got %d", k.alg, status)
+				}
+			}
+		})
+
+		t.Run("Rejected", func(t *testing.T) {
+			cases := map[string]string{
+				"expired":      keys[2].sign(t, claims("jwt_user", func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Minute).Unix() })),
+				"not yet":      keys[2].sign(t, claims("jwt_user", func(c map[string]interface{}) { c["nbf"] = time.Now().Add(time.Hour).Unix() })),
+				"issuer":       keys[2].sign(t, claims("jwt_user", func(c map[string]interface{}) { c["iss"] = "https://evil.test" })),
+				"audience":     keys[2].sign(t, claims("jwt_user", func(c map[string]interface{}) { c["aud"] = "other" })),
+				"no user":      keys[2].sign(t, claims("", nil)),
+				"unknown key":  rotated.sign(t, claims("jwt_user", nil)),
+				"wrong alg":    jwtTestKey{kid: "ed-1", alg: "RS256", priv: rsaKey}.sign(t, claims("jwt_user", nil)),
+				"alg none":     jwtTestKey{kid: "ed-1", alg: "none", priv: edKey}.sign(t, claims("jwt_user", nil)),
+				"missing exp":  keys[2].sign(t, claims("jwt_user", func(c map[string]interface{}) { delete(c, "exp") })),
+				"tampered sig": keys[2].sign(t, claims("jwt_user", nil)) + "x",
+			}
+			for name, token := range cases {
+				if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, jwtHeaders(token)); status != http.StatusUnauthorized {
+					t.Errorf("%s: expected status 401, got %d", name, status)
+				}
+			}
+
+			// the jwt replaces the signature but not the frontend api key
+			token := keys[2].sign(t, claims("jwt_user", nil))
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, map[string]string{"Authorization": "Bearer " + token}); status != http.StatusUnauthorized {
+				t.Errorf("Expected status 401 without an api key, got %d", status)
+			}
+		})
+
+		t.Run("JWKSReload", func(t *testing.T) {
+			writeJWKS(t, jwksPath, append(keys, rotated)...)
+			token := rotated.sign(t, claims("jwt_rotated_user", nil))
+			Retry(t, 10, 500*time.Millisecond, func() bool {
+				return requestStatus(t, "GET", EndpointFrontendThreads, nil, jwtHeaders(token)) == http.StatusOK
+			})
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, jwtHeaders(token)); status != http.StatusOK {
+				t.Errorf("Expected the added key to be picked up, got %d", status)
+			}
+		})
+
+		t.Run("LegacySignatureStillAccepted", func(t *testing.T) {
+			headers, err := SignedAuthHeaders(TestFrontendKey, "jwt_legacy_user")
+			if err != nil {
+				t.Fatalf("Failed to get signed auth headers: %v", err)
+			}
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, headers); status != http.StatusOK {
+				t.Errorf("Expected status 200 with a signature, got %d", status)
+			}
+		})
+	})
+}
@@ -0,0 +1,232 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type MentionsListResponse struct {
+	Mentions []struct {
+		MessageKey string `json:"message_key"`
+		Thread     string `json:"thread"`
+		Author     string `json:"author"`
+		Read       bool   `json:"read"`
+	} `json:"mentions"`
+	Unread int `json:"unread"`
+}
+
+// mentions are off in the shared config; these tests read them from the body content
+const mentionsConfig = `mentions:
+  enabled: true
+  field: body.content`
+
+func TestMentions_Suite(t *testing.T) {
+	WithTestServerConfig(t, mentionsConfig, func() {
+		author := "mentions_author"
+		mentioned := "mentions_target"
+		outsider := "mentions_outsider"
+
+		authorHeaders, err := SignedAuthHeaders(TestFrontendKey, author)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		mentionedHeaders, err := SignedAuthHeaders(TestFrontendKey, mentioned)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		outsiderHeaders, err := SignedAuthHeaders(TestFrontendKey, outsider)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+
+		threadKey := createTestThreads(t, authorHeaders, author, 1)[0]
+		baseURL := EndpointFrontendThreads[:len(EndpointFrontendThreads)-len("/threads")]
+		mentionsURL := baseURL + "/mentions"
+
+		body, _ := json.Marshal(map[string]string{"role": "member"})
+		resp, err := DoRequest(t, "PUT", EndpointFrontendThreads+"/"+threadKey+"/participants/"+mentioned, body, authorHeaders)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		time.Sleep(2 * time.Second)
+
+		body, _ = json.Marshal(map[string]interface{}{"body": map[string]string{
+			"content": "hey @" + mentioned + " and @" + outsider + ", take a look",
+		}})
+		resp, err = DoRequest(t, "POST", ThreadMessagesURL(threadKey), body, authorHeaders)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+		}
+
+		listMentions := func(headers map[string]string, query string) MentionsListResponse {
+			resp, err := DoRequest(t, "GET", mentionsURL+query, nil, headers)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+			}
+			var out MentionsListResponse
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			return out
+		}
+
+		t.Run("ParticipantMentionIndexed", func(t *testing.T) {
+			Retry(t, 10, 500*time.Millisecond, func() bool {
+				return len(listMentions(mentionedHeaders, "").Mentions) == 1
+			})
+			out := listMentions(mentionedHeaders, "")
+			if out.Unread != 1 {
+				t.Errorf("Expected 1 unread mention, got %d", out.Unread)
+			}
+			if out.Mentions[0].Thread != threadKey || out.Mentions[0].Author != author {
+				t.Errorf("Unexpected mention: %+v", out.Mentions[0])
+			}
+		})
+
+		t.Run("NonParticipantNotIndexed", func(t *testing.T) {
+			if out := listMentions(outsiderHeaders, ""); len(out.Mentions) != 0 {
+				t.Errorf("Expected no mentions for non-participant, got %d", len(out.Mentions))
+			}
+		})
+
+		t.Run("MarkRead", func(t *testing.T) {
+			body, _ := json.Marshal(map[string]bool{"all": true})
+			resp, err := DoRequest(t, "POST", mentionsURL+"/read", body, mentionedHeaders)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+			}
+
+			if out := listMentions(mentionedHeaders, "?unread=true"); len(out.Mentions) != 0 || out.Unread != 0 {
+				t.Errorf("Expected no unread mentions, got %d (unread %d)", len(out.Mentions), out.Unread)
+			}
+			if out := listMentions(mentionedHeaders, ""); len(out.Mentions) != 1 || !out.Mentions[0].Read {
+				t.Errorf("Expected one read mention, got %+v", out.Mentions)
+			}
+		})
+
+		t.Run("EditRetractsMention", func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{"body": map[string]string{"content": "ping @" + mentioned}})
+			resp, err := DoRequest(t, "POST", ThreadMessagesURL(threadKey), body, authorHeaders)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			var created struct {
+				Key string `json:"key"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&created)
+			resp.Body.Close()
+			Retry(t, 10, 500*time.Millisecond, func() bool {
+				return len(listMentions(mentionedHeaders, "").Mentions) == 2
+			})
+
+			body, _ = json.Marshal(map[string]interface{}{"body": map[string]string{"content": "never mind"}})
+			resp, err = DoRequest(t, "PUT", ThreadMessagesURL(threadKey)+"/"+created.Key, body, authorHeaders)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+				t.Fatalf("Expected the edit to be accepted, got %d", resp.StatusCode)
+			}
+			Retry(t, 10, 500*time.Millisecond, func() bool {
+				return len(listMentions(mentionedHeaders, "").Mentions) == 1
+			})
+		})
+	})
+}
+
+func TestMentions_AutoParticipate(t *testing.T) {
+	WithTestServerConfig(t, mentionsConfig+"\n  auto_participate: true", func() {
+		owner := "auto_owner"
+		member := "auto_member"
+		byMember := "auto_by_member"
+		byOwner := "auto_by_owner"
+
+		headers := func(userID string) map[string]string {
+			h, err := SignedAuthHeaders(TestFrontendKey, userID)
+			if err != nil {
+				t.Fatalf("Failed to get signed auth headers: %v", err)
+			}
+			return h
+		}
+		status := func(method, url string, body []byte, h map[string]string) int {
+			resp, err := DoRequest(t, method, url, body, h)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+		ownerHeaders := headers(owner)
+		threadKey := createTestThreads(t, ownerHeaders, owner, 1)[0]
+
+		body, _ := json.Marshal(map[string]string{"role": "member"})
+		if status := status("PUT", EndpointFrontendThreads+"/"+threadKey+"/participants/"+member, body, ownerHeaders); status != http.StatusAccepted {
+			t.Fatalf("Expected status 202 adding the member, got %d", status)
+		}
+		time.Sleep(2 * time.Second)
+
+		post := func(threadKey string, h map[string]string, content string) {
+			body, _ := json.Marshal(map[string]interface{}{"body": map[string]string{"content": content}})
+			if code := status("POST", ThreadMessagesURL(threadKey), body, h); code != http.StatusAccepted {
+				t.Fatalf("Expected status 202 posting, got %d", code)
+			}
+		}
+		post(threadKey, headers(member), "hi @"+byMember)
+		post(threadKey, ownerHeaders, "hi @"+byOwner)
+
+		canRead := func(threadKey, userID string) bool {
+			return status("GET", ThreadMessagesURL(threadKey), nil, headers(userID)) == http.StatusOK
+		}
+		Retry(t, 10, 500*time.Millisecond, func() bool { return canRead(threadKey, byOwner) })
+		if canRead(threadKey, byMember) {
+			t.Errorf("Expected a member's mention not to add a participant")
+		}
+
+		t.Run("DirectThreadKeepsParticipants", func(t *testing.T) {
+			byDirect := "auto_by_direct"
+			payload, _ := json.Marshal(map[string]interface{}{"participants": []string{member}})
+			resp, err := DoRequest(t, "POST", EndpointFrontendThreads+"/dm", payload, ownerHeaders)
+			if err != nil {
+				t.Fatalf("dm request failed: %v", err)
+			}
+			var dm directThreadResponse
+			_ = json.NewDecoder(resp.Body).Decode(&dm)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusAccepted || dm.Key == "" {
+				t.Fatalf("Expected status 202 with a thread key, got %d %+v", resp.StatusCode, dm)
+			}
+			Retry(t, 10, 500*time.Millisecond, func() bool { return canRead(dm.Key, owner) })
+
+			post(dm.Key, ownerHeaders, "hi @"+byDirect)
+			Retry(t, 10, 500*time.Millisecond, func() bool {
+				resp, err := DoRequest(t, "GET", ThreadMessagesURL(dm.Key), nil, ownerHeaders)
+				if err != nil {
+					return false
+				}
+				defer resp.Body.Close()
+				var list MessagesListResponse
+				_ = json.NewDecoder(resp.Body).Decode(&list)
+				return len(list.Messages) == 1
+			})
+			if canRead(dm.Key, byDirect) {
+				t.Errorf("Expected a mention in a direct thread not to add a participant")
+			}
+		})
+	})
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"progressdb/pkg/store/keys"
+)
+
+type messageBatchResponse struct {
+	Thread string   `json:"thread"`
+	Keys   []string `json:"keys"`
+}
+
+func TestMessageBatch_Suite(t *testing.T) {
+	WithTestServer(t, func() {
+		user := "message_batch_user"
+		headers, err := SignedAuthHeaders(TestFrontendKey, user)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		threadKey := createTestThreads(t, headers, user, 1)[0]
+		batchURL := ThreadMessagesURL(threadKey) + ":batch"
+
+		postBatch := func(messages []map[string]interface{}) (int, messageBatchResponse) {
+			payload, _ := json.Marshal(map[string]interface{}{"messages": messages})
+			resp, err := DoRequest(t, "POST", batchURL, payload, headers)
+			if err != nil {
+				t.Fatalf("batch request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out messageBatchResponse
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return resp.StatusCode, out
+		}
+		message := func(content string) map[string]interface{} {
+			return map[string]interface{}{"body": map[string]string{"content": content}}
+		}
+
+		t.Run("Validation", func(t *testing.T) {
+			if status, _ := postBatch(nil); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for an empty batch, got %d", status)
+			}
+			tooMany := make([]map[string]interface{}, 101)
+			for i := range tooMany {
+				tooMany[i] = message("x")
+			}
+			if status, _ := postBatch(tooMany); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for an oversized batch, got %d", status)
+			}
+			scheduled := message("later")
+			scheduled["deliver_at"] = time.Now().Add(time.Hour).UnixNano()
+			if status, _ := postBatch([]map[string]interface{}{message("now"), scheduled}); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for deliver_at in a batch, got %d", status)
+			}
+			dup := []map[string]interface{}{message("a"), message("b")}
+			dup[0]["external_id"], dup[1]["external_id"] = "same", "same"
+			if status, _ := postBatch(dup); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for duplicate external ids, got %d", status)
+			}
+		})
+
+		t.Run("OrderedContiguousSequences", func(t *testing.T) {
+			const count = 20
+			messages := make([]map[string]interface{}, count)
+			for i := range messages {
+				messages[i] = message(fmt.Sprintf("part-%02d", i))
+			}
+			status, out := postBatch(messages)
+			if status != http.StatusAccepted || len(out.Keys) != count {
+				t.Fatalf("Expected status 202 with %d keys, got %d %+v", count, status, out)
+			}
+
+			var list MessagesListResponse
+			Retry(t, 20, 300*time.Millisecond, func() bool {
+				resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey)+"?limit=100", nil, headers)
+				if err != nil {
+					return false
+				}
+				defer resp.Body.Close()
+				list = MessagesListResponse{}
+				_ = json.NewDecoder(resp.Body).Decode(&list)
+				return len(list.Messages) == count
+			})
+			if len(list.Messages) != count {
+				t.Fatalf("Expected %d messages, got %d", count, len(list.Messages))
+			}
+
+			seqs := make(map[string]uint64, count)
+			for _, m := range list.Messages {
+				parts, err := keys.ParseMessageKey(m.Key)
+				if err != nil {
+					t.Fatalf("Failed to parse message key %s: %v", m.Key, err)
+				}
+				seq, err := strconv.ParseUint(parts.Seq, 10, 64)
+				if err != nil {
+					t.Fatalf("Failed to parse sequence of %s: %v", m.Key, err)
+				}
+				body, _ := m.Body.(map[string]interface{})
+				content, _ := body["content"].(string)
+				seqs[content] = seq
+			}
+			for i := 1; i < count; i++ {
+				prev, cur := seqs[fmt.Sprintf("part-%02d", i-1)], seqs[fmt.Sprintf("part-%02d", i)]
+				if cur != prev+1 {
+					t.Fatalf("Expected contiguous sequences in order, got %v", seqs)
+				}
+			}
+
+			// provisional keys resolve once applied
+			resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey)+"/"+out.Keys[count-1], nil, headers)
+			if err != nil {
+				t.Fatalf("read message failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var read MessageResponse
+			_ = json.NewDecoder(resp.Body).Decode(&read)
+			body, _ := read.Message.Body.(map[string]interface{})
+			if resp.StatusCode != http.StatusOK || body["content"] != fmt.Sprintf("part-%02d", count-1) {
+				t.Errorf("Expected last provisional key to resolve, got %d %+v", resp.StatusCode, read.Message)
+			}
+		})
+
+		t.Run("ExternalIDsReservedTogether", func(t *testing.T) {
+			first := []map[string]interface{}{message("one"), message("two")}
+			first[0]["external_id"], first[1]["external_id"] = "imp-1", "imp-2"
+			if status, _ := postBatch(first); status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+
+			again := []map[string]interface{}{message("three"), message("two again")}
+			again[0]["external_id"], again[1]["external_id"] = "imp-3", "imp-2"
+			if status, _ := postBatch(again); status != http.StatusConflict {
+				t.Fatalf("Expected status 409, got %d", status)
+			}
+
+			// nothing from the rejected batch was reserved
+			retry := []map[string]interface{}{message("three")}
+			retry[0]["external_id"] = "imp-3"
+			if status, _ := postBatch(retry); status != http.StatusAccepted {
+				t.Errorf("Expected imp-3 to remain free, got %d", status)
+			}
+		})
+	})
+}
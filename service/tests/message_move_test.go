@@ -0,0 +1,253 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"progressdb/pkg/store/keys"
+)
+
+type messageMoveResponse struct {
+	Thread   string `json:"thread"`
+	Target   string `json:"target"`
+	Messages []struct {
+		From string `json:"from"`
+		Key  string `json:"key"`
+	} `json:"messages"`
+}
+
+func TestMessageMove_Suite(t *testing.T) {
+	WithTestServer(t, func() {
+		user := "message_move_user"
+		headers, err := SignedAuthHeaders(TestFrontendKey, user)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+
+		postMessages := func(threadKey string, contents ...string) []string {
+			messages := make([]map[string]interface{}, len(contents))
+			for i, content := range contents {
+				messages[i] = map[string]interface{}{"body": map[string]string{"content": content}}
+			}
+			payload, _ := json.Marshal(map[string]interface{}{"messages": messages})
+			resp, err := DoRequest(t, "POST", ThreadMessagesURL(threadKey)+":batch", payload, headers)
+			if err != nil {
+				t.Fatalf("batch request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out messageBatchResponse
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			if resp.StatusCode != http.StatusAccepted || len(out.Keys) != len(contents) {
+				t.Fatalf("Expected status 202 with %d keys, got %d %+v", len(contents), resp.StatusCode, out)
+			}
+			return out.Keys
+		}
+		listMessages := func(threadKey string, want int) []string {
+			var contents []string
+			Retry(t, 20, 300*time.Millisecond, func() bool {
+				resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey)+"?limit=100", nil, headers)
+				if err != nil {
+					return false
+				}
+				defer resp.Body.Close()
+				var list MessagesListResponse
+				_ = json.NewDecoder(resp.Body).Decode(&list)
+				contents = contents[:0]
+				for _, m := range list.Messages {
+					body, _ := m.Body.(map[string]interface{})
+					content, _ := body["content"].(string)
+					contents = append(contents, content)
+				}
+				return len(list.Messages) == want
+			})
+			if len(contents) != want {
+				t.Fatalf("Expected %d messages in %s, got %v", want, threadKey, contents)
+			}
+			return contents
+		}
+		postMove := func(action, threadKey, target string, messages []string) (int, messageMoveResponse) {
+			payload, _ := json.Marshal(map[string]interface{}{"target": target, "messages": messages})
+			resp, err := DoRequest(t, "POST", ThreadMessagesURL(threadKey)+":"+action, payload, headers)
+			if err != nil {
+				t.Fatalf("%s request failed: %v", action, err)
+			}
+			defer resp.Body.Close()
+			var out messageMoveResponse
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return resp.StatusCode, out
+		}
+
+		t.Run("Validation", func(t *testing.T) {
+			threads := createTestThreads(t, headers, user, 2)
+			keys := postMessages(threads[0], "only")
+			listMessages(threads[0], 1)
+
+			if status, _ := postMove("move", threads[0], threads[1], nil); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 without messages, got %d", status)
+			}
+			if status, _ := postMove("move", threads[0], threads[0], keys); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 when target is the source, got %d", status)
+			}
+			if status, _ := postMove("move", threads[0], threads[1], []string{keys[0], keys[0]}); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for duplicated messages, got %d", status)
+			}
+			if status, _ := postMove("move", threads[1], threads[0], keys); status != http.StatusNotFound {
+				t.Errorf("Expected status 404 for a message from another thread, got %d", status)
+			}
+		})
+
+		t.Run("MoveKeepsOrderAndRedirects", func(t *testing.T) {
+			threads := createTestThreads(t, headers, user, 2)
+			source, target := threads[0], threads[1]
+			postMessages(target, "existing")
+			sourceKeys := postMessages(source, "a", "b", "c", "d")
+			listMessages(target, 1)
+			listMessages(source, 4)
+
+			status, out := postMove("move", source, target, []string{sourceKeys[2], sourceKeys[0]})
+			if status != http.StatusAccepted || len(out.Messages) != 2 {
+				t.Fatalf("Expected status 202 with 2 moved messages, got %d %+v", status, out)
+			}
+
+			if got := listMessages(source, 2); fmt.Sprint(got) != "[b d]" {
+				t.Errorf("Expected source to keep [b d], got %v", got)
+			}
+			if got := listMessages(target, 3); fmt.Sprint(got) != "[existing c a]" {
+				t.Errorf("Expected target to hold [existing c a], got %v", got)
+			}
+
+			// the moved messages take the next contiguous sequences of the target
+			var seqs []uint64
+			for _, m := range out.Messages {
+				resp, err := DoRequest(t, "GET", ThreadMessagesURL(target)+"/"+m.Key, nil, headers)
+				if err != nil {
+					t.Fatalf("read message failed: %v", err)
+				}
+				var read MessageResponse
+				_ = json.NewDecoder(resp.Body).Decode(&read)
+				resp.Body.Close()
+				parts, err := keys.ParseMessageKey(read.Message.Key)
+				if err != nil {
+					t.Fatalf("Failed to parse message key %q: %v", read.Message.Key, err)
+				}
+				seq, _ := strconv.ParseUint(parts.Seq, 10, 64)
+				seqs = append(seqs, seq)
+			}
+			if len(seqs) != 2 || seqs[1] != seqs[0]+1 {
+				t.Errorf("Expected contiguous target sequences, got %v", seqs)
+			}
+
+			// old keys resolve to the message in its new thread
+			resp, err := DoRequest(t, "GET", ThreadMessagesURL(source)+"/"+sourceKeys[0], nil, headers)
+			if err != nil {
+				t.Fatalf("read message failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var read MessageResponse
+			_ = json.NewDecoder(resp.Body).Decode(&read)
+			body, _ := read.Message.Body.(map[string]interface{})
+			if resp.StatusCode != http.StatusOK || read.Message.Thread != target || body["content"] != "a" {
+				t.Errorf("Expected old key to resolve to the moved message, got %d %+v", resp.StatusCode, read.Message)
+			}
+
+			// a moved message cannot be moved again from its old thread
+			if status, _ := postMove("move", source, target, []string{sourceKeys[0]}); status != http.StatusNotFound {
+				t.Errorf("Expected status 404 moving an already moved message, got %d", status)
+			}
+		})
+
+		t.Run("SameBatchCreateAndMove", func(t *testing.T) {
+			source := createTestThreads(t, headers, user, 1)[0]
+			existing := postMessages(source, "old")
+			listMessages(source, 1)
+
+			// the target and one of the moved messages only exist staged in the same batch
+			payload, _ := json.Marshal(map[string]interface{}{"ops": []map[string]interface{}{
+				{"op": "thread.create", "ref": "dest", "payload": map[string]string{"title": "dest"}},
+				{"op": "message.create", "ref": "fresh", "thread": source, "payload": map[string]interface{}{"body": map[string]string{"content": "fresh"}}},
+				{"op": "message.move", "thread": source, "payload": map[string]interface{}{"target": "$dest", "messages": []string{"$fresh", existing[0]}}},
+			}})
+			batchURL := strings.TrimSuffix(EndpointFrontendThreads, "/threads") + "/batch"
+			resp, err := DoRequest(t, "POST", batchURL, payload, headers)
+			if err != nil {
+				t.Fatalf("batch request failed: %v", err)
+			}
+			var out struct {
+				Results []struct {
+					Key  string   `json:"key"`
+					Keys []string `json:"keys"`
+				} `json:"results"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusAccepted || len(out.Results) != 3 || len(out.Results[2].Keys) != 2 {
+				t.Fatalf("Expected status 202 with the moved keys, got %d %+v", resp.StatusCode, out)
+			}
+			target := out.Results[0].Key
+
+			if got := listMessages(target, 2); fmt.Sprint(got) != "[fresh old]" {
+				t.Errorf("Expected target to hold [fresh old], got %v", got)
+			}
+			listMessages(source, 0)
+
+			// the message created and moved in one batch still resolves by its first key
+			resp, err = DoRequest(t, "GET", ThreadMessagesURL(source)+"/"+out.Results[1].Key, nil, headers)
+			if err != nil {
+				t.Fatalf("read message failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var read MessageResponse
+			_ = json.NewDecoder(resp.Body).Decode(&read)
+			body, _ := read.Message.Body.(map[string]interface{})
+			if resp.StatusCode != http.StatusOK || read.Message.Thread != target || body["content"] != "fresh" {
+				t.Errorf("Expected the created key to resolve to the moved message, got %d %+v", resp.StatusCode, read.Message)
+			}
+		})
+
+		t.Run("MoveIsAllOrNothing", func(t *testing.T) {
+			threads := createTestThreads(t, headers, user, 2)
+			source, target := threads[0], threads[1]
+			sourceKeys := postMessages(source, "a", "b")
+			listMessages(source, 2)
+
+			// the first move takes b before the second is applied, so only apply sees it is gone
+			if status, _ := postMove("move", source, target, []string{sourceKeys[1]}); status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+			if status, _ := postMove("move", source, target, []string{sourceKeys[0], sourceKeys[1]}); status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+
+			if got := listMessages(target, 1); fmt.Sprint(got) != "[b]" {
+				t.Errorf("Expected target to hold only [b], got %v", got)
+			}
+			time.Sleep(2 * time.Second)
+			if got := listMessages(source, 1); fmt.Sprint(got) != "[a]" {
+				t.Errorf("Expected the failed move to leave [a] in the source, got %v", got)
+			}
+		})
+
+		t.Run("CopyLeavesSource", func(t *testing.T) {
+			threads := createTestThreads(t, headers, user, 2)
+			source, target := threads[0], threads[1]
+			sourceKeys := postMessages(source, "x", "y")
+			listMessages(source, 2)
+
+			status, _ := postMove("copy", source, target, sourceKeys)
+			if status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+			if got := listMessages(target, 2); fmt.Sprint(got) != "[x y]" {
+				t.Errorf("Expected target to hold copies [x y], got %v", got)
+			}
+			if got := listMessages(source, 2); fmt.Sprint(got) != "[x y]" {
+				t.Errorf("Expected source to keep [x y], got %v", got)
+			}
+		})
+	})
+}
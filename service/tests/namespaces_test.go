@@ -0,0 +1,230 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	acmeBackendKey  = "acme-backend-key"
+	acmeFrontendKey = "acme-frontend-key"
+	acmeAdminKey    = "acme-admin-key"
+	acmeSigningKey  = "acme-signing-key"
+)
+
+// acme keeps message bodies in the clear while the default namespace encrypts them
+var namespacesConfig = `namespaces:
+  - id: acme
+    api_keys:
+      backend: ["` + acmeBackendKey + `"]
+      frontend: ["` + acmeFrontendKey + `"]
+      admin: ["` + acmeAdminKey + `"]
+      signing: ["` + acmeSigningKey + `"]
+    encryption:
+      enabled: false`
+
+// namespaceHeaders signs userID with the backend key of a namespace and returns frontend
+// headers for the frontend key of the same namespace.
+func namespaceHeaders(t *testing.T, backendKey, frontendKey, userID string) map[string]string {
+	t.Helper()
+	payload, _ := json.Marshal(map[string]string{"userId": userID})
+	resp, err := DoRequest(t, "POST", EndpointBackendSign, payload, AuthHeaders(backendKey))
+	if err != nil {
+		t.Fatalf("sign request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected sign to succeed, got %d", resp.StatusCode)
+	}
+	var out struct {
+		Signature string `json:"signature"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return map[string]string{
+		"Authorization":    "Bearer " + frontendKey,
+		"X-User-ID":        userID,
+		"X-User-Signature": out.Signature,
+	}
+}
+
+func TestNamespaces_Suite(t *testing.T) {
+	WithTestServerConfig(t, namespacesConfig, func() {
+		user := "ns_user"
+		adminURL := strings.TrimSuffix(EndpointAdminHealth, "/health")
+		defaultHeaders := namespaceHeaders(t, TestBackendKey, TestFrontendKey, user)
+		acmeHeaders := namespaceHeaders(t, acmeBackendKey, acmeFrontendKey, user)
+
+		createThread := func(headers map[string]string, title string) string {
+			payload, _ := json.Marshal(map[string]string{"title": title})
+			resp, err := DoRequest(t, "POST", EndpointFrontendThreads, payload, headers)
+			if err != nil {
+				t.Fatalf("create thread failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out struct {
+				Key string `json:"key"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			if resp.StatusCode != http.StatusAccepted || out.Key == "" {
+				t.Fatalf("Expected thread creation to be accepted, got %d %+v", resp.StatusCode, out)
+			}
+			return out.Key
+		}
+		listThreads := func(headers map[string]string) []string {
+			resp, err := DoRequest(t, "GET", EndpointFrontendThreads, nil, headers)
+			if err != nil {
+				t.Fatalf("list threads failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out ThreadsListResponse
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			threadKeys := make([]string, 0, len(out.Threads))
+			for _, th := range out.Threads {
+				threadKeys = append(threadKeys, th.Key)
+			}
+			return threadKeys
+		}
+
+		defaultThread := createThread(defaultHeaders, "default thread")
+		acmeThread := createThread(acmeHeaders, "acme thread")
+		if strings.HasPrefix(defaultThread, "n:") || !strings.HasPrefix(acmeThread, "n:acme:t:") {
+			t.Fatalf("Expected an unprefixed default key and an n:acme: key, got %s and %s", defaultThread, acmeThread)
+		}
+		Retry(t, 20, 200*time.Millisecond, func() bool {
+			return requestStatus(t, "GET", EndpointFrontendThreads+"/"+acmeThread, nil, acmeHeaders) == http.StatusOK &&
+				requestStatus(t, "GET", EndpointFrontendThreads+"/"+defaultThread, nil, defaultHeaders) == http.StatusOK
+		})
+
+		t.Run("ThreadsAreIsolated", func(t *testing.T) {
+			if got := listThreads(acmeHeaders); len(got) != 1 || got[0] != acmeThread {
+				t.Errorf("Expected acme to list only %s, got %v", acmeThread, got)
+			}
+			if got := listThreads(defaultHeaders); len(got) != 1 || got[0] != defaultThread {
+				t.Errorf("Expected the default namespace to list only %s, got %v", defaultThread, got)
+			}
+			if status := requestStatus(t, "GET", EndpointFrontendThreads+"/"+defaultThread, nil, acmeHeaders); status != http.StatusNotFound {
+				t.Errorf("Expected 404 reading a default thread from acme, got %d", status)
+			}
+			if status := requestStatus(t, "GET", EndpointFrontendThreads+"/"+acmeThread, nil, defaultHeaders); status != http.StatusNotFound {
+				t.Errorf("Expected 404 reading an acme thread from the default namespace, got %d", status)
+			}
+			payload, _ := json.Marshal(map[string]interface{}{"body": map[string]string{"content": "intruder"}})
+			if status := requestStatus(t, "POST", ThreadMessagesURL(acmeThread), payload, defaultHeaders); status != http.StatusNotFound {
+				t.Errorf("Expected 404 posting into an acme thread from the default namespace, got %d", status)
+			}
+		})
+
+		t.Run("SignaturesAreScoped", func(t *testing.T) {
+			crossed := map[string]string{
+				"Authorization":    "Bearer " + acmeFrontendKey,
+				"X-User-ID":        user,
+				"X-User-Signature": defaultHeaders["X-User-Signature"],
+			}
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, crossed); status != http.StatusUnauthorized {
+				t.Errorf("Expected a default namespace signature to be rejected by acme, got %d", status)
+			}
+		})
+
+		t.Run("EncryptionPolicyPerNamespace", func(t *testing.T) {
+			post := func(headers map[string]string, threadKey, content string) {
+				payload, _ := json.Marshal(map[string]interface{}{"body": map[string]string{"content": content}})
+				if status := requestStatus(t, "POST", ThreadMessagesURL(threadKey), payload, headers); status != http.StatusAccepted {
+					t.Fatalf("Expected message to be accepted, got %d", status)
+				}
+			}
+			messageKey := func(headers map[string]string, threadKey string) string {
+				var key string
+				Retry(t, 20, 200*time.Millisecond, func() bool {
+					resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey), nil, headers)
+					if err != nil {
+						return false
+					}
+					defer resp.Body.Close()
+					var out MessagesListResponse
+					_ = json.NewDecoder(resp.Body).Decode(&out)
+					if len(out.Messages) == 0 {
+						return false
+					}
+					key = out.Messages[0].Key
+					return true
+				})
+				return key
+			}
+			rawValue := func(key, namespace string) string {
+				u := adminURL + "/keys/" + url.PathEscape(key) + "?store=main"
+				if namespace != "" {
+					u += "&namespace=" + namespace
+				}
+				resp, err := DoRequest(t, "GET", u, nil, AuthHeaders(TestAdminKey))
+				if err != nil {
+					t.Fatalf("get key failed: %v", err)
+				}
+				defer resp.Body.Close()
+				body, _ := io.ReadAll(resp.Body)
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("Expected raw key %s to be readable, got %d: %s", key, resp.StatusCode, body)
+				}
+				return string(body)
+			}
+
+			post(acmeHeaders, acmeThread, "acme plaintext")
+			post(defaultHeaders, defaultThread, "default secret")
+			if raw := rawValue(messageKey(acmeHeaders, acmeThread), "acme"); !strings.Contains(raw, "acme plaintext") {
+				t.Errorf("Expected acme to store bodies in the clear, got %s", raw)
+			}
+			if raw := rawValue(messageKey(defaultHeaders, defaultThread), ""); strings.Contains(raw, "default secret") {
+				t.Errorf("Expected the default namespace to encrypt bodies, got %s", raw)
+			}
+		})
+
+		t.Run("AdminScope", func(t *testing.T) {
+			acmeAdmin := AuthHeaders(acmeAdminKey)
+			if status := requestStatus(t, "GET", adminURL+"/signing-keys?namespace=other", nil, acmeAdmin); status != http.StatusForbidden {
+				t.Errorf("Expected 403 for an acme admin picking another namespace, got %d", status)
+			}
+			if status := requestStatus(t, "GET", adminURL+"/signing-keys?namespace=missing", nil, AuthHeaders(TestAdminKey)); status != http.StatusNotFound {
+				t.Errorf("Expected 404 for an unknown namespace, got %d", status)
+			}
+			if status := requestStatus(t, "POST", adminURL+"/jobs/purge", nil, acmeAdmin); status != http.StatusForbidden {
+				t.Errorf("Expected 403 for an acme admin on a server-wide route, got %d", status)
+			}
+			if status := requestStatus(t, "GET", adminURL+"/keys/"+url.PathEscape(defaultThread)+"?store=main", nil, acmeAdmin); status != http.StatusNotFound {
+				t.Errorf("Expected 404 for an acme admin reading a default key, got %d", status)
+			}
+
+			userThreads := func(headers map[string]string, query string) string {
+				resp, err := DoRequest(t, "GET", adminURL+"/users/"+user+"/threads"+query, nil, headers)
+				if err != nil {
+					t.Fatalf("list user threads failed: %v", err)
+				}
+				defer resp.Body.Close()
+				body, _ := io.ReadAll(resp.Body)
+				return string(body)
+			}
+			if body := userThreads(acmeAdmin, ""); !strings.Contains(body, acmeThread) || strings.Contains(body, `"`+defaultThread+`"`) {
+				t.Errorf("Expected the acme admin to see only acme threads, got %s", body)
+			}
+			if body := userThreads(AuthHeaders(TestAdminKey), "?namespace=acme"); !strings.Contains(body, acmeThread) {
+				t.Errorf("Expected ?namespace=acme to list acme threads, got %s", body)
+			}
+
+			resp, err := DoRequest(t, "GET", adminURL+"/signing-keys", nil, acmeAdmin)
+			if err != nil {
+				t.Fatalf("list signing keys failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out struct {
+				Keys []signingKeyResponse `json:"keys"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			if len(out.Keys) != 1 || out.Keys[0].State != "active" {
+				t.Errorf("Expected acme to list its single signing key, got %+v", out.Keys)
+			}
+		})
+	})
+}
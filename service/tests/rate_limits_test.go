@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRateLimits_Suite(t *testing.T) {
+	limits := "limits:\n  user:\n    rps: 0.01\n    burst: 8\n  routes:\n    message_create:\n      rps: 0.01\n      burst: 2\n"
+	WithTestServerConfig(t, limits, func() {
+		message, _ := json.Marshal(map[string]interface{}{"body": map[string]string{"content": "hi"}})
+		signed := func(user string) map[string]string {
+			headers, err := SignedAuthHeaders(TestFrontendKey, user)
+			if err != nil {
+				t.Fatalf("Failed to get signed auth headers: %v", err)
+			}
+			return headers
+		}
+		alice, bob := signed("limits_alice"), signed("limits_bob")
+		aliceThread := createTestThreads(t, alice, "limits_alice", 1)[0]
+		bobThread := createTestThreads(t, bob, "limits_bob", 1)[0]
+
+		t.Run("RouteClass", func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				if status := requestStatus(t, "POST", ThreadMessagesURL(aliceThread), message, alice); status != http.StatusAccepted {
+					t.Fatalf("Expected status 202 for message %d, got %d", i+1, status)
+				}
+			}
+			resp, err := DoRequest(t, "POST", ThreadMessagesURL(aliceThread), message, alice)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusTooManyRequests {
+				t.Fatalf("Expected status 429 once message creates are used up, got %d", resp.StatusCode)
+			}
+			if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err != nil || secs < 1 {
+				t.Errorf("Expected a Retry-After in seconds, got %q", resp.Header.Get("Retry-After"))
+			}
+			if resp.Header.Get("RateLimit-Limit") != "2" || resp.Header.Get("RateLimit-Remaining") != "0" {
+				t.Errorf("Expected the message bucket in the headers, got limit %q remaining %q", resp.Header.Get("RateLimit-Limit"), resp.Header.Get("RateLimit-Remaining"))
+			}
+			if resp.Header.Get("RateLimit-Reset") == "" {
+				t.Error("Expected a RateLimit-Reset header")
+			}
+
+			// batches, moves and copies put messages into threads too
+			batch := []byte(`{"ops":[{"op":"message.create","thread":"` + aliceThread + `","payload":{"body":{"content":"hi"}}}]}`)
+			if status := requestStatus(t, "POST", strings.TrimSuffix(EndpointFrontendThreads, "/threads")+"/batch", batch, alice); status != http.StatusTooManyRequests {
+				t.Errorf("Expected status 429 for a batch once message creates are used up, got %d", status)
+			}
+			move := []byte(`{"target":"` + aliceThread + `","messages":["m:missing"]}`)
+			if status := requestStatus(t, "POST", ThreadMessagesURL(aliceThread)+":copy", move, alice); status != http.StatusTooManyRequests {
+				t.Errorf("Expected status 429 for a copy once message creates are used up, got %d", status)
+			}
+
+			// reads are another class
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, alice); status != http.StatusOK {
+				t.Errorf("Expected status 200 reading threads, got %d", status)
+			}
+		})
+
+		t.Run("PerUser", func(t *testing.T) {
+			limited := false
+			for i := 0; i < 10 && !limited; i++ {
+				limited = requestStatus(t, "GET", EndpointFrontendThreads, nil, alice) == http.StatusTooManyRequests
+			}
+			if !limited {
+				t.Fatal("Expected the user limit to throttle alice")
+			}
+			if status := requestStatus(t, "POST", ThreadMessagesURL(bobThread), message, bob); status != http.StatusAccepted {
+				t.Errorf("Expected bob to be unaffected, got %d", status)
+			}
+			resp, err := DoRequest(t, "GET", EndpointFrontendThreads, nil, bob)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Expected status 200 for bob, got %d", resp.StatusCode)
+			}
+			if n, err := strconv.Atoi(resp.Header.Get("RateLimit-Remaining")); err != nil || n < 1 {
+				t.Errorf("Expected bob to have requests left, got %q", resp.Header.Get("RateLimit-Remaining"))
+			}
+		})
+	})
+}
+
+func TestDailyQuotas_Suite(t *testing.T) {
+	WithTestServerConfig(t, "limits:\n  quotas:\n    messages_per_day: 3\n    threads_per_day: 1\n", func() {
+		user := "quota_user"
+		headers, err := SignedAuthHeaders(TestFrontendKey, user)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		threadKey := createTestThreads(t, headers, user, 1)[0]
+		message := map[string]interface{}{"body": map[string]string{"content": "hi"}}
+
+		t.Run("Threads", func(t *testing.T) {
+			if status := requestStatus(t, "POST", EndpointFrontendThreads, []byte(`{"title":"second"}`), headers); status != http.StatusTooManyRequests {
+				t.Errorf("Expected status 429 for a second thread, got %d", status)
+			}
+		})
+
+		t.Run("Messages", func(t *testing.T) {
+			batch, _ := json.Marshal(map[string]interface{}{"messages": []interface{}{message, message}})
+			if status := requestStatus(t, "POST", ThreadMessagesURL(threadKey)+":batch", batch, headers); status != http.StatusAccepted {
+				t.Fatalf("Expected status 202 for a batch within quota, got %d", status)
+			}
+			over, _ := json.Marshal(map[string]interface{}{"messages": []interface{}{message, message}})
+			if status := requestStatus(t, "POST", ThreadMessagesURL(threadKey)+":batch", over, headers); status != http.StatusTooManyRequests {
+				t.Errorf("Expected status 429 for a batch over quota, got %d", status)
+			}
+
+			single, _ := json.Marshal(message)
+			if status := requestStatus(t, "POST", ThreadMessagesURL(threadKey), single, headers); status != http.StatusAccepted {
+				t.Fatalf("Expected status 202 for the last message, got %d", status)
+			}
+			resp, err := DoRequest(t, "POST", ThreadMessagesURL(threadKey), single, headers)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusTooManyRequests {
+				t.Fatalf("Expected status 429 once the quota is used up, got %d", resp.StatusCode)
+			}
+			if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err != nil || secs < 1 || secs > 86400 {
+				t.Errorf("Expected Retry-After until midnight, got %q", resp.Header.Get("Retry-After"))
+			}
+
+			// other users have their own quota
+			other, err := SignedAuthHeaders(TestFrontendKey, "quota_other")
+			if err != nil {
+				t.Fatalf("Failed to get signed auth headers: %v", err)
+			}
+			createTestThreads(t, other, "quota_other", 1)
+		})
+
+		t.Run("Refunds", func(t *testing.T) {
+			user := "quota_refund"
+			headers, err := SignedAuthHeaders(TestFrontendKey, user)
+			if err != nil {
+				t.Fatalf("Failed to get signed auth headers: %v", err)
+			}
+
+			// the thread counted before the messages were refused is given back
+			ops := []map[string]interface{}{{"op": "thread.create", "ref": "t", "payload": map[string]string{"title": "refund"}}}
+			for i := 0; i < 4; i++ {
+				ops = append(ops, map[string]interface{}{"op": "message.create", "thread": "$t", "payload": message})
+			}
+			batch, _ := json.Marshal(map[string]interface{}{"ops": ops})
+			if status := requestStatus(t, "POST", strings.TrimSuffix(EndpointFrontendThreads, "/threads")+"/batch", batch, headers); status != http.StatusTooManyRequests {
+				t.Fatalf("Expected status 429 for a batch over the message quota, got %d", status)
+			}
+			threadKey := createTestThreads(t, headers, user, 1)[0]
+
+			// a rejected create is not counted
+			dup, _ := json.Marshal(map[string]interface{}{"external_id": "once", "body": map[string]string{"content": "hi"}})
+			if status := requestStatus(t, "POST", ThreadMessagesURL(threadKey), dup, headers); status != http.StatusAccepted {
+				t.Fatalf("Expected status 202 for the first message, got %d", status)
+			}
+			if status := requestStatus(t, "POST", ThreadMessagesURL(threadKey), dup, headers); status != http.StatusConflict {
+				t.Fatalf("Expected status 409 reusing an external id, got %d", status)
+			}
+			single, _ := json.Marshal(message)
+			for i := 0; i < 2; i++ {
+				if status := requestStatus(t, "POST", ThreadMessagesURL(threadKey), single, headers); status != http.StatusAccepted {
+					t.Errorf("Expected status 202 for message %d within quota, got %d", i+2, status)
+				}
+			}
+		})
+	})
+}
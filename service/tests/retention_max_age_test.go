@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetentionMaxAge_Suite(t *testing.T) {
+	WithTestServerConfig(t, "retention:\n  enabled: true\n  cron: \"0 0 1 1 *\"\n  mttl: 720h\n  tttl: 720h\n  max_age: 3s\n  max_idle: 10s", func() {
+		adminURL := strings.TrimSuffix(EndpointAdminHealth, "/health")
+		admin := AuthHeaders(TestAdminKey)
+		user := "max_age_user"
+		headers, err := SignedAuthHeaders(TestFrontendKey, user)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+
+		runPurge := func() {
+			resp, err := DoRequest(t, "POST", adminURL+"/jobs/purge", nil, admin)
+			if err != nil {
+				t.Fatalf("purge failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status 200 from purge, got %d", resp.StatusCode)
+			}
+		}
+		storedKeyExists := func(key string) bool {
+			resp, err := DoRequest(t, "GET", adminURL+"/keys/"+url.PathEscape(key)+"?store=main", nil, admin)
+			if err != nil {
+				t.Fatalf("get key failed: %v", err)
+			}
+			resp.Body.Close()
+			return resp.StatusCode == http.StatusOK
+		}
+		listMessages := func(threadKey string) MessagesListResponse {
+			var out MessagesListResponse
+			resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey), nil, headers)
+			if err != nil {
+				t.Fatalf("list messages failed: %v", err)
+			}
+			defer resp.Body.Close()
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return out
+		}
+		waitForMessages := func(threadKey string, n int) {
+			Retry(t, 10, 300*time.Millisecond, func() bool {
+				return len(listMessages(threadKey).Messages) == n
+			})
+		}
+
+		threadKeys := createTestThreads(t, headers, user, 2)
+		active, idle := threadKeys[0], threadKeys[1]
+		createTestMessages(t, headers, active, 2)
+		createTestMessages(t, headers, idle, 1)
+		waitForMessages(active, 2)
+		waitForMessages(idle, 1)
+
+		t.Run("OldMessagesArePurged", func(t *testing.T) {
+			time.Sleep(3500 * time.Millisecond)
+			createTestMessages(t, headers, active, 1)
+			waitForMessages(active, 3)
+
+			runPurge()
+			out := listMessages(active)
+			if len(out.Messages) != 1 {
+				t.Fatalf("Expected 1 message after purge, got %d", len(out.Messages))
+			}
+			if out.Pagination == nil || out.Pagination.Total != 1 {
+				t.Errorf("Expected pagination total 1, got %+v", out.Pagination)
+			}
+			if len(listMessages(idle).Messages) != 0 {
+				t.Errorf("Expected old message in idle thread to be purged")
+			}
+			if !storedKeyExists(idle) {
+				t.Errorf("Thread was purged before reaching max idle")
+			}
+
+			createTestMessages(t, headers, active, 1)
+			waitForMessages(active, 2)
+			if out := listMessages(active); out.Pagination == nil || out.Pagination.Total != 2 {
+				t.Errorf("Expected pagination total 2 after new message, got %+v", out.Pagination)
+			}
+		})
+
+		t.Run("IdleThreadsArePurged", func(t *testing.T) {
+			time.Sleep(3 * time.Second)
+			runPurge()
+			if storedKeyExists(idle) {
+				t.Errorf("Expected idle thread to be purged")
+			}
+			if !storedKeyExists(active) {
+				t.Errorf("Active thread was purged")
+			}
+		})
+	})
+}
@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"progressdb/pkg/models"
+)
+
+func TestRetentionPolicies_Suite(t *testing.T) {
+	// short ttls so deleted items are purgeable by the time the admin job runs
+	WithTestServerConfig(t, "retention:\n  enabled: true\n  cron: \"0 0 1 1 *\"\n  mttl: 1s\n  tttl: 1s", func() {
+		adminURL := strings.TrimSuffix(EndpointAdminHealth, "/health")
+		admin := AuthHeaders(TestAdminKey)
+		user := "retention_user"
+		headers, err := SignedAuthHeaders(TestFrontendKey, user)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+
+		do := func(method, url string, body interface{}, headers map[string]string) int {
+			var payload []byte
+			if body != nil {
+				payload, _ = json.Marshal(body)
+			}
+			resp, err := DoRequest(t, method, url, payload, headers)
+			if err != nil {
+				t.Fatalf("%s %s failed: %v", method, url, err)
+			}
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+		storedKeyExists := func(key string) bool {
+			return do("GET", adminURL+"/keys/"+url.PathEscape(key)+"?store=main", nil, admin) == http.StatusOK
+		}
+		runPurge := func() {
+			if status := do("POST", adminURL+"/jobs/purge", nil, admin); status != http.StatusOK {
+				t.Fatalf("Expected status 200 from purge, got %d", status)
+			}
+		}
+		deleteThread := func(threadKey string) {
+			if status := do("DELETE", EndpointFrontendThreads+"/"+threadKey, nil, headers); status != http.StatusOK && status != http.StatusAccepted {
+				t.Fatalf("Expected thread delete to be accepted, got %d", status)
+			}
+		}
+
+		t.Run("PolicyManagement", func(t *testing.T) {
+			if status := do("PUT", adminURL+"/retention/policies/tag/legal", map[string]interface{}{"never_purge": true, "keep_days": 30}, admin); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for never_purge with keep_days, got %d", status)
+			}
+			if status := do("PUT", adminURL+"/retention/policies/team/legal", map[string]interface{}{"keep_days": 30}, admin); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for unknown scope, got %d", status)
+			}
+			if status := do("PUT", adminURL+"/retention/policies/tag/support", map[string]interface{}{"keep_days": 90, "purge_deleted_days": 7}, admin); status != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", status)
+			}
+
+			resp, err := DoRequest(t, "GET", adminURL+"/retention/policies", nil, admin)
+			if err != nil {
+				t.Fatalf("list policies failed: %v", err)
+			}
+			var listed struct {
+				Policies []models.RetentionPolicy `json:"policies"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&listed)
+			resp.Body.Close()
+			if len(listed.Policies) != 1 || listed.Policies[0].Target != "support" || listed.Policies[0].KeepDays != 90 {
+				t.Errorf("Unexpected policies: %+v", listed.Policies)
+			}
+
+			if status := do("DELETE", adminURL+"/retention/policies/tag/support", nil, admin); status != http.StatusNoContent {
+				t.Errorf("Expected status 204, got %d", status)
+			}
+			if status := do("DELETE", adminURL+"/retention/policies/tag/support", nil, admin); status != http.StatusNotFound {
+				t.Errorf("Expected status 404, got %d", status)
+			}
+		})
+
+		threadKeys := createTestThreads(t, headers, user, 3)
+		held, neverPurge, plain := threadKeys[0], threadKeys[1], threadKeys[2]
+
+		t.Run("HoldAndPolicyBlockPurge", func(t *testing.T) {
+			if status := do("PUT", adminURL+"/retention/holds/"+held, map[string]string{"reason": "case 42"}, admin); status != http.StatusOK {
+				t.Fatalf("Expected status 200 placing hold, got %d", status)
+			}
+			if status := do("PUT", adminURL+"/retention/policies/thread/"+neverPurge, map[string]interface{}{"never_purge": true}, admin); status != http.StatusOK {
+				t.Fatalf("Expected status 200 setting policy, got %d", status)
+			}
+
+			resp, err := DoRequest(t, "GET", adminURL+"/retention/holds", nil, admin)
+			if err != nil {
+				t.Fatalf("list holds failed: %v", err)
+			}
+			var listed struct {
+				Holds []models.LegalHold `json:"holds"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&listed)
+			resp.Body.Close()
+			if len(listed.Holds) != 1 || listed.Holds[0].Thread != held || listed.Holds[0].Reason != "case 42" {
+				t.Errorf("Unexpected holds: %+v", listed.Holds)
+			}
+
+			for _, threadKey := range threadKeys {
+				deleteThread(threadKey)
+			}
+			time.Sleep(2500 * time.Millisecond) // apply + past tttl
+
+			runPurge()
+			if !storedKeyExists(held) {
+				t.Errorf("Held thread was purged")
+			}
+			if !storedKeyExists(neverPurge) {
+				t.Errorf("never_purge thread was purged")
+			}
+			if storedKeyExists(plain) {
+				t.Errorf("Expected thread without policy to be purged")
+			}
+		})
+
+		t.Run("ReleasedHoldIsPurged", func(t *testing.T) {
+			if status := do("DELETE", adminURL+"/retention/holds/"+held, nil, admin); status != http.StatusNoContent {
+				t.Fatalf("Expected status 204 releasing hold, got %d", status)
+			}
+			if status := do("DELETE", adminURL+"/retention/holds/"+held, nil, admin); status != http.StatusNotFound {
+				t.Errorf("Expected status 404 releasing twice, got %d", status)
+			}
+			runPurge()
+			if storedKeyExists(held) {
+				t.Errorf("Expected released thread to be purged")
+			}
+			if !storedKeyExists(neverPurge) {
+				t.Errorf("never_purge thread was purged")
+			}
+		})
+
+		t.Run("DeletedMessagesFollowThreadHold", func(t *testing.T) {
+			threads := createTestThreads(t, headers, user, 2)
+			heldThread, openThread := threads[0], threads[1]
+			if status := do("PUT", adminURL+"/retention/holds/"+heldThread, nil, admin); status != http.StatusOK {
+				t.Fatalf("Expected status 200 placing hold, got %d", status)
+			}
+
+			messageKeys := make(map[string]string)
+			for _, threadKey := range threads {
+				createTestMessages(t, headers, threadKey, 1)
+				var out MessagesListResponse
+				Retry(t, 10, 300*time.Millisecond, func() bool {
+					resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey), nil, headers)
+					if err != nil {
+						return false
+					}
+					defer resp.Body.Close()
+					_ = json.NewDecoder(resp.Body).Decode(&out)
+					return len(out.Messages) == 1
+				})
+				messageKey := out.Messages[0].Key
+				messageKeys[threadKey] = messageKey
+				if status := do("DELETE", ThreadMessagesURL(threadKey)+"/"+messageKey, nil, headers); status != http.StatusOK && status != http.StatusAccepted {
+					t.Fatalf("Expected message delete to be accepted, got %d", status)
+				}
+			}
+			time.Sleep(2500 * time.Millisecond) // apply + past mttl
+
+			runPurge()
+			if !storedKeyExists(messageKeys[heldThread]) {
+				t.Errorf("Deleted message in held thread was purged")
+			}
+			if storedKeyExists(messageKeys[openThread]) {
+				t.Errorf("Expected deleted message to be purged")
+			}
+			if !storedKeyExists(openThread) {
+				t.Errorf("Live thread was purged with its deleted message")
+			}
+		})
+	})
+}
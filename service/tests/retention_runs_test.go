@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type retentionRun struct {
+	ID         string         `json:"id"`
+	Trigger    string         `json:"trigger"`
+	DryRun     bool           `json:"dry_run"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Threads    int            `json:"threads"`
+	Messages   int            `json:"messages"`
+	Bytes      int64          `json:"bytes"`
+	Reasons    map[string]int `json:"reasons"`
+	ThreadKeys []string       `json:"thread_keys"`
+}
+
+func TestRetentionRuns_Suite(t *testing.T) {
+	WithTestServerConfig(t, "retention:\n  enabled: true\n  cron: \"0 0 1 1 *\"\n  mttl: 1s\n  tttl: 1s", func() {
+		adminURL := strings.TrimSuffix(EndpointAdminHealth, "/health")
+		admin := AuthHeaders(TestAdminKey)
+		user := "retention_runs_user"
+		headers, err := SignedAuthHeaders(TestFrontendKey, user)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+
+		purge := func(query string) retentionRun {
+			resp, err := DoRequest(t, "POST", adminURL+"/jobs/purge"+query, nil, admin)
+			if err != nil {
+				t.Fatalf("purge failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status 200 from purge, got %d", resp.StatusCode)
+			}
+			var out struct {
+				Run retentionRun `json:"run"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return out.Run
+		}
+		listRuns := func(query string) (int, []retentionRun) {
+			resp, err := DoRequest(t, "GET", adminURL+"/jobs/retention/runs"+query, nil, admin)
+			if err != nil {
+				t.Fatalf("list runs failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out struct {
+				Runs []retentionRun `json:"runs"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return resp.StatusCode, out.Runs
+		}
+		threadExists := func(threadKey string) bool {
+			resp, err := DoRequest(t, "GET", adminURL+"/keys/"+url.PathEscape(threadKey)+"?store=main", nil, admin)
+			if err != nil {
+				t.Fatalf("get key failed: %v", err)
+			}
+			resp.Body.Close()
+			return resp.StatusCode == http.StatusOK
+		}
+
+		threadKey := createTestThreads(t, headers, user, 1)[0]
+		resp, err := DoRequest(t, "DELETE", EndpointFrontendThreads+"/"+threadKey, nil, headers)
+		if err != nil {
+			t.Fatalf("delete thread failed: %v", err)
+		}
+		resp.Body.Close()
+		time.Sleep(2500 * time.Millisecond) // apply + past tttl
+
+		t.Run("DryRunPurgesNothing", func(t *testing.T) {
+			run := purge("?dry_run=true")
+			if !run.DryRun || run.Trigger != "manual" {
+				t.Errorf("Expected manual dry run, got %+v", run)
+			}
+			if run.Threads != 1 || run.Reasons["deleted_thread"] != 1 || run.Bytes <= 0 {
+				t.Errorf("Expected one deleted thread with a size, got %+v", run)
+			}
+			if len(run.ThreadKeys) != 1 || run.ThreadKeys[0] != threadKey {
+				t.Errorf("Expected dry run to list %s, got %v", threadKey, run.ThreadKeys)
+			}
+			if !threadExists(threadKey) {
+				t.Errorf("Dry run purged the thread")
+			}
+		})
+
+		t.Run("RealRunPurges", func(t *testing.T) {
+			run := purge("")
+			if run.DryRun || run.Threads != 1 {
+				t.Errorf("Expected one purged thread, got %+v", run)
+			}
+			if len(run.ThreadKeys) != 0 {
+				t.Errorf("Expected no key listing outside dry runs, got %v", run.ThreadKeys)
+			}
+			if threadExists(threadKey) {
+				t.Errorf("Expected thread to be purged")
+			}
+		})
+
+		t.Run("History", func(t *testing.T) {
+			status, runs := listRuns("")
+			if status != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", status)
+			}
+			if len(runs) != 2 {
+				t.Fatalf("Expected 2 runs, got %d", len(runs))
+			}
+			if runs[0].DryRun || !runs[1].DryRun {
+				t.Errorf("Expected newest run first, got %+v", runs)
+			}
+			for _, run := range runs {
+				if run.StartedAt.IsZero() || run.FinishedAt.Before(run.StartedAt) {
+					t.Errorf("Expected start and end times, got %+v", run)
+				}
+				if len(run.ThreadKeys) != 0 {
+					t.Errorf("Expected persisted runs to omit key listings, got %v", run.ThreadKeys)
+				}
+			}
+
+			if _, runs := listRuns("?limit=1"); len(runs) != 1 {
+				t.Errorf("Expected 1 run with limit=1, got %d", len(runs))
+			}
+			if status, _ := listRuns("?limit=0"); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for limit=0, got %d", status)
+			}
+		})
+	})
+}
@@ -0,0 +1,230 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"progressdb/pkg/models"
+)
+
+type ScheduledMessagesListResponse struct {
+	Thread   string           `json:"thread"`
+	Messages []models.Message `json:"messages"`
+}
+
+func TestScheduledMessages_Suite(t *testing.T) {
+	WithTestServerConfig(t, "scheduler:\n  poll_interval: 200ms", func() {
+		user := "scheduled_user"
+		headers, err := SignedAuthHeaders(TestFrontendKey, user)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		threadKey := createTestThreads(t, headers, user, 1)[0]
+		scheduledURL := EndpointFrontendThreads + "/" + threadKey + "/scheduled"
+
+		schedule := func(content string, deliverAt int64) (int, string) {
+			body, _ := json.Marshal(map[string]interface{}{
+				"body":       map[string]string{"content": content},
+				"deliver_at": deliverAt,
+			})
+			resp, err := DoRequest(t, "POST", ThreadMessagesURL(threadKey), body, headers)
+			if err != nil {
+				t.Fatalf("schedule request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out struct {
+				Key string `json:"key"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return resp.StatusCode, out.Key
+		}
+
+		listScheduled := func() []models.Message {
+			resp, err := DoRequest(t, "GET", scheduledURL, nil, headers)
+			if err != nil {
+				t.Fatalf("list scheduled request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+			}
+			var out ScheduledMessagesListResponse
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			return out.Messages
+		}
+
+		threadContents := func() []string {
+			resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey), nil, headers)
+			if err != nil {
+				t.Fatalf("list messages request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out MessagesListResponse
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			contents := make([]string, 0, len(out.Messages))
+			for _, m := range out.Messages {
+				body, _ := m.Body.(map[string]interface{})
+				content, _ := body["content"].(string)
+				contents = append(contents, content)
+			}
+			return contents
+		}
+
+		t.Run("PastDeliverAtRejected", func(t *testing.T) {
+			if status, _ := schedule("too late", time.Now().Add(-time.Minute).UnixNano()); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", status)
+			}
+		})
+
+		t.Run("HiddenUntilDeliveredThenReleased", func(t *testing.T) {
+			status, key := schedule("reminder", time.Now().Add(3*time.Second).UnixNano())
+			if status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+
+			pending := listScheduled()
+			if len(pending) != 1 || pending[0].Key != key {
+				t.Fatalf("Expected scheduled message %s to be pending, got %+v", key, pending)
+			}
+			for _, c := range threadContents() {
+				if c == "reminder" {
+					t.Fatalf("Scheduled message visible in thread before delivery")
+				}
+			}
+
+			// a message posted meanwhile is sequenced before the scheduled one
+			createTestMessages(t, headers, threadKey, 1)
+
+			var contents []string
+			Retry(t, 20, 500*time.Millisecond, func() bool {
+				contents = threadContents()
+				return len(contents) == 2
+			})
+			if len(contents) != 2 || contents[1] != "reminder" {
+				t.Errorf("Expected released message after the earlier post, got %v", contents)
+			}
+			if pending := listScheduled(); len(pending) != 0 {
+				t.Errorf("Expected no pending scheduled messages, got %d", len(pending))
+			}
+		})
+
+		t.Run("CancelBeforeDelivery", func(t *testing.T) {
+			status, key := schedule("cancelled announcement", time.Now().Add(2*time.Second).UnixNano())
+			if status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+
+			resp, err := DoRequest(t, "DELETE", scheduledURL+"/"+key, nil, headers)
+			if err != nil {
+				t.Fatalf("cancel request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+			}
+
+			time.Sleep(3 * time.Second)
+			for _, c := range threadContents() {
+				if c == "cancelled announcement" {
+					t.Errorf("Cancelled scheduled message was delivered")
+				}
+			}
+
+			resp, err = DoRequest(t, "DELETE", scheduledURL+"/"+key, nil, headers)
+			if err != nil {
+				t.Fatalf("cancel request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNotFound {
+				t.Errorf("Expected status 404 on second cancel, got %d", resp.StatusCode)
+			}
+		})
+
+		t.Run("OtherUsersCannotCancel", func(t *testing.T) {
+			status, key := schedule("mine", time.Now().Add(time.Hour).UnixNano())
+			if status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+			otherHeaders, err := SignedAuthHeaders(TestFrontendKey, "scheduled_other")
+			if err != nil {
+				t.Fatalf("Failed to get signed auth headers: %v", err)
+			}
+			resp, err := DoRequest(t, "DELETE", scheduledURL+"/"+key, nil, otherHeaders)
+			if err != nil {
+				t.Fatalf("cancel request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusForbidden {
+				t.Errorf("Expected status 403, got %d", resp.StatusCode)
+			}
+		})
+	})
+}
+
+func TestScheduledMessages_Release(t *testing.T) {
+	// counts the content policy calls made for message creates
+	var hookCalls int32
+	hookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hookCalls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"action": "allow"})
+	}))
+	defer hookServer.Close()
+
+	extra := fmt.Sprintf(`scheduler:
+  poll_interval: 200ms
+content_policy:
+  http:
+    - url: %s
+      handlers: ["message.create"]
+      timeout: 1s`, hookServer.URL)
+
+	WithTestServerConfig(t, extra, func() {
+		user := "scheduled_release_user"
+		headers, err := SignedAuthHeaders(TestFrontendKey, user)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		threadKey := createTestThreads(t, headers, user, 1)[0]
+
+		headers["X-Request-Id"] = "scheduled-req-1"
+		body, _ := json.Marshal(map[string]interface{}{
+			"body":       map[string]string{"content": "later"},
+			"deliver_at": time.Now().Add(time.Second).UnixNano(),
+		})
+		if status := requestStatus(t, "POST", ThreadMessagesURL(threadKey), body, headers); status != http.StatusAccepted {
+			t.Fatalf("Expected status 202, got %d", status)
+		}
+
+		// the released message carries the scheduling request into the audit trail
+		auditURL := strings.TrimSuffix(EndpointAdminHealth, "/health") + "/audit?" + url.Values{"thread": {threadKey}, "action": {"message.create"}}.Encode()
+		var records []auditRecord
+		Retry(t, 20, 500*time.Millisecond, func() bool {
+			resp, err := DoRequest(t, "GET", auditURL, nil, AuthHeaders(TestAdminKey))
+			if err != nil {
+				return false
+			}
+			defer resp.Body.Close()
+			var page auditPage
+			_ = json.NewDecoder(resp.Body).Decode(&page)
+			records = page.Records
+			return len(records) == 1
+		})
+		if rec := records[0]; rec.ReqID != "scheduled-req-1" || rec.Role != "frontend" || rec.IP == "" {
+			t.Errorf("Expected the scheduling request's metadata on the release, got %+v", rec)
+		}
+
+		if calls := atomic.LoadInt32(&hookCalls); calls != 1 {
+			t.Errorf("Expected content policies to run once, ran %d times", calls)
+		}
+	})
+}
@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageSchemas_Suite(t *testing.T) {
+	WithTestServer(t, func() {
+		user := "schema_user"
+		headers, err := SignedAuthHeaders(TestFrontendKey, user)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		adminHeaders := AuthHeaders(TestAdminKey)
+		schemasURL := strings.TrimSuffix(EndpointAdminHealth, "/health") + "/schemas"
+
+		plainThread := createTestThreads(t, headers, user, 1)[0]
+
+		body, _ := json.Marshal(map[string]interface{}{"title": "tickets", "tags": []string{"Ticket"}})
+		resp, err := DoRequest(t, "POST", EndpointFrontendThreads, body, headers)
+		if err != nil {
+			t.Fatalf("create thread request failed: %v", err)
+		}
+		var created map[string]string
+		_ = json.NewDecoder(resp.Body).Decode(&created)
+		resp.Body.Close()
+		ticketThread := created["key"]
+		Retry(t, 10, 500*time.Millisecond, func() bool {
+			resp, err := DoRequest(t, "GET", EndpointFrontendThreads+"/"+ticketThread, nil, headers)
+			if err != nil {
+				return false
+			}
+			defer resp.Body.Close()
+			var out ThreadResponse
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				return false
+			}
+			return len(out.Thread.Tags) == 1 && out.Thread.Tags[0] == "ticket"
+		})
+
+		putSchema := func(url string, schema string) int {
+			resp, err := DoRequest(t, "PUT", url, []byte(schema), adminHeaders)
+			if err != nil {
+				t.Fatalf("put schema request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			return resp.StatusCode
+		}
+
+		type schemaError struct {
+			Error  string `json:"error"`
+			Fields []struct {
+				Field   string `json:"field"`
+				Message string `json:"message"`
+			} `json:"fields"`
+		}
+		postMessage := func(threadKey string, msgBody map[string]interface{}) (int, schemaError) {
+			payload, _ := json.Marshal(map[string]interface{}{"body": msgBody})
+			resp, err := DoRequest(t, "POST", ThreadMessagesURL(threadKey), payload, headers)
+			if err != nil {
+				t.Fatalf("post message request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out schemaError
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return resp.StatusCode, out
+		}
+
+		t.Run("InvalidSchemaRejected", func(t *testing.T) {
+			if status := putSchema(schemasURL+"/global", `{"type":"strng"}`); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", status)
+			}
+		})
+
+		t.Run("GlobalSchemaEnforced", func(t *testing.T) {
+			schema := `{"type":"object","required":["content"],"properties":{"content":{"type":"string","maxLength":20}}}`
+			if status := putSchema(schemasURL+"/global", schema); status != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", status)
+			}
+
+			status, out := postMessage(plainThread, map[string]interface{}{"text": "wrong field"})
+			if status != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d", status)
+			}
+			if len(out.Fields) != 1 || out.Fields[0].Field != "body.content" {
+				t.Errorf("Expected a body.content field error, got %+v", out.Fields)
+			}
+
+			status, out = postMessage(plainThread, map[string]interface{}{"content": strings.Repeat("x", 21)})
+			if status != http.StatusBadRequest || len(out.Fields) != 1 || out.Fields[0].Field != "body.content" {
+				t.Errorf("Expected maxLength violation on body.content, got %d %+v", status, out.Fields)
+			}
+
+			if status, _ := postMessage(plainThread, map[string]interface{}{"content": "hello"}); status != http.StatusAccepted {
+				t.Errorf("Expected status 202, got %d", status)
+			}
+		})
+
+		t.Run("TagSchemaEnforced", func(t *testing.T) {
+			schema := `{"type":"object","required":["priority"],"properties":{"priority":{"enum":["low","high"]}}}`
+			if status := putSchema(schemasURL+"/tags/ticket", schema); status != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", status)
+			}
+
+			status, out := postMessage(ticketThread, map[string]interface{}{"content": "help"})
+			if status != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d", status)
+			}
+			if len(out.Fields) != 1 || out.Fields[0].Field != "body.priority" {
+				t.Errorf("Expected a body.priority field error, got %+v", out.Fields)
+			}
+
+			if status, _ := postMessage(ticketThread, map[string]interface{}{"content": "help", "priority": "high"}); status != http.StatusAccepted {
+				t.Errorf("Expected status 202, got %d", status)
+			}
+			// untagged threads only see the global schema
+			if status, _ := postMessage(plainThread, map[string]interface{}{"content": "no priority"}); status != http.StatusAccepted {
+				t.Errorf("Expected status 202, got %d", status)
+			}
+		})
+
+		t.Run("UpdateValidated", func(t *testing.T) {
+			payload, _ := json.Marshal(map[string]interface{}{"body": map[string]string{"content": "original"}})
+			resp, err := DoRequest(t, "POST", ThreadMessagesURL(plainThread), payload, headers)
+			if err != nil {
+				t.Fatalf("post message request failed: %v", err)
+			}
+			var msg map[string]string
+			_ = json.NewDecoder(resp.Body).Decode(&msg)
+			resp.Body.Close()
+
+			payload, _ = json.Marshal(map[string]interface{}{"body": map[string]int{"content": 5}})
+			resp, err = DoRequest(t, "PUT", ThreadMessagesURL(plainThread)+"/"+msg["key"], payload, headers)
+			if err != nil {
+				t.Fatalf("update message request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", resp.StatusCode)
+			}
+		})
+
+		t.Run("MoveValidatedAgainstTarget", func(t *testing.T) {
+			payload, _ := json.Marshal(map[string]interface{}{"body": map[string]string{"content": "untriaged"}})
+			resp, err := DoRequest(t, "POST", ThreadMessagesURL(plainThread), payload, headers)
+			if err != nil {
+				t.Fatalf("post message request failed: %v", err)
+			}
+			var msg map[string]string
+			_ = json.NewDecoder(resp.Body).Decode(&msg)
+			resp.Body.Close()
+
+			// fine in the plain thread, missing the priority the ticket schema requires
+			payload, _ = json.Marshal(map[string]interface{}{"target": ticketThread, "messages": []string{msg["key"]}})
+			for _, action := range []string{"move", "copy"} {
+				resp, err := DoRequest(t, "POST", ThreadMessagesURL(plainThread)+":"+action, payload, headers)
+				if err != nil {
+					t.Fatalf("%s request failed: %v", action, err)
+				}
+				var out schemaError
+				_ = json.NewDecoder(resp.Body).Decode(&out)
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusBadRequest || len(out.Fields) != 1 || out.Fields[0].Field != "body.priority" {
+					t.Errorf("Expected %s to fail the target's schema, got %d %+v", action, resp.StatusCode, out.Fields)
+				}
+			}
+		})
+
+		t.Run("ListAndDelete", func(t *testing.T) {
+			resp, err := DoRequest(t, "GET", schemasURL, nil, adminHeaders)
+			if err != nil {
+				t.Fatalf("list schemas request failed: %v", err)
+			}
+			var out struct {
+				Schemas []struct {
+					Scope string `json:"scope"`
+					Tag   string `json:"tag"`
+				} `json:"schemas"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			resp.Body.Close()
+			if len(out.Schemas) != 2 || out.Schemas[0].Scope != "global" || out.Schemas[1].Tag != "ticket" {
+				t.Fatalf("Expected global and ticket schemas, got %+v", out.Schemas)
+			}
+
+			resp, err = DoRequest(t, "DELETE", schemasURL+"/tags/ticket", nil, adminHeaders)
+			if err != nil {
+				t.Fatalf("delete schema request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNoContent {
+				t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+			}
+
+			if status, _ := postMessage(ticketThread, map[string]interface{}{"content": "no priority"}); status != http.StatusAccepted {
+				t.Errorf("Expected status 202 after removing tag schema, got %d", status)
+			}
+		})
+	})
+}
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"progressdb/pkg/api/auth"
+)
+
+type signingKeyResponse struct {
+	ID     string `json:"id"`
+	Secret string `json:"secret"`
+	State  string `json:"state"`
+	Source string `json:"source"`
+}
+
+func TestSigningKeys_Suite(t *testing.T) {
+	WithTestServer(t, func() {
+		adminHeaders := AuthHeaders(TestAdminKey)
+		keysURL := strings.TrimSuffix(EndpointAdminHealth, "/health") + "/signing-keys"
+		user := "signing_key_user"
+
+		listKeys := func() map[string]signingKeyResponse {
+			resp, err := DoRequest(t, "GET", keysURL, nil, adminHeaders)
+			if err != nil {
+				t.Fatalf("list signing keys failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out struct {
+				Keys []signingKeyResponse `json:"keys"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			byID := make(map[string]signingKeyResponse, len(out.Keys))
+			for _, k := range out.Keys {
+				if k.Secret != "" {
+					t.Errorf("Expected listings to omit secrets, got one for %s", k.ID)
+				}
+				byID[k.ID] = k
+			}
+			return byID
+		}
+		keyRequest := func(method, url string, body map[string]string) (int, signingKeyResponse) {
+			payload, _ := json.Marshal(body)
+			resp, err := DoRequest(t, method, url, payload, adminHeaders)
+			if err != nil {
+				t.Fatalf("signing key request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out signingKeyResponse
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			return resp.StatusCode, out
+		}
+		signatureHeaders := func(sig string) map[string]string {
+			return map[string]string{
+				"Authorization":    "Bearer " + TestFrontendKey,
+				"X-User-ID":        user,
+				"X-User-Signature": sig,
+			}
+		}
+
+		configKey := listKeys()
+		if len(configKey) != 1 {
+			t.Fatalf("Expected the single configured key, got %+v", configKey)
+		}
+		var configID string
+		for id, k := range configKey {
+			configID = id
+			if k.State != "active" || k.Source != "config" {
+				t.Fatalf("Expected an active config key, got %+v", k)
+			}
+		}
+
+		_, before := signToken(t, map[string]interface{}{"userId": user})
+		if before.KeyID != configID || !strings.HasPrefix(before.Signature, configID+":") {
+			t.Fatalf("Expected signature and token from %s, got %+v", configID, before)
+		}
+		bare, err := auth.CreateHMACSignature(user, TestSigningKey)
+		if err != nil {
+			t.Fatalf("Failed to create signature: %v", err)
+		}
+
+		t.Run("Rotate", func(t *testing.T) {
+			status, rotated := keyRequest("POST", keysURL+"/rotate", map[string]string{"id": "2026-10"})
+			if status != http.StatusOK || rotated.ID != "2026-10" || rotated.State != "active" || rotated.Secret != "" {
+				t.Fatalf("Expected the new key to be active, got %d %+v", status, rotated)
+			}
+			if got := listKeys()[configID].State; got != "verify" {
+				t.Errorf("Expected the previous key to be verify-only, got %q", got)
+			}
+
+			_, after := signToken(t, map[string]interface{}{"userId": user})
+			if after.KeyID != "2026-10" || !strings.HasPrefix(after.Signature, "2026-10:") {
+				t.Fatalf("Expected signature and token from the new key, got %+v", after)
+			}
+			for name, sig := range map[string]string{"old signature": before.Signature, "old token": before.Token, "bare signature": bare, "new signature": after.Signature, "new token": after.Token} {
+				if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, signatureHeaders(sig)); status != http.StatusOK {
+					t.Errorf("%s: expected status 200, got %d", name, status)
+				}
+			}
+
+			// a signature naming the wrong key fails even with a valid mac
+			wrongKey := "2026-10:" + strings.TrimPrefix(before.Signature, configID+":")
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, signatureHeaders(wrongKey)); status != http.StatusUnauthorized {
+				t.Errorf("Expected status 401 for a signature naming the wrong key, got %d", status)
+			}
+		})
+
+		t.Run("Validation", func(t *testing.T) {
+			if status, _ := keyRequest("POST", keysURL+"/rotate", map[string]string{"id": "2026-10"}); status != http.StatusConflict {
+				t.Errorf("Expected status 409 for a duplicate id, got %d", status)
+			}
+			if status, _ := keyRequest("POST", keysURL+"/rotate", map[string]string{"id": "bad:id"}); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for an invalid id, got %d", status)
+			}
+			if status, _ := keyRequest("PUT", keysURL+"/2026-10", map[string]string{"state": "retired"}); status != http.StatusConflict {
+				t.Errorf("Expected status 409 retiring the active key, got %d", status)
+			}
+			if status, _ := keyRequest("PUT", keysURL+"/missing", map[string]string{"state": "verify"}); status != http.StatusNotFound {
+				t.Errorf("Expected status 404 for an unknown key, got %d", status)
+			}
+			if status, _ := keyRequest("PUT", keysURL+"/"+configID, map[string]string{"state": "disabled"}); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for an unknown state, got %d", status)
+			}
+		})
+
+		t.Run("Retire", func(t *testing.T) {
+			status, retired := keyRequest("PUT", keysURL+"/"+configID, map[string]string{"state": "retired"})
+			if status != http.StatusOK || retired.State != "retired" {
+				t.Fatalf("Expected the key to be retired, got %d %+v", status, retired)
+			}
+			for name, sig := range map[string]string{"old signature": before.Signature, "old token": before.Token, "bare signature": bare} {
+				if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, signatureHeaders(sig)); status != http.StatusUnauthorized {
+					t.Errorf("%s: expected status 401 after retiring, got %d", name, status)
+				}
+			}
+			if _, err := SignedAuthHeaders(TestFrontendKey, user); err != nil {
+				t.Errorf("Expected signing to keep working with the active key: %v", err)
+			}
+		})
+
+		t.Run("Reactivate", func(t *testing.T) {
+			status, active := keyRequest("PUT", keysURL+"/"+configID, map[string]string{"state": "active"})
+			if status != http.StatusOK || active.State != "active" {
+				t.Fatalf("Expected the key to be active again, got %d %+v", status, active)
+			}
+			if got := listKeys()["2026-10"].State; got != "verify" {
+				t.Errorf("Expected the rotated key to be demoted to verify, got %q", got)
+			}
+			if status := requestStatus(t, "GET", EndpointFrontendThreads, nil, signatureHeaders(before.Signature)); status != http.StatusOK {
+				t.Errorf("Expected the old signature to verify again, got %d", status)
+			}
+		})
+	})
+}
@@ -3,6 +3,7 @@ package tests
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,6 +14,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
@@ -44,17 +46,22 @@ type ServerOpts struct {
 	Env map[string]string
 	// Optional binary to run, empty triggers build.
 	BinaryPath string
+	// If set, the server is reached over https with this client config.
+	ClientTLS *tls.Config
 }
 
 // Running server process and paths.
 type ServerProcess struct {
-	Addr       string // http://host:port
+	Addr       string // http://host:port, or https:// with ClientTLS
+	Client     *http.Client
 	Cmd        *exec.Cmd
 	StdoutPath string
 	StderrPath string
 	ConfigPath string
 	WorkDir    string
 	exitCh     chan error
+	stopOnce   sync.Once
+	stopErr    error
 }
 
 // Test server process wrapper
@@ -261,8 +268,13 @@ func StartServerProcess(t *testing.T, opts ServerOpts) *ServerProcess {
 	}
 	t.Logf("Server process started with PID: %d", cmd.Process.Pid)
 
+	scheme, client := "http", http.DefaultClient
+	if opts.ClientTLS != nil {
+		scheme, client = "https", &http.Client{Transport: &http.Transport{TLSClientConfig: opts.ClientTLS}}
+	}
 	sp := &ServerProcess{
-		Addr:       fmt.Sprintf("http://127.0.0.1:%d", port),
+		Addr:       fmt.Sprintf("%s://127.0.0.1:%d", scheme, port),
+		Client:     client,
 		Cmd:        cmd,
 		StdoutPath: stdoutPath,
 		StderrPath: stderrPath,
@@ -297,8 +309,11 @@ func StartServerProcess(t *testing.T, opts ServerOpts) *ServerProcess {
 		}
 	}(cmd, sp, stdoutF, stderrF)
 
+	// Stop the server before its workdir is removed, even when the test did not.
+	t.Cleanup(func() { _ = sp.Stop(nil) })
+
 	// Wait for ready (up to 1 minute).
-	if err := waitForReady(sp.Addr, 1*time.Minute); err != nil {
+	if err := waitForReady(sp.Addr, sp.Client, 1*time.Minute); err != nil {
 		// Capture logs.
 		stdout, _ := os.ReadFile(sp.StdoutPath)
 		stderr, _ := os.ReadFile(sp.StderrPath)
@@ -310,7 +325,7 @@ func StartServerProcess(t *testing.T, opts ServerOpts) *ServerProcess {
 	for i := 0; i < 10; i++ {
 		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
 		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, sp.Addr+"/healthz", nil)
-		resp, err := http.DefaultClient.Do(req)
+		resp, err := sp.Client.Do(req)
 		cancel()
 		if err == nil && resp != nil {
 			if resp.StatusCode == 200 {
@@ -345,7 +360,8 @@ func StartServerProcess(t *testing.T, opts ServerOpts) *ServerProcess {
 	return sp
 }
 
-// Stops process, returns exit error. Tries SIGINT, falls back to SIGKILL.
+// Stops process, returns exit error. Tries SIGINT, falls back to SIGKILL. Later calls
+// return the first result.
 func (s *ServerProcess) Stop(t *testing.T) error {
 	if t != nil {
 		t.Helper()
@@ -353,6 +369,11 @@ func (s *ServerProcess) Stop(t *testing.T) error {
 	if s == nil || s.Cmd == nil || s.Cmd.Process == nil {
 		return nil
 	}
+	s.stopOnce.Do(func() { s.stopErr = s.stop() })
+	return s.stopErr
+}
+
+func (s *ServerProcess) stop() error {
 	// Send SIGINT.
 	_ = s.Cmd.Process.Signal(syscall.SIGINT)
 	// Wait for monitored exit, fallback to kill on timeout.
@@ -366,8 +387,10 @@ func (s *ServerProcess) Stop(t *testing.T) error {
 		}
 		return err
 	case <-time.After(5 * time.Second):
-		// Force kill.
+		// Force kill, and wait for the process to go so nothing writes to the workdir
+		// while it is removed.
 		_ = s.Cmd.Process.Kill()
+		<-s.exitCh
 		// Record forced kill.
 		if s != nil && s.StderrPath != "" {
 			if f, ferr := os.OpenFile(s.StderrPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600); ferr == nil {
@@ -389,13 +412,13 @@ func pickFreePort() (int, error) {
 	return a.Port, nil
 }
 
-func waitForReady(addr string, timeout time.Duration) error {
+func waitForReady(addr string, client *http.Client, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 	url := addr + "/readyz"
 	for time.Now().Before(deadline) {
 		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-		resp, err := http.DefaultClient.Do(req)
+		resp, err := client.Do(req)
 		cancel()
 		if err == nil {
 			if resp.StatusCode == 200 {
@@ -418,10 +441,9 @@ func envMapToSlice(m map[string]string) []string {
 	return out
 }
 
-// StartTestServer starts a real ProgressDB server process for testing
-func StartTestServer(t *testing.T) *TestServer {
-	t.Helper()
-
+// TestConfigYAML returns the shared test config with serverYAML (indented lines) added to
+// the server section and extraYAML appended top-level.
+func TestConfigYAML(serverYAML, extraYAML string) string {
 	cfg := fmt.Sprintf(`server:
   address: 127.0.0.1
   port: {{PORT}}
@@ -434,7 +456,7 @@ func StartTestServer(t *testing.T) *TestServer {
   rate_limit:
     rps: 1000
     burst: 1000
-logging:
+%slogging:
   level: debug
 encryption:
   enabled: true
@@ -459,8 +481,31 @@ sensor:
   disk_high_pct: 99
   mem_high_pct: 99
   cpu_high_pct: 99
-  recovery_window: 10s`, TestBackendKey, TestFrontendKey, TestAdminKey, TestSigningKey)
+  recovery_window: 10s`, TestBackendKey, TestFrontendKey, TestAdminKey, TestSigningKey, serverYAML)
+	if extraYAML != "" {
+		cfg += "\n" + extraYAML
+	}
+	return cfg
+}
+
+// StartTestServer starts a real ProgressDB server process for testing
+func StartTestServer(t *testing.T) *TestServer {
+	t.Helper()
+	return StartTestServerWithConfig(t, "")
+}
 
+// StartTestServerWithConfig starts a test server with extra top-level YAML appended to the shared config
+func StartTestServerWithConfig(t *testing.T, extraYAML string) *TestServer {
+	t.Helper()
+	return StartTestServerWithServerConfig(t, "", extraYAML)
+}
+
+// StartTestServerWithServerConfig starts a test server with serverYAML added to the server
+// section and extraYAML appended top-level
+func StartTestServerWithServerConfig(t *testing.T, serverYAML, extraYAML string) *TestServer {
+	t.Helper()
+
+	cfg := TestConfigYAML(serverYAML, extraYAML)
 	process := StartServerProcess(t, ServerOpts{ConfigYAML: cfg})
 
 	// Update test endpoints with actual server address
@@ -558,6 +603,32 @@ func WithTestServer(t *testing.T, testFunc func()) {
 	testFunc()
 }
 
+// WithTestServerConfig runs a test with a real server using extra top-level YAML config
+func WithTestServerConfig(t *testing.T, extraYAML string, testFunc func()) {
+	server := StartTestServerWithConfig(t, extraYAML)
+	defer server.Stop()
+	testFunc()
+}
+
+// WithTestServerServerConfig runs a test with a real server using extra server-section YAML
+// (indented lines) and extra top-level YAML
+func WithTestServerServerConfig(t *testing.T, serverYAML, extraYAML string, testFunc func()) {
+	server := StartTestServerWithServerConfig(t, serverYAML, extraYAML)
+	defer server.Stop()
+	testFunc()
+}
+
+// requestStatus sends a request and returns its status code, failing the test on transport errors
+func requestStatus(t *testing.T, method, url string, body []byte, headers map[string]string) int {
+	t.Helper()
+	resp, err := DoRequest(t, method, url, body, headers)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
 // Utility functions
 func SplitPath(p string) []string {
 	out := make([]string, 0)
@@ -0,0 +1,234 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestThreadRoles_Suite(t *testing.T) {
+	WithTestServer(t, func() {
+		owner := "roles_owner"
+		reader := "roles_reader"
+		admin := "roles_admin"
+
+		ownerHeaders, err := SignedAuthHeaders(TestFrontendKey, owner)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		readerHeaders, err := SignedAuthHeaders(TestFrontendKey, reader)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		adminHeaders, err := SignedAuthHeaders(TestFrontendKey, admin)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+
+		threadKey := createTestThreads(t, ownerHeaders, owner, 1)[0]
+		participantsURL := EndpointFrontendThreads + "/" + threadKey + "/participants"
+
+		setRole := func(headers map[string]string, userID, role string) int {
+			body, _ := json.Marshal(map[string]string{"role": role})
+			resp, err := DoRequest(t, "PUT", participantsURL+"/"+userID, body, headers)
+			if err != nil {
+				t.Fatalf("set role request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			return resp.StatusCode
+		}
+
+		roleOf := func(userID string) string {
+			resp, err := DoRequest(t, "GET", participantsURL, nil, ownerHeaders)
+			if err != nil {
+				t.Fatalf("list participants request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out struct {
+				Participants []struct {
+					UserID string `json:"user_id"`
+					Role   string `json:"role"`
+				} `json:"participants"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			for _, p := range out.Participants {
+				if p.UserID == userID {
+					return p.Role
+				}
+			}
+			return ""
+		}
+
+		updateTitle := func(headers map[string]string, title string) int {
+			body, _ := json.Marshal(map[string]string{"title": title})
+			resp, err := DoRequest(t, "PUT", EndpointFrontendThreads+"/"+threadKey, body, headers)
+			if err != nil {
+				t.Fatalf("update thread request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			return resp.StatusCode
+		}
+
+		titleOf := func() string {
+			resp, err := DoRequest(t, "GET", EndpointFrontendThreads+"/"+threadKey, nil, ownerHeaders)
+			if err != nil {
+				t.Fatalf("get thread request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			raw, _ := io.ReadAll(resp.Body)
+			var out ThreadResponse
+			_ = json.Unmarshal(raw, &out)
+			return out.Thread.Title
+		}
+
+		t.Run("OwnerCanUpdateTitle", func(t *testing.T) {
+			if status := updateTitle(ownerHeaders, "renamed by owner"); status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+			Retry(t, 10, 500*time.Millisecond, func() bool { return titleOf() == "renamed by owner" })
+		})
+
+		t.Run("NonParticipantDenied", func(t *testing.T) {
+			resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey), nil, readerHeaders)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusForbidden {
+				t.Errorf("Expected status 403, got %d", resp.StatusCode)
+			}
+		})
+
+		t.Run("InvalidRoleRejected", func(t *testing.T) {
+			if status := setRole(ownerHeaders, reader, "owner"); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", status)
+			}
+		})
+
+		t.Run("ReadOnlyCanReadButNotPost", func(t *testing.T) {
+			if status := setRole(ownerHeaders, reader, "read_only"); status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+			Retry(t, 10, 500*time.Millisecond, func() bool { return roleOf(reader) == "read_only" })
+
+			resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey), nil, readerHeaders)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+			}
+
+			body, _ := json.Marshal(map[string]interface{}{"body": map[string]string{"content": "not allowed"}})
+			resp, err = DoRequest(t, "POST", ThreadMessagesURL(threadKey), body, readerHeaders)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			// writes are rejected at apply time, so nothing lands in the thread
+			time.Sleep(2 * time.Second)
+			resp, err = DoRequest(t, "GET", ThreadMessagesURL(threadKey), nil, ownerHeaders)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var list MessagesListResponse
+			if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(list.Messages) != 0 {
+				t.Errorf("Expected read-only post to be rejected, got %d messages", len(list.Messages))
+			}
+		})
+
+		t.Run("ReadOnlyCannotUpdateTitle", func(t *testing.T) {
+			updateTitle(readerHeaders, "renamed by reader")
+
+			// rejected at apply time
+			time.Sleep(2 * time.Second)
+			if title := titleOf(); title != "renamed by owner" {
+				t.Errorf("Expected title to be unchanged, got %q", title)
+			}
+		})
+
+		t.Run("ReadOnlyCannotManageParticipants", func(t *testing.T) {
+			setRole(readerHeaders, admin, "member")
+			time.Sleep(2 * time.Second)
+			if role := roleOf(admin); role != "" {
+				t.Errorf("Expected no role for %s, got %q", admin, role)
+			}
+		})
+
+		t.Run("AdminCanUpdateTitle", func(t *testing.T) {
+			if status := setRole(ownerHeaders, admin, "admin"); status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+			Retry(t, 10, 500*time.Millisecond, func() bool { return roleOf(admin) == "admin" })
+
+			if status := updateTitle(adminHeaders, "renamed by admin"); status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+			Retry(t, 10, 500*time.Millisecond, func() bool { return titleOf() == "renamed by admin" })
+		})
+
+		t.Run("MemberCannotUpdateTitle", func(t *testing.T) {
+			if status := setRole(adminHeaders, reader, "member"); status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+			Retry(t, 10, 500*time.Millisecond, func() bool { return roleOf(reader) == "member" })
+
+			updateTitle(readerHeaders, "renamed by member")
+
+			time.Sleep(2 * time.Second)
+			if title := titleOf(); title != "renamed by admin" {
+				t.Errorf("Expected title to be unchanged, got %q", title)
+			}
+		})
+
+		t.Run("MessageEditThroughAnotherThreadRejected", func(t *testing.T) {
+			send := func(method, url string, body []byte, headers map[string]string) int {
+				resp, err := DoRequest(t, method, url, body, headers)
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				defer resp.Body.Close()
+				return resp.StatusCode
+			}
+
+			body, _ := json.Marshal(map[string]interface{}{"body": map[string]string{"content": "owner message"}})
+			if status := send("POST", ThreadMessagesURL(threadKey), body, ownerHeaders); status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+			var messageKey string
+			Retry(t, 10, 500*time.Millisecond, func() bool {
+				resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey), nil, ownerHeaders)
+				if err != nil {
+					return false
+				}
+				defer resp.Body.Close()
+				var list MessagesListResponse
+				_ = json.NewDecoder(resp.Body).Decode(&list)
+				if len(list.Messages) == 0 {
+					return false
+				}
+				messageKey = list.Messages[0].Key
+				return true
+			})
+
+			// the reader owns its own thread, but the message lives in the owner's
+			readerThread := createTestThreads(t, readerHeaders, reader, 1)[0]
+			messageURL := ThreadMessagesURL(readerThread) + "/" + messageKey
+			update, _ := json.Marshal(map[string]interface{}{"body": map[string]string{"content": "hijacked"}})
+			if status := send("PUT", messageURL, update, readerHeaders); status != http.StatusNotFound {
+				t.Errorf("Expected status 404 for update, got %d", status)
+			}
+			if status := send("DELETE", messageURL, nil, readerHeaders); status != http.StatusNotFound {
+				t.Errorf("Expected status 404 for delete, got %d", status)
+			}
+		})
+	})
+}
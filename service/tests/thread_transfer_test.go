@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"progressdb/pkg/models"
+)
+
+func TestThreadTransfer_Suite(t *testing.T) {
+	WithTestServer(t, func() {
+		adminURL := strings.TrimSuffix(EndpointAdminHealth, "/health")
+		admin := AuthHeaders(TestAdminKey)
+		leaver, successor, archivist := "transfer_leaver", "transfer_successor", "transfer_archivist"
+
+		leaverHeaders, err := SignedAuthHeaders(TestFrontendKey, leaver)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+		successorHeaders, err := SignedAuthHeaders(TestFrontendKey, successor)
+		if err != nil {
+			t.Fatalf("Failed to get signed auth headers: %v", err)
+		}
+
+		transfer := func(path string, body map[string]interface{}) int {
+			payload, _ := json.Marshal(body)
+			resp, err := DoRequest(t, "POST", adminURL+path, payload, admin)
+			if err != nil {
+				t.Fatalf("transfer request failed: %v", err)
+			}
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+		authorOf := func(threadKey string) string {
+			resp, err := DoRequest(t, "GET", adminURL+"/keys/"+url.PathEscape(threadKey)+"?store=main", nil, admin)
+			if err != nil {
+				t.Fatalf("get key failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var thread models.Thread
+			_ = json.NewDecoder(resp.Body).Decode(&thread)
+			return thread.Author
+		}
+		roleOf := func(threadKey, userID string, headers map[string]string) string {
+			resp, err := DoRequest(t, "GET", EndpointFrontendThreads+"/"+threadKey+"/participants", nil, headers)
+			if err != nil {
+				t.Fatalf("list participants request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out struct {
+				Participants []struct {
+					UserID string `json:"user_id"`
+					Role   string `json:"role"`
+				} `json:"participants"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			for _, p := range out.Participants {
+				if p.UserID == userID {
+					return p.Role
+				}
+			}
+			return ""
+		}
+		listsThread := func(headers map[string]string, threadKey string) bool {
+			resp, err := DoRequest(t, "GET", EndpointFrontendThreads, nil, headers)
+			if err != nil {
+				t.Fatalf("list threads request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var out ThreadsListResponse
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			for _, th := range out.Threads {
+				if th.Key == threadKey {
+					return true
+				}
+			}
+			return false
+		}
+
+		threadKeys := createTestThreads(t, leaverHeaders, leaver, 3)
+		single := threadKeys[0]
+
+		t.Run("Validation", func(t *testing.T) {
+			if status := transfer("/threads/"+single+"/transfer", map[string]interface{}{"to": leaver}); status != http.StatusConflict {
+				t.Errorf("Expected status 409 for current owner, got %d", status)
+			}
+			if status := transfer("/threads/"+single+"/transfer", map[string]interface{}{"to": successor, "keep_previous_owner": true, "previous_owner_role": "owner"}); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for owner role, got %d", status)
+			}
+			if status := transfer("/threads/"+single+"/transfer", map[string]interface{}{"to": successor, "previous_owner_role": "admin"}); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for role without keep, got %d", status)
+			}
+			if status := transfer("/threads/t:1/transfer", map[string]interface{}{"to": successor}); status != http.StatusNotFound {
+				t.Errorf("Expected status 404 for unknown thread, got %d", status)
+			}
+		})
+
+		t.Run("SingleThreadKeepsPreviousOwner", func(t *testing.T) {
+			if status := transfer("/threads/"+single+"/transfer", map[string]interface{}{"to": successor, "keep_previous_owner": true, "previous_owner_role": "admin"}); status != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", status)
+			}
+			Retry(t, 10, 500*time.Millisecond, func() bool { return authorOf(single) == successor })
+
+			if role := roleOf(single, successor, successorHeaders); role != "owner" {
+				t.Errorf("Expected successor to be owner, got %q", role)
+			}
+			if role := roleOf(single, leaver, successorHeaders); role != "admin" {
+				t.Errorf("Expected previous owner to stay as admin, got %q", role)
+			}
+			if !listsThread(successorHeaders, single) {
+				t.Errorf("Expected thread in successor's thread list")
+			}
+			if listsThread(leaverHeaders, single) {
+				t.Errorf("Expected thread to leave the previous owner's thread list")
+			}
+		})
+
+		var direct string
+		t.Run("DirectThreadRefused", func(t *testing.T) {
+			payload, _ := json.Marshal(map[string]interface{}{"participants": []string{successor}})
+			resp, err := DoRequest(t, "POST", EndpointFrontendThreads+"/dm", payload, leaverHeaders)
+			if err != nil {
+				t.Fatalf("dm request failed: %v", err)
+			}
+			var dm directThreadResponse
+			_ = json.NewDecoder(resp.Body).Decode(&dm)
+			resp.Body.Close()
+			if dm.Key == "" {
+				t.Fatalf("Expected a direct thread key, got %d", resp.StatusCode)
+			}
+			direct = dm.Key
+			Retry(t, 10, 500*time.Millisecond, func() bool { return authorOf(direct) == leaver })
+
+			if status := transfer("/threads/"+direct+"/transfer", map[string]interface{}{"to": archivist}); status != http.StatusConflict {
+				t.Errorf("Expected status 409 for a direct thread, got %d", status)
+			}
+		})
+
+		t.Run("BulkTransferMovesRemainingThreads", func(t *testing.T) {
+			payload, _ := json.Marshal(map[string]interface{}{"to": archivist})
+			resp, err := DoRequest(t, "POST", adminURL+"/users/"+leaver+"/threads/transfer", payload, admin)
+			if err != nil {
+				t.Fatalf("bulk transfer request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+			}
+			var out struct {
+				Threads []string `json:"threads"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+			if len(out.Threads) != 2 {
+				t.Fatalf("Expected 2 threads queued, got %v", out.Threads)
+			}
+
+			for _, threadKey := range threadKeys[1:] {
+				threadKey := threadKey
+				Retry(t, 10, 500*time.Millisecond, func() bool { return authorOf(threadKey) == archivist })
+
+				resp, err := DoRequest(t, "GET", ThreadMessagesURL(threadKey), nil, leaverHeaders)
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusForbidden {
+					t.Errorf("Expected previous owner to lose access, got %d", resp.StatusCode)
+				}
+			}
+			if authorOf(single) != successor {
+				t.Errorf("Bulk transfer touched a thread the user no longer owns")
+			}
+			if direct != "" && authorOf(direct) != leaver {
+				t.Errorf("Bulk transfer moved a direct thread")
+			}
+		})
+	})
+}
@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for the tls tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ca key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create ca: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for name, usable by a server or a client.
+func (ca *testCA) issue(t *testing.T, name string) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("issue certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (ca *testCA) clientCert(t *testing.T, name string) tls.Certificate {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, name)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("load client certificate: %v", err)
+	}
+	return cert
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestTLS_Suite(t *testing.T) {
+	ca := newTestCA(t, "progressdb test ca")
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem"), filepath.Join(dir, "ca.pem")
+	certPEM, keyPEM := ca.issue(t, "progressdb")
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	writeFile(t, caFile, ca.pem)
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	serverYAML := fmt.Sprintf("  tls:\n    cert_file: %s\n    key_file: %s\n    reload_interval: 1s\n    client_ca_file: %s\n    client_roles:\n      admin: [\"ops-admin\"]\n      backend: [\"billing-service\"]\n", certFile, keyFile, caFile)
+	server := &TestServer{ServerProcess: StartServerProcess(t, ServerOpts{
+		ConfigYAML: TestConfigYAML(serverYAML, ""),
+		ClientTLS:  &tls.Config{RootCAs: roots},
+	})}
+	defer server.Stop()
+	baseURL := server.Addr
+
+	// each call dials a fresh connection so certificates are presented anew
+	request := func(t *testing.T, method, path string, body []byte, headers map[string]string, certs ...tls.Certificate) (*http.Response, error) {
+		t.Helper()
+		tlsCfg := &tls.Config{RootCAs: roots}
+		if len(certs) > 0 {
+			// present the certificate even when the server does not ask for its issuer
+			tlsCfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return &certs[0], nil
+			}
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg, DisableKeepAlives: true}}
+		req, err := http.NewRequest(method, baseURL+path, strings.NewReader(string(body)))
+		if err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return client.Do(req)
+	}
+	status := func(t *testing.T, method, path string, body []byte, headers map[string]string, certs ...tls.Certificate) int {
+		t.Helper()
+		resp, err := request(t, method, path, body, headers, certs...)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("APIKeysOverTLS", func(t *testing.T) {
+		if got := status(t, "GET", "/healthz", nil, nil); got != http.StatusOK {
+			t.Errorf("Expected status 200 for healthz, got %d", got)
+		}
+		if got := status(t, "GET", "/admin/health", nil, AuthHeaders(TestAdminKey)); got != http.StatusOK {
+			t.Errorf("Expected status 200 with an admin key, got %d", got)
+		}
+		if got := status(t, "GET", "/admin/health", nil, nil); got != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 without key or certificate, got %d", got)
+		}
+	})
+
+	t.Run("ClientCertificateRoles", func(t *testing.T) {
+		backend := ca.clientCert(t, "billing-service")
+		if got := status(t, "POST", "/backend/v1/sign", []byte(`{"userId":"tls_user"}`), nil, backend); got != http.StatusOK {
+			t.Errorf("Expected status 200 signing with a backend certificate, got %d", got)
+		}
+		if got := status(t, "GET", "/admin/health", nil, nil, backend); got != http.StatusForbidden {
+			t.Errorf("Expected status 403 for a backend certificate on admin paths, got %d", got)
+		}
+
+		admin := ca.clientCert(t, "ops-admin")
+		if got := status(t, "GET", "/admin/health", nil, nil, admin); got != http.StatusOK {
+			t.Errorf("Expected status 200 with an admin certificate, got %d", got)
+		}
+
+		stranger := ca.clientCert(t, "stranger")
+		if got := status(t, "GET", "/admin/health", nil, nil, stranger); got != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 for an unmapped certificate, got %d", got)
+		}
+	})
+
+	t.Run("UntrustedClientCertificate", func(t *testing.T) {
+		other := newTestCA(t, "other ca").clientCert(t, "ops-admin")
+		resp, err := request(t, "GET", "/admin/health", nil, nil, other)
+		if err == nil {
+			resp.Body.Close()
+			t.Fatalf("Expected the handshake to fail for a certificate from another ca, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("CertificateReload", func(t *testing.T) {
+		certPEM, keyPEM := ca.issue(t, "progressdb-rotated")
+		writeFile(t, keyFile, keyPEM)
+		writeFile(t, certFile, certPEM)
+		Retry(t, 20, 250*time.Millisecond, func() bool {
+			resp, err := request(t, "GET", "/healthz", nil, nil)
+			if err != nil {
+				return false
+			}
+			resp.Body.Close()
+			return resp.TLS != nil && resp.TLS.PeerCertificates[0].Subject.CommonName == "progressdb-rotated"
+		})
+	})
+}